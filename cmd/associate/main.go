@@ -8,11 +8,21 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/Thomas-Fitz/associate/internal/embedding"
 	"github.com/Thomas-Fitz/associate/internal/graph"
 	mcpserver "github.com/Thomas-Fitz/associate/internal/mcp"
+	"github.com/Thomas-Fitz/associate/internal/sqlitestore"
+	"github.com/Thomas-Fitz/associate/internal/store"
+)
+
+// Storage backends selectable with -backend or ASSOCIATE_BACKEND
+const (
+	backendAGE    = "age"
+	backendSQLite = "sqlite"
 )
 
 func main() {
@@ -20,6 +30,7 @@ func main() {
 	httpMode := flag.Bool("http", false, "Run as HTTP server (default: stdio for MCP)")
 	port := flag.Int("port", 8080, "HTTP port to listen on (only used with -http)")
 	waitForDB := flag.Bool("wait", true, "Wait for PostgreSQL/AGE to be available (with retries)")
+	backend := flag.String("backend", envOrDefault("ASSOCIATE_BACKEND", backendAGE), "Storage backend: age (PostgreSQL/AGE) or sqlite (local file at SQLITE_PATH)")
 	flag.Parse()
 
 	// Setup logger
@@ -39,34 +50,104 @@ func main() {
 		cancel()
 	}()
 
-	// Connect to PostgreSQL/AGE
-	cfg := graph.ConfigFromEnv()
-	logger.Info("connecting to PostgreSQL/AGE", "host", cfg.Host, "port", cfg.Port, "database", cfg.Database)
+	if flag.Arg(0) == "migrate" {
+		if *backend != backendAGE {
+			logger.Error("schema migrations are only supported for the age backend", "backend", *backend)
+			os.Exit(1)
+		}
+		if err := runMigrate(ctx, flag.Args()[1:]); err != nil {
+			logger.Error("migrate failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	var client *graph.Client
-	var err error
+	embedder, err := embedding.FromEnv()
+	if err != nil {
+		logger.Error("invalid embedding configuration", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("using embedding model", "model", embedder.Model())
 
-	if *waitForDB {
-		// Use retry logic - useful when starting before PostgreSQL is ready
-		logger.Info("waiting for PostgreSQL/AGE to be available...")
-		client, err = graph.NewClientWithRetry(ctx, cfg, nil)
-	} else {
-		// Direct connection - fails immediately if PostgreSQL unavailable
-		client, err = graph.NewClient(ctx, cfg)
+	trashRetention, err := time.ParseDuration(envOrDefault("TRASH_RETENTION", "720h"))
+	if err != nil {
+		logger.Error("invalid TRASH_RETENTION", "error", err)
+		os.Exit(1)
 	}
+	statusRules, err := strconv.ParseBool(envOrDefault("TASK_STATUS_RULES", "false"))
 	if err != nil {
-		logger.Error("failed to connect to PostgreSQL/AGE", "error", err)
+		logger.Error("invalid TASK_STATUS_RULES", "error", err)
 		os.Exit(1)
 	}
-	defer client.Close(ctx)
+	planCompletion, err := strconv.ParseBool(envOrDefault("PLAN_AUTO_COMPLETE", "false"))
+	if err != nil {
+		logger.Error("invalid PLAN_AUTO_COMPLETE", "error", err)
+		os.Exit(1)
+	}
+
+	var server *mcpserver.Server
+	switch *backend {
+	case backendAGE:
+		// Connect to PostgreSQL/AGE
+		cfg := graph.ConfigFromEnv()
+		logger.Info("connecting to PostgreSQL/AGE", "host", cfg.Host, "port", cfg.Port, "database", cfg.Database)
 
-	logger.Info("connected to PostgreSQL/AGE")
+		var client *graph.Client
 
-	// Create repositories and MCP server
-	repo := graph.NewRepository(client)
-	planRepo := graph.NewPlanRepository(client)
-	taskRepo := graph.NewTaskRepository(client)
-	server := mcpserver.NewServer(repo, planRepo, taskRepo, logger)
+		if *waitForDB {
+			// Use retry logic - useful when starting before PostgreSQL is ready
+			logger.Info("waiting for PostgreSQL/AGE to be available...")
+			client, err = graph.NewClientWithRetry(ctx, cfg, nil)
+		} else {
+			// Direct connection - fails immediately if PostgreSQL unavailable
+			client, err = graph.NewClient(ctx, cfg)
+		}
+		if err != nil {
+			logger.Error("failed to connect to PostgreSQL/AGE", "error", err)
+			os.Exit(1)
+		}
+		defer client.Close(ctx)
+		client.SetEmbedder(embedder)
+		client.SetLogger(logger)
+
+		logger.Info("connected to PostgreSQL/AGE")
+
+		// Create repositories and MCP server
+		repo := graph.NewRepository(client)
+		planRepo := graph.NewPlanRepository(client)
+		taskRepo := graph.NewTaskRepository(client)
+		taskRepo.SetStatusRules(statusRules)
+		taskRepo.SetPlanCompletion(planCompletion)
+		server = mcpserver.NewServer(repo, planRepo, taskRepo, logger)
+		go purgeTrash(ctx, repo, trashRetention, logger)
+		go backfillEmbeddings(ctx, client, logger)
+
+	case backendSQLite:
+		cfg := sqlitestore.ConfigFromEnv()
+		logger.Info("opening SQLite database", "path", cfg.Path)
+
+		db, err := sqlitestore.Open(ctx, cfg)
+		if err != nil {
+			logger.Error("failed to open SQLite database", "error", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+		db.SetEmbedder(embedder)
+		db.SetLogger(logger)
+
+		repo := sqlitestore.NewRepository(db)
+		planRepo := sqlitestore.NewPlanRepository(db)
+		taskRepo := sqlitestore.NewTaskRepository(db)
+		taskRepo.SetStatusRules(statusRules)
+		taskRepo.SetPlanCompletion(planCompletion)
+		server = mcpserver.NewServer(repo, planRepo, taskRepo, logger)
+		go purgeTrash(ctx, repo, trashRetention, logger)
+		go backfillEmbeddings(ctx, db, logger)
+
+	default:
+		logger.Error("unknown storage backend", "backend", *backend)
+		os.Exit(1)
+	}
 
 	if *httpMode {
 		// Run as HTTP server
@@ -100,3 +181,126 @@ func main() {
 
 	logger.Info("server stopped")
 }
+
+// trashPurgeInterval is how often purgeTrash looks for expired trash entries.
+const trashPurgeInterval = time.Hour
+
+// purgeTrash permanently removes trash entries older than retention, at
+// startup and then every trashPurgeInterval, until ctx is done. A retention
+// of zero or less keeps the trash until it is restored.
+func purgeTrash(ctx context.Context, repo store.MemoryStore, retention time.Duration, logger *slog.Logger) {
+	if retention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+	for {
+		purged, err := repo.PurgeTrash(ctx, time.Now().Add(-retention))
+		if err != nil {
+			logger.Error("failed to purge trash", "error", err)
+		} else if purged > 0 {
+			logger.Info("purged trash", "entries", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Embedding backfill settings: how many nodes each call embeds, and how often
+// backfillEmbeddings looks for nodes without a vector.
+const (
+	embeddingBackfillBatch    = 100
+	embeddingBackfillInterval = 15 * time.Minute
+)
+
+// embeddingBackfiller embeds the nodes that have no vector for the current
+// embedding model.
+type embeddingBackfiller interface {
+	BackfillEmbeddings(ctx context.Context, limit int) (int, error)
+}
+
+// backfillEmbeddings embeds the nodes that have no vector for the current
+// model, in batches, at startup and then every embeddingBackfillInterval,
+// until ctx is done. Semantic search skips those nodes until then. A failing
+// embedder ends the run; the next one retries.
+func backfillEmbeddings(ctx context.Context, b embeddingBackfiller, logger *slog.Logger) {
+	ticker := time.NewTicker(embeddingBackfillInterval)
+	defer ticker.Stop()
+	for {
+		total := 0
+		for {
+			n, err := b.BackfillEmbeddings(ctx, embeddingBackfillBatch)
+			total += n
+			if err != nil {
+				logger.Warn("failed to backfill embeddings", "embedded", total, "error", err)
+				break
+			}
+			if n < embeddingBackfillBatch {
+				break
+			}
+		}
+		if total > 0 {
+			logger.Info("backfilled embeddings", "nodes", total)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runMigrate implements the "migrate up" and "migrate status" subcommands.
+func runMigrate(ctx context.Context, args []string) error {
+	if len(args) != 1 || (args[0] != "up" && args[0] != "status") {
+		return fmt.Errorf("usage: associate migrate up|status")
+	}
+
+	client, err := graph.Connect(ctx, graph.ConfigFromEnv())
+	if err != nil {
+		return err
+	}
+	defer client.Close(ctx)
+
+	if args[0] == "up" {
+		applied, err := client.Migrate(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s), schema is at version %d\n", applied, graph.LatestSchemaVersion())
+		return nil
+	}
+
+	current, err := client.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	statuses, err := client.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("schema version: %d (latest known: %d)\n", current, graph.LatestSchemaVersion())
+	for _, st := range statuses {
+		applied := "pending"
+		if st.AppliedAt != nil {
+			applied = st.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%4d  %-40s %s\n", st.Version, st.Name, applied)
+	}
+	if current > graph.LatestSchemaVersion() {
+		fmt.Println("warning: database has migrations this binary does not know about")
+	}
+	return nil
+}
+
+func envOrDefault(key, defaultVal string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return defaultVal
+}
@@ -0,0 +1,121 @@
+// Package embedding turns text into vectors for semantic search. Providers
+// implement Embedder; the backends store one vector per node alongside the
+// name of the model that produced it, and only compare vectors of one model.
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Embedder computes embedding vectors for text.
+type Embedder interface {
+	// Embed returns the embedding of text.
+	Embed(ctx context.Context, text string) ([]float32, error)
+	// Model identifies the embedding space. Vectors from different models
+	// are never compared.
+	Model() string
+}
+
+// Provider names selectable with EMBEDDING_PROVIDER
+const (
+	ProviderHash = "hash"
+	ProviderHTTP = "http"
+)
+
+// FromEnv creates the embedder selected by environment variables:
+// EMBEDDING_PROVIDER (hash or http, default hash), EMBEDDING_DIMENSIONS for
+// the hash embedder, and EMBEDDING_URL, EMBEDDING_MODEL and EMBEDDING_API_KEY
+// for the HTTP embedder.
+func FromEnv() (Embedder, error) {
+	switch provider := getEnvOrDefault("EMBEDDING_PROVIDER", ProviderHash); provider {
+	case ProviderHash:
+		dims := DefaultDimensions
+		if v := os.Getenv("EMBEDDING_DIMENSIONS"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid EMBEDDING_DIMENSIONS: %q", v)
+			}
+			dims = n
+		}
+		return NewHashEmbedder(dims), nil
+	case ProviderHTTP:
+		url := os.Getenv("EMBEDDING_URL")
+		if url == "" {
+			return nil, fmt.Errorf("EMBEDDING_URL is required for the http embedding provider")
+		}
+		return NewHTTPEmbedder(HTTPConfig{
+			URL:     url,
+			Model:   os.Getenv("EMBEDDING_MODEL"),
+			APIKey:  os.Getenv("EMBEDDING_API_KEY"),
+			Timeout: 30 * time.Second,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider: %q", provider)
+	}
+}
+
+func getEnvOrDefault(key, defaultVal string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return defaultVal
+}
+
+// Cosine returns the cosine similarity of two vectors, or 0 if their lengths
+// differ or either is zero.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// Normalize scales v to unit length in place, so that cosine similarity is a
+// plain dot product. Zero vectors are left unchanged.
+func Normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+	return v
+}
+
+// TryEmbed returns the embedding of text, or nil after logging a warning to
+// logger if the provider fails. Nodes are still saved without a vector in that
+// case, and semantic search embeds them when it next runs.
+func TryEmbed(ctx context.Context, e Embedder, logger *slog.Logger, text string) []float32 {
+	v, err := e.Embed(ctx, text)
+	if err != nil {
+		logger.Warn("failed to compute embedding", "model", e.Model(), "error", err)
+		return nil
+	}
+	return v
+}
+
+// PlanText returns the text embedded for a plan: its name and description.
+func PlanText(name, description string) string {
+	return strings.TrimSpace(name + "\n" + description)
+}
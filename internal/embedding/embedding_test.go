@@ -0,0 +1,151 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHashEmbedder_Deterministic(t *testing.T) {
+	e := NewHashEmbedder(64)
+	ctx := context.Background()
+
+	a, err := e.Embed(ctx, "Rotate the database credentials")
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	b, _ := e.Embed(ctx, "Rotate the database credentials")
+	if len(a) != 64 {
+		t.Fatalf("len = %d, want 64", len(a))
+	}
+	if got := Cosine(a, b); math.Abs(got-1) > 1e-6 {
+		t.Errorf("same text cosine = %v, want 1", got)
+	}
+	if e.Model() != "hash-64" {
+		t.Errorf("Model() = %q", e.Model())
+	}
+}
+
+func TestHashEmbedder_Similarity(t *testing.T) {
+	e := NewHashEmbedder(DefaultDimensions)
+	ctx := context.Background()
+	embed := func(s string) []float32 {
+		v, err := e.Embed(ctx, s)
+		if err != nil {
+			t.Fatalf("Embed: %v", err)
+		}
+		return v
+	}
+
+	doc := embed("The deploy pipeline rotates database credentials every night")
+	related := embed("how are db credentials rotated by deployment?")
+	unrelated := embed("Frontend button colours follow the brand palette")
+
+	if Cosine(doc, related) <= Cosine(doc, unrelated) {
+		t.Errorf("related cosine %v should exceed unrelated cosine %v", Cosine(doc, related), Cosine(doc, unrelated))
+	}
+
+	if v := embed("the and of"); Cosine(v, v) != 0 {
+		t.Error("stop words alone should embed to the zero vector")
+	}
+}
+
+func TestHTTPEmbedder(t *testing.T) {
+	var got embeddingsRequest
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"data": [{"embedding": [3, 4]}]}`))
+	}))
+	defer srv.Close()
+
+	e := NewHTTPEmbedder(HTTPConfig{URL: srv.URL, Model: "stub", APIKey: "secret"})
+	v, err := e.Embed(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if got.Model != "stub" || len(got.Input) != 1 || got.Input[0] != "hello" {
+		t.Errorf("request = %+v", got)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if len(v) != 2 || math.Abs(float64(v[0])-0.6) > 1e-6 || math.Abs(float64(v[1])-0.8) > 1e-6 {
+		t.Errorf("vector = %v, want normalized [0.6 0.8]", v)
+	}
+	if e.Model() != "http:stub" {
+		t.Errorf("Model() = %q", e.Model())
+	}
+}
+
+func TestHTTPEmbedder_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	if _, err := NewHTTPEmbedder(HTTPConfig{URL: srv.URL}).Embed(context.Background(), "x"); err == nil {
+		t.Error("expected an error for a non-200 response")
+	}
+}
+
+func TestTryEmbed_LogsFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	if v := TryEmbed(context.Background(), NewHTTPEmbedder(HTTPConfig{URL: srv.URL}), logger, "x"); v != nil {
+		t.Errorf("TryEmbed: got %v, want nil", v)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("failed to compute embedding")) {
+		t.Errorf("TryEmbed logged %q, want a warning", buf.String())
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("EMBEDDING_PROVIDER", "")
+	t.Setenv("EMBEDDING_DIMENSIONS", "32")
+	e, err := FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv: %v", err)
+	}
+	if e.Model() != "hash-32" {
+		t.Errorf("default provider model = %q, want hash-32", e.Model())
+	}
+
+	t.Setenv("EMBEDDING_PROVIDER", ProviderHTTP)
+	if _, err := FromEnv(); err == nil {
+		t.Error("http provider without EMBEDDING_URL should fail")
+	}
+	t.Setenv("EMBEDDING_URL", "http://localhost:11434/v1/embeddings")
+	t.Setenv("EMBEDDING_MODEL", "nomic-embed-text")
+	if e, err = FromEnv(); err != nil || e.Model() != "http:nomic-embed-text" {
+		t.Errorf("http provider: %v, %v", e, err)
+	}
+
+	t.Setenv("EMBEDDING_PROVIDER", "magic")
+	if _, err := FromEnv(); err == nil {
+		t.Error("unknown provider should fail")
+	}
+}
+
+func TestCosine(t *testing.T) {
+	if got := Cosine([]float32{1, 0}, []float32{0, 1}); got != 0 {
+		t.Errorf("orthogonal cosine = %v", got)
+	}
+	if got := Cosine([]float32{1, 2}, []float32{1, 2, 3}); got != 0 {
+		t.Errorf("mismatched lengths cosine = %v", got)
+	}
+}
@@ -0,0 +1,75 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/Thomas-Fitz/associate/internal/textsearch"
+)
+
+// DefaultDimensions is the vector size of the hash embedder when none is configured.
+const DefaultDimensions = 256
+
+// stopWords are skipped by the hash embedder because they carry no meaning
+// and would otherwise make unrelated texts look similar.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"but": true, "by": true, "for": true, "from": true, "has": true, "have": true, "how": true,
+	"i": true, "if": true, "in": true, "into": true, "is": true, "it": true, "its": true,
+	"of": true, "on": true, "or": true, "so": true, "that": true, "the": true, "this": true,
+	"to": true, "was": true, "we": true, "were": true, "what": true, "when": true, "where": true,
+	"which": true, "who": true, "why": true, "will": true, "with": true, "you": true,
+}
+
+// HashEmbedder is a deterministic, offline embedder. It hashes stemmed words
+// and their character trigrams into a fixed number of buckets (the "hashing
+// trick"), so texts sharing words or word fragments point the same way.
+type HashEmbedder struct {
+	dims int
+}
+
+// NewHashEmbedder creates a hash embedder producing vectors of the given size.
+func NewHashEmbedder(dims int) *HashEmbedder {
+	if dims <= 0 {
+		dims = DefaultDimensions
+	}
+	return &HashEmbedder{dims: dims}
+}
+
+// Model returns "hash-<dimensions>".
+func (e *HashEmbedder) Model() string {
+	return fmt.Sprintf("hash-%d", e.dims)
+}
+
+// Embed returns the unit-length hashed bag-of-words vector of text.
+func (e *HashEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	v := make([]float32, e.dims)
+	for _, word := range textsearch.Tokenize(text) {
+		if stopWords[word] {
+			continue
+		}
+		stem := textsearch.Stem(word)
+		e.add(v, "w:"+stem, 1)
+
+		// Trigrams of the padded stem let related word forms and typos overlap
+		padded := "^" + stem + "$"
+		runes := []rune(padded)
+		for i := 0; i+3 <= len(runes); i++ {
+			e.add(v, "t:"+string(runes[i:i+3]), 0.5)
+		}
+	}
+	return Normalize(v), nil
+}
+
+// add hashes a feature into a bucket, with a hash-derived sign so that
+// collisions cancel out on average instead of accumulating.
+func (e *HashEmbedder) add(v []float32, feature string, weight float32) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	v[sum%uint64(e.dims)] += weight
+}
@@ -0,0 +1,91 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPConfig configures an HTTPEmbedder.
+type HTTPConfig struct {
+	// URL is the embeddings endpoint, e.g. http://localhost:11434/v1/embeddings
+	URL string
+	// Model is sent as the "model" field and identifies the embedding space
+	Model string
+	// APIKey, if set, is sent as a bearer token
+	APIKey string
+	// Timeout bounds each request (default: 30s)
+	Timeout time.Duration
+}
+
+// HTTPEmbedder calls an OpenAI-compatible embeddings endpoint, as served by
+// OpenAI, Ollama, llama.cpp and most local model servers.
+type HTTPEmbedder struct {
+	cfg    HTTPConfig
+	client *http.Client
+}
+
+// NewHTTPEmbedder creates an embedder for the endpoint in cfg.
+func NewHTTPEmbedder(cfg HTTPConfig) *HTTPEmbedder {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return &HTTPEmbedder{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// Model returns "http:<model>".
+func (e *HTTPEmbedder) Model() string {
+	return "http:" + e.cfg.Model
+}
+
+type embeddingsRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+type embeddingsResponse struct {
+	Data []struct {
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed requests the embedding of text and returns it scaled to unit length.
+func (e *HTTPEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	body, err := json.Marshal(embeddingsRequest{Model: e.cfg.Model, Input: []string{text}})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.cfg.APIKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("embedding request failed: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var out embeddingsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %w", err)
+	}
+	if len(out.Data) != 1 || len(out.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("embedding response has %d embeddings, want 1", len(out.Data))
+	}
+	return Normalize(out.Data[0].Embedding), nil
+}
@@ -0,0 +1,214 @@
+//go:build integration
+// +build integration
+
+package graph
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+)
+
+// benchNodeCount returns how many Memory nodes to seed, from BENCH_NODES (default 100k).
+func benchNodeCount() int {
+	if n, err := strconv.Atoi(os.Getenv("BENCH_NODES")); err == nil && n > 0 {
+		return n
+	}
+	return 100000
+}
+
+// seedBenchGraph creates n Memory nodes, a plan with n/100 tasks, and returns
+// the plan ID. Nodes are created in batches with UNWIND to keep seeding fast.
+func seedBenchGraph(ctx context.Context, b *testing.B, client *Client, prefix string, n int) string {
+	b.Helper()
+	const batchSize = 1000
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	for start := 0; start < n; start += batchSize {
+		batch := make([]map[string]any, 0, batchSize)
+		for i := start; i < start+batchSize && i < n; i++ {
+			batch = append(batch, map[string]any{
+				"id":      fmt.Sprintf("%s-mem-%d", prefix, i),
+				"content": fmt.Sprintf("benchmark memory %d", i),
+			})
+		}
+		err := client.execCypherNoReturn(ctx, nil,
+			`UNWIND $batch AS row
+			 CREATE (m:Memory {id: row.id, node_type: 'Memory', type: 'Note', content: row.content,
+				metadata: '', tags: ['bench'], created_at: $now, updated_at: $now})
+			 RETURN true`,
+			map[string]any{"batch": batch, "now": now})
+		if err != nil {
+			b.Fatalf("failed to seed memories: %v", err)
+		}
+	}
+
+	planID := prefix + "-plan"
+	if _, err := NewPlanRepository(client).Add(ctx, models.Plan{ID: planID, Name: "benchmark plan"}, nil); err != nil {
+		b.Fatalf("failed to seed plan: %v", err)
+	}
+	tasks := NewTaskRepository(client)
+	for i := 0; i < n/100; i++ {
+		if _, _, err := tasks.Add(ctx, models.Task{ID: fmt.Sprintf("%s-task-%d", prefix, i), Content: "benchmark task"}, []string{planID}, nil, nil, nil); err != nil {
+			b.Fatalf("failed to seed task: %v", err)
+		}
+	}
+	return planID
+}
+
+// BenchmarkLookups measures id lookups and filtered lists against a graph of
+// BENCH_NODES memories. Compare runs before and after `migrate up` to see the
+// effect of the property indexes.
+func BenchmarkLookups(b *testing.B) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	client, err := NewClient(ctx, ConfigFromEnv())
+	if err != nil {
+		b.Fatalf("Failed to connect to PostgreSQL/AGE: %v", err)
+	}
+	defer client.Close(ctx)
+
+	n := benchNodeCount()
+	prefix := fmt.Sprintf("bench-%d", time.Now().UnixNano())
+	planID := seedBenchGraph(ctx, b, client, prefix, n)
+	defer func() {
+		client.execCypherNoReturn(ctx, nil,
+			`MATCH (n) WHERE n.id STARTS WITH $prefix DETACH DELETE n RETURN true`,
+			map[string]any{"prefix": prefix})
+	}()
+
+	memories := NewRepository(client)
+	plans := NewPlanRepository(client)
+	tasks := NewTaskRepository(client)
+
+	b.Run("Memory.GetByID", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			id := fmt.Sprintf("%s-mem-%d", prefix, i%n)
+			if _, err := memories.GetByID(ctx, id); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Plan.List", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, _, err := plans.List(ctx, "active", nil, 50, ""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Task.ListByPlan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, _, err := tasks.List(ctx, planID, "pending", nil, 50, ""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkBatchedLookups reports the Cypher queries run per search and per
+// plan fetch as the number of hits and tasks grows. Related IDs and task
+// dependencies are fetched in one query each, so the count must not depend
+// on the limit.
+func BenchmarkBatchedLookups(b *testing.B) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	client, err := NewClient(ctx, ConfigFromEnv())
+	if err != nil {
+		b.Fatalf("Failed to connect to PostgreSQL/AGE: %v", err)
+	}
+	defer client.Close(ctx)
+
+	const n = 200
+	prefix := fmt.Sprintf("bench-%d", time.Now().UnixNano())
+	word := "zqbatched" + strconv.FormatInt(time.Now().UnixNano()%1e6, 36)
+	memories := NewRepository(client)
+	plans := NewPlanRepository(client)
+	tasks := NewTaskRepository(client)
+	defer func() {
+		client.execCypherNoReturn(ctx, nil,
+			`MATCH (n) WHERE n.id STARTS WITH $prefix DETACH DELETE n RETURN true`,
+			map[string]any{"prefix": prefix})
+	}()
+
+	var prev string
+	for i := 0; i < n; i++ {
+		var rels []models.Relationship
+		if prev != "" {
+			rels = append(rels, models.Relationship{ToID: prev, Type: models.RelRelatesTo})
+		}
+		mem, err := memories.Add(ctx, models.Memory{ID: fmt.Sprintf("%s-mem-%d", prefix, i), Type: models.TypeNote, Content: word + " memory"}, rels)
+		if err != nil {
+			b.Fatalf("failed to seed memory: %v", err)
+		}
+		prev = mem.ID
+	}
+
+	planIDs := map[int]string{}
+	for _, size := range []int{10, 100} {
+		planID := fmt.Sprintf("%s-plan-%d", prefix, size)
+		if _, err := plans.Add(ctx, models.Plan{ID: planID, Name: "benchmark plan"}, nil); err != nil {
+			b.Fatalf("failed to seed plan: %v", err)
+		}
+		var prevTask string
+		for i := 0; i < size; i++ {
+			var deps []models.Relationship
+			if prevTask != "" {
+				deps = append(deps, models.Relationship{ToID: prevTask, Type: models.RelDependsOn})
+			}
+			task, _, err := tasks.Add(ctx, models.Task{ID: fmt.Sprintf("%s-%d", planID, i), Content: "benchmark task"}, []string{planID}, deps, nil, nil)
+			if err != nil {
+				b.Fatalf("failed to seed task: %v", err)
+			}
+			prevTask = task.ID
+		}
+		planIDs[size] = planID
+	}
+
+	// queriesPerOp runs fn b.N times and reports the Cypher queries per run
+	queriesPerOp := func(b *testing.B, fn func() error) float64 {
+		start := client.cypherQueries.Load()
+		for i := 0; i < b.N; i++ {
+			if err := fn(); err != nil {
+				b.Fatal(err)
+			}
+		}
+		perOp := float64(client.cypherQueries.Load()-start) / float64(b.N)
+		b.ReportMetric(perOp, "queries/op")
+		return perOp
+	}
+
+	searchQueries := map[int]float64{}
+	for _, limit := range []int{10, 100} {
+		b.Run(fmt.Sprintf("Search/limit=%d", limit), func(b *testing.B) {
+			searchQueries[limit] = queriesPerOp(b, func() error {
+				_, _, err := memories.Search(ctx, word, models.SearchOptions{Limit: limit})
+				return err
+			})
+		})
+	}
+	if searchQueries[10] != searchQueries[100] {
+		b.Errorf("Search: %v queries at limit 10 but %v at limit 100", searchQueries[10], searchQueries[100])
+	}
+
+	planQueries := map[int]float64{}
+	for size, planID := range planIDs {
+		b.Run(fmt.Sprintf("Plan.GetWithTasks/tasks=%d", size), func(b *testing.B) {
+			planQueries[size] = queriesPerOp(b, func() error {
+				_, _, err := plans.GetWithTasks(ctx, planID)
+				return err
+			})
+		})
+	}
+	if planQueries[10] != planQueries[100] {
+		b.Errorf("GetWithTasks: %v queries for 10 tasks but %v for 100", planQueries[10], planQueries[100])
+	}
+}
@@ -3,10 +3,14 @@ package graph
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"sync/atomic"
 	"time"
 
+	"github.com/Thomas-Fitz/associate/internal/embedding"
 	_ "github.com/lib/pq"
 )
 
@@ -36,6 +40,14 @@ func DefaultRetryOptions() RetryOptions {
 type Client struct {
 	db        *sql.DB
 	graphName string
+	embedder  embedding.Embedder
+	logger    *slog.Logger
+	// statusRules and planCompletion are set through TaskRepository
+	statusRules    bool
+	planCompletion bool
+	// cypherQueries counts the Cypher queries run, so benchmarks can check
+	// that lookups stay batched
+	cypherQueries atomic.Int64
 }
 
 // Config holds PostgreSQL/AGE connection configuration
@@ -73,8 +85,26 @@ func (c Config) DSN() string {
 	)
 }
 
-// NewClient creates a new AGE client
+// NewClient creates a new AGE client and applies any pending schema migrations.
+// It fails with ErrSchemaTooNew if the database was migrated by a newer release.
 func NewClient(ctx context.Context, cfg Config) (*Client, error) {
+	client, err := Connect(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bring the schema up to date
+	if _, err := client.Migrate(ctx); err != nil {
+		client.db.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	return client, nil
+}
+
+// Connect opens the database and ensures the AGE extension and graph exist,
+// without applying schema migrations.
+func Connect(ctx context.Context, cfg Config) (*Client, error) {
 	db, err := sql.Open("postgres", cfg.DSN())
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -89,6 +119,8 @@ func NewClient(ctx context.Context, cfg Config) (*Client, error) {
 	client := &Client{
 		db:        db,
 		graphName: GraphName,
+		embedder:  embedding.NewHashEmbedder(embedding.DefaultDimensions),
+		logger:    slog.Default(),
 	}
 
 	// Initialize AGE extension and graph
@@ -97,12 +129,6 @@ func NewClient(ctx context.Context, cfg Config) (*Client, error) {
 		return nil, fmt.Errorf("failed to initialize AGE: %w", err)
 	}
 
-	// Initialize schema (indexes)
-	if err := client.initSchema(ctx); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
-	}
-
 	return client, nil
 }
 
@@ -121,6 +147,10 @@ func NewClientWithRetry(ctx context.Context, cfg Config, opts *RetryOptions) (*C
 		if lastErr == nil {
 			return client, nil
 		}
+		// Waiting will not make an older binary understand a newer schema
+		if errors.Is(lastErr, ErrSchemaTooNew) {
+			return nil, lastErr
+		}
 
 		if attempt == opts.MaxAttempts {
 			break
@@ -186,7 +216,7 @@ func (c *Client) initAGE(ctx context.Context) error {
 	}
 
 	if !exists {
-		_, err = c.db.ExecContext(ctx, fmt.Sprintf("SELECT create_graph('%s')", c.graphName))
+		_, err = c.db.ExecContext(ctx, "SELECT create_graph($1)", c.graphName)
 		if err != nil {
 			return fmt.Errorf("failed to create graph: %w", err)
 		}
@@ -195,73 +225,28 @@ func (c *Client) initAGE(ctx context.Context) error {
 	return nil
 }
 
-// initSchema creates indexes for the graph on AGE's internal label tables.
-// Note: AGE uses agtype columns, which require special handling for indexes.
-// For now, we ensure label tables exist but skip indexes (they're an optimization).
-func (c *Client) initSchema(ctx context.Context) error {
-	// Ensure pg_trgm extension is available for full-text search
-	if _, err := c.db.ExecContext(ctx, "CREATE EXTENSION IF NOT EXISTS pg_trgm"); err != nil {
-		return fmt.Errorf("failed to create pg_trgm extension: %w", err)
-	}
-
-	// Ensure label tables exist by creating and deleting a dummy vertex for each type
-	seedLabels := []string{"Memory", "Plan", "Task"}
-	for _, label := range seedLabels {
-		// Create a seed node
-		createQuery := fmt.Sprintf(
-			`SELECT * FROM cypher('%s', $$ CREATE (n:%s {id: '__seed__'}) RETURN n $$) as (v agtype)`,
-			c.graphName, label)
-		if _, err := c.db.ExecContext(ctx, createQuery); err != nil {
-			return fmt.Errorf("failed to seed %s label table: %w", label, err)
-		}
-
-		// Delete the seed node
-		deleteQuery := fmt.Sprintf(
-			`SELECT * FROM cypher('%s', $$ MATCH (n:%s {id: '__seed__'}) DELETE n $$) as (v agtype)`,
-			c.graphName, label)
-		if _, err := c.db.ExecContext(ctx, deleteQuery); err != nil {
-			return fmt.Errorf("failed to delete seed %s node: %w", label, err)
-		}
-	}
-
-	// AGE uses agtype columns, not JSONB. Creating indexes on agtype requires
-	// special functions like agtype_access_operator(). For simplicity, we skip
-	// index creation - the graph queries will still work, just without index optimization.
-	// TODO: Add AGE-compatible indexes if performance becomes an issue
-
-	return nil
-}
-
 // execCypher executes a Cypher query and returns the result rows.
 // The cypher query should NOT include RETURN if you don't expect results.
 // For queries with RETURN, specify the appropriate column definitions.
-func (c *Client) execCypher(ctx context.Context, tx *sql.Tx, cypher string, returnCols string) (*sql.Rows, error) {
-	query := fmt.Sprintf(
-		`SELECT * FROM cypher('%s', $$ %s $$) as (%s)`,
-		c.graphName, cypher, returnCols,
-	)
+// Values in params are bound to $name placeholders through AGE's agtype
+// parameter map, so they never need escaping. Pass nil when the query has none.
+func (c *Client) execCypher(ctx context.Context, tx *sql.Tx, cypher string, returnCols string, params map[string]any) (*sql.Rows, error) {
+	query, args, err := c.cypherQuery(cypher, returnCols, params)
+	if err != nil {
+		return nil, err
+	}
+	c.cypherQueries.Add(1)
 
 	if tx != nil {
-		return tx.QueryContext(ctx, query)
+		return tx.QueryContext(ctx, query, args...)
 	}
-	return c.db.QueryContext(ctx, query)
+	return c.db.QueryContext(ctx, query, args...)
 }
 
 // execCypherNoReturn executes a Cypher query that doesn't return rows (e.g., CREATE, DELETE).
-func (c *Client) execCypherNoReturn(ctx context.Context, tx *sql.Tx, cypher string) error {
+func (c *Client) execCypherNoReturn(ctx context.Context, tx *sql.Tx, cypher string, params map[string]any) error {
 	// For mutations, we use a RETURN with a dummy result since AGE requires it
-	query := fmt.Sprintf(
-		`SELECT * FROM cypher('%s', $$ %s $$) as (v agtype)`,
-		c.graphName, cypher,
-	)
-
-	var rows *sql.Rows
-	var err error
-	if tx != nil {
-		rows, err = tx.QueryContext(ctx, query)
-	} else {
-		rows, err = c.db.QueryContext(ctx, query)
-	}
+	rows, err := c.execCypher(ctx, tx, cypher, "v agtype", params)
 	if err != nil {
 		return err
 	}
@@ -272,3 +257,23 @@ func (c *Client) execCypherNoReturn(ctx context.Context, tx *sql.Tx, cypher stri
 	}
 	return rows.Err()
 }
+
+// cypherQuery wraps a Cypher query in the SQL cypher() call. When params is
+// non-empty it is encoded as an agtype map and passed as the $1 argument.
+func (c *Client) cypherQuery(cypher string, returnCols string, params map[string]any) (string, []any, error) {
+	if len(params) == 0 {
+		return fmt.Sprintf(
+			`SELECT * FROM cypher('%s', $$ %s $$) as (%s)`,
+			c.graphName, cypher, returnCols,
+		), nil, nil
+	}
+
+	encoded, err := encodeParams(params)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode query parameters: %w", err)
+	}
+	return fmt.Sprintf(
+		`SELECT * FROM cypher('%s', $$ %s $$, $1) as (%s)`,
+		c.graphName, cypher, returnCols,
+	), []any{encoded}, nil
+}
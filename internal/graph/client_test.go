@@ -1,6 +1,7 @@
 package graph
 
 import (
+	"math"
 	"testing"
 	"time"
 )
@@ -87,86 +88,86 @@ func TestConfig_DSN(t *testing.T) {
 	}
 }
 
-func TestEscapeCypherString(t *testing.T) {
+func TestEncodeParams(t *testing.T) {
 	tests := []struct {
-		input    string
+		input    map[string]any
 		expected string
 	}{
-		{"simple", "simple"},
-		{"with'quote", "with\\'quote"},
-		{"with\"double", "with\\\"double"},
-		{"with\nnewline", "with\\nnewline"},
-		{"with\ttab", "with\\ttab"},
-		{"with\\backslash", "with\\\\backslash"},
+		{map[string]any{"id": "abc"}, `{"id":"abc"}`},
+		{map[string]any{"q": "it's <b>\"x\"</b>"}, `{"q":"it's <b>\"x\"</b>"}`},
+		{map[string]any{"s": "nul\x00byte"}, `{"s":"nulbyte"}`},
+		{map[string]any{"tags": []string{"a\x00", "b"}}, `{"tags":["a","b"]}`},
+		{map[string]any{"position": 1000.0}, `{"position":1000.0}`},
+		{map[string]any{"position": 1500.5}, `{"position":1500.5}`},
 	}
 
 	for _, tt := range tests {
-		result := EscapeCypherString(tt.input)
+		result, err := encodeParams(tt.input)
+		if err != nil {
+			t.Errorf("encodeParams(%v) error: %v", tt.input, err)
+			continue
+		}
 		if result != tt.expected {
-			t.Errorf("EscapeCypherString(%q) = %q, want %q", tt.input, result, tt.expected)
+			t.Errorf("encodeParams(%v) = %s, want %s", tt.input, result, tt.expected)
 		}
 	}
+
+	if _, err := encodeParams(map[string]any{"position": math.NaN()}); err == nil {
+		t.Error("encodeParams with NaN should fail")
+	}
 }
 
-func TestTagsToCypherList(t *testing.T) {
+func TestRelationshipPattern(t *testing.T) {
 	tests := []struct {
-		input    []string
-		expected string
+		relType   string
+		direction string
+		expected  string
 	}{
-		{nil, "[]"},
-		{[]string{}, "[]"},
-		{[]string{"one"}, "['one']"},
-		{[]string{"one", "two"}, "['one', 'two']"},
-		{[]string{"with'quote"}, "['with\\'quote']"},
+		{"", "both", "-[r]-"},
+		{"", "outgoing", "-[r]->"},
+		{"RELATES_TO", "incoming", "<-[r:RELATES_TO]-"},
+		{"DEPENDS_ON", "outgoing", "-[r:DEPENDS_ON]->"},
 	}
 
 	for _, tt := range tests {
-		result := tagsToCypherList(tt.input)
+		result, err := relationshipPattern(tt.relType, tt.direction)
+		if err != nil {
+			t.Errorf("relationshipPattern(%q, %q) error: %v", tt.relType, tt.direction, err)
+			continue
+		}
 		if result != tt.expected {
-			t.Errorf("tagsToCypherList(%v) = %q, want %q", tt.input, result, tt.expected)
+			t.Errorf("relationshipPattern(%q, %q) = %q, want %q", tt.relType, tt.direction, result, tt.expected)
 		}
 	}
+
+	if _, err := relationshipPattern("X]->(n) DETACH DELETE n //", "both"); err == nil {
+		t.Error("relationshipPattern with invalid type should fail")
+	}
 }
 
-func TestCalculateInsertPositions(t *testing.T) {
-	tests := []struct {
-		name      string
-		afterPos  float64
-		beforePos float64
-		count     int
-		wantLen   int
-	}{
-		{"Empty plan", 0, 0, 1, 1},
-		{"Append to end", 1000, 0, 1, 1},
-		{"Insert at start", 0, 1000, 1, 1},
-		{"Insert between", 1000, 2000, 1, 1},
-		{"Multiple insert", 1000, 2000, 3, 3},
-		{"Zero count", 1000, 2000, 0, 0},
+func TestParseAGTypePath(t *testing.T) {
+	input := `[{"id": 1, "label": "Memory", "properties": {"id": "m1", "content": "a \"quoted\"::edge} note"}}::vertex, ` +
+		`{"id": 7, "label": "PART_OF", "end_id": 1, "start_id": 2, "properties": {}}::edge, ` +
+		`{"id": 2, "label": "Task", "properties": {"id": "t1"}}::vertex]::path`
+
+	path, err := parseAGTypePath(input)
+	if err != nil {
+		t.Fatalf("parseAGTypePath: %v", err)
+	}
+	if len(path) != 3 {
+		t.Fatalf("got %d elements, want 3", len(path))
+	}
+	if got := getString(path[0].Properties, "content"); got != `a "quoted"::edge} note` {
+		t.Errorf("content inside strings should be kept, got %q", got)
+	}
+	if edge := path[1]; edge.Label != "PART_OF" || edge.StartID != 2 || edge.EndID != 1 {
+		t.Errorf("edge: got %+v", edge)
+	}
+	if path[2].Label != "Task" || getString(path[2].Properties, "id") != "t1" {
+		t.Errorf("vertex: got %+v", path[2])
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			positions := CalculateInsertPositions(tt.afterPos, tt.beforePos, tt.count)
-			if len(positions) != tt.wantLen {
-				t.Errorf("CalculateInsertPositions(%f, %f, %d) returned %d positions, want %d",
-					tt.afterPos, tt.beforePos, tt.count, len(positions), tt.wantLen)
-			}
-
-			// Verify positions are in order
-			for i := 1; i < len(positions); i++ {
-				if positions[i] <= positions[i-1] {
-					t.Errorf("Positions not in ascending order: %v", positions)
-				}
-			}
-
-			// Verify positions are between afterPos and beforePos (when both are set)
-			if tt.afterPos > 0 && tt.beforePos > 0 {
-				for _, pos := range positions {
-					if pos <= tt.afterPos || pos >= tt.beforePos {
-						t.Errorf("Position %f not between %f and %f", pos, tt.afterPos, tt.beforePos)
-					}
-				}
-			}
-		})
+	if _, err := parseAGTypePath(`[{"id": 1}::vertex, {"id": 7}::edge]::path`); err == nil {
+		t.Error("path ending in an edge should fail")
 	}
 }
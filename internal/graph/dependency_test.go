@@ -4,6 +4,7 @@
 package graph
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -41,7 +42,7 @@ func TestTaskDependencyCreation(t *testing.T) {
 		Content: "Task 1",
 		Status:  models.TaskStatusPending,
 	}
-	_, err = taskRepo.Add(ctx, task1, []string{planID}, nil, nil, nil)
+	_, _, err = taskRepo.Add(ctx, task1, []string{planID}, nil, nil, nil)
 	if err != nil {
 		t.Fatalf("Failed to create task 1: %v", err)
 	}
@@ -51,7 +52,7 @@ func TestTaskDependencyCreation(t *testing.T) {
 		Content: "Task 2 - depends on task 1",
 		Status:  models.TaskStatusPending,
 	}
-	_, err = taskRepo.Add(ctx, task2, []string{planID}, nil, nil, nil)
+	_, _, err = taskRepo.Add(ctx, task2, []string{planID}, nil, nil, nil)
 	if err != nil {
 		t.Fatalf("Failed to create task 2: %v", err)
 	}
@@ -61,10 +62,29 @@ func TestTaskDependencyCreation(t *testing.T) {
 		rels := []models.Relationship{
 			{ToID: task1ID, Type: models.RelDependsOn},
 		}
-		updated, err := taskRepo.Update(ctx, task2ID, nil, nil, nil, nil, nil, rels)
+		updated, _, err := taskRepo.Update(ctx, task2ID, 0, nil, nil, nil, nil, nil, rels)
 		if err != nil {
 			t.Fatalf("Failed to add dependency: %v", err)
 		}
 		t.Logf("Added dependency: task %s now depends on %s", updated.ID, task1ID)
 	})
+
+	// Task 1 blocking task 2 agrees with the dependency; the reverse closes a cycle
+	t.Run("RejectCycle", func(t *testing.T) {
+		rels := []models.Relationship{
+			{ToID: task1ID, Type: models.RelBlocks},
+		}
+		_, _, err := taskRepo.Update(ctx, task2ID, 0, nil, nil, nil, nil, nil, rels)
+		if !errors.Is(err, models.ErrDependencyCycle) {
+			t.Fatalf("Expected a dependency cycle, got %v", err)
+		}
+
+		cycles, err := planRepo.DependencyCycles(ctx, planID)
+		if err != nil {
+			t.Fatalf("Failed to check plan for cycles: %v", err)
+		}
+		if len(cycles) != 0 {
+			t.Errorf("Expected no cycles, got %v", cycles)
+		}
+	})
 }
@@ -0,0 +1,174 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Thomas-Fitz/associate/internal/embedding"
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/lib/pq"
+)
+
+// embeddingsTable holds one embedding vector per node, tagged with the model
+// that produced it. Vectors are unit length, so their dot product is the
+// cosine similarity.
+const embeddingsTable = "public.associate_embeddings"
+
+// createEmbeddingsTable creates the embeddings table. Existing memories are
+// embedded by BackfillEmbeddings.
+func createEmbeddingsTable(ctx context.Context, tx *sql.Tx, graphName string) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS ` + embeddingsTable + ` (
+			id TEXT PRIMARY KEY,
+			label TEXT NOT NULL,
+			model TEXT NOT NULL,
+			vector REAL[] NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS associate_embeddings_label_model_idx ON ` + embeddingsTable + ` (label, model)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create embeddings table: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetEmbedder replaces the embedder used for semantic search (default: the
+// offline hash embedder). Stored vectors from another model are recomputed
+// by BackfillEmbeddings; until then those nodes are left out of semantic
+// search.
+func (c *Client) SetEmbedder(e embedding.Embedder) {
+	c.embedder = e
+}
+
+// SetLogger replaces the logger that warnings, such as embedder failures, are
+// written to (default: slog.Default()).
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.logger = logger
+}
+
+// saveEmbedding stores the embedding of text for a node. If the embedder
+// fails, any previous vector is dropped so semantic search recomputes it.
+func (c *Client) saveEmbedding(ctx context.Context, tx *sql.Tx, id, label, text string) error {
+	vec := embedding.TryEmbed(ctx, c.embedder, c.logger, text)
+	if vec == nil {
+		return removeEmbeddings(ctx, tx, id)
+	}
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO `+embeddingsTable+` (id, label, model, vector) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (id) DO UPDATE SET label = EXCLUDED.label, model = EXCLUDED.model, vector = EXCLUDED.vector`,
+		id, label, c.embedder.Model(), pq.Array(vec))
+	if err != nil {
+		return fmt.Errorf("failed to store embedding: %w", err)
+	}
+	return nil
+}
+
+// removeEmbeddings deletes the embeddings of the given nodes.
+func removeEmbeddings(ctx context.Context, tx *sql.Tx, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+embeddingsTable+` WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+		return fmt.Errorf("failed to remove embedding: %w", err)
+	}
+	return nil
+}
+
+// searchSemantic scores the nodes with the given labels that pass the filter
+// by the cosine similarity of their embedding to the query's, in opts.Sort
+// order, and returns up to limit of them, or all if it is zero, skipping the
+// first offset, along with the number of matches. Nodes without a vector for
+// the current model, such as those written before the embedder changed, are
+// skipped until BackfillEmbeddings embeds them. Only positively similar nodes
+// are returned.
+func (c *Client) searchSemantic(ctx context.Context, tx *sql.Tx, labels []string, query string, opts models.SearchOptions, offset, limit int) ([]searchHit, int, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, 0, nil
+	}
+	model := c.embedder.Model()
+
+	queryVec, err := c.embedder.Embed(ctx, query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	filter, args := filterSQL(opts.Filter, []any{model, pq.Array(queryVec), limitArg(limit), pq.Array(labels), offset})
+	rows, err := tx.QueryContext(ctx,
+		`SELECT s.id, s.score, count(*) OVER () FROM (
+			SELECT s.id, s.created_at, s.updated_at,
+				(SELECT sum(a * b) FROM unnest(e.vector, $2::real[]) AS v(a, b)) AS score
+			FROM `+embeddingsTable+` e JOIN `+searchTable+` s ON s.id = e.id
+			WHERE e.label = ANY($4) AND e.model = $1`+filter+`
+		 ) s
+		 WHERE s.score > 0
+		 ORDER BY `+orderSQL(opts, "s.score")+`
+		 LIMIT $3 OFFSET $5`,
+		args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return scanSearchHits(rows)
+}
+
+// BackfillEmbeddings embeds up to limit nodes that have no vector for the
+// current model, such as those written while the embedder was unavailable or
+// before it changed, and returns how many it embedded. Fewer than limit
+// without an error means none are left. The embedder is called outside any transaction, so a slow
+// provider never holds one open; it fails on the first node the embedder
+// fails on, after storing the vectors computed before it.
+func (c *Client) BackfillEmbeddings(ctx context.Context, limit int) (int, error) {
+	model := c.embedder.Model()
+	rows, err := c.db.QueryContext(ctx,
+		`SELECT s.id, s.label, s.body FROM `+searchTable+` s
+		 LEFT JOIN `+embeddingsTable+` e ON e.id = s.id AND e.model = $1
+		 WHERE e.id IS NULL
+		 ORDER BY s.id
+		 LIMIT $2`,
+		model, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find nodes to embed: %w", err)
+	}
+	var missing []searchRow
+	for rows.Next() {
+		var row searchRow
+		if err := rows.Scan(&row.id, &row.label, &row.body); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		missing = append(missing, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var embedErr error
+	vectors := make([][]float32, 0, len(missing))
+	for _, row := range missing {
+		vec, err := c.embedder.Embed(ctx, row.body)
+		if err != nil {
+			embedErr = fmt.Errorf("failed to embed %s: %w", row.id, err)
+			break
+		}
+		vectors = append(vectors, vec)
+	}
+	for i, vec := range vectors {
+		row := missing[i]
+		// The node may have been deleted or re-embedded since it was read
+		_, err := c.db.ExecContext(ctx,
+			`INSERT INTO `+embeddingsTable+` (id, label, model, vector)
+			 SELECT s.id, s.label, $3::text, $4::real[] FROM `+searchTable+` s WHERE s.id = $1 AND s.body = $2
+			 ON CONFLICT (id) DO UPDATE SET label = EXCLUDED.label, model = EXCLUDED.model, vector = EXCLUDED.vector
+			 WHERE `+embeddingsTable+`.model <> EXCLUDED.model`,
+			row.id, row.body, model, pq.Array(vec))
+		if err != nil {
+			return i, fmt.Errorf("failed to store embedding: %w", err)
+		}
+	}
+	return len(vectors), embedErr
+}
@@ -1,44 +1,200 @@
 package graph
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"math"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Thomas-Fitz/associate/internal/models"
 )
 
-// ValidRelationTypes is the complete set of allowed relationship type names.
-var ValidRelationTypes = map[models.RelationType]bool{
-	models.RelRelatesTo:  true,
-	models.RelPartOf:     true,
-	models.RelReferences: true,
-	models.RelDependsOn:  true,
-	models.RelBlocks:     true,
-	models.RelFollows:    true,
-	models.RelImplements: true,
+// agtypeFloat marshals a float64 so that AGE parses it as a float even when it
+// has no fractional part; 1000 would otherwise become an agtype integer.
+type agtypeFloat float64
+
+func (f agtypeFloat) MarshalJSON() ([]byte, error) {
+	s := strconv.FormatFloat(float64(f), 'f', -1, 64)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return []byte(s), nil
+}
+
+// encodeParams encodes a Cypher parameter map as agtype text, the form AGE
+// expects for the third argument of cypher(). Null bytes are stripped from
+// strings because agtype cannot store them.
+func encodeParams(params map[string]any) (string, error) {
+	converted := make(map[string]any, len(params))
+	for k, v := range params {
+		switch val := v.(type) {
+		case float64:
+			if math.IsNaN(val) || math.IsInf(val, 0) {
+				return "", fmt.Errorf("parameter %s is not a finite number", k)
+			}
+			v = agtypeFloat(val)
+		case string:
+			v = strings.ReplaceAll(val, "\x00", "")
+		case []string:
+			cleaned := make([]string, len(val))
+			for i, s := range val {
+				cleaned[i] = strings.ReplaceAll(s, "\x00", "")
+			}
+			v = cleaned
+		}
+		converted[k] = v
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(converted); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// relationshipPattern returns the Cypher pattern for a single relationship in
+// a traversal direction, optionally restricted to one relationship type. The
+// type is validated because AGE cannot bind relationship types as parameters.
+func relationshipPattern(relationType string, direction string) (string, error) {
+	relPart := "r"
+	if relationType != "" {
+		if err := models.ValidateRelationType(models.RelationType(relationType)); err != nil {
+			return "", err
+		}
+		relPart = "r:" + relationType
+	}
+
+	switch direction {
+	case "outgoing":
+		return "-[" + relPart + "]->", nil
+	case "incoming":
+		return "<-[" + relPart + "]-", nil
+	default:
+		return "-[" + relPart + "]-", nil
+	}
+}
+
+// tagsFilter returns a Cypher predicate matching nodes whose tags contain any
+// of the given tags, adding one parameter per tag to params.
+func tagsFilter(nodeVar string, tags []string, params map[string]any) string {
+	checks := make([]string, len(tags))
+	for i, tag := range tags {
+		name := fmt.Sprintf("tag%d", i)
+		params[name] = tag
+		checks[i] = fmt.Sprintf("$%s IN %s.tags", name, nodeVar)
+	}
+	return "(" + joinStrings(checks, " OR ") + ")"
+}
+
+// whereSQL joins the clauses into a WHERE clause, or returns "" if there are none.
+func whereSQL(clauses []string) string {
+	if len(clauses) == 0 {
+		return ""
+	}
+	return "WHERE " + joinStrings(clauses, " AND ")
 }
 
-// ValidateRelationType checks that a relationship type is one of the known constants.
-func ValidateRelationType(relType models.RelationType) error {
-	if !ValidRelationTypes[relType] {
-		return fmt.Errorf("invalid relationship type: %q", relType)
+// recencyKeyset returns a predicate keeping the nodes after the cursor in a
+// list ordered by updated_at DESC, id DESC, and adds its parameters.
+func recencyKeyset(nodeVar string, c models.Cursor, params map[string]any) string {
+	params["cursor_updated_at"] = c.UpdatedAt.UTC().Format(time.RFC3339)
+	params["cursor_id"] = c.ID
+	return fmt.Sprintf("(%[1]s.updated_at < $cursor_updated_at OR (%[1]s.updated_at = $cursor_updated_at AND %[1]s.id < $cursor_id))", nodeVar)
+}
+
+// positionKeyset returns a predicate keeping the tasks after the cursor in a
+// plan's task list, ordered by r.position, t.id, and adds its parameters.
+func positionKeyset(c models.Cursor, params map[string]any) string {
+	params["cursor_position"] = c.Position
+	params["cursor_id"] = c.ID
+	return "(r.position > $cursor_position OR (r.position = $cursor_position AND t.id > $cursor_id))"
+}
+
+// count runs a cypher query returning a single count.
+func (c *Client) count(ctx context.Context, tx *sql.Tx, cypher string, params map[string]any) (int, error) {
+	rows, err := c.execCypher(ctx, tx, cypher, "count agtype", params)
+	if err != nil {
+		return 0, err
 	}
-	return nil
+	defer rows.Close()
+
+	var n int
+	if rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return 0, err
+		}
+		if n, err = strconv.Atoi(s); err != nil {
+			return 0, fmt.Errorf("failed to parse count: %w", err)
+		}
+	}
+	return n, rows.Err()
+}
+
+// versionPredicate returns the WHERE clause restricting an update of nodeVar
+// to the expected version, or "" when expectedVersion is 0. Nodes created
+// before versions existed are at version 1.
+func versionPredicate(nodeVar string, expectedVersion int64, params map[string]any) string {
+	if expectedVersion == 0 {
+		return ""
+	}
+	params["expected_version"] = expectedVersion
+	return fmt.Sprintf("WHERE coalesce(%s.version, 1) = $expected_version", nodeVar)
 }
 
-// EscapeCypherString escapes a string for safe interpolation into Cypher queries.
-func EscapeCypherString(s string) string {
-	s = strings.ReplaceAll(s, "\\", "\\\\")
-	s = strings.ReplaceAll(s, "'", "\\'")
-	s = strings.ReplaceAll(s, "\"", "\\\"")
-	s = strings.ReplaceAll(s, "\n", "\\n")
-	s = strings.ReplaceAll(s, "\r", "\\r")
-	s = strings.ReplaceAll(s, "\t", "\\t")
-	s = strings.ReplaceAll(s, "\x00", "") // Strip null bytes
-	return s
+// versionSet is the SET clause incrementing the version of nodeVar.
+func versionSet(nodeVar string) string {
+	return fmt.Sprintf("%s.version = coalesce(%s.version, 1) + 1", nodeVar, nodeVar)
+}
+
+// versionConflict explains an update restricted by versionPredicate that
+// matched nothing: it returns a VersionConflictError, or nil if there is no
+// node with the label and ID.
+func (c *Client) versionConflict(ctx context.Context, tx *sql.Tx, label, id string, expectedVersion int64) error {
+	actual, err := c.nodeVersion(ctx, tx, label, id)
+	if err != nil || actual == 0 {
+		return err
+	}
+	return models.CheckVersion(id, expectedVersion, actual)
+}
+
+// nodeVersion returns the version of the node with the label and ID, or 0 if
+// there is no such node.
+func (c *Client) nodeVersion(ctx context.Context, tx *sql.Tx, label, id string) (int64, error) {
+	rows, err := c.execCypher(ctx, tx, fmt.Sprintf(`MATCH (n:%s {id: $id}) RETURN coalesce(n.version, 1)`, label), "version agtype", map[string]any{"id": id})
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var version int64
+	if rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return 0, err
+		}
+		if version, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return 0, fmt.Errorf("failed to parse version: %w", err)
+		}
+	}
+	return version, rows.Err()
+}
+
+// getVersion extracts the version property of a node, which is 1 for nodes
+// created before versions existed.
+func getVersion(props map[string]interface{}) int64 {
+	if v := int64(toFloat64(props["version"])); v > 0 {
+		return v
+	}
+	return 1
 }
 
 // NodeLabelPredicate returns an AGE-compatible label predicate for use in WHERE clauses.
@@ -48,16 +204,12 @@ func NodeLabelPredicate(nodeVar string) string {
 	return fmt.Sprintf("label(%s) IN ['Memory', 'Plan', 'Task']", nodeVar)
 }
 
-// tagsToCypherList converts a Go string slice to a Cypher list literal.
-func tagsToCypherList(tags []string) string {
-	if len(tags) == 0 {
-		return "[]"
-	}
-	escaped := make([]string, len(tags))
-	for i, t := range tags {
-		escaped[i] = fmt.Sprintf("'%s'", EscapeCypherString(t))
+// tagsParam returns tags as a Cypher list parameter, using an empty list for nil.
+func tagsParam(tags []string) []string {
+	if tags == nil {
+		return []string{}
 	}
-	return "[" + strings.Join(escaped, ", ") + "]"
+	return tags
 }
 
 // metadataToJSON converts a metadata map to a JSON string.
@@ -100,6 +252,55 @@ func joinStrings(strs []string, sep string) string {
 	return strings.Join(strs, sep)
 }
 
+// ageEntity is a vertex or edge in an agtype path.
+type ageEntity struct {
+	ID         int64          `json:"id"`
+	Label      string         `json:"label"`
+	StartID    int64          `json:"start_id"` // Edges only
+	EndID      int64          `json:"end_id"`   // Edges only
+	Properties map[string]any `json:"properties"`
+}
+
+// parseAGTypePath parses an agtype path, which alternates vertices and edges:
+// [{...}::vertex, {...}::edge, {...}::vertex]::path
+func parseAGTypePath(agtypeStr string) ([]ageEntity, error) {
+	// Drop the ::type annotations, which may only appear outside strings
+	var sb strings.Builder
+	inString, escaped := false, false
+	for i := 0; i < len(agtypeStr); i++ {
+		ch := agtypeStr[i]
+		switch {
+		case inString:
+			if escaped {
+				escaped = false
+			} else if ch == '\\' {
+				escaped = true
+			} else if ch == '"' {
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+		case ch == ':' && strings.HasPrefix(agtypeStr[i:], "::"):
+			i += 2
+			for i < len(agtypeStr) && agtypeStr[i] >= 'a' && agtypeStr[i] <= 'z' {
+				i++
+			}
+			i--
+			continue
+		}
+		sb.WriteByte(ch)
+	}
+
+	var path []ageEntity
+	if err := json.Unmarshal([]byte(sb.String()), &path); err != nil {
+		return nil, err
+	}
+	if len(path)%2 == 0 {
+		return nil, fmt.Errorf("malformed path: %d elements", len(path))
+	}
+	return path, nil
+}
+
 // parseAGTypeProperties parses the properties from an agtype vertex/edge string.
 // AGE returns vertices like: {"id": 12345, "label": "Memory", "properties": {"id": "abc", ...}}::vertex
 func parseAGTypeProperties(agtypeStr string) (map[string]interface{}, error) {
@@ -116,6 +317,18 @@ func parseAGTypeProperties(agtypeStr string) (map[string]interface{}, error) {
 	return wrapper.Properties, nil
 }
 
+// propsToRelatedMemory flattens a node of any label into the Memory shape
+// used by get_related. Plans expose their name as content, and Type holds
+// the node label.
+func propsToRelatedMemory(label string, props map[string]interface{}) models.Memory {
+	mem := propsToMemory(props)
+	mem.Type = models.MemoryType(label)
+	if label == "Plan" {
+		mem.Content = getString(props, "name")
+	}
+	return mem
+}
+
 // propsToMemory converts a properties map to a Memory struct.
 func propsToMemory(props map[string]interface{}) models.Memory {
 	mem := models.Memory{
@@ -149,6 +362,8 @@ func propsToMemory(props map[string]interface{}) models.Memory {
 		}
 	}
 
+	mem.Version = getVersion(props)
+
 	return mem
 }
 
@@ -186,6 +401,8 @@ func propsToPlan(props map[string]interface{}) models.Plan {
 		}
 	}
 
+	plan.Version = getVersion(props)
+
 	return plan
 }
 
@@ -222,9 +439,39 @@ func propsToTask(props map[string]interface{}) models.Task {
 		}
 	}
 
+	task.Version = getVersion(props)
+
 	return task
 }
 
+// propsToRelationshipProperties converts the properties map of an edge.
+func propsToRelationshipProperties(props map[string]interface{}) models.RelationshipProperties {
+	p := models.RelationshipProperties{
+		Reason:   getString(props, "reason"),
+		Metadata: jsonToMetadata(getString(props, "metadata")),
+	}
+	if weight, ok := props["weight"]; ok && weight != nil {
+		w := toFloat64(weight)
+		p.Weight = &w
+	}
+	if createdStr := getString(props, "created_at"); createdStr != "" {
+		if t, err := time.Parse(time.RFC3339, createdStr); err == nil {
+			p.CreatedAt = t
+		}
+	}
+	return p
+}
+
+// parseRelationshipProperties parses the agtype map returned by
+// properties(r). Unparseable properties are treated as unset.
+func parseRelationshipProperties(agtypeStr string) models.RelationshipProperties {
+	var props map[string]interface{}
+	if err := json.Unmarshal([]byte(agtypeStr), &props); err != nil {
+		return models.RelationshipProperties{}
+	}
+	return propsToRelationshipProperties(props)
+}
+
 // toFloat64 converts various numeric types to float64.
 func toFloat64(v interface{}) float64 {
 	switch val := v.(type) {
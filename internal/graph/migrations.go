@@ -0,0 +1,338 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/lib/pq"
+)
+
+// migrationsTable records which schema migrations have been applied. It lives
+// in the public schema because AGE puts ag_catalog first on the search path.
+const migrationsTable = "public.associate_schema_migrations"
+
+// ErrSchemaTooNew is returned when the database has migrations applied that
+// this binary does not know about, i.e. it was migrated by a newer release.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary supports")
+
+// Migration is one ordered step in the graph schema history. Each migration
+// runs in its own transaction together with the row that records it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, tx *sql.Tx, graphName string) error
+}
+
+// MigrationStatus describes a known migration and when it was applied.
+// AppliedAt is nil for pending migrations.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// migrations is the full schema history. Append new steps to the end with the
+// next version number; never edit or reorder a migration that has shipped.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create label tables",
+		Up: func(ctx context.Context, tx *sql.Tx, graphName string) error {
+			if _, err := tx.ExecContext(ctx, "CREATE EXTENSION IF NOT EXISTS pg_trgm"); err != nil {
+				return fmt.Errorf("failed to create pg_trgm extension: %w", err)
+			}
+			for _, label := range []string{"Memory", "Plan", "Task"} {
+				if err := ensureVertexLabel(ctx, tx, graphName, label); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add property indexes",
+		Up: func(ctx context.Context, tx *sql.Tx, graphName string) error {
+			if err := ensureEdgeLabel(ctx, tx, graphName, string(models.RelPartOf)); err != nil {
+				return err
+			}
+			for _, stmt := range propertyIndexStatements(graphName) {
+				if _, err := tx.ExecContext(ctx, stmt); err != nil {
+					return fmt.Errorf("failed to create index: %w", err)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create full-text search table",
+		Up:      createSearchTable,
+	},
+	{
+		Version: 4,
+		Name:    "create embeddings table",
+		Up:      createEmbeddingsTable,
+	},
+	{
+		Version: 5,
+		Name:    "add search filter columns",
+		Up:      addSearchFilterColumns,
+	},
+	{
+		Version: 6,
+		Name:    "index plans and tasks for search",
+		Up:      indexPlansAndTasks,
+	},
+	{
+		Version: 7,
+		Name:    "create revisions table",
+		Up:      createRevisionsTable,
+	},
+	{
+		Version: 8,
+		Name:    "create trash table",
+		Up:      createTrashTable,
+	},
+	{
+		Version: 9,
+		Name:    "backfill revisions",
+		Up:      backfillRevisions,
+	},
+}
+
+// indexedProperties are the vertex properties used in equality filters and
+// ordering, indexed through AGE's property access operator.
+var indexedProperties = []string{"id", "status", "node_type", "updated_at"}
+
+// propertyIndexStatements returns the CREATE INDEX statements for the label
+// tables. Map patterns such as {id: $id} compile to properties @> containment,
+// which the GIN indexes serve; WHERE n.prop = $x comparisons use the btree
+// expression indexes.
+func propertyIndexStatements(graphName string) []string {
+	schema := pq.QuoteIdentifier(graphName)
+	var stmts []string
+
+	for _, label := range []string{"Memory", "Plan", "Task"} {
+		table := schema + "." + pq.QuoteIdentifier(label)
+		prefix := strings.ToLower(label)
+		stmts = append(stmts,
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_graphid_idx ON %s USING btree (id)`, prefix, table),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_properties_idx ON %s USING gin (properties)`, prefix, table),
+		)
+		for _, prop := range indexedProperties {
+			stmts = append(stmts, fmt.Sprintf(
+				`CREATE INDEX IF NOT EXISTS %s_%s_idx ON %s USING btree (ag_catalog.agtype_access_operator(VARIADIC ARRAY[properties, '"%s"'::ag_catalog.agtype]))`,
+				prefix, prop, table, prop))
+		}
+	}
+
+	edges := schema + "." + pq.QuoteIdentifier(string(models.RelPartOf))
+	stmts = append(stmts,
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS part_of_start_id_idx ON %s USING btree (start_id)`, edges),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS part_of_end_id_idx ON %s USING btree (end_id)`, edges),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS part_of_properties_idx ON %s USING gin (properties)`, edges),
+	)
+	return stmts
+}
+
+// LatestSchemaVersion returns the version of the newest migration this binary knows.
+func LatestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// ensureVertexLabel creates the label table for a vertex label unless it already
+// exists. Graphs created before migrations existed have them from seed nodes.
+func ensureVertexLabel(ctx context.Context, tx *sql.Tx, graphName, label string) error {
+	exists, err := labelExists(ctx, tx, graphName, label)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT ag_catalog.create_vlabel($1, $2)", graphName, label); err != nil {
+		return fmt.Errorf("failed to create %s label: %w", label, err)
+	}
+	return nil
+}
+
+// ensureEdgeLabel creates the label table for an edge label unless it already
+// exists. AGE only creates edge tables on first use, and indexes need the table.
+func ensureEdgeLabel(ctx context.Context, tx *sql.Tx, graphName, label string) error {
+	exists, err := labelExists(ctx, tx, graphName, label)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT ag_catalog.create_elabel($1, $2)", graphName, label); err != nil {
+		return fmt.Errorf("failed to create %s label: %w", label, err)
+	}
+	return nil
+}
+
+// labelExists reports whether a vertex or edge label exists in the graph.
+func labelExists(ctx context.Context, tx *sql.Tx, graphName, label string) (bool, error) {
+	var exists bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS(
+			SELECT 1 FROM ag_catalog.ag_label l
+			JOIN ag_catalog.ag_graph g ON g.graphid = l.graph
+			WHERE g.name = $1 AND l.name = $2)`,
+		graphName, label).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check %s label: %w", label, err)
+	}
+	return exists, nil
+}
+
+// checkSchemaVersion fails when the database is ahead of the given latest version.
+func checkSchemaVersion(current, latest int) error {
+	if current > latest {
+		return fmt.Errorf("%w: database is at version %d, this binary supports up to %d", ErrSchemaTooNew, current, latest)
+	}
+	return nil
+}
+
+// ensureMigrationsTable creates the migrations table if needed.
+func (c *Client) ensureMigrationsTable(ctx context.Context) error {
+	_, err := c.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+migrationsTable+` (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	return nil
+}
+
+// SchemaVersion returns the highest applied migration version, or 0 for a
+// graph that has never been migrated.
+func (c *Client) SchemaVersion(ctx context.Context) (int, error) {
+	if err := c.ensureMigrationsTable(ctx); err != nil {
+		return 0, err
+	}
+	var version int
+	err := c.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM `+migrationsTable).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// MigrationStatus lists every known migration along with when it was applied.
+func (c *Client) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	if err := c.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := c.db.QueryContext(ctx, `SELECT version, applied_at FROM `+migrationsTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = MigrationStatus{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			statuses[i].AppliedAt = &at
+		}
+	}
+	return statuses, nil
+}
+
+// Migrate applies all pending migrations in order and returns how many ran.
+// It refuses to touch a database whose schema is newer than this binary.
+func (c *Client) Migrate(ctx context.Context) (int, error) {
+	current, err := c.SchemaVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkSchemaVersion(current, LatestSchemaVersion()); err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		ran, err := c.applyMigration(ctx, m)
+		if err != nil {
+			return applied, fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		if ran {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// applyMigration runs a single migration under an advisory lock so that
+// concurrent servers starting against the same database apply it only once.
+func (c *Client) applyMigration(ctx context.Context, m Migration) (bool, error) {
+	tx, err := c.BeginTx(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", migrationsTable); err != nil {
+		return false, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+
+	var done bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM `+migrationsTable+` WHERE version = $1)`, m.Version).Scan(&done)
+	if err != nil {
+		return false, fmt.Errorf("failed to check migration: %w", err)
+	}
+	if done {
+		return false, nil
+	}
+
+	// The pooled connection may not have run initAGE's SET, so scope the
+	// AGE search path to this transaction for migrations that use cypher().
+	if _, err := tx.ExecContext(ctx, `SET LOCAL search_path = ag_catalog, "$user", public`); err != nil {
+		return false, fmt.Errorf("failed to set search path: %w", err)
+	}
+
+	if err := m.Up(ctx, tx, c.graphName); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO `+migrationsTable+` (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+		return false, fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,59 @@
+//go:build integration
+// +build integration
+
+package graph
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMigrate_Idempotent(t *testing.T) {
+	client, ctx, cancel := getTestClient(t)
+	defer cancel()
+	defer client.Close(ctx)
+
+	// NewClient has already migrated, so a second run applies nothing
+	applied, err := client.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if applied != 0 {
+		t.Errorf("expected no pending migrations, applied %d", applied)
+	}
+
+	version, err := client.SchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("SchemaVersion failed: %v", err)
+	}
+	if version != LatestSchemaVersion() {
+		t.Errorf("schema version = %d, want %d", version, LatestSchemaVersion())
+	}
+
+	statuses, err := client.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus failed: %v", err)
+	}
+	for _, st := range statuses {
+		if st.AppliedAt == nil {
+			t.Errorf("migration %d (%s) not applied", st.Version, st.Name)
+		}
+	}
+}
+
+func TestMigrate_RefusesNewerSchema(t *testing.T) {
+	client, ctx, cancel := getTestClient(t)
+	defer cancel()
+	defer client.Close(ctx)
+
+	future := LatestSchemaVersion() + 1
+	if _, err := client.DB().ExecContext(ctx,
+		`INSERT INTO `+migrationsTable+` (version, name) VALUES ($1, 'from the future')`, future); err != nil {
+		t.Fatalf("failed to insert future migration: %v", err)
+	}
+	defer client.DB().ExecContext(ctx, `DELETE FROM `+migrationsTable+` WHERE version = $1`, future)
+
+	if _, err := client.Migrate(ctx); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Migrate: got %v, want ErrSchemaTooNew", err)
+	}
+}
@@ -0,0 +1,61 @@
+package graph
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMigrations_Ordered(t *testing.T) {
+	if len(migrations) == 0 {
+		t.Fatal("expected at least one migration")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %d has version %d, want %d", i, m.Version, i+1)
+		}
+		if m.Name == "" {
+			t.Errorf("migration %d has no name", m.Version)
+		}
+		if m.Up == nil {
+			t.Errorf("migration %d has no Up function", m.Version)
+		}
+	}
+	if got := LatestSchemaVersion(); got != len(migrations) {
+		t.Errorf("LatestSchemaVersion() = %d, want %d", got, len(migrations))
+	}
+}
+
+func TestCheckSchemaVersion(t *testing.T) {
+	if err := checkSchemaVersion(0, 3); err != nil {
+		t.Errorf("unmigrated database: unexpected error %v", err)
+	}
+	if err := checkSchemaVersion(3, 3); err != nil {
+		t.Errorf("current database: unexpected error %v", err)
+	}
+	if err := checkSchemaVersion(4, 3); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("newer database: got %v, want ErrSchemaTooNew", err)
+	}
+}
+
+func TestPropertyIndexStatements(t *testing.T) {
+	stmts := propertyIndexStatements(GraphName)
+
+	want := []string{
+		`CREATE INDEX IF NOT EXISTS memory_properties_idx ON "associate"."Memory" USING gin (properties)`,
+		`CREATE INDEX IF NOT EXISTS task_status_idx ON "associate"."Task" USING btree (ag_catalog.agtype_access_operator(VARIADIC ARRAY[properties, '"status"'::ag_catalog.agtype]))`,
+		`CREATE INDEX IF NOT EXISTS plan_updated_at_idx ON "associate"."Plan" USING btree (ag_catalog.agtype_access_operator(VARIADIC ARRAY[properties, '"updated_at"'::ag_catalog.agtype]))`,
+		`CREATE INDEX IF NOT EXISTS part_of_start_id_idx ON "associate"."PART_OF" USING btree (start_id)`,
+	}
+	for _, w := range want {
+		found := false
+		for _, s := range stmts {
+			if s == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("missing index statement: %s", w)
+		}
+	}
+}
@@ -4,13 +4,18 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Thomas-Fitz/associate/internal/embedding"
 	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/store"
 	"github.com/google/uuid"
 )
 
+var _ store.PlanStore = (*PlanRepository)(nil)
+
 // PlanRepository provides CRUD operations for plans.
 type PlanRepository struct {
 	client *Client
@@ -35,46 +40,54 @@ func (r *PlanRepository) Add(ctx context.Context, plan models.Plan, relationship
 	now := time.Now().UTC()
 	plan.CreatedAt = now
 	plan.UpdatedAt = now
+	plan.Version = 1
 
 	if plan.Status == "" {
 		plan.Status = models.PlanStatusActive
 	}
 
-	metadataJSON := metadataToJSON(plan.Metadata)
-	tagsList := tagsToCypherList(plan.Tags)
-
-	cypher := fmt.Sprintf(
-		`CREATE (p:Plan {
-			id: '%s',
+	cypher := `CREATE (p:Plan {
+			id: $id,
 			node_type: 'Plan',
-			name: '%s',
-			description: '%s',
-			status: '%s',
-			metadata: '%s',
-			tags: %s,
-			created_at: '%s',
-			updated_at: '%s'
-		}) RETURN p`,
-		EscapeCypherString(plan.ID),
-		EscapeCypherString(plan.Name),
-		EscapeCypherString(plan.Description),
-		EscapeCypherString(string(plan.Status)),
-		EscapeCypherString(metadataJSON),
-		tagsList,
-		plan.CreatedAt.Format(time.RFC3339),
-		plan.UpdatedAt.Format(time.RFC3339),
-	)
-
-	rows, err := r.client.execCypher(ctx, tx, cypher, "p agtype")
+			name: $name,
+			description: $description,
+			status: $status,
+			metadata: $metadata,
+			tags: $tags,
+			created_at: $created_at,
+			updated_at: $updated_at,
+			version: 1
+		}) RETURN p`
+
+	rows, err := r.client.execCypher(ctx, tx, cypher, "p agtype", map[string]any{
+		"id":          plan.ID,
+		"name":        plan.Name,
+		"description": plan.Description,
+		"status":      string(plan.Status),
+		"metadata":    metadataToJSON(plan.Metadata),
+		"tags":        tagsParam(plan.Tags),
+		"created_at":  plan.CreatedAt.Format(time.RFC3339),
+		"updated_at":  plan.UpdatedAt.Format(time.RFC3339),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create plan: %w", err)
 	}
 	rows.Close()
+	if err := recordRevision(ctx, tx, models.PlanRevision(plan, store.Actor(ctx))); err != nil {
+		return nil, err
+	}
+
+	if err := indexPlan(ctx, tx, plan); err != nil {
+		return nil, err
+	}
+	if err := r.client.saveEmbedding(ctx, tx, plan.ID, "Plan", embedding.PlanText(plan.Name, plan.Description)); err != nil {
+		return nil, err
+	}
 
 	// Create relationships
 	for _, rel := range relationships {
-		if err := r.createRelationshipFromPlan(ctx, tx, plan.ID, rel.ToID, rel.Type); err != nil {
-			fmt.Printf("warning: failed to create relationship: %v\n", err)
+		if err := r.client.createRelationship(ctx, tx, plan.ID, rel); err != nil {
+			return nil, err
 		}
 	}
 
@@ -87,9 +100,9 @@ func (r *PlanRepository) Add(ctx context.Context, plan models.Plan, relationship
 
 // GetByID retrieves a plan by ID
 func (r *PlanRepository) GetByID(ctx context.Context, id string) (*models.Plan, error) {
-	cypher := fmt.Sprintf(`MATCH (p:Plan {id: '%s'}) RETURN p`, EscapeCypherString(id))
+	cypher := `MATCH (p:Plan {id: $id}) RETURN p`
 
-	rows, err := r.client.execCypher(ctx, nil, cypher, "p agtype")
+	rows, err := r.client.execCypher(ctx, nil, cypher, "p agtype", map[string]any{"id": id})
 	if err != nil {
 		return nil, err
 	}
@@ -121,9 +134,11 @@ func (r *PlanRepository) GetWithTasks(ctx context.Context, id string) (*models.P
 	}
 	defer tx.Rollback()
 
+	params := map[string]any{"id": id}
+
 	// First get the plan
-	planCypher := fmt.Sprintf(`MATCH (p:Plan {id: '%s'}) RETURN p`, EscapeCypherString(id))
-	planRows, err := r.client.execCypher(ctx, tx, planCypher, "p agtype")
+	planCypher := `MATCH (p:Plan {id: $id}) RETURN p`
+	planRows, err := r.client.execCypher(ctx, tx, planCypher, "p agtype", params)
 	if err != nil {
 		return nil, nil, fmt.Errorf("query failed: %w", err)
 	}
@@ -146,19 +161,16 @@ func (r *PlanRepository) GetWithTasks(ctx context.Context, id string) (*models.P
 	}
 
 	// Get tasks with position
-	tasksCypher := fmt.Sprintf(
-		`MATCH (t:Task)-[r:PART_OF]->(p:Plan {id: '%s'})
+	tasksCypher := `MATCH (t:Task)-[r:PART_OF]->(p:Plan {id: $id})
 		 RETURN t, r.position
-		 ORDER BY r.position ASC`,
-		EscapeCypherString(id))
+		 ORDER BY r.position ASC`
 
-	tasksRows, err := r.client.execCypher(ctx, tx, tasksCypher, "t agtype, position agtype")
+	tasksRows, err := r.client.execCypher(ctx, tx, tasksCypher, "t agtype, position agtype", params)
 	if err != nil {
 		return nil, nil, fmt.Errorf("tasks query failed: %w", err)
 	}
 
 	var tasks []models.TaskInPlan
-	taskIDs := []string{}
 	for tasksRows.Next() {
 		var taskStr, posStr string
 		if err := tasksRows.Scan(&taskStr, &posStr); err != nil {
@@ -173,7 +185,6 @@ func (r *PlanRepository) GetWithTasks(ctx context.Context, id string) (*models.P
 		task := propsToTask(props)
 		position := parseAGTypeFloat(posStr)
 
-		taskIDs = append(taskIDs, task.ID)
 		tasks = append(tasks, models.TaskInPlan{
 			Task:     task,
 			Position: position,
@@ -181,55 +192,50 @@ func (r *PlanRepository) GetWithTasks(ctx context.Context, id string) (*models.P
 	}
 	tasksRows.Close()
 
-	// Get dependencies and blocks for each task
-	for i, taskInPlan := range tasks {
-		// Get DEPENDS_ON relationships
-		depCypher := fmt.Sprintf(
-			`MATCH (t:Task {id: '%s'})-[:DEPENDS_ON]->(dep:Task)-[:PART_OF]->(p:Plan {id: '%s'})
-			 RETURN dep.id`,
-			EscapeCypherString(taskInPlan.Task.ID),
-			EscapeCypherString(id))
-		depRows, err := r.client.execCypher(ctx, tx, depCypher, "dep_id agtype")
-		if err == nil {
-			for depRows.Next() {
-				var depID string
-				if err := depRows.Scan(&depID); err == nil {
-					depID = strings.Trim(depID, "\"")
-					if depID != "" {
-						tasks[i].DependsOn = append(tasks[i].DependsOn, depID)
-					}
-				}
-			}
-			depRows.Close()
-		}
+	// Get dependencies and blocks within the plan for every task in one query
+	depRows, err := r.client.execCypher(ctx, tx,
+		`MATCH (t:Task)-[:PART_OF]->(p:Plan {id: $id}), (t)-[rel:DEPENDS_ON|BLOCKS]->(other:Task)-[:PART_OF]->(p)
+		 RETURN t.id, type(rel), other.id`,
+		"task_id agtype, rel_type agtype, other_id agtype", params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dependencies query failed: %w", err)
+	}
+	defer depRows.Close()
 
-		// Get BLOCKS relationships
-		blkCypher := fmt.Sprintf(
-			`MATCH (t:Task {id: '%s'})-[:BLOCKS]->(blk:Task)-[:PART_OF]->(p:Plan {id: '%s'})
-			 RETURN blk.id`,
-			EscapeCypherString(taskInPlan.Task.ID),
-			EscapeCypherString(id))
-		blkRows, err := r.client.execCypher(ctx, tx, blkCypher, "blk_id agtype")
-		if err == nil {
-			for blkRows.Next() {
-				var blkID string
-				if err := blkRows.Scan(&blkID); err == nil {
-					blkID = strings.Trim(blkID, "\"")
-					if blkID != "" {
-						tasks[i].Blocks = append(tasks[i].Blocks, blkID)
-					}
-				}
-			}
-			blkRows.Close()
+	index := make(map[string]int, len(tasks))
+	for i, t := range tasks {
+		index[t.Task.ID] = i
+	}
+	for depRows.Next() {
+		var taskID, relType, otherID string
+		if err := depRows.Scan(&taskID, &relType, &otherID); err != nil {
+			return nil, nil, err
+		}
+		i, ok := index[strings.Trim(taskID, "\"")]
+		otherID = strings.Trim(otherID, "\"")
+		if !ok || otherID == "" {
+			continue
 		}
+		switch models.RelationType(strings.Trim(relType, "\"")) {
+		case models.RelDependsOn:
+			tasks[i].DependsOn = append(tasks[i].DependsOn, otherID)
+		case models.RelBlocks:
+			tasks[i].Blocks = append(tasks[i].Blocks, otherID)
+		}
+	}
+	if err := depRows.Err(); err != nil {
+		return nil, nil, err
 	}
+	depRows.Close()
 
-	tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit: %w", err)
+	}
 	return plan, tasks, nil
 }
 
 // Update modifies an existing plan
-func (r *PlanRepository) Update(ctx context.Context, id string, name *string, description *string, status *string, metadata map[string]string, tags []string, newRelationships []models.Relationship) (*models.Plan, error) {
+func (r *PlanRepository) Update(ctx context.Context, id string, expectedVersion int64, name *string, description *string, status *string, metadata map[string]string, tags []string, newRelationships []models.Relationship) (*models.Plan, error) {
 	tx, err := r.client.BeginTx(ctx)
 	if err != nil {
 		return nil, err
@@ -237,32 +243,41 @@ func (r *PlanRepository) Update(ctx context.Context, id string, name *string, de
 	defer tx.Rollback()
 
 	// Build dynamic SET clause
-	setClauses := []string{fmt.Sprintf("p.updated_at = '%s'", time.Now().UTC().Format(time.RFC3339))}
+	params := map[string]any{
+		"id":         id,
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	}
+	setClauses := []string{"p.updated_at = $updated_at", versionSet("p")}
 
 	if name != nil {
-		setClauses = append(setClauses, fmt.Sprintf("p.name = '%s'", EscapeCypherString(*name)))
+		setClauses = append(setClauses, "p.name = $name")
+		params["name"] = *name
 	}
 	if description != nil {
-		setClauses = append(setClauses, fmt.Sprintf("p.description = '%s'", EscapeCypherString(*description)))
+		setClauses = append(setClauses, "p.description = $description")
+		params["description"] = *description
 	}
 	if status != nil {
-		setClauses = append(setClauses, fmt.Sprintf("p.status = '%s'", EscapeCypherString(*status)))
+		setClauses = append(setClauses, "p.status = $status")
+		params["status"] = *status
 	}
 	if metadata != nil {
-		setClauses = append(setClauses, fmt.Sprintf("p.metadata = '%s'", EscapeCypherString(metadataToJSON(metadata))))
+		setClauses = append(setClauses, "p.metadata = $metadata")
+		params["metadata"] = metadataToJSON(metadata)
 	}
 	if tags != nil {
-		setClauses = append(setClauses, fmt.Sprintf("p.tags = %s", tagsToCypherList(tags)))
+		setClauses = append(setClauses, "p.tags = $tags")
+		params["tags"] = tagsParam(tags)
 	}
 
 	cypher := fmt.Sprintf(`
-		MATCH (p:Plan {id: '%s'})
+		MATCH (p:Plan {id: $id})
+		%s
 		SET %s
 		RETURN p`,
-		EscapeCypherString(id),
-		joinStrings(setClauses, ", "))
+		versionPredicate("p", expectedVersion, params), joinStrings(setClauses, ", "))
 
-	rows, err := r.client.execCypher(ctx, tx, cypher, "p agtype")
+	rows, err := r.client.execCypher(ctx, tx, cypher, "p agtype", params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update plan: %w", err)
 	}
@@ -281,13 +296,28 @@ func (r *PlanRepository) Update(ctx context.Context, id string, name *string, de
 	rows.Close()
 
 	if plan == nil {
+		if err := r.client.versionConflict(ctx, tx, "Plan", id, expectedVersion); err != nil {
+			return nil, err
+		}
 		return nil, fmt.Errorf("plan not found: %s", id)
 	}
+	if err := recordRevision(ctx, tx, models.PlanRevision(*plan, store.Actor(ctx))); err != nil {
+		return nil, err
+	}
+
+	if err := indexPlan(ctx, tx, *plan); err != nil {
+		return nil, err
+	}
+	if name != nil || description != nil {
+		if err := r.client.saveEmbedding(ctx, tx, id, "Plan", embedding.PlanText(plan.Name, plan.Description)); err != nil {
+			return nil, err
+		}
+	}
 
 	// Create new relationships
 	for _, rel := range newRelationships {
-		if err := r.createRelationshipFromPlan(ctx, tx, id, rel.ToID, rel.Type); err != nil {
-			fmt.Printf("warning: failed to create relationship: %v\n", err)
+		if err := r.client.createRelationship(ctx, tx, id, rel); err != nil {
+			return nil, err
 		}
 	}
 
@@ -298,130 +328,332 @@ func (r *PlanRepository) Update(ctx context.Context, id string, name *string, de
 	return plan, nil
 }
 
-// Delete removes a plan and cascades to tasks not linked to other plans.
-// Uses multi-step Go loop since AGE doesn't support FOREACH/NOT EXISTS patterns.
-func (r *PlanRepository) Delete(ctx context.Context, id string) (int, error) {
+// Delete moves a plan to the trash and cascades to tasks not linked to other
+// plans, unless the options keep them.
+func (r *PlanRepository) Delete(ctx context.Context, id string, opts models.PlanDeleteOptions) (int, models.StatusChanges, error) {
+	if err := opts.Validate(id); err != nil {
+		return 0, models.StatusChanges{}, err
+	}
+
 	tx, err := r.client.BeginTx(ctx)
 	if err != nil {
-		return 0, err
+		return 0, models.StatusChanges{}, err
 	}
 	defer tx.Rollback()
 
-	// Step 1: Get all tasks that belong to this plan
-	tasksCypher := fmt.Sprintf(
-		`MATCH (t:Task)-[:PART_OF]->(p:Plan {id: '%s'})
-		 RETURN t.id`,
-		EscapeCypherString(id))
-
-	tasksRows, err := r.client.execCypher(ctx, tx, tasksCypher, "task_id agtype")
+	label, err := r.client.nodeLabel(ctx, tx, id)
+	if err != nil {
+		return 0, models.StatusChanges{}, fmt.Errorf("delete failed: %w", err)
+	}
+	if label != "Plan" {
+		return 0, models.StatusChanges{}, nil
+	}
+	ids, kept, err := r.deletion(ctx, tx, id, opts)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get tasks: %w", err)
+		return 0, models.StatusChanges{}, err
 	}
 
-	var taskIDs []string
-	for tasksRows.Next() {
-		var taskID string
-		if err := tasksRows.Scan(&taskID); err == nil {
-			taskID = strings.Trim(taskID, "\"")
-			if taskID != "" {
-				taskIDs = append(taskIDs, taskID)
+	tasks := NewTaskRepository(r.client)
+
+	// Append kept tasks to the target plan in their order
+	if opts.OrphanTasks == models.OrphanTasksMove {
+		for _, taskID := range kept {
+			maxPos, err := tasks.getMaxPosition(ctx, tx, opts.TargetPlanID)
+			if err != nil {
+				return 0, models.StatusChanges{}, fmt.Errorf("failed to get max position for plan %s: %w", opts.TargetPlanID, err)
+			}
+			if err := tasks.createTaskToPlanRelationship(ctx, tx, taskID, opts.TargetPlanID, appendPosition(maxPos)); err != nil {
+				return 0, models.StatusChanges{}, fmt.Errorf("failed to move task %s: %w", taskID, err)
 			}
 		}
 	}
-	tasksRows.Close()
 
-	// Step 2: For each task, check if it belongs to other plans
-	deletedCount := 0
-	for _, taskID := range taskIDs {
-		// Check for other plans
-		otherPlanCypher := fmt.Sprintf(
-			`MATCH (t:Task {id: '%s'})-[:PART_OF]->(other:Plan)
-			 WHERE other.id <> '%s'
-			 RETURN count(other) > 0`,
-			EscapeCypherString(taskID),
-			EscapeCypherString(id))
-
-		otherRows, err := r.client.execCypher(ctx, tx, otherPlanCypher, "has_other agtype")
-		if err != nil {
+	// Move the plan and the tasks going with it to the trash
+	var changes models.StatusChanges
+	if changes.Tasks, err = tasks.trashNodes(ctx, tx, ids...); err != nil {
+		return 0, models.StatusChanges{}, err
+	}
+	if r.client.planCompletion && opts.OrphanTasks == models.OrphanTasksMove && len(kept) > 0 {
+		if changes.Plans, err = tasks.applyPlanCompletion(ctx, tx, []string{opts.TargetPlanID}); err != nil {
+			return 0, models.StatusChanges{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, models.StatusChanges{}, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	return len(ids) - 1, changes, nil
+}
+
+// PreviewDelete returns what Delete would move to the trash
+func (r *PlanRepository) PreviewDelete(ctx context.Context, id string, opts models.PlanDeleteOptions) (*models.TrashEntry, error) {
+	if err := opts.Validate(id); err != nil {
+		return nil, err
+	}
+
+	tx, err := r.client.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Nothing is written, so the transaction is only ever rolled back
+	defer tx.Rollback()
+
+	if label, err := r.client.nodeLabel(ctx, tx, id); err != nil || label != "Plan" {
+		return nil, err
+	}
+	ids, _, err := r.deletion(ctx, tx, id, opts)
+	if err != nil {
+		return nil, err
+	}
+	return r.client.trashEntry(ctx, tx, ids...)
+}
+
+// DependencyCycles returns the dependency cycles the plan's tasks are on or wait on
+func (r *PlanRepository) DependencyCycles(ctx context.Context, id string) ([][]string, error) {
+	tx, err := r.client.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Nothing is written, so the transaction is only ever rolled back
+	defer tx.Rollback()
+
+	taskIDs, err := r.client.planTaskIDs(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	return store.FindCycles(ctx, taskIDs, r.client.dependencyEdges(tx))
+}
+
+// Waits returns the tasks that each of the plan's tasks waits for
+func (r *PlanRepository) Waits(ctx context.Context, id string) (map[string][]models.Wait, error) {
+	tx, err := r.client.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Nothing is written, so the transaction is only ever rolled back
+	defer tx.Rollback()
+
+	taskIDs, err := r.client.planTaskIDs(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	waits, err := r.client.taskWaits(ctx, tx, taskIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find dependencies: %w", err)
+	}
+	return waits, nil
+}
+
+// Progress returns the progress of each of the given plans
+func (r *PlanRepository) Progress(ctx context.Context, ids []string) (map[string]models.PlanProgress, error) {
+	tx, err := r.client.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Nothing is written, so the transaction is only ever rolled back
+	defer tx.Rollback()
+
+	progress, err := r.client.planProgress(ctx, tx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tasks: %w", err)
+	}
+	return progress, nil
+}
+
+// planProgress returns the progress of each of the given plans that exists,
+// keyed by plan ID, counting their tasks by status rather than reading them.
+func (c *Client) planProgress(ctx context.Context, tx *sql.Tx, ids []string) (map[string]models.PlanProgress, error) {
+	progress := make(map[string]models.PlanProgress, len(ids))
+	if len(ids) == 0 {
+		return progress, nil
+	}
+	params := map[string]any{"ids": ids}
+
+	rows, err := c.execCypher(ctx, tx,
+		`MATCH (p:Plan) WHERE p.id IN $ids RETURN p.id, p.updated_at`,
+		"id agtype, updated_at agtype", params)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id, updatedAt string
+		if err := rows.Scan(&id, &updatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		t, _ := time.Parse(time.RFC3339, strings.Trim(updatedAt, "\""))
+		progress[strings.Trim(id, "\"")] = models.NewPlanProgress(t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = c.execCypher(ctx, tx,
+		`MATCH (t:Task)-[:PART_OF]->(p:Plan) WHERE p.id IN $ids
+		 RETURN p.id, t.status, count(t), max(t.updated_at)`,
+		"id agtype, status agtype, count agtype, updated_at agtype", params)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, status, count, updatedAt string
+		if err := rows.Scan(&id, &status, &count, &updatedAt); err != nil {
+			return nil, err
+		}
+		p, ok := progress[strings.Trim(id, "\"")]
+		if !ok {
 			continue
 		}
+		n, _ := strconv.Atoi(count)
+		t, _ := time.Parse(time.RFC3339, strings.Trim(updatedAt, "\""))
+		p.Add(models.TaskStatus(strings.Trim(status, "\"")), n, t)
+		progress[strings.Trim(id, "\"")] = p
+	}
+	return progress, rows.Err()
+}
 
-		hasOther := false
-		if otherRows.Next() {
-			var hasOtherStr string
-			if err := otherRows.Scan(&hasOtherStr); err == nil {
-				hasOther = strings.Trim(hasOtherStr, "\"") == "true"
-			}
+// planTaskIDs returns the IDs of the tasks of a plan in position order. It
+// fails when there is no such plan.
+func (c *Client) planTaskIDs(ctx context.Context, tx *sql.Tx, id string) ([]string, error) {
+	label, err := c.nodeLabel(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if label != "Plan" {
+		return nil, fmt.Errorf("plan not found: %s", id)
+	}
+
+	rows, err := c.execCypher(ctx, tx,
+		`MATCH (t:Task)-[r:PART_OF]->(p:Plan {id: $id})
+		 RETURN t.id
+		 ORDER BY r.position`,
+		"task_id agtype", map[string]any{"id": id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var taskIDs []string
+	for rows.Next() {
+		var taskID string
+		if err := rows.Scan(&taskID); err != nil {
+			return nil, err
 		}
-		otherRows.Close()
-
-		// If task has no other plans, delete it
-		if !hasOther {
-			deleteCypher := fmt.Sprintf(
-				`MATCH (t:Task {id: '%s'}) DETACH DELETE t RETURN true`,
-				EscapeCypherString(taskID))
-			delRows, err := r.client.execCypher(ctx, tx, deleteCypher, "result agtype")
-			if err == nil {
-				delRows.Close()
-				deletedCount++
-			}
+		taskIDs = append(taskIDs, strings.Trim(taskID, "\""))
+	}
+	return taskIDs, rows.Err()
+}
+
+// deletion returns the IDs of the nodes deleting a plan moves to the trash,
+// the plan first, and of the tasks belonging to no other plan that the options
+// keep, in position order.
+// Uses multi-step Go loop since AGE doesn't support FOREACH/NOT EXISTS patterns.
+func (r *PlanRepository) deletion(ctx context.Context, tx *sql.Tx, id string, opts models.PlanDeleteOptions) (trashed, kept []string, err error) {
+	if opts.OrphanTasks == models.OrphanTasksMove {
+		label, err := r.client.nodeLabel(ctx, tx, opts.TargetPlanID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if label != "Plan" {
+			return nil, nil, fmt.Errorf("target plan not found: %s", opts.TargetPlanID)
 		}
 	}
 
-	// Step 3: Delete the plan itself
-	planDeleteCypher := fmt.Sprintf(
-		`MATCH (p:Plan {id: '%s'}) DETACH DELETE p RETURN true`,
-		EscapeCypherString(id))
+	// Step 1: Get all tasks that belong to this plan
+	tasksCypher := `MATCH (t:Task)-[r:PART_OF]->(p:Plan {id: $id})
+		 RETURN t.id
+		 ORDER BY r.position`
 
-	planRows, err := r.client.execCypher(ctx, tx, planDeleteCypher, "result agtype")
+	tasksRows, err := r.client.execCypher(ctx, tx, tasksCypher, "task_id agtype", map[string]any{"id": id})
 	if err != nil {
-		return 0, fmt.Errorf("delete failed: %w", err)
+		return nil, nil, fmt.Errorf("failed to get tasks: %w", err)
 	}
-	planRows.Close()
 
-	if err := tx.Commit(); err != nil {
-		return 0, fmt.Errorf("failed to commit: %w", err)
+	var taskIDs, orphans []string
+	for tasksRows.Next() {
+		var taskID string
+		if err := tasksRows.Scan(&taskID); err != nil {
+			tasksRows.Close()
+			return nil, nil, err
+		}
+		if taskID = strings.Trim(taskID, "\""); taskID != "" {
+			taskIDs = append(taskIDs, taskID)
+		}
+	}
+	tasksRows.Close()
+	if err := tasksRows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to get tasks: %w", err)
 	}
 
-	return deletedCount, nil
+	// Step 2: For each task, check if it belongs to other plans
+	for _, taskID := range taskIDs {
+		others, err := r.client.count(ctx, tx,
+			`MATCH (t:Task {id: $task_id})-[:PART_OF]->(other:Plan)
+			 WHERE other.id <> $plan_id
+			 RETURN count(other)`,
+			map[string]any{"task_id": taskID, "plan_id": id})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check other plans of %s: %w", taskID, err)
+		}
+
+		// If task has no other plans, it goes with the plan
+		if others == 0 {
+			orphans = append(orphans, taskID)
+		}
+	}
+
+	if opts.OrphanTasks == models.OrphanTasksDetach || opts.OrphanTasks == models.OrphanTasksMove {
+		return []string{id}, orphans, nil
+	}
+	return append([]string{id}, orphans...), nil, nil
 }
 
-// List retrieves plans with optional filtering
-func (r *PlanRepository) List(ctx context.Context, status string, tags []string, limit int) ([]models.Plan, error) {
+// List retrieves a page of plans with optional filtering
+func (r *PlanRepository) List(ctx context.Context, status string, tags []string, limit int, cursor string) ([]models.Plan, models.Page, error) {
 	if limit <= 0 {
 		limit = 50
 	}
+	c, err := models.DecodeListCursor(cursor)
+	if err != nil {
+		return nil, models.Page{}, err
+	}
 
 	// Build WHERE clause
+	params := map[string]any{}
 	whereClauses := []string{}
 	if status != "" {
-		whereClauses = append(whereClauses, fmt.Sprintf("p.status = '%s'", EscapeCypherString(status)))
+		whereClauses = append(whereClauses, "p.status = $status")
+		params["status"] = status
 	}
 	if len(tags) > 0 {
 		// Check if any provided tag is in the plan's tags
-		tagChecks := make([]string, len(tags))
-		for i, tag := range tags {
-			tagChecks[i] = fmt.Sprintf("'%s' IN p.tags", EscapeCypherString(tag))
-		}
-		whereClauses = append(whereClauses, "("+joinStrings(tagChecks, " OR ")+")")
+		whereClauses = append(whereClauses, tagsFilter("p", tags, params))
 	}
 
-	whereClause := ""
-	if len(whereClauses) > 0 {
-		whereClause = "WHERE " + joinStrings(whereClauses, " AND ")
+	var page models.Page
+	if page.Total, err = r.client.count(ctx, nil, `MATCH (p:Plan) `+whereSQL(whereClauses)+` RETURN count(p)`, params); err != nil {
+		return nil, models.Page{}, fmt.Errorf("list failed: %w", err)
 	}
 
+	if !c.IsZero() {
+		whereClauses = append(whereClauses, recencyKeyset("p", c, params))
+	}
+
+	// One extra row tells whether there is a next page
 	cypher := fmt.Sprintf(`
 		MATCH (p:Plan)
 		%s
 		RETURN p
-		ORDER BY p.updated_at DESC
+		ORDER BY p.updated_at DESC, p.id DESC
 		LIMIT %d`,
-		whereClause, limit)
+		whereSQL(whereClauses), limit+1)
 
-	rows, err := r.client.execCypher(ctx, nil, cypher, "p agtype")
+	rows, err := r.client.execCypher(ctx, nil, cypher, "p agtype", params)
 	if err != nil {
-		return nil, fmt.Errorf("list failed: %w", err)
+		return nil, models.Page{}, fmt.Errorf("list failed: %w", err)
 	}
 	defer rows.Close()
 
@@ -440,56 +672,12 @@ func (r *PlanRepository) List(ctx context.Context, status string, tags []string,
 		plans = append(plans, propsToPlan(props))
 	}
 
-	return plans, nil
-}
-
-// createRelationshipFromPlan creates a relationship from a Plan to another node
-func (r *PlanRepository) createRelationshipFromPlan(ctx context.Context, tx *sql.Tx, fromID, toID string, relType models.RelationType) error {
-	if err := ValidateRelationType(relType); err != nil {
-		return err
+	if len(plans) > limit {
+		plans = plans[:limit]
+		last := plans[limit-1]
+		page.NextCursor = models.Cursor{UpdatedAt: last.UpdatedAt, ID: last.ID}.Encode()
 	}
-
-	// Check if relationship already exists
-	checkCypher := fmt.Sprintf(
-		`MATCH (a:Plan {id: '%s'})-[r:%s]->(b)
-		 WHERE b.id = '%s'
-		 RETURN r`,
-		EscapeCypherString(fromID),
-		relType,
-		EscapeCypherString(toID),
-	)
-
-	rows, err := r.client.execCypher(ctx, tx, checkCypher, "r agtype")
-	if err != nil {
-		return err
-	}
-
-	exists := rows.Next()
-	rows.Close()
-
-	if exists {
-		return nil
-	}
-
-	// Create the relationship - use label() function for AGE-compatible label filtering
-	createCypher := fmt.Sprintf(
-		`MATCH (a:Plan {id: '%s'}), (b)
-		 WHERE b.id = '%s' AND %s
-		 CREATE (a)-[r:%s]->(b)
-		 RETURN r`,
-		EscapeCypherString(fromID),
-		EscapeCypherString(toID),
-		NodeLabelPredicate("b"),
-		relType,
-	)
-
-	createRows, err := r.client.execCypher(ctx, tx, createCypher, "r agtype")
-	if err != nil {
-		return err
-	}
-	createRows.Close()
-
-	return nil
+	return plans, page, nil
 }
 
 // parseAGTypeFloat parses a float from an agtype string
@@ -5,7 +5,6 @@ package graph
 
 import (
 	"context"
-	"fmt"
 	"testing"
 	"time"
 
@@ -42,8 +41,8 @@ func cleanupTestData(ctx context.Context, client *Client, ids ...string) {
 	defer tx.Rollback()
 
 	for _, id := range ids {
-		cypher := fmt.Sprintf(`MATCH (n {id: '%s'}) DETACH DELETE n RETURN true`, EscapeCypherString(id))
-		rows, err := client.execCypher(ctx, tx, cypher, "result agtype")
+		cypher := `MATCH (n {id: $id}) DETACH DELETE n RETURN true`
+		rows, err := client.execCypher(ctx, tx, cypher, "result agtype", map[string]any{"id": id})
 		if err == nil {
 			rows.Close()
 		}
@@ -113,7 +112,7 @@ func TestPlanRepository_CRUD(t *testing.T) {
 		newName := "Updated Plan"
 		newStatus := string(models.PlanStatusCompleted)
 
-		updated, err := repo.Update(ctx, testID, &newName, nil, &newStatus, nil, nil, nil)
+		updated, err := repo.Update(ctx, testID, 0, &newName, nil, &newStatus, nil, nil, nil)
 		if err != nil {
 			t.Fatalf("Failed to update plan: %v", err)
 		}
@@ -129,7 +128,7 @@ func TestPlanRepository_CRUD(t *testing.T) {
 
 	// Test List
 	t.Run("List", func(t *testing.T) {
-		plans, err := repo.List(ctx, "", nil, 50)
+		plans, _, err := repo.List(ctx, "", nil, 50, "")
 		if err != nil {
 			t.Fatalf("Failed to list plans: %v", err)
 		}
@@ -149,7 +148,7 @@ func TestPlanRepository_CRUD(t *testing.T) {
 
 	// Test Delete
 	t.Run("Delete", func(t *testing.T) {
-		_, err := repo.Delete(ctx, testID)
+		_, _, err := repo.Delete(ctx, testID, models.PlanDeleteOptions{})
 		if err != nil {
 			t.Fatalf("Failed to delete plan: %v", err)
 		}
@@ -200,7 +199,7 @@ func TestTaskRepository_CRUD(t *testing.T) {
 			Metadata: map[string]string{"key": "value"},
 		}
 
-		created, err := taskRepo.Add(ctx, task, []string{planID}, nil, nil, nil)
+		created, _, err := taskRepo.Add(ctx, task, []string{planID}, nil, nil, nil)
 		if err != nil {
 			t.Fatalf("Failed to create task: %v", err)
 		}
@@ -253,7 +252,7 @@ func TestTaskRepository_CRUD(t *testing.T) {
 		newContent := "Updated task content"
 		newStatus := string(models.TaskStatusInProgress)
 
-		updated, err := taskRepo.Update(ctx, taskID, &newContent, &newStatus, nil, nil, nil, nil)
+		updated, _, err := taskRepo.Update(ctx, taskID, 0, &newContent, &newStatus, nil, nil, nil, nil)
 		if err != nil {
 			t.Fatalf("Failed to update task: %v", err)
 		}
@@ -269,7 +268,7 @@ func TestTaskRepository_CRUD(t *testing.T) {
 
 	// Test List
 	t.Run("List", func(t *testing.T) {
-		tasks, err := taskRepo.List(ctx, planID, "", nil, 50)
+		tasks, _, err := taskRepo.List(ctx, planID, "", nil, 50, "")
 		if err != nil {
 			t.Fatalf("Failed to list tasks: %v", err)
 		}
@@ -292,7 +291,7 @@ func TestTaskRepository_CRUD(t *testing.T) {
 
 	// Test Delete
 	t.Run("Delete", func(t *testing.T) {
-		err := taskRepo.Delete(ctx, taskID)
+		_, err := taskRepo.Delete(ctx, taskID)
 		if err != nil {
 			t.Fatalf("Failed to delete task: %v", err)
 		}
@@ -358,7 +357,7 @@ func TestMemoryRepository_CRUD(t *testing.T) {
 
 	// Test Search
 	t.Run("Search", func(t *testing.T) {
-		results, err := repo.Search(ctx, "Test memory", 10)
+		results, _, err := repo.Search(ctx, "Test memory", models.SearchOptions{Limit: 10})
 		if err != nil {
 			t.Fatalf("Failed to search: %v", err)
 		}
@@ -420,19 +419,19 @@ func TestTaskPositioning(t *testing.T) {
 	// Create tasks in order
 	t.Run("CreateTasksInOrder", func(t *testing.T) {
 		task1 := models.Task{ID: task1ID, Content: "Task 1", Status: models.TaskStatusPending}
-		_, err := taskRepo.Add(ctx, task1, []string{planID}, nil, nil, nil)
+		_, _, err := taskRepo.Add(ctx, task1, []string{planID}, nil, nil, nil)
 		if err != nil {
 			t.Fatalf("Failed to create task 1: %v", err)
 		}
 
 		task2 := models.Task{ID: task2ID, Content: "Task 2", Status: models.TaskStatusPending}
-		_, err = taskRepo.Add(ctx, task2, []string{planID}, nil, nil, nil)
+		_, _, err = taskRepo.Add(ctx, task2, []string{planID}, nil, nil, nil)
 		if err != nil {
 			t.Fatalf("Failed to create task 2: %v", err)
 		}
 
 		task3 := models.Task{ID: task3ID, Content: "Task 3", Status: models.TaskStatusPending}
-		_, err = taskRepo.Add(ctx, task3, []string{planID}, nil, nil, nil)
+		_, _, err = taskRepo.Add(ctx, task3, []string{planID}, nil, nil, nil)
 		if err != nil {
 			t.Fatalf("Failed to create task 3: %v", err)
 		}
@@ -440,7 +439,7 @@ func TestTaskPositioning(t *testing.T) {
 
 	// Verify order
 	t.Run("VerifyOrder", func(t *testing.T) {
-		tasks, err := taskRepo.List(ctx, planID, "", nil, 50)
+		tasks, _, err := taskRepo.List(ctx, planID, "", nil, 50, "")
 		if err != nil {
 			t.Fatalf("Failed to list tasks: %v", err)
 		}
@@ -475,7 +474,7 @@ func TestTaskPositioning(t *testing.T) {
 		}
 
 		// Verify new order: 3, 1, 2
-		tasks, err := taskRepo.List(ctx, planID, "", nil, 50)
+		tasks, _, err := taskRepo.List(ctx, planID, "", nil, 50, "")
 		if err != nil {
 			t.Fatalf("Failed to list tasks: %v", err)
 		}
@@ -519,19 +518,19 @@ func TestCascadeDelete(t *testing.T) {
 	}
 
 	// Create orphan task (only in plan 1)
-	_, err = taskRepo.Add(ctx, models.Task{ID: taskOrphanID, Content: "Orphan Task", Status: models.TaskStatusPending}, []string{plan1ID}, nil, nil, nil)
+	_, _, err = taskRepo.Add(ctx, models.Task{ID: taskOrphanID, Content: "Orphan Task", Status: models.TaskStatusPending}, []string{plan1ID}, nil, nil, nil)
 	if err != nil {
 		t.Fatalf("Failed to create orphan task: %v", err)
 	}
 
 	// Create shared task (in both plans)
-	_, err = taskRepo.Add(ctx, models.Task{ID: taskSharedID, Content: "Shared Task", Status: models.TaskStatusPending}, []string{plan1ID, plan2ID}, nil, nil, nil)
+	_, _, err = taskRepo.Add(ctx, models.Task{ID: taskSharedID, Content: "Shared Task", Status: models.TaskStatusPending}, []string{plan1ID, plan2ID}, nil, nil, nil)
 	if err != nil {
 		t.Fatalf("Failed to create shared task: %v", err)
 	}
 
 	// Delete plan 1
-	deletedCount, err := planRepo.Delete(ctx, plan1ID)
+	deletedCount, _, err := planRepo.Delete(ctx, plan1ID, models.PlanDeleteOptions{})
 	if err != nil {
 		t.Fatalf("Failed to delete plan: %v", err)
 	}
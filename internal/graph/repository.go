@@ -3,15 +3,21 @@ package graph
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
-	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/ranking"
+	"github.com/Thomas-Fitz/associate/internal/store"
+	"github.com/Thomas-Fitz/associate/internal/textsearch"
 	"github.com/google/uuid"
 )
 
+var _ store.MemoryStore = (*Repository)(nil)
+
 // Repository provides CRUD operations for memories.
 type Repository struct {
 	client *Client
@@ -22,41 +28,89 @@ func NewRepository(client *Client) *Repository {
 	return &Repository{client: client}
 }
 
-// Search finds memories matching the query using Cypher string matching.
-// Note: AGE uses agtype columns, not JSONB, so we can't use pg_trgm directly.
-// Instead, we use Cypher's CONTAINS for case-insensitive matching.
-func (r *Repository) Search(ctx context.Context, query string, limit int) ([]models.SearchResult, error) {
-	if limit <= 0 {
-		limit = 10
+// Search ranks memories against the query using the full-text search table,
+// or the embeddings table in semantic mode, and returns the page of matches
+// the cursor asks for, highest score first, with highlighted snippets. Hybrid
+// mode re-ranks the full-text matches with graph proximity and recency.
+func (r *Repository) Search(ctx context.Context, query string, opts models.SearchOptions) ([]models.SearchResult, models.Page, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, models.Page{}, err
+	}
+	opts = opts.WithDefaults(query)
+	if models.ListOnly(query) && opts.Filter.Empty() && opts.AnchorID == "" {
+		return nil, models.Page{}, nil
 	}
 
 	tx, err := r.client.BeginTx(ctx)
 	if err != nil {
-		return nil, err
+		return nil, models.Page{}, err
 	}
 	defer tx.Rollback()
 
-	// Use Cypher for search - CONTAINS for substring matching
-	// toLower for case-insensitive matching
-	escapedQuery := EscapeCypherString(strings.ToLower(query))
-	cypher := fmt.Sprintf(
-		`MATCH (m:Memory)
-		 WHERE toLower(m.content) CONTAINS '%s' OR toLower(m.id) CONTAINS '%s'
-		 RETURN m
-		 LIMIT %d`,
-		escapedQuery, escapedQuery, limit)
+	// candidates pages through the matches in the order of the query
+	candidates := func(offset, limit int) ([]searchHit, int, error) {
+		switch {
+		case models.ListOnly(query):
+			return listSearchTable(ctx, tx, "Memory", opts, offset, limit)
+		case opts.Mode == models.SearchModeSemantic:
+			return r.client.searchSemantic(ctx, tx, []string{"Memory"}, query, opts, offset, limit)
+		default:
+			return searchText(ctx, tx, []string{"Memory"}, query, opts, offset, limit)
+		}
+	}
 
-	rows, err := r.client.execCypher(ctx, tx, cypher, "m agtype")
+	offset := opts.Offset()
+	var ranked []searchHit
+	var total int
+	if opts.Mode == models.SearchModeHybrid && opts.Sort == models.SortRelevance {
+		ranked, total, err = r.client.rerank(ctx, tx, opts, offset+opts.Limit, candidates)
+		if len(ranked) > offset {
+			ranked = ranked[offset:]
+		} else {
+			ranked = nil
+		}
+	} else {
+		ranked, total, err = candidates(offset, opts.Limit)
+		if err == nil && len(ranked) == 0 && offset > 0 {
+			// Past the end the query returns no row to count
+			_, total, err = candidates(0, 1)
+		}
+		if err == nil && opts.Mode == models.SearchModeHybrid {
+			hops, hopsErr := r.client.anchorHops(ctx, tx, opts.AnchorID)
+			if err = hopsErr; err == nil {
+				err = r.client.hybridScore(ctx, tx, ranked, hops, time.Now().UTC())
+			}
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("search query failed: %w", err)
+		return nil, models.Page{}, fmt.Errorf("search query failed: %w", err)
+	}
+
+	page := models.PageAt(offset, opts.Limit, total)
+	if len(ranked) == 0 {
+		return nil, page, tx.Commit()
+	}
+	ids := make([]string, len(ranked))
+	for i, h := range ranked {
+		ids[i] = h.id
+	}
+	if opts.Mode != models.SearchModeSemantic {
+		snippets, err := headlines(ctx, tx, query, ids)
+		if err != nil {
+			return nil, models.Page{}, fmt.Errorf("search query failed: %w", err)
+		}
+		for i := range ranked {
+			ranked[i].snippet = snippets[ranked[i].id]
+		}
 	}
-	defer rows.Close()
 
-	type searchHit struct {
-		mem models.Memory
+	rows, err := r.client.execCypher(ctx, tx, `MATCH (m:Memory) WHERE m.id IN $ids RETURN m`, "m agtype", map[string]any{"ids": ids})
+	if err != nil {
+		return nil, models.Page{}, fmt.Errorf("search query failed: %w", err)
 	}
-	var hits []searchHit
+	defer rows.Close()
 
+	memories := make(map[string]models.Memory, len(ranked))
 	for rows.Next() {
 		var agtypeStr string
 		if err := rows.Scan(&agtypeStr); err != nil {
@@ -69,47 +123,209 @@ func (r *Repository) Search(ctx context.Context, query string, limit int) ([]mod
 		}
 
 		mem := propsToMemory(props)
-		hits = append(hits, searchHit{mem: mem})
+		memories[mem.ID] = mem
 	}
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, models.Page{}, err
 	}
 
-	// Phase 2: For each hit, fetch related Memory IDs via Cypher.
 	var results []models.SearchResult
-	for _, hit := range hits {
-		sr := models.SearchResult{
-			Memory: hit.mem,
-			Score:  1.0, // Cypher CONTAINS doesn't provide similarity scores
-		}
-
-		// Fetch related IDs (best-effort)
-		cypher := fmt.Sprintf(
-			`MATCH (m:Memory {id: '%s'})-[:RELATES_TO|PART_OF|REFERENCES|DEPENDS_ON|BLOCKS|FOLLOWS|IMPLEMENTS]-(related:Memory)
-			 RETURN related.id`,
-			EscapeCypherString(hit.mem.ID))
-
-		relRows, err := r.client.execCypher(ctx, tx, cypher, "related_id agtype")
-		if err == nil {
-			defer relRows.Close()
-			seen := make(map[string]bool)
-			for relRows.Next() {
-				var relatedID string
-				if err := relRows.Scan(&relatedID); err == nil {
-					// Strip quotes from agtype string
-					relatedID = strings.Trim(relatedID, "\"")
-					if relatedID != "" && !seen[relatedID] {
-						seen[relatedID] = true
-						sr.Related = append(sr.Related, relatedID)
-					}
-				}
-			}
+	for _, h := range ranked {
+		if mem, ok := memories[h.id]; ok {
+			results = append(results, models.SearchResult{Memory: mem, Score: h.score, Snippet: h.snippet})
+		}
+	}
+
+	parsed := textsearch.Parse(query)
+	for i := range results {
+		if results[i].Snippet == "" {
+			results[i].Snippet = textsearch.Snippet(parsed, results[i].Memory.Content)
 		}
+	}
 
-		results = append(results, sr)
+	// Fetch related memory IDs for every hit in one query
+	related, err := r.client.relatedMemoryIDs(ctx, tx, ids)
+	if err != nil {
+		return nil, models.Page{}, fmt.Errorf("related query failed: %w", err)
+	}
+	for i := range results {
+		results[i].Related = related[results[i].Memory.ID]
 	}
 
-	tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return nil, models.Page{}, fmt.Errorf("failed to commit: %w", err)
+	}
+	return results, page, nil
+}
+
+// rerank returns the first n hybrid matches, best first, or worst first for
+// OrderAsc, along with the number of matches. A match's hybrid score stays
+// within ranking.Bounds of its text score, so rerank scores the text matches
+// in batches of n, in text order, and stops once no later match can displace
+// the nth, rather than computing graph features for every match.
+func (c *Client) rerank(ctx context.Context, tx *sql.Tx, opts models.SearchOptions, n int,
+	candidates func(offset, limit int) ([]searchHit, int, error)) ([]searchHit, int, error) {
+	hops, err := c.anchorHops(ctx, tx, opts.AnchorID)
+	if err != nil {
+		return nil, 0, err
+	}
+	now := time.Now().UTC()
+	better := func(a, b float64) bool {
+		if opts.Order == models.OrderAsc {
+			return a < b
+		}
+		return a > b
+	}
+
+	var ranked []searchHit
+	total := 0
+	for offset := 0; ; {
+		batch, count, err := candidates(offset, n)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		total = count
+		offset += len(batch)
+		lo, hi := ranking.Bounds(batch[len(batch)-1].score)
+		if err := c.hybridScore(ctx, tx, batch, hops, now); err != nil {
+			return nil, 0, err
+		}
+
+		// Ties keep text order, so later matches rank after equal scores
+		ranked = append(ranked, batch...)
+		sort.SliceStable(ranked, func(i, j int) bool { return better(ranked[i].score, ranked[j].score) })
+		ranked = ranked[:min(n, len(ranked))]
+
+		if offset >= total || len(ranked) < n {
+			break
+		}
+		bound := hi
+		if opts.Order == models.OrderAsc {
+			bound = lo
+		}
+		if !better(bound, ranked[n-1].score) {
+			break
+		}
+	}
+	return ranked, total, nil
+}
+
+// relatedMemoryIDs returns the IDs of the memories linked to each of the given
+// memories, by relationships of any type, keyed by memory ID.
+func (c *Client) relatedMemoryIDs(ctx context.Context, tx *sql.Tx, ids []string) (map[string][]string, error) {
+	rows, err := c.execCypher(ctx, tx,
+		`MATCH (m:Memory)-[:RELATES_TO|PART_OF|REFERENCES|DEPENDS_ON|BLOCKS|FOLLOWS|IMPLEMENTS]-(related:Memory)
+		 WHERE m.id IN $ids
+		 RETURN m.id, related.id`,
+		"id agtype, related_id agtype", map[string]any{"ids": ids})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	related := make(map[string][]string, len(ids))
+	seen := make(map[[2]string]bool)
+	for rows.Next() {
+		var id, relatedID string
+		if err := rows.Scan(&id, &relatedID); err != nil {
+			return nil, err
+		}
+		// Strip quotes from agtype strings
+		id, relatedID = strings.Trim(id, "\""), strings.Trim(relatedID, "\"")
+		if relatedID == "" || seen[[2]string{id, relatedID}] {
+			continue
+		}
+		seen[[2]string{id, relatedID}] = true
+		related[id] = append(related[id], relatedID)
+	}
+	return related, rows.Err()
+}
+
+// SearchAll ranks memories, plans and tasks of the requested kinds against
+// the query using the search table, or the embeddings table in semantic mode,
+// and returns the best matches, highest score first.
+func (r *Repository) SearchAll(ctx context.Context, query string, opts models.NodeSearchOptions) ([]models.NodeSearchResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts = opts.WithDefaults()
+	if models.ListOnly(query) {
+		return nil, nil
+	}
+
+	labels := make([]string, len(opts.Kinds))
+	for i, k := range opts.Kinds {
+		labels[i] = string(k)
+	}
+
+	tx, err := r.client.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	textOpts := models.SearchOptions{Sort: models.SortRelevance, Order: models.OrderDesc}
+	var ranked []searchHit
+	if opts.Mode == models.SearchModeSemantic {
+		ranked, _, err = r.client.searchSemantic(ctx, tx, labels, query, textOpts, 0, opts.Limit)
+	} else {
+		ranked, _, err = searchText(ctx, tx, labels, query, textOpts, 0, opts.Limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("search query failed: %w", err)
+	}
+	if len(ranked) == 0 {
+		return nil, tx.Commit()
+	}
+
+	ids := make([]string, len(ranked))
+	for i, h := range ranked {
+		ids[i] = h.id
+	}
+	nodes, err := searchRows(ctx, tx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("search query failed: %w", err)
+	}
+	snippets := make(map[string]string)
+	if opts.Mode != models.SearchModeSemantic {
+		if snippets, err = headlines(ctx, tx, query, ids); err != nil {
+			return nil, fmt.Errorf("search query failed: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	parsed := textsearch.Parse(query)
+
+	var results []models.NodeSearchResult
+	for _, h := range ranked {
+		node, ok := nodes[h.id]
+		if !ok {
+			continue
+		}
+		title := node.title
+		if title == "" {
+			title = models.NodeTitle(node.body)
+		}
+		snippet, ok := snippets[h.id]
+		if !ok {
+			snippet = textsearch.Snippet(parsed, node.body)
+		}
+		results = append(results, models.NodeSearchResult{
+			Kind:      models.NodeKind(node.label),
+			ID:        h.id,
+			Title:     title,
+			Snippet:   snippet,
+			Status:    node.status,
+			Score:     h.score,
+			UpdatedAt: node.updatedAt,
+		})
+	}
+	models.SortNodeResults(results)
 	return results, nil
 }
 
@@ -127,44 +343,52 @@ func (r *Repository) Add(ctx context.Context, mem models.Memory, relationships [
 	now := time.Now().UTC()
 	mem.CreatedAt = now
 	mem.UpdatedAt = now
+	mem.Version = 1
 
 	if mem.Type == "" {
 		mem.Type = models.TypeGeneral
 	}
 
-	metadataJSON := metadataToJSON(mem.Metadata)
-	tagsList := tagsToCypherList(mem.Tags)
-
-	cypher := fmt.Sprintf(
-		`CREATE (m:Memory {
-			id: '%s',
+	cypher := `CREATE (m:Memory {
+			id: $id,
 			node_type: 'Memory',
-			type: '%s',
-			content: '%s',
-			metadata: '%s',
-			tags: %s,
-			created_at: '%s',
-			updated_at: '%s'
-		}) RETURN m`,
-		EscapeCypherString(mem.ID),
-		EscapeCypherString(string(mem.Type)),
-		EscapeCypherString(mem.Content),
-		EscapeCypherString(metadataJSON),
-		tagsList,
-		mem.CreatedAt.Format(time.RFC3339),
-		mem.UpdatedAt.Format(time.RFC3339),
-	)
-
-	rows, err := r.client.execCypher(ctx, tx, cypher, "m agtype")
+			type: $type,
+			content: $content,
+			metadata: $metadata,
+			tags: $tags,
+			created_at: $created_at,
+			updated_at: $updated_at,
+			version: 1
+		}) RETURN m`
+
+	rows, err := r.client.execCypher(ctx, tx, cypher, "m agtype", map[string]any{
+		"id":         mem.ID,
+		"type":       string(mem.Type),
+		"content":    mem.Content,
+		"metadata":   metadataToJSON(mem.Metadata),
+		"tags":       tagsParam(mem.Tags),
+		"created_at": mem.CreatedAt.Format(time.RFC3339),
+		"updated_at": mem.UpdatedAt.Format(time.RFC3339),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create memory: %w", err)
 	}
 	rows.Close()
+	if err := recordRevision(ctx, tx, models.MemoryRevision(mem, store.Actor(ctx))); err != nil {
+		return nil, err
+	}
+
+	if err := indexMemory(ctx, tx, mem); err != nil {
+		return nil, err
+	}
+	if err := r.client.saveEmbedding(ctx, tx, mem.ID, "Memory", mem.Content); err != nil {
+		return nil, err
+	}
 
 	// Create relationships
 	for _, rel := range relationships {
-		if err := r.createRelationship(ctx, tx, mem.ID, rel.ToID, rel.Type); err != nil {
-			fmt.Fprintf(os.Stderr, "warning: failed to create relationship: %v\n", err)
+		if err := r.client.createRelationship(ctx, tx, mem.ID, rel); err != nil {
+			return nil, err
 		}
 	}
 
@@ -176,7 +400,7 @@ func (r *Repository) Add(ctx context.Context, mem models.Memory, relationships [
 }
 
 // Update modifies an existing memory
-func (r *Repository) Update(ctx context.Context, id string, content *string, metadata map[string]string, tags []string, newRelationships []models.Relationship) (*models.Memory, error) {
+func (r *Repository) Update(ctx context.Context, id string, expectedVersion int64, content *string, metadata map[string]string, tags []string, newRelationships []models.Relationship) (*models.Memory, error) {
 	tx, err := r.client.BeginTx(ctx)
 	if err != nil {
 		return nil, err
@@ -184,26 +408,33 @@ func (r *Repository) Update(ctx context.Context, id string, content *string, met
 	defer tx.Rollback()
 
 	// Build dynamic SET clause
-	setClauses := []string{fmt.Sprintf("m.updated_at = '%s'", time.Now().UTC().Format(time.RFC3339))}
+	params := map[string]any{
+		"id":         id,
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	}
+	setClauses := []string{"m.updated_at = $updated_at", versionSet("m")}
 
 	if content != nil {
-		setClauses = append(setClauses, fmt.Sprintf("m.content = '%s'", EscapeCypherString(*content)))
+		setClauses = append(setClauses, "m.content = $content")
+		params["content"] = *content
 	}
 	if metadata != nil {
-		setClauses = append(setClauses, fmt.Sprintf("m.metadata = '%s'", EscapeCypherString(metadataToJSON(metadata))))
+		setClauses = append(setClauses, "m.metadata = $metadata")
+		params["metadata"] = metadataToJSON(metadata)
 	}
 	if tags != nil {
-		setClauses = append(setClauses, fmt.Sprintf("m.tags = %s", tagsToCypherList(tags)))
+		setClauses = append(setClauses, "m.tags = $tags")
+		params["tags"] = tagsParam(tags)
 	}
 
 	cypher := fmt.Sprintf(`
-		MATCH (m:Memory {id: '%s'})
+		MATCH (m:Memory {id: $id})
+		%s
 		SET %s
 		RETURN m`,
-		EscapeCypherString(id),
-		joinStrings(setClauses, ", "))
+		versionPredicate("m", expectedVersion, params), joinStrings(setClauses, ", "))
 
-	rows, err := r.client.execCypher(ctx, tx, cypher, "m agtype")
+	rows, err := r.client.execCypher(ctx, tx, cypher, "m agtype", params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update memory: %w", err)
 	}
@@ -222,13 +453,28 @@ func (r *Repository) Update(ctx context.Context, id string, content *string, met
 	rows.Close()
 
 	if mem == nil {
+		if err := r.client.versionConflict(ctx, tx, "Memory", id, expectedVersion); err != nil {
+			return nil, err
+		}
 		return nil, fmt.Errorf("memory not found: %s", id)
 	}
+	if err := recordRevision(ctx, tx, models.MemoryRevision(*mem, store.Actor(ctx))); err != nil {
+		return nil, err
+	}
+
+	if err := indexMemory(ctx, tx, *mem); err != nil {
+		return nil, err
+	}
+	if content != nil {
+		if err := r.client.saveEmbedding(ctx, tx, id, "Memory", *content); err != nil {
+			return nil, err
+		}
+	}
 
 	// Create new relationships
 	for _, rel := range newRelationships {
-		if err := r.createRelationship(ctx, tx, id, rel.ToID, rel.Type); err != nil {
-			fmt.Fprintf(os.Stderr, "warning: failed to create relationship: %v\n", err)
+		if err := r.client.createRelationship(ctx, tx, id, rel); err != nil {
+			return nil, err
 		}
 	}
 
@@ -241,9 +487,9 @@ func (r *Repository) Update(ctx context.Context, id string, content *string, met
 
 // GetByID retrieves a memory by ID
 func (r *Repository) GetByID(ctx context.Context, id string) (*models.Memory, error) {
-	cypher := fmt.Sprintf(`MATCH (m:Memory {id: '%s'}) RETURN m`, EscapeCypherString(id))
+	cypher := `MATCH (m:Memory {id: $id}) RETURN m`
 
-	rows, err := r.client.execCypher(ctx, nil, cypher, "m agtype")
+	rows, err := r.client.execCypher(ctx, nil, cypher, "m agtype", map[string]any{"id": id})
 	if err != nil {
 		return nil, err
 	}
@@ -275,9 +521,11 @@ func (r *Repository) GetByIDWithRelated(ctx context.Context, id string) (*models
 	}
 	defer tx.Rollback()
 
+	params := map[string]any{"id": id}
+
 	// Get the memory
-	cypher := fmt.Sprintf(`MATCH (m:Memory {id: '%s'}) RETURN m`, EscapeCypherString(id))
-	rows, err := r.client.execCypher(ctx, tx, cypher, "m agtype")
+	cypher := `MATCH (m:Memory {id: $id}) RETURN m`
+	rows, err := r.client.execCypher(ctx, tx, cypher, "m agtype", params)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -302,24 +550,23 @@ func (r *Repository) GetByIDWithRelated(ctx context.Context, id string) (*models
 	var related []models.RelatedInfo
 
 	// Get outgoing relationships
-	outCypher := fmt.Sprintf(
-		`MATCH (m:Memory {id: '%s'})-[r]->(out:Memory)
-		 RETURN out.id, out.type, type(r)`,
-		EscapeCypherString(id))
-	outRows, err := r.client.execCypher(ctx, tx, outCypher, "out_id agtype, out_type agtype, rel_type agtype")
+	outCypher := `MATCH (m:Memory {id: $id})-[r]->(out:Memory)
+		 RETURN out.id, out.type, type(r), properties(r)`
+	outRows, err := r.client.execCypher(ctx, tx, outCypher, "out_id agtype, out_type agtype, rel_type agtype, props agtype", params)
 	if err == nil {
 		for outRows.Next() {
-			var outID, outType, relType string
-			if err := outRows.Scan(&outID, &outType, &relType); err == nil {
+			var outID, outType, relType, propsStr string
+			if err := outRows.Scan(&outID, &outType, &relType, &propsStr); err == nil {
 				outID = strings.Trim(outID, "\"")
 				outType = strings.Trim(outType, "\"")
 				relType = strings.Trim(relType, "\"")
 				if outID != "" {
 					related = append(related, models.RelatedInfo{
-						ID:           outID,
-						Type:         models.MemoryType(outType),
-						RelationType: relType,
-						Direction:    "outgoing",
+						ID:                     outID,
+						Type:                   models.MemoryType(outType),
+						RelationType:           relType,
+						Direction:              "outgoing",
+						RelationshipProperties: parseRelationshipProperties(propsStr),
 					})
 				}
 			}
@@ -328,24 +575,23 @@ func (r *Repository) GetByIDWithRelated(ctx context.Context, id string) (*models
 	}
 
 	// Get incoming relationships
-	inCypher := fmt.Sprintf(
-		`MATCH (inc:Memory)-[r]->(m:Memory {id: '%s'})
-		 RETURN inc.id, inc.type, type(r)`,
-		EscapeCypherString(id))
-	inRows, err := r.client.execCypher(ctx, tx, inCypher, "inc_id agtype, inc_type agtype, rel_type agtype")
+	inCypher := `MATCH (inc:Memory)-[r]->(m:Memory {id: $id})
+		 RETURN inc.id, inc.type, type(r), properties(r)`
+	inRows, err := r.client.execCypher(ctx, tx, inCypher, "inc_id agtype, inc_type agtype, rel_type agtype, props agtype", params)
 	if err == nil {
 		for inRows.Next() {
-			var incID, incType, relType string
-			if err := inRows.Scan(&incID, &incType, &relType); err == nil {
+			var incID, incType, relType, propsStr string
+			if err := inRows.Scan(&incID, &incType, &relType, &propsStr); err == nil {
 				incID = strings.Trim(incID, "\"")
 				incType = strings.Trim(incType, "\"")
 				relType = strings.Trim(relType, "\"")
 				if incID != "" {
 					related = append(related, models.RelatedInfo{
-						ID:           incID,
-						Type:         models.MemoryType(incType),
-						RelationType: relType,
-						Direction:    "incoming",
+						ID:                     incID,
+						Type:                   models.MemoryType(incType),
+						RelationType:           relType,
+						Direction:              "incoming",
+						RelationshipProperties: parseRelationshipProperties(propsStr),
 					})
 				}
 			}
@@ -357,7 +603,7 @@ func (r *Repository) GetByIDWithRelated(ctx context.Context, id string) (*models
 	return mem, related, nil
 }
 
-// Delete removes a memory and all its relationships
+// Delete moves a memory and all its relationships to the trash
 func (r *Repository) Delete(ctx context.Context, id string) error {
 	tx, err := r.client.BeginTx(ctx)
 	if err != nil {
@@ -365,167 +611,423 @@ func (r *Repository) Delete(ctx context.Context, id string) error {
 	}
 	defer tx.Rollback()
 
-	cypher := fmt.Sprintf(`MATCH (m:Memory {id: '%s'}) DETACH DELETE m RETURN true`, EscapeCypherString(id))
-	rows, err := r.client.execCypher(ctx, tx, cypher, "result agtype")
+	label, err := r.client.nodeLabel(ctx, tx, id)
 	if err != nil {
 		return fmt.Errorf("delete failed: %w", err)
 	}
-	rows.Close()
+	if label != "Memory" {
+		return nil
+	}
+	if err := r.client.trashNodes(ctx, tx, id); err != nil {
+		return err
+	}
 
 	return tx.Commit()
 }
 
+// PreviewDelete returns what Delete would move to the trash
+func (r *Repository) PreviewDelete(ctx context.Context, id string) (*models.TrashEntry, error) {
+	return r.client.previewTrash(ctx, id, "Memory")
+}
+
 // GetRelated retrieves nodes related to the given ID with optional filtering.
-// Uses iterative depth expansion since AGE has limited support for variable-length path features.
+// It walks breadth-first with store.Traverse, one query per depth level, so
+// results match the other backends.
 func (r *Repository) GetRelated(ctx context.Context, id string, relationType string, direction string, depth int) ([]models.RelatedMemoryResult, error) {
+	// Relationship types cannot be bound as parameters, so validate before building the pattern
+	relPattern, err := relationshipPattern(relationType, direction)
+	if err != nil {
+		return nil, err
+	}
+	cypher := fmt.Sprintf(
+		`MATCH p = (a)%s(b)
+		 WHERE a.id IN $ids AND %s
+		 RETURN p
+		 ORDER BY id(r)`,
+		relPattern, NodeLabelPredicate("b"))
+
 	tx, err := r.client.BeginTx(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	var results []models.RelatedMemoryResult
-	seen := map[string]bool{id: true}
-	frontier := []string{id}
-
-	for d := 1; d <= depth && len(frontier) > 0; d++ {
-		var nextFrontier []string
-		for _, currentID := range frontier {
-			// Build relationship pattern for this hop
-			var relPattern string
-			if relationType != "" {
-				switch direction {
-				case "outgoing":
-					relPattern = fmt.Sprintf("-[r:%s]->", relationType)
-				case "incoming":
-					relPattern = fmt.Sprintf("<-[r:%s]-", relationType)
-				default:
-					relPattern = fmt.Sprintf("-[r:%s]-", relationType)
-				}
-			} else {
-				switch direction {
-				case "outgoing":
-					relPattern = "-[r]->"
-				case "incoming":
-					relPattern = "<-[r]-"
-				default:
-					relPattern = "-[r]-"
-				}
-			}
-
-			cypher := fmt.Sprintf(
-				`MATCH (a {id: '%s'})%s(b)
-				 WHERE %s
-				 RETURN b, type(r), b.node_type`,
-				EscapeCypherString(currentID), relPattern, NodeLabelPredicate("b"))
+	results, err := store.Traverse(ctx, id, depth, func(ctx context.Context, frontier []string) ([]store.Step, error) {
+		rows, err := r.client.execCypher(ctx, tx, cypher, "p agtype", map[string]any{"ids": frontier})
+		if err != nil {
+			return nil, fmt.Errorf("related query failed: %w", err)
+		}
+		defer rows.Close()
 
-			rows, err := r.client.execCypher(ctx, tx, cypher, "b agtype, rel_type agtype, node_type agtype")
+		var steps []store.Step
+		for rows.Next() {
+			var pathStr string
+			if err := rows.Scan(&pathStr); err != nil {
+				return nil, err
+			}
+			path, err := parseAGTypePath(pathStr)
 			if err != nil {
-				continue
+				return nil, fmt.Errorf("failed to parse path: %w", err)
+			}
+			if len(path) != 3 {
+				return nil, fmt.Errorf("failed to parse path: got %d elements, want 3", len(path))
 			}
 
-			for rows.Next() {
-				var bStr, relTypeStr, nodeTypeStr string
-				if err := rows.Scan(&bStr, &relTypeStr, &nodeTypeStr); err != nil {
-					continue
-				}
+			from, edge, to := path[0], path[1], path[2]
+			if edge.StartID != from.ID {
+				from, to = to, from
+			}
+			steps = append(steps, store.Step{
+				Edge: models.Relationship{
+					FromID:                 getString(from.Properties, "id"),
+					ToID:                   getString(to.Properties, "id"),
+					Type:                   models.RelationType(edge.Label),
+					RelationshipProperties: propsToRelationshipProperties(edge.Properties),
+				},
+				Node: propsToRelatedMemory(path[2].Label, path[2].Properties),
+			})
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("related query failed: %w", err)
+		}
+		return steps, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, tx.Commit()
+}
 
-				props, err := parseAGTypeProperties(bStr)
-				if err != nil {
-					continue
-				}
+// ListRelationships lists the relationships of a node, oldest first.
+func (r *Repository) ListRelationships(ctx context.Context, id string, relationType string, direction string) ([]models.Edge, error) {
+	tx, err := r.client.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
-				nodeID := getString(props, "id")
-				if seen[nodeID] {
-					continue
-				}
-				seen[nodeID] = true
-				nextFrontier = append(nextFrontier, nodeID)
+	label, err := r.client.nodeLabel(ctx, tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("list relationships failed: %w", err)
+	}
+	if label == "" {
+		return nil, fmt.Errorf("node not found: %s", id)
+	}
+	edges, err := r.client.listEdges(ctx, tx, id, relationType, direction)
+	if err != nil {
+		return nil, fmt.Errorf("list relationships failed: %w", err)
+	}
+	return edges, tx.Commit()
+}
 
-				// Build result
-				nodeType := strings.Trim(nodeTypeStr, "\"")
-				if nodeType == "" {
-					nodeType = "Memory"
-				}
+// DeleteRelationship removes the relationship of the given type from one node
+// to another.
+func (r *Repository) DeleteRelationship(ctx context.Context, fromID, toID string, relType models.RelationType) error {
+	// Relationship types cannot be bound as parameters, so validate before building the pattern
+	if err := models.ValidateRelationType(relType); err != nil {
+		return err
+	}
 
-				mem := models.Memory{
-					ID:   nodeID,
-					Type: models.MemoryType(nodeType),
-				}
-				if nodeType == "Plan" {
-					mem.Content = getString(props, "name")
-				} else {
-					mem.Content = getString(props, "content")
-				}
+	tx, err := r.client.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-				if metaStr := getString(props, "metadata"); metaStr != "" {
-					mem.Metadata = jsonToMetadata(metaStr)
-				}
+	params := map[string]any{"from_id": fromID, "to_id": toID}
+	match := fmt.Sprintf(`MATCH (a {id: $from_id})-[r:%s]->(b {id: $to_id})`, relType)
+	n, err := r.client.count(ctx, tx, match+` RETURN count(r)`, params)
+	if err != nil {
+		return fmt.Errorf("delete relationship failed: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("relationship not found: %s", models.EdgeID(fromID, toID, relType))
+	}
+	if err := r.client.execCypherNoReturn(ctx, tx, match+` DELETE r RETURN true`, params); err != nil {
+		return fmt.Errorf("delete relationship failed: %w", err)
+	}
 
-				if tagsRaw, ok := props["tags"]; ok {
-					if tagsArr, ok := tagsRaw.([]interface{}); ok {
-						for _, t := range tagsArr {
-							if s, ok := t.(string); ok {
-								mem.Tags = append(mem.Tags, s)
-							}
-						}
-					}
-				}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
 
-				if createdStr := getString(props, "created_at"); createdStr != "" {
-					if t, err := time.Parse(time.RFC3339, createdStr); err == nil {
-						mem.CreatedAt = t
-					}
-				}
-				if updatedStr := getString(props, "updated_at"); updatedStr != "" {
-					if t, err := time.Parse(time.RFC3339, updatedStr); err == nil {
-						mem.UpdatedAt = t
-					}
-				}
+// ReplaceRelationships replaces the outgoing relationships of a node with the
+// given ones in one transaction.
+func (r *Repository) ReplaceRelationships(ctx context.Context, id string, relationships []models.Relationship) ([]models.Edge, error) {
+	tx, err := r.client.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
-				relTypeClean := strings.Trim(relTypeStr, "\"")
+	label, err := r.client.nodeLabel(ctx, tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("replace relationships failed: %w", err)
+	}
+	if label == "" {
+		return nil, fmt.Errorf("node not found: %s", id)
+	}
+	set, err := store.ReplacementSet(id, label, relationships)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(set))
+	for _, rel := range set {
+		wanted[models.EdgeID(id, rel.ToID, rel.Type)] = true
+	}
 
-				// Determine direction based on query pattern
-				dirStr := direction
-				if dirStr == "" || dirStr == "both" {
-					dirStr = "both"
-				}
+	current, err := r.client.listEdges(ctx, tx, id, "", "outgoing")
+	if err != nil {
+		return nil, fmt.Errorf("replace relationships failed: %w", err)
+	}
+	for _, e := range current {
+		if wanted[e.ID] || (label == "Task" && e.Type == models.RelPartOf) {
+			continue
+		}
+		err := r.client.execCypherNoReturn(ctx, tx,
+			fmt.Sprintf(`MATCH (a {id: $from_id})-[r:%s]->(b {id: $to_id}) DELETE r RETURN true`, e.Type),
+			map[string]any{"from_id": e.FromID, "to_id": e.ToID})
+		if err != nil {
+			return nil, fmt.Errorf("replace relationships failed: %w", err)
+		}
+	}
+
+	// Create after removing the relationships being replaced, so that those
+	// cannot make a false dependency cycle
+	for _, rel := range set {
+		if err := r.client.createRelationship(ctx, tx, id, rel); err != nil {
+			return nil, err
+		}
+	}
+
+	edges, err := r.client.listEdges(ctx, tx, id, "", "outgoing")
+	if err != nil {
+		return nil, fmt.Errorf("replace relationships failed: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return edges, nil
+}
 
-				results = append(results, models.RelatedMemoryResult{
-					Memory:       mem,
-					RelationType: relTypeClean,
-					Direction:    dirStr,
-					Depth:        d,
-				})
+// ExistingNodes reports which of the IDs name a node of any type.
+func (r *Repository) ExistingNodes(ctx context.Context, ids []string) (map[string]bool, error) {
+	return r.client.existingNodes(ctx, nil, ids)
+}
+
+// existingNodes reports which of the IDs name a node of any type.
+func (c *Client) existingNodes(ctx context.Context, tx *sql.Tx, ids []string) (map[string]bool, error) {
+	existing := make(map[string]bool, len(ids))
+	if len(ids) == 0 {
+		return existing, nil
+	}
+	rows, err := c.execCypher(ctx, tx,
+		fmt.Sprintf(`MATCH (n) WHERE n.id IN $ids AND %s RETURN n.id`, NodeLabelPredicate("n")),
+		"id agtype", map[string]any{"ids": ids})
+	if err != nil {
+		return nil, fmt.Errorf("node lookup failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		existing[strings.Trim(id, "\"")] = true
+	}
+	return existing, rows.Err()
+}
+
+// nodeLabel returns the label of the node with the given ID, or "" if there
+// is none.
+func (c *Client) nodeLabel(ctx context.Context, tx *sql.Tx, id string) (string, error) {
+	rows, err := c.execCypher(ctx, tx,
+		fmt.Sprintf(`MATCH (n {id: $id}) WHERE %s RETURN label(n)`, NodeLabelPredicate("n")),
+		"label agtype", map[string]any{"id": id})
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var label string
+	if rows.Next() {
+		if err := rows.Scan(&label); err != nil {
+			return "", err
+		}
+	}
+	return strings.Trim(label, "\""), rows.Err()
+}
+
+// listEdges returns the relationships of a node, oldest first, optionally
+// restricted to one type and to a direction (incoming, outgoing or both).
+func (c *Client) listEdges(ctx context.Context, tx *sql.Tx, id, relationType, direction string) ([]models.Edge, error) {
+	relPattern, err := relationshipPattern(relationType, direction)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := c.execCypher(ctx, tx,
+		fmt.Sprintf(
+			`MATCH (a {id: $id})%s(b)
+			 RETURN a.id, b.id, type(r), start_id(r) = id(a), properties(r), id(r)
+			 ORDER BY id(r)`,
+			relPattern),
+		"a_id agtype, b_id agtype, rel_type agtype, outgoing agtype, props agtype, edge_id agtype",
+		map[string]any{"id": id})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var edges []models.Edge
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var aID, bID, relType, outgoing, propsStr, edgeID string
+		if err := rows.Scan(&aID, &bID, &relType, &outgoing, &propsStr, &edgeID); err != nil {
+			return nil, err
+		}
+		// A relationship from a node to itself matches in both directions
+		if seen[edgeID] {
+			continue
+		}
+		seen[edgeID] = true
+
+		fromID, toID := strings.Trim(aID, "\""), strings.Trim(bID, "\"")
+		if outgoing != "true" {
+			fromID, toID = toID, fromID
+		}
+		var properties map[string]any
+		if err := json.Unmarshal([]byte(propsStr), &properties); err != nil {
+			return nil, fmt.Errorf("failed to parse relationship properties: %w", err)
+		}
+		edge := models.NewEdge(fromID, toID, models.RelationType(strings.Trim(relType, "\"")), propsToRelationshipProperties(properties))
+		if position, ok := properties["position"]; ok && position != nil {
+			f := toFloat64(position)
+			edge.Position = &f
+		}
+		edges = append(edges, edge)
+	}
+	return edges, rows.Err()
+}
+
+// dependencyEdges finds the DEPENDS_ON and BLOCKS relationships touching nodes.
+func (c *Client) dependencyEdges(tx *sql.Tx) store.DependencyEdges {
+	return func(ctx context.Context, ids []string) ([]models.Relationship, error) {
+		rows, err := c.execCypher(ctx, tx,
+			`MATCH (a)-[r:DEPENDS_ON|BLOCKS]-(b)
+			 WHERE a.id IN $ids
+			 RETURN a.id, b.id, type(r), start_id(r) = id(a)`,
+			"a_id agtype, b_id agtype, rel_type agtype, outgoing agtype", map[string]any{"ids": ids})
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var rels []models.Relationship
+		for rows.Next() {
+			var aID, bID, relType, outgoing string
+			if err := rows.Scan(&aID, &bID, &relType, &outgoing); err != nil {
+				return nil, err
+			}
+			rel := models.Relationship{
+				FromID: strings.Trim(aID, "\""),
+				ToID:   strings.Trim(bID, "\""),
+				Type:   models.RelationType(strings.Trim(relType, "\"")),
+			}
+			if outgoing != "true" {
+				rel.FromID, rel.ToID = rel.ToID, rel.FromID
 			}
-			rows.Close()
+			rels = append(rels, rel)
 		}
-		frontier = nextFrontier
+		return rels, rows.Err()
 	}
+}
 
-	tx.Commit()
-	return results, nil
+// taskWaits returns the tasks that each of the given tasks waits for, keyed
+// by the waiting task's ID, those it depends on before those blocking it.
+func (c *Client) taskWaits(ctx context.Context, tx *sql.Tx, ids []string) (map[string][]models.Wait, error) {
+	waits := make(map[string][]models.Wait)
+	if len(ids) == 0 {
+		return waits, nil
+	}
+	queries := []struct {
+		relType models.RelationType
+		cypher  string
+	}{
+		{models.RelDependsOn, `MATCH (t:Task)-[:DEPENDS_ON]->(o:Task) WHERE t.id IN $ids RETURN t.id, o`},
+		{models.RelBlocks, `MATCH (o:Task)-[:BLOCKS]->(t:Task) WHERE t.id IN $ids RETURN t.id, o`},
+	}
+	for _, q := range queries {
+		rows, err := c.execCypher(ctx, tx, q.cypher, "task_id agtype, other agtype", map[string]any{"ids": ids})
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var taskID, otherStr string
+			if err := rows.Scan(&taskID, &otherStr); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			props, err := parseAGTypeProperties(otherStr)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			waiter := strings.Trim(taskID, "\"")
+			waits[waiter] = append(waits[waiter], models.Wait{Task: propsToTask(props), Type: q.relType})
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return waits, nil
 }
 
-// createRelationship creates a relationship between two nodes.
+// createRelationship creates the relationship rel from fromID, stamped with
+// its creation time, or sets the properties rel gives on the existing one.
+// It fails if either node is missing.
 // Uses check-then-create pattern since AGE doesn't support MERGE for relationships.
-func (r *Repository) createRelationship(ctx context.Context, tx *sql.Tx, fromID, toID string, relType models.RelationType) error {
+func (c *Client) createRelationship(ctx context.Context, tx *sql.Tx, fromID string, rel models.Relationship) error {
 	// Validate relationship type
-	if err := ValidateRelationType(relType); err != nil {
-		return err
+	if err := models.ValidateRelationType(rel.Type); err != nil {
+		return &models.RelationshipError{FromID: fromID, ToID: rel.ToID, Type: rel.Type, Err: err}
+	}
+	rel.FromID = fromID
+	path, err := store.DependencyCycle(ctx, rel, c.dependencyEdges(tx))
+	if err != nil {
+		return fmt.Errorf("failed to check for dependency cycles: %w", err)
+	}
+	if path != nil {
+		return &models.RelationshipError{FromID: fromID, ToID: rel.ToID, Type: rel.Type, Err: &models.CycleError{Path: path}}
+	}
+
+	params := map[string]any{"from_id": fromID, "to_id": rel.ToID}
+	var keys []string
+	if rel.Reason != "" {
+		keys = append(keys, "reason")
+		params["reason"] = rel.Reason
+	}
+	if rel.Weight != nil {
+		keys = append(keys, "weight")
+		params["weight"] = *rel.Weight
+	}
+	if rel.Metadata != nil {
+		keys = append(keys, "metadata")
+		params["metadata"] = metadataToJSON(rel.Metadata)
 	}
 
 	// Check if relationship already exists
 	checkCypher := fmt.Sprintf(
 		`MATCH (a)-[r:%s]->(b)
-		 WHERE a.id = '%s' AND b.id = '%s'
+		 WHERE a.id = $from_id AND b.id = $to_id
 		 RETURN r`,
-		relType,
-		EscapeCypherString(fromID),
-		EscapeCypherString(toID),
+		rel.Type,
 	)
 
-	rows, err := r.client.execCypher(ctx, tx, checkCypher, "r agtype")
+	rows, err := c.execCypher(ctx, tx, checkCypher, "r agtype", params)
 	if err != nil {
 		return err
 	}
@@ -534,27 +1036,49 @@ func (r *Repository) createRelationship(ctx context.Context, tx *sql.Tx, fromID,
 	rows.Close()
 
 	if exists {
-		return nil // Relationship already exists
+		if len(keys) == 0 {
+			return nil // Relationship already exists
+		}
+		sets := make([]string, len(keys))
+		for i, k := range keys {
+			sets[i] = fmt.Sprintf("r.%s = $%s", k, k)
+		}
+		return c.execCypherNoReturn(ctx, tx, fmt.Sprintf(
+			`MATCH (a)-[r:%s]->(b)
+			 WHERE a.id = $from_id AND b.id = $to_id
+			 SET %s
+			 RETURN r`,
+			rel.Type, strings.Join(sets, ", ")), params)
+	}
+
+	keys = append(keys, "created_at")
+	params["created_at"] = time.Now().UTC().Format(time.RFC3339)
+	props := make([]string, len(keys))
+	for i, k := range keys {
+		props[i] = fmt.Sprintf("%s: $%s", k, k)
 	}
 
 	// Create the relationship - use label() function for AGE-compatible label filtering
 	createCypher := fmt.Sprintf(
 		`MATCH (a), (b)
-		 WHERE a.id = '%s' AND b.id = '%s' AND %s AND %s
-		 CREATE (a)-[r:%s]->(b)
+		 WHERE a.id = $from_id AND b.id = $to_id AND %s AND %s
+		 CREATE (a)-[r:%s {%s}]->(b)
 		 RETURN r`,
-		EscapeCypherString(fromID),
-		EscapeCypherString(toID),
 		NodeLabelPredicate("a"),
 		NodeLabelPredicate("b"),
-		relType,
+		rel.Type,
+		strings.Join(props, ", "),
 	)
 
-	createRows, err := r.client.execCypher(ctx, tx, createCypher, "r agtype")
+	createRows, err := c.execCypher(ctx, tx, createCypher, "r agtype", params)
 	if err != nil {
 		return err
 	}
+	created := createRows.Next()
 	createRows.Close()
 
+	if !created {
+		return &models.RelationshipError{FromID: fromID, ToID: rel.ToID, Type: rel.Type, Err: models.ErrNodeNotFound}
+	}
 	return nil
 }
@@ -0,0 +1,171 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/lib/pq"
+)
+
+// revisionsTable holds the state of each node at each of its versions. Like
+// the search and embeddings tables it lives outside the graph, keyed by node
+// ID, and is kept in step with the graph in the same transaction.
+const revisionsTable = "public.associate_revisions"
+
+// revisionColumns lists the columns read by scanRevision, in order.
+const revisionColumns = "node_id, version, kind, type, content, name, description, status, metadata, tags, actor, created_at"
+
+// createRevisionsTable creates the revisions table. Nodes written before it
+// existed get their first revision from backfillRevisions.
+func createRevisionsTable(ctx context.Context, tx *sql.Tx, graphName string) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+revisionsTable+` (
+		node_id TEXT NOT NULL,
+		version BIGINT NOT NULL,
+		kind TEXT NOT NULL,
+		type TEXT NOT NULL DEFAULT '',
+		content TEXT NOT NULL DEFAULT '',
+		name TEXT NOT NULL DEFAULT '',
+		description TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL DEFAULT '',
+		metadata TEXT NOT NULL DEFAULT '',
+		tags TEXT[] NOT NULL DEFAULT '{}',
+		actor TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (node_id, version)
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create revisions table: %w", err)
+	}
+	return nil
+}
+
+// recordRevision stores a revision unless its version is already recorded.
+func recordRevision(ctx context.Context, tx *sql.Tx, rev models.Revision) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO `+revisionsTable+` (`+revisionColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		 ON CONFLICT (node_id, version) DO NOTHING`,
+		rev.NodeID, rev.Version, string(rev.Kind), string(rev.Type), rev.Content, rev.Name, rev.Description,
+		rev.Status, metadataToJSON(rev.Metadata), pq.Array(tagsParam(rev.Tags)), rev.Actor, rev.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	return nil
+}
+
+// backfillRevisions records the current state of every node that has no
+// revision for its version, such as nodes written before the revisions table
+// existed, with no actor.
+func backfillRevisions(ctx context.Context, tx *sql.Tx, graphName string) error {
+	var revs []models.Revision
+	for _, label := range []string{"Memory", "Plan", "Task"} {
+		rows, err := tx.QueryContext(ctx,
+			fmt.Sprintf(`SELECT * FROM cypher('%s', $$ MATCH (n:%s) RETURN n $$) AS (n agtype)`, graphName, label))
+		if err != nil {
+			return fmt.Errorf("failed to read %s nodes: %w", label, err)
+		}
+		for rows.Next() {
+			var agtypeStr string
+			if err := rows.Scan(&agtypeStr); err != nil {
+				rows.Close()
+				return err
+			}
+			props, err := parseAGTypeProperties(agtypeStr)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			switch label {
+			case "Plan":
+				revs = append(revs, models.PlanRevision(propsToPlan(props), ""))
+			case "Task":
+				revs = append(revs, models.TaskRevision(propsToTask(props), ""))
+			default:
+				revs = append(revs, models.MemoryRevision(propsToMemory(props), ""))
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	for _, rev := range revs {
+		if err := recordRevision(ctx, tx, rev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeRevisions deletes the revisions of the given nodes.
+func removeRevisions(ctx context.Context, tx *sql.Tx, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+revisionsTable+` WHERE node_id = ANY($1)`, pq.Array(ids)); err != nil {
+		return fmt.Errorf("failed to remove revisions: %w", err)
+	}
+	return nil
+}
+
+// scanRevision reads a row selected with revisionColumns.
+func scanRevision(scan func(dest ...any) error) (*models.Revision, error) {
+	var rev models.Revision
+	var kind, nodeType, metadata string
+	var tags []string
+	err := scan(&rev.NodeID, &rev.Version, &kind, &nodeType, &rev.Content, &rev.Name, &rev.Description,
+		&rev.Status, &metadata, pq.Array(&tags), &rev.Actor, &rev.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	rev.Kind = models.NodeKind(kind)
+	rev.Type = models.MemoryType(nodeType)
+	rev.Metadata = jsonToMetadata(metadata)
+	if len(tags) > 0 {
+		rev.Tags = tags
+	}
+	rev.CreatedAt = rev.CreatedAt.UTC()
+	return &rev, nil
+}
+
+// ListRevisions lists the revisions of a node of any type, newest first
+func (r *Repository) ListRevisions(ctx context.Context, id string) ([]models.Revision, error) {
+	label, err := r.client.nodeLabel(ctx, nil, id)
+	if err != nil {
+		return nil, err
+	}
+	if label == "" {
+		return nil, fmt.Errorf("node not found: %s", id)
+	}
+
+	rows, err := r.client.db.QueryContext(ctx,
+		`SELECT `+revisionColumns+` FROM `+revisionsTable+` WHERE node_id = $1 ORDER BY version DESC`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []models.Revision
+	for rows.Next() {
+		rev, err := scanRevision(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *rev)
+	}
+	return revisions, rows.Err()
+}
+
+// GetRevision retrieves the revision of a node at the given version
+func (r *Repository) GetRevision(ctx context.Context, id string, version int64) (*models.Revision, error) {
+	row := r.client.db.QueryRowContext(ctx,
+		`SELECT `+revisionColumns+` FROM `+revisionsTable+` WHERE node_id = $1 AND version = $2`, id, version)
+	rev, err := scanRevision(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rev, err
+}
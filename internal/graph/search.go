@@ -0,0 +1,480 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/embedding"
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/ranking"
+	"github.com/Thomas-Fitz/associate/internal/textsearch"
+	"github.com/lib/pq"
+)
+
+// searchTable holds the searchable text of each node in a plain PostgreSQL
+// table, since tsvector and pg_trgm cannot index AGE's agtype properties.
+// Repositories keep it in step with the graph inside the same transaction.
+const searchTable = "public.associate_search"
+
+// headlineOptions configures ts_headline to produce textsearch-style snippets.
+const headlineOptions = `StartSel="` + textsearch.HighlightStart + `", StopSel="` + textsearch.HighlightEnd +
+	`", MaxWords=24, MinWords=12, MaxFragments=2, FragmentDelimiter=" … "`
+
+// createSearchTable creates the search table and its indexes, then indexes
+// the memories already in the graph.
+func createSearchTable(ctx context.Context, tx *sql.Tx, graphName string) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS ` + searchTable + ` (
+			id TEXT PRIMARY KEY,
+			label TEXT NOT NULL,
+			body TEXT NOT NULL,
+			tsv tsvector GENERATED ALWAYS AS (to_tsvector('english', body)) STORED
+		)`,
+		`CREATE INDEX IF NOT EXISTS associate_search_tsv_idx ON ` + searchTable + ` USING gin (tsv)`,
+		`CREATE INDEX IF NOT EXISTS associate_search_body_trgm_idx ON ` + searchTable + ` USING gin (body gin_trgm_ops)`,
+		fmt.Sprintf(`INSERT INTO `+searchTable+` (id, label, body)
+			SELECT c.id::text::jsonb #>> '{}', 'Memory', COALESCE(c.content::text::jsonb #>> '{}', '')
+			FROM cypher('%s', $$ MATCH (m:Memory) RETURN m.id, m.content $$) AS c(id agtype, content agtype)
+			ON CONFLICT (id) DO NOTHING`, graphName),
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create search table: %w", err)
+		}
+	}
+	return nil
+}
+
+// searchRow is a node's row in the search table. Title is only set for plans;
+// memories and tasks take their title from the body.
+type searchRow struct {
+	id         string
+	label      string
+	body       string
+	title      string
+	status     string
+	memoryType models.MemoryType
+	tags       []string
+	metadata   map[string]string
+	createdAt  time.Time
+	updatedAt  time.Time
+}
+
+// indexNode stores the searchable text of a node along with the fields
+// search filters and results use.
+func indexNode(ctx context.Context, tx *sql.Tx, row searchRow) error {
+	metadata, err := json.Marshal(row.metadata)
+	if err != nil || row.metadata == nil {
+		metadata = []byte("{}")
+	}
+	tags, err := json.Marshal(tagsParam(row.tags))
+	if err != nil {
+		tags = []byte("[]")
+	}
+	// The graph stores timestamps as RFC 3339 with whole seconds
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO `+searchTable+` (id, label, body, title, status, memory_type, tags, metadata, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 ON CONFLICT (id) DO UPDATE SET label = EXCLUDED.label, body = EXCLUDED.body,
+			title = EXCLUDED.title, status = EXCLUDED.status,
+			memory_type = EXCLUDED.memory_type, tags = EXCLUDED.tags, metadata = EXCLUDED.metadata,
+			created_at = EXCLUDED.created_at, updated_at = EXCLUDED.updated_at`,
+		row.id, row.label, row.body, row.title, row.status, string(row.memoryType), string(tags), string(metadata),
+		row.createdAt.Truncate(time.Second), row.updatedAt.Truncate(time.Second))
+	if err != nil {
+		return fmt.Errorf("failed to index search text: %w", err)
+	}
+	return nil
+}
+
+// indexMemory stores the searchable text of a memory.
+func indexMemory(ctx context.Context, tx *sql.Tx, mem models.Memory) error {
+	return indexNode(ctx, tx, searchRow{
+		id: mem.ID, label: "Memory", body: mem.Content, memoryType: mem.Type,
+		tags: mem.Tags, metadata: mem.Metadata, createdAt: mem.CreatedAt, updatedAt: mem.UpdatedAt,
+	})
+}
+
+// indexPlan stores the searchable text of a plan: its name and description.
+func indexPlan(ctx context.Context, tx *sql.Tx, plan models.Plan) error {
+	return indexNode(ctx, tx, searchRow{
+		id: plan.ID, label: "Plan", body: embedding.PlanText(plan.Name, plan.Description), title: plan.Name,
+		status: string(plan.Status), tags: plan.Tags, metadata: plan.Metadata,
+		createdAt: plan.CreatedAt, updatedAt: plan.UpdatedAt,
+	})
+}
+
+// indexTask stores the searchable text of a task.
+func indexTask(ctx context.Context, tx *sql.Tx, task models.Task) error {
+	return indexNode(ctx, tx, searchRow{
+		id: task.ID, label: "Task", body: task.Content, status: string(task.Status),
+		tags: task.Tags, metadata: task.Metadata, createdAt: task.CreatedAt, updatedAt: task.UpdatedAt,
+	})
+}
+
+// removeSearchText deletes the searchable text of the given nodes.
+func removeSearchText(ctx context.Context, tx *sql.Tx, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+searchTable+` WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+		return fmt.Errorf("failed to remove search text: %w", err)
+	}
+	return nil
+}
+
+// searchHit is a node matched in the search table.
+type searchHit struct {
+	id      string
+	score   float64
+	snippet string
+}
+
+// searchText ranks the nodes with the given labels that pass the filter, in
+// opts.Sort order, and returns up to limit of them, or all if it is zero,
+// skipping the first offset, along with the number of matches. Full-text
+// matches, which honour stemming, phrases and -exclusions, score in [0.5, 1)
+// by ts_rank_cd. When the query has no phrases, texts that only match
+// approximately through pg_trgm word similarity are also returned, scoring in
+// [0.25, 0.5). Snippets are left to headlines.
+func searchText(ctx context.Context, tx *sql.Tx, labels []string, query string, opts models.SearchOptions, offset, limit int) ([]searchHit, int, error) {
+	parsed := textsearch.Parse(query)
+	if parsed.Empty() {
+		return nil, 0, nil
+	}
+	fuzzy := len(parsed.Phrases) == 0
+
+	filter, args := filterSQL(opts.Filter, []any{query, pq.Array(labels), fuzzy, limitArg(limit), offset})
+	rows, err := tx.QueryContext(ctx,
+		`WITH q AS (SELECT websearch_to_tsquery('english', $1) AS tsq)
+		 SELECT s.id,
+			CASE WHEN s.tsv @@ q.tsq THEN 0.5 + 0.5 * ts_rank_cd(s.tsv, q.tsq, 32)
+			     ELSE 0.25 + 0.25 * word_similarity($1, s.body) END AS score,
+			count(*) OVER ()
+		 FROM `+searchTable+` s, q
+		 WHERE s.label = ANY($2) AND (s.tsv @@ q.tsq OR ($3 AND $1 <% s.body))`+filter+`
+		 ORDER BY `+orderSQL(opts, "score")+`
+		 LIMIT $4 OFFSET $5`,
+		args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return scanSearchHits(rows)
+}
+
+// listSearchTable returns up to limit nodes with the given label that pass
+// the filter, or all if it is zero, in opts.Sort order, skipping the first
+// offset, with a zero score, along with the number of such nodes. It serves
+// searches without a query.
+func listSearchTable(ctx context.Context, tx *sql.Tx, label string, opts models.SearchOptions, offset, limit int) ([]searchHit, int, error) {
+	filter, args := filterSQL(opts.Filter, []any{label, limitArg(limit), offset})
+	rows, err := tx.QueryContext(ctx,
+		`SELECT s.id, 0::float8, count(*) OVER () FROM `+searchTable+` s
+		 WHERE s.label = $1`+filter+`
+		 ORDER BY `+orderSQL(opts, "s.updated_at")+`
+		 LIMIT $2 OFFSET $3`,
+		args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return scanSearchHits(rows)
+}
+
+// limitArg returns the argument of a LIMIT clause for limit. Zero becomes
+// NULL, which returns every row.
+func limitArg(limit int) any {
+	if limit <= 0 {
+		return nil
+	}
+	return limit
+}
+
+// scanSearchHits reads rows of id, score and the number of matches before
+// paging, and returns the hits with that number.
+func scanSearchHits(rows *sql.Rows) ([]searchHit, int, error) {
+	defer rows.Close()
+	var hits []searchHit
+	total := 0
+	for rows.Next() {
+		var h searchHit
+		if err := rows.Scan(&h.id, &h.score, &total); err != nil {
+			return nil, 0, err
+		}
+		hits = append(hits, h)
+	}
+	return hits, total, rows.Err()
+}
+
+// headlines returns ts_headline snippets of the given nodes for the query,
+// keyed by node ID. It runs on a page of hits rather than every match, since
+// ts_headline re-parses the whole body.
+func headlines(ctx context.Context, tx *sql.Tx, query string, ids []string) (map[string]string, error) {
+	snippets := make(map[string]string, len(ids))
+	if len(ids) == 0 || textsearch.Parse(query).Empty() {
+		return snippets, nil
+	}
+	rows, err := tx.QueryContext(ctx,
+		`SELECT s.id, ts_headline('english', s.body, websearch_to_tsquery('english', $1), '`+headlineOptions+`')
+		 FROM `+searchTable+` s WHERE s.id = ANY($2)`,
+		query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, snippet string
+		if err := rows.Scan(&id, &snippet); err != nil {
+			return nil, err
+		}
+		snippets[id] = snippet
+	}
+	return snippets, rows.Err()
+}
+
+// filterSQL returns the predicates for f, each prefixed with AND, over the
+// search table aliased s. Their arguments are appended to args and numbered
+// after them.
+func filterSQL(f models.SearchFilter, args []any) (string, []any) {
+	var sb strings.Builder
+	add := func(predicate string, arg any) {
+		args = append(args, arg)
+		sb.WriteString(" AND " + strings.ReplaceAll(predicate, "$?", "$"+strconv.Itoa(len(args))))
+	}
+	if f.Type != "" {
+		add("s.memory_type = $?", string(f.Type))
+	}
+	if len(f.Tags) > 0 {
+		if f.TagMatch == models.TagMatchAll {
+			add("s.tags ?& $?::text[]", pq.Array(f.Tags))
+		} else {
+			add("s.tags ?| $?::text[]", pq.Array(f.Tags))
+		}
+	}
+	if len(f.Metadata) > 0 {
+		metadata, _ := json.Marshal(f.Metadata)
+		add("s.metadata @> $?::jsonb", string(metadata))
+	}
+	if !f.CreatedAfter.IsZero() {
+		add("s.created_at >= $?", f.CreatedAfter)
+	}
+	if !f.CreatedBefore.IsZero() {
+		add("s.created_at < $?", f.CreatedBefore)
+	}
+	if !f.UpdatedAfter.IsZero() {
+		add("s.updated_at >= $?", f.UpdatedAfter)
+	}
+	if !f.UpdatedBefore.IsZero() {
+		add("s.updated_at < $?", f.UpdatedBefore)
+	}
+	return sb.String(), args
+}
+
+// orderSQL returns the ORDER BY terms for opts, using relevance, where higher
+// is better, for SortRelevance.
+func orderSQL(opts models.SearchOptions, relevance string) string {
+	expr := relevance
+	switch opts.Sort {
+	case models.SortCreatedAt:
+		expr = "s.created_at"
+	case models.SortUpdatedAt:
+		expr = "s.updated_at"
+	}
+	if opts.Order == models.OrderAsc {
+		return expr + " ASC NULLS LAST, s.id"
+	}
+	return expr + " DESC NULLS LAST, s.id"
+}
+
+// addSearchFilterColumns copies the memory fields that search filters apply
+// to into the search table, so filters run as indexed predicates alongside
+// full-text matching.
+func addSearchFilterColumns(ctx context.Context, tx *sql.Tx, graphName string) error {
+	stmts := []string{
+		`ALTER TABLE ` + searchTable + `
+			ADD COLUMN IF NOT EXISTS memory_type TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]',
+			ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}',
+			ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ,
+			ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ`,
+		fmt.Sprintf(`UPDATE `+searchTable+` s SET
+				memory_type = COALESCE(c.mtype::text::jsonb #>> '{}', ''),
+				tags = CASE WHEN jsonb_typeof(c.tags::text::jsonb) = 'array' THEN c.tags::text::jsonb ELSE '[]' END,
+				metadata = COALESCE(NULLIF(c.metadata::text::jsonb #>> '{}', '')::jsonb, '{}'),
+				created_at = (c.created_at::text::jsonb #>> '{}')::timestamptz,
+				updated_at = (c.updated_at::text::jsonb #>> '{}')::timestamptz
+			FROM cypher('%s', $$ MATCH (m:Memory) RETURN m.id, m.type, m.tags, m.metadata, m.created_at, m.updated_at $$)
+				AS c(id agtype, mtype agtype, tags agtype, metadata agtype, created_at agtype, updated_at agtype)
+			WHERE s.id = c.id::text::jsonb #>> '{}'`, graphName),
+		`CREATE INDEX IF NOT EXISTS associate_search_tags_idx ON ` + searchTable + ` USING gin (tags)`,
+		`CREATE INDEX IF NOT EXISTS associate_search_metadata_idx ON ` + searchTable + ` USING gin (metadata jsonb_path_ops)`,
+		`CREATE INDEX IF NOT EXISTS associate_search_type_idx ON ` + searchTable + ` (label, memory_type)`,
+		`CREATE INDEX IF NOT EXISTS associate_search_created_at_idx ON ` + searchTable + ` (label, created_at)`,
+		`CREATE INDEX IF NOT EXISTS associate_search_updated_at_idx ON ` + searchTable + ` (label, updated_at)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to add search filter columns: %w", err)
+		}
+	}
+	return nil
+}
+
+// indexPlansAndTasks adds plans and tasks to the search table, which until
+// now only held memories, so that one query can search every node type.
+// Plans are searched by name and description and keep their name as title.
+func indexPlansAndTasks(ctx context.Context, tx *sql.Tx, graphName string) error {
+	stmts := []string{
+		`ALTER TABLE ` + searchTable + `
+			ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT ''`,
+		fmt.Sprintf(`INSERT INTO `+searchTable+` (id, label, body, title, status, tags, metadata, created_at, updated_at)
+			SELECT c.id::text::jsonb #>> '{}', 'Plan',
+				btrim(COALESCE(c.name::text::jsonb #>> '{}', '') || E'\n' || COALESCE(c.description::text::jsonb #>> '{}', ''), E' \t\r\n'),
+				COALESCE(c.name::text::jsonb #>> '{}', ''), COALESCE(c.status::text::jsonb #>> '{}', ''),
+				%s
+			FROM cypher('%s', $$ MATCH (p:Plan) RETURN p.id, p.name, p.description, p.status, p.tags, p.metadata, p.created_at, p.updated_at $$)
+				AS c(id agtype, name agtype, description agtype, status agtype, tags agtype, metadata agtype, created_at agtype, updated_at agtype)
+			ON CONFLICT (id) DO NOTHING`, searchFilterColumnsSQL, graphName),
+		fmt.Sprintf(`INSERT INTO `+searchTable+` (id, label, body, status, tags, metadata, created_at, updated_at)
+			SELECT c.id::text::jsonb #>> '{}', 'Task',
+				COALESCE(c.content::text::jsonb #>> '{}', ''), COALESCE(c.status::text::jsonb #>> '{}', ''),
+				%s
+			FROM cypher('%s', $$ MATCH (t:Task) RETURN t.id, t.content, t.status, t.tags, t.metadata, t.created_at, t.updated_at $$)
+				AS c(id agtype, content agtype, status agtype, tags agtype, metadata agtype, created_at agtype, updated_at agtype)
+			ON CONFLICT (id) DO NOTHING`, searchFilterColumnsSQL, graphName),
+		`CREATE INDEX IF NOT EXISTS associate_search_label_idx ON ` + searchTable + ` (label)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to index plans and tasks: %w", err)
+		}
+	}
+	return nil
+}
+
+// searchFilterColumnsSQL converts the tags, metadata and timestamps returned
+// by a cypher subquery aliased c into search table columns.
+const searchFilterColumnsSQL = `CASE WHEN jsonb_typeof(c.tags::text::jsonb) = 'array' THEN c.tags::text::jsonb ELSE '[]' END,
+				COALESCE(NULLIF(c.metadata::text::jsonb #>> '{}', '')::jsonb, '{}'),
+				(c.created_at::text::jsonb #>> '{}')::timestamptz,
+				(c.updated_at::text::jsonb #>> '{}')::timestamptz`
+
+// searchRows returns the search table rows of the given nodes by ID.
+func searchRows(ctx context.Context, tx *sql.Tx, ids []string) (map[string]searchRow, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, label, body, title, status, updated_at FROM `+searchTable+` WHERE id = ANY($1)`,
+		pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[string]searchRow, len(ids))
+	for rows.Next() {
+		var row searchRow
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&row.id, &row.label, &row.body, &row.title, &row.status, &updatedAt); err != nil {
+			return nil, err
+		}
+		row.updatedAt = updatedAt.Time.UTC()
+		found[row.id] = row
+	}
+	return found, rows.Err()
+}
+
+// linkCounts returns the number of links of any type of each of the given
+// memories, keyed by memory ID.
+func (c *Client) linkCounts(ctx context.Context, tx *sql.Tx, ids []string) (map[string]int, error) {
+	rows, err := c.execCypher(ctx, tx,
+		`MATCH (m:Memory)-[r]-() WHERE m.id IN $ids RETURN m.id, count(r)`,
+		"id agtype, links agtype", map[string]any{"ids": ids})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	links := make(map[string]int)
+	for rows.Next() {
+		var id, count string
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, err
+		}
+		links[strings.Trim(id, "\"")], _ = strconv.Atoi(count)
+	}
+	return links, rows.Err()
+}
+
+// anchorHops returns the distance from the anchor, as defined by
+// ranking.Features.AnchorHops, of every node within two links of it, keyed by
+// node ID. It is empty without an anchor; an unknown anchor is an error.
+func (c *Client) anchorHops(ctx context.Context, tx *sql.Tx, anchorID string) (map[string]int, error) {
+	hops := make(map[string]int)
+	if anchorID == "" {
+		return hops, nil
+	}
+
+	for _, step := range []struct {
+		hops   int
+		cypher string
+	}{
+		{1, `MATCH (a {id: $id}) RETURN a.id`},
+		{1, `MATCH (a {id: $id})-[]-(n) RETURN DISTINCT n.id`},
+		{2, `MATCH (a {id: $id})-[]-()-[]-(n) RETURN DISTINCT n.id`},
+	} {
+		rows, err := c.execCypher(ctx, tx, step.cypher, "id agtype", map[string]any{"id": anchorID})
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if id = strings.Trim(id, "\""); hops[id] == 0 {
+				hops[id] = step.hops
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		if len(hops) == 0 {
+			return nil, fmt.Errorf("anchor not found: %s", anchorID)
+		}
+	}
+	return hops, nil
+}
+
+// hybridScore replaces the text score of each hit with its hybrid score at
+// time now, given the anchor distances from anchorHops.
+func (c *Client) hybridScore(ctx context.Context, tx *sql.Tx, hits []searchHit, hops map[string]int, now time.Time) error {
+	if len(hits) == 0 {
+		return nil
+	}
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.id
+	}
+	links, err := c.linkCounts(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+	nodes, err := searchRows(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+	for i, h := range hits {
+		hits[i].score = ranking.Score(ranking.Features{
+			Text:       h.score,
+			Links:      links[h.id],
+			AnchorHops: hops[h.id],
+			UpdatedAt:  nodes[h.id].updatedAt,
+		}, now)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+)
+
+func TestFilterSQL(t *testing.T) {
+	if sql, args := filterSQL(models.SearchFilter{}, []any{"q"}); sql != "" || len(args) != 1 {
+		t.Errorf("empty filter: got %q, %v", sql, args)
+	}
+
+	after := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	sql, args := filterSQL(models.SearchFilter{
+		Type:         models.TypeRepository,
+		Tags:         []string{"auth"},
+		TagMatch:     models.TagMatchAll,
+		Metadata:     map[string]string{"repo": "api"},
+		UpdatedAfter: after,
+	}, []any{"q", "Memory"})
+
+	want := " AND s.memory_type = $3 AND s.tags ?& $4::text[] AND s.metadata @> $5::jsonb AND s.updated_at >= $6"
+	if sql != want {
+		t.Errorf("filterSQL:\n got %q\nwant %q", sql, want)
+	}
+	if len(args) != 6 || args[2] != "Repository" || args[4] != `{"repo":"api"}` || args[5] != after {
+		t.Errorf("args: %v", args)
+	}
+
+	sql, _ = filterSQL(models.SearchFilter{Tags: []string{"a", "b"}}, nil)
+	if !strings.Contains(sql, "s.tags ?| $1::text[]") {
+		t.Errorf("any-tag filter: got %q", sql)
+	}
+}
+
+func TestOrderSQL(t *testing.T) {
+	tests := []struct {
+		opts models.SearchOptions
+		want string
+	}{
+		{models.SearchOptions{Sort: models.SortRelevance, Order: models.OrderDesc}, "score DESC NULLS LAST, s.id"},
+		{models.SearchOptions{Sort: models.SortUpdatedAt, Order: models.OrderDesc}, "s.updated_at DESC NULLS LAST, s.id"},
+		{models.SearchOptions{Sort: models.SortCreatedAt, Order: models.OrderAsc}, "s.created_at ASC NULLS LAST, s.id"},
+	}
+	for _, tt := range tests {
+		if got := orderSQL(tt.opts, "score"); got != tt.want {
+			t.Errorf("orderSQL(%s %s): got %q, want %q", tt.opts.Sort, tt.opts.Order, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+//go:build integration
+// +build integration
+
+package graph
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/store/storetest"
+)
+
+// TestStoreConformance runs the shared storage behaviour suite against PostgreSQL/AGE.
+func TestStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		client, ctx, cancel := getTestClient(t)
+		t.Cleanup(func() {
+			client.Close(ctx)
+			cancel()
+		})
+		return storetest.Stores{
+			Memories: NewRepository(client),
+			Plans:    NewPlanRepository(client),
+			Tasks:    NewTaskRepository(client),
+		}
+	})
+}
+
+// TestGetRelated_OneQueryPerDepth checks that GetRelated runs one Cypher
+// query per depth level, however many nodes each level holds.
+func TestGetRelated_OneQueryPerDepth(t *testing.T) {
+	client, ctx, cancel := getTestClient(t)
+	defer cancel()
+	defer client.Close(ctx)
+
+	// A chain of memories, each with two leaves of its own
+	repo := NewRepository(client)
+	prefix := fmt.Sprintf("related-%d", time.Now().UnixNano())
+	var ids []string
+	defer func() { cleanupTestData(ctx, client, ids...) }()
+	add := func(name string, rels ...models.Relationship) string {
+		t.Helper()
+		mem, err := repo.Add(ctx, models.Memory{ID: prefix + "-" + name, Type: models.TypeNote, Content: name}, rels)
+		if err != nil {
+			t.Fatalf("Add %s: %v", name, err)
+		}
+		ids = append(ids, mem.ID)
+		return mem.ID
+	}
+	root := add("chain-0")
+	prev := root
+	for i := 1; i <= models.MaxRelatedDepth; i++ {
+		prev = add(fmt.Sprintf("chain-%d", i), models.Relationship{ToID: prev, Type: models.RelRelatesTo})
+		for j := range 2 {
+			add(fmt.Sprintf("leaf-%d-%d", i, j), models.Relationship{ToID: prev, Type: models.RelRelatesTo})
+		}
+	}
+
+	for depth := 1; depth <= models.MaxRelatedDepth; depth++ {
+		start := client.cypherQueries.Load()
+		results, err := repo.GetRelated(ctx, root, "", "both", depth)
+		if err != nil {
+			t.Fatalf("GetRelated at depth %d: %v", depth, err)
+		}
+		if queries := client.cypherQueries.Load() - start; queries != int64(depth) {
+			t.Errorf("GetRelated at depth %d: ran %d queries, want %d", depth, queries, depth)
+		}
+		if len(results) == 0 || results[len(results)-1].Depth != depth {
+			t.Errorf("GetRelated at depth %d: got %+v", depth, results)
+		}
+	}
+}
@@ -9,9 +9,12 @@ import (
 	"time"
 
 	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/store"
 	"github.com/google/uuid"
 )
 
+var _ store.TaskStore = (*TaskRepository)(nil)
+
 // TaskRepository provides CRUD operations for tasks.
 type TaskRepository struct {
 	client *Client
@@ -22,20 +25,15 @@ func NewTaskRepository(client *Client) *TaskRepository {
 	return &TaskRepository{client: client}
 }
 
-// Position constants for task ordering
-const (
-	DefaultPositionIncrement = 1000.0
-)
-
 // Add creates a new task with required plan links and optional relationships.
-func (r *TaskRepository) Add(ctx context.Context, task models.Task, planIDs []string, relationships []models.Relationship, afterTaskID, beforeTaskID *string) (*models.Task, error) {
+func (r *TaskRepository) Add(ctx context.Context, task models.Task, planIDs []string, relationships []models.Relationship, afterTaskID, beforeTaskID *string) (*models.Task, models.StatusChanges, error) {
 	if len(planIDs) == 0 {
-		return nil, fmt.Errorf("task must belong to at least one plan")
+		return nil, models.StatusChanges{}, fmt.Errorf("task must belong to at least one plan")
 	}
 
 	tx, err := r.client.BeginTx(ctx)
 	if err != nil {
-		return nil, err
+		return nil, models.StatusChanges{}, err
 	}
 	defer tx.Rollback()
 
@@ -43,10 +41,10 @@ func (r *TaskRepository) Add(ctx context.Context, task models.Task, planIDs []st
 	for _, planID := range planIDs {
 		exists, err := r.planExists(ctx, tx, planID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to verify plan %s: %w", planID, err)
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to verify plan %s: %w", planID, err)
 		}
 		if !exists {
-			return nil, fmt.Errorf("plan not found: %s", planID)
+			return nil, models.StatusChanges{}, fmt.Errorf("plan not found: %s", planID)
 		}
 	}
 
@@ -56,71 +54,100 @@ func (r *TaskRepository) Add(ctx context.Context, task models.Task, planIDs []st
 	now := time.Now().UTC()
 	task.CreatedAt = now
 	task.UpdatedAt = now
+	task.Version = 1
 
 	if task.Status == "" {
 		task.Status = models.TaskStatusPending
 	}
 
-	metadataJSON := metadataToJSON(task.Metadata)
-	tagsList := tagsToCypherList(task.Tags)
-
-	cypher := fmt.Sprintf(
-		`CREATE (t:Task {
-			id: '%s',
+	cypher := `CREATE (t:Task {
+			id: $id,
 			node_type: 'Task',
-			content: '%s',
-			status: '%s',
-			metadata: '%s',
-			tags: %s,
-			created_at: '%s',
-			updated_at: '%s'
-		}) RETURN t`,
-		EscapeCypherString(task.ID),
-		EscapeCypherString(task.Content),
-		EscapeCypherString(string(task.Status)),
-		EscapeCypherString(metadataJSON),
-		tagsList,
-		task.CreatedAt.Format(time.RFC3339),
-		task.UpdatedAt.Format(time.RFC3339),
-	)
-
-	rows, err := r.client.execCypher(ctx, tx, cypher, "t agtype")
+			content: $content,
+			status: $status,
+			metadata: $metadata,
+			tags: $tags,
+			created_at: $created_at,
+			updated_at: $updated_at,
+			version: 1
+		}) RETURN t`
+
+	rows, err := r.client.execCypher(ctx, tx, cypher, "t agtype", map[string]any{
+		"id":         task.ID,
+		"content":    task.Content,
+		"status":     string(task.Status),
+		"metadata":   metadataToJSON(task.Metadata),
+		"tags":       tagsParam(task.Tags),
+		"created_at": task.CreatedAt.Format(time.RFC3339),
+		"updated_at": task.UpdatedAt.Format(time.RFC3339),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create task: %w", err)
+		return nil, models.StatusChanges{}, fmt.Errorf("failed to create task: %w", err)
 	}
 	rows.Close()
+	if err := recordRevision(ctx, tx, models.TaskRevision(task, store.Actor(ctx))); err != nil {
+		return nil, models.StatusChanges{}, err
+	}
+
+	if err := indexTask(ctx, tx, task); err != nil {
+		return nil, models.StatusChanges{}, err
+	}
+	if err := r.client.saveEmbedding(ctx, tx, task.ID, "Task", task.Content); err != nil {
+		return nil, models.StatusChanges{}, err
+	}
 
 	// Create PART_OF relationships to plans
 	for _, planID := range planIDs {
 		position, err := r.calculateNewTaskPosition(ctx, tx, planID, afterTaskID, beforeTaskID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to calculate position for plan %s: %w", planID, err)
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to calculate position for plan %s: %w", planID, err)
 		}
 
 		if err := r.createTaskToPlanRelationship(ctx, tx, task.ID, planID, position); err != nil {
-			return nil, fmt.Errorf("failed to link task to plan %s: %w", planID, err)
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to link task to plan %s: %w", planID, err)
 		}
 	}
 
 	// Create other relationships
 	for _, rel := range relationships {
-		if err := r.createRelationshipFromTask(ctx, tx, task.ID, rel.ToID, rel.Type); err != nil {
-			return nil, fmt.Errorf("failed to create %s relationship to %s: %w", rel.Type, rel.ToID, err)
+		if err := r.client.createRelationship(ctx, tx, task.ID, rel); err != nil {
+			return nil, models.StatusChanges{}, err
+		}
+	}
+
+	var changes models.StatusChanges
+	if r.client.statusRules {
+		targets, err := store.StatusRuleTargets(ctx, task.ID, false, relationships, r.client.dependencyEdges(tx))
+		if err != nil {
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to find dependants: %w", err)
+		}
+		if changes.Tasks, err = r.applyStatusRules(ctx, tx, targets, store.RuledStatus); err != nil {
+			return nil, models.StatusChanges{}, err
+		}
+	}
+	if r.client.planCompletion {
+		if changes.Plans, err = r.applyPlanCompletion(ctx, tx, planIDs); err != nil {
+			return nil, models.StatusChanges{}, err
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit: %w", err)
+		return nil, models.StatusChanges{}, fmt.Errorf("failed to commit: %w", err)
 	}
 
-	return &task, nil
+	for _, c := range changes.Tasks {
+		if c.Task.ID == task.ID {
+			task = c.Task
+		}
+	}
+	return &task, changes, nil
 }
 
 // GetByID retrieves a task by ID
 func (r *TaskRepository) GetByID(ctx context.Context, id string) (*models.Task, error) {
-	cypher := fmt.Sprintf(`MATCH (t:Task {id: '%s'}) RETURN t`, EscapeCypherString(id))
+	cypher := `MATCH (t:Task {id: $id}) RETURN t`
 
-	rows, err := r.client.execCypher(ctx, nil, cypher, "t agtype")
+	rows, err := r.client.execCypher(ctx, nil, cypher, "t agtype", map[string]any{"id": id})
 	if err != nil {
 		return nil, err
 	}
@@ -152,9 +179,11 @@ func (r *TaskRepository) GetWithPlans(ctx context.Context, id string) (*models.T
 	}
 	defer tx.Rollback()
 
+	params := map[string]any{"id": id}
+
 	// Get task
-	taskCypher := fmt.Sprintf(`MATCH (t:Task {id: '%s'}) RETURN t`, EscapeCypherString(id))
-	taskRows, err := r.client.execCypher(ctx, tx, taskCypher, "t agtype")
+	taskCypher := `MATCH (t:Task {id: $id}) RETURN t`
+	taskRows, err := r.client.execCypher(ctx, tx, taskCypher, "t agtype", params)
 	if err != nil {
 		return nil, nil, fmt.Errorf("query failed: %w", err)
 	}
@@ -177,12 +206,10 @@ func (r *TaskRepository) GetWithPlans(ctx context.Context, id string) (*models.T
 	}
 
 	// Get plans
-	plansCypher := fmt.Sprintf(
-		`MATCH (t:Task {id: '%s'})-[:PART_OF]->(p:Plan)
-		 RETURN p`,
-		EscapeCypherString(id))
+	plansCypher := `MATCH (t:Task {id: $id})-[:PART_OF]->(p:Plan)
+		 RETURN p`
 
-	plansRows, err := r.client.execCypher(ctx, tx, plansCypher, "p agtype")
+	plansRows, err := r.client.execCypher(ctx, tx, plansCypher, "p agtype", params)
 	if err != nil {
 		return nil, nil, fmt.Errorf("plans query failed: %w", err)
 	}
@@ -206,10 +233,10 @@ func (r *TaskRepository) GetWithPlans(ctx context.Context, id string) (*models.T
 }
 
 // Update modifies an existing task
-func (r *TaskRepository) Update(ctx context.Context, id string, content *string, status *string, metadata map[string]string, tags []string, addPlanIDs []string, newRelationships []models.Relationship) (*models.Task, error) {
+func (r *TaskRepository) Update(ctx context.Context, id string, expectedVersion int64, content *string, status *string, metadata map[string]string, tags []string, addPlanIDs []string, newRelationships []models.Relationship) (*models.Task, models.StatusChanges, error) {
 	tx, err := r.client.BeginTx(ctx)
 	if err != nil {
-		return nil, err
+		return nil, models.StatusChanges{}, err
 	}
 	defer tx.Rollback()
 
@@ -217,39 +244,47 @@ func (r *TaskRepository) Update(ctx context.Context, id string, content *string,
 	for _, planID := range addPlanIDs {
 		exists, err := r.planExists(ctx, tx, planID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to verify plan %s: %w", planID, err)
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to verify plan %s: %w", planID, err)
 		}
 		if !exists {
-			return nil, fmt.Errorf("plan not found: %s", planID)
+			return nil, models.StatusChanges{}, fmt.Errorf("plan not found: %s", planID)
 		}
 	}
 
 	// Build dynamic SET clause
-	setClauses := []string{fmt.Sprintf("t.updated_at = '%s'", time.Now().UTC().Format(time.RFC3339))}
+	params := map[string]any{
+		"id":         id,
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	}
+	setClauses := []string{"t.updated_at = $updated_at", versionSet("t")}
 
 	if content != nil {
-		setClauses = append(setClauses, fmt.Sprintf("t.content = '%s'", EscapeCypherString(*content)))
+		setClauses = append(setClauses, "t.content = $content")
+		params["content"] = *content
 	}
 	if status != nil {
-		setClauses = append(setClauses, fmt.Sprintf("t.status = '%s'", EscapeCypherString(*status)))
+		setClauses = append(setClauses, "t.status = $status")
+		params["status"] = *status
 	}
 	if metadata != nil {
-		setClauses = append(setClauses, fmt.Sprintf("t.metadata = '%s'", EscapeCypherString(metadataToJSON(metadata))))
+		setClauses = append(setClauses, "t.metadata = $metadata")
+		params["metadata"] = metadataToJSON(metadata)
 	}
 	if tags != nil {
-		setClauses = append(setClauses, fmt.Sprintf("t.tags = %s", tagsToCypherList(tags)))
+		setClauses = append(setClauses, "t.tags = $tags")
+		params["tags"] = tagsParam(tags)
 	}
 
 	cypher := fmt.Sprintf(`
-		MATCH (t:Task {id: '%s'})
+		MATCH (t:Task {id: $id})
+		%s
 		SET %s
 		RETURN t`,
-		EscapeCypherString(id),
-		joinStrings(setClauses, ", "))
+		versionPredicate("t", expectedVersion, params), joinStrings(setClauses, ", "))
 
-	rows, err := r.client.execCypher(ctx, tx, cypher, "t agtype")
+	rows, err := r.client.execCypher(ctx, tx, cypher, "t agtype", params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update task: %w", err)
+		return nil, models.StatusChanges{}, fmt.Errorf("failed to update task: %w", err)
 	}
 
 	var task *models.Task
@@ -266,47 +301,285 @@ func (r *TaskRepository) Update(ctx context.Context, id string, content *string,
 	rows.Close()
 
 	if task == nil {
-		return nil, fmt.Errorf("task not found: %s", id)
+		if err := r.client.versionConflict(ctx, tx, "Task", id, expectedVersion); err != nil {
+			return nil, models.StatusChanges{}, err
+		}
+		return nil, models.StatusChanges{}, fmt.Errorf("task not found: %s", id)
+	}
+	if err := recordRevision(ctx, tx, models.TaskRevision(*task, store.Actor(ctx))); err != nil {
+		return nil, models.StatusChanges{}, err
+	}
+
+	if err := indexTask(ctx, tx, *task); err != nil {
+		return nil, models.StatusChanges{}, err
+	}
+	if content != nil {
+		if err := r.client.saveEmbedding(ctx, tx, id, "Task", *content); err != nil {
+			return nil, models.StatusChanges{}, err
+		}
 	}
 
 	// Add to new plans (append to end)
 	for _, planID := range addPlanIDs {
 		maxPos, err := r.getMaxPosition(ctx, tx, planID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get max position for plan %s: %w", planID, err)
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to get max position for plan %s: %w", planID, err)
 		}
 		position := appendPosition(maxPos)
 
 		if err := r.createTaskToPlanRelationship(ctx, tx, id, planID, position); err != nil {
-			return nil, fmt.Errorf("failed to link task to plan %s: %w", planID, err)
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to link task to plan %s: %w", planID, err)
 		}
 	}
 
 	// Create new relationships
 	for _, rel := range newRelationships {
-		if err := r.createRelationshipFromTask(ctx, tx, id, rel.ToID, rel.Type); err != nil {
-			return nil, fmt.Errorf("failed to create %s relationship to %s: %w", rel.Type, rel.ToID, err)
+		if err := r.client.createRelationship(ctx, tx, id, rel); err != nil {
+			return nil, models.StatusChanges{}, err
+		}
+	}
+
+	var changes models.StatusChanges
+	if r.client.statusRules {
+		targets, err := store.StatusRuleTargets(ctx, id, status != nil, newRelationships, r.client.dependencyEdges(tx))
+		if err != nil {
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to find dependants: %w", err)
+		}
+		if changes.Tasks, err = r.applyStatusRules(ctx, tx, targets, store.RuledStatus); err != nil {
+			return nil, models.StatusChanges{}, err
+		}
+	}
+	if r.client.planCompletion && (status != nil || len(addPlanIDs) > 0) {
+		planIDs, err := r.client.taskPlanIDs(ctx, tx, id)
+		if err != nil {
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to find plans: %w", err)
+		}
+		if changes.Plans, err = r.applyPlanCompletion(ctx, tx, planIDs); err != nil {
+			return nil, models.StatusChanges{}, err
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit: %w", err)
+		return nil, models.StatusChanges{}, fmt.Errorf("failed to commit: %w", err)
 	}
 
-	return task, nil
+	for _, c := range changes.Tasks {
+		if c.Task.ID == id {
+			*task = c.Task
+		}
+	}
+	return task, changes, nil
 }
 
-// Delete removes a task and all its relationships
-func (r *TaskRepository) Delete(ctx context.Context, id string) error {
-	cypher := fmt.Sprintf(`MATCH (t:Task {id: '%s'}) DETACH DELETE t RETURN true`, EscapeCypherString(id))
+// SetStatusRules turns the status rules on or off for every repository
+// sharing the client
+func (r *TaskRepository) SetStatusRules(enabled bool) {
+	r.client.statusRules = enabled
+}
 
-	rows, err := r.client.execCypher(ctx, nil, cypher, "result agtype")
+// SetPlanCompletion turns plan completion on or off for every repository
+// sharing the client
+func (r *TaskRepository) SetPlanCompletion(enabled bool) {
+	r.client.planCompletion = enabled
+}
+
+// applyStatusRules applies rule to each of the target tasks.
+func (r *TaskRepository) applyStatusRules(ctx context.Context, tx *sql.Tx, targets []string, rule func([]models.Wait) (from, to models.TaskStatus, ok bool)) ([]models.StatusChange, error) {
+	waits, err := r.client.taskWaits(ctx, tx, targets)
 	if err != nil {
-		return fmt.Errorf("delete failed: %w", err)
+		return nil, fmt.Errorf("failed to find dependencies: %w", err)
 	}
-	rows.Close()
 
-	return nil
+	var changes []models.StatusChange
+	for _, taskID := range targets {
+		from, to, ok := rule(waits[taskID])
+		if !ok {
+			continue
+		}
+		rows, err := r.client.execCypher(ctx, tx,
+			`MATCH (t:Task {id: $id})
+			 WHERE t.status = $from
+			 SET t.status = $to, t.updated_at = $updated_at, `+versionSet("t")+`
+			 RETURN t`,
+			"t agtype", map[string]any{
+				"id":         taskID,
+				"from":       string(from),
+				"to":         string(to),
+				"updated_at": time.Now().UTC().Format(time.RFC3339),
+			})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update status of task %s: %w", taskID, err)
+		}
+		var task *models.Task
+		if rows.Next() {
+			var agtypeStr string
+			if err := rows.Scan(&agtypeStr); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			props, err := parseAGTypeProperties(agtypeStr)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			t := propsToTask(props)
+			task = &t
+		}
+		rows.Close()
+		if task == nil {
+			continue
+		}
+
+		if err := recordRevision(ctx, tx, models.TaskRevision(*task, store.Actor(ctx))); err != nil {
+			return nil, err
+		}
+		if err := indexTask(ctx, tx, *task); err != nil {
+			return nil, err
+		}
+		changes = append(changes, models.StatusChange{Task: *task, From: from})
+	}
+	return changes, nil
+}
+
+// taskPlanIDs returns the IDs of the plans a task belongs to.
+func (c *Client) taskPlanIDs(ctx context.Context, tx *sql.Tx, id string) ([]string, error) {
+	rows, err := c.execCypher(ctx, tx,
+		`MATCH (t:Task {id: $id})-[:PART_OF]->(p:Plan) RETURN p.id`,
+		"plan_id agtype", map[string]any{"id": id})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var planIDs []string
+	for rows.Next() {
+		var planID string
+		if err := rows.Scan(&planID); err != nil {
+			return nil, err
+		}
+		planIDs = append(planIDs, strings.Trim(planID, "\""))
+	}
+	return planIDs, rows.Err()
+}
+
+// applyPlanCompletion applies plan completion to the given plans.
+func (r *TaskRepository) applyPlanCompletion(ctx context.Context, tx *sql.Tx, planIDs []string) ([]models.PlanStatusChange, error) {
+	progress, err := r.client.planProgress(ctx, tx, planIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tasks: %w", err)
+	}
+
+	var changes []models.PlanStatusChange
+	for _, planID := range planIDs {
+		from, to, ok := store.PlanCompletion(progress[planID])
+		if !ok {
+			continue
+		}
+		rows, err := r.client.execCypher(ctx, tx,
+			`MATCH (p:Plan {id: $id})
+			 WHERE p.status = $from
+			 SET p.status = $to, p.updated_at = $updated_at, `+versionSet("p")+`
+			 RETURN p`,
+			"p agtype", map[string]any{
+				"id":         planID,
+				"from":       string(from),
+				"to":         string(to),
+				"updated_at": time.Now().UTC().Format(time.RFC3339),
+			})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update status of plan %s: %w", planID, err)
+		}
+		var plan *models.Plan
+		if rows.Next() {
+			var agtypeStr string
+			if err := rows.Scan(&agtypeStr); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			props, err := parseAGTypeProperties(agtypeStr)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			p := propsToPlan(props)
+			plan = &p
+		}
+		rows.Close()
+		if plan == nil {
+			continue
+		}
+
+		if err := recordRevision(ctx, tx, models.PlanRevision(*plan, store.Actor(ctx))); err != nil {
+			return nil, err
+		}
+		if err := indexPlan(ctx, tx, *plan); err != nil {
+			return nil, err
+		}
+		changes = append(changes, models.PlanStatusChange{Plan: *plan, From: from})
+	}
+	return changes, nil
+}
+
+// Delete moves a task and all its relationships to the trash
+func (r *TaskRepository) Delete(ctx context.Context, id string) (models.StatusChanges, error) {
+	tx, err := r.client.BeginTx(ctx)
+	if err != nil {
+		return models.StatusChanges{}, err
+	}
+	defer tx.Rollback()
+
+	var changes models.StatusChanges
+	label, err := r.client.nodeLabel(ctx, tx, id)
+	if err != nil {
+		return changes, fmt.Errorf("delete failed: %w", err)
+	}
+	if label != "Task" {
+		return changes, nil
+	}
+	// The plans lose the task with its relationships, so find them first
+	planIDs, err := r.client.taskPlanIDs(ctx, tx, id)
+	if err != nil {
+		return models.StatusChanges{}, fmt.Errorf("failed to find plans: %w", err)
+	}
+	if changes.Tasks, err = r.trashNodes(ctx, tx, id); err != nil {
+		return models.StatusChanges{}, err
+	}
+	if r.client.planCompletion {
+		if changes.Plans, err = r.applyPlanCompletion(ctx, tx, planIDs); err != nil {
+			return models.StatusChanges{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.StatusChanges{}, fmt.Errorf("failed to commit: %w", err)
+	}
+	return changes, nil
+}
+
+// trashNodes moves the given nodes to the trash and, with status rules on,
+// applies them to the tasks that waited for those.
+func (r *TaskRepository) trashNodes(ctx context.Context, tx *sql.Tx, ids ...string) ([]models.StatusChange, error) {
+	var targets []string
+	if r.client.statusRules {
+		// The relationships go to the trash with the nodes, so find the
+		// dependants first
+		var err error
+		if targets, err = store.DeletionRuleTargets(ctx, ids, r.client.dependencyEdges(tx)); err != nil {
+			return nil, fmt.Errorf("failed to find dependants: %w", err)
+		}
+	}
+	if err := r.client.trashNodes(ctx, tx, ids...); err != nil {
+		return nil, err
+	}
+	if !r.client.statusRules {
+		return nil, nil
+	}
+	return r.applyStatusRules(ctx, tx, targets, store.RuledStatusAfterDeletion)
+}
+
+// PreviewDelete returns what Delete would move to the trash
+func (r *TaskRepository) PreviewDelete(ctx context.Context, id string) (*models.TrashEntry, error) {
+	return r.client.previewTrash(ctx, id, "Task")
 }
 
 // UpdatePositions batch updates task positions within a plan.
@@ -318,15 +591,15 @@ func (r *TaskRepository) UpdatePositions(ctx context.Context, planID string, tas
 	defer tx.Rollback()
 
 	for taskID, position := range taskPositions {
-		cypher := fmt.Sprintf(
-			`MATCH (t:Task {id: '%s'})-[r:PART_OF]->(p:Plan {id: '%s'})
-			 SET r.position = %f
-			 RETURN r`,
-			EscapeCypherString(taskID),
-			EscapeCypherString(planID),
-			position)
-
-		rows, err := r.client.execCypher(ctx, tx, cypher, "r agtype")
+		cypher := `MATCH (t:Task {id: $task_id})-[r:PART_OF]->(p:Plan {id: $plan_id})
+			 SET r.position = $position
+			 RETURN r`
+
+		rows, err := r.client.execCypher(ctx, tx, cypher, "r agtype", map[string]any{
+			"task_id":  taskID,
+			"plan_id":  planID,
+			"position": position,
+		})
 		if err != nil {
 			return fmt.Errorf("failed to update position for task %s: %w", taskID, err)
 		}
@@ -340,71 +613,81 @@ func (r *TaskRepository) UpdatePositions(ctx context.Context, planID string, tas
 	return tx.Commit()
 }
 
-// List retrieves tasks with optional filtering.
-func (r *TaskRepository) List(ctx context.Context, planID string, status string, tags []string, limit int) ([]models.TaskListResult, error) {
+// NormalizePositions rewrites the positions of the given tasks within a plan,
+// evenly spaced in the given order.
+func (r *TaskRepository) NormalizePositions(ctx context.Context, planID string, taskIDs []string) ([]float64, error) {
+	positions := models.CalculateInsertPositions(0, 0, len(taskIDs))
+	taskPositions := make(map[string]float64, len(taskIDs))
+	for i, taskID := range taskIDs {
+		taskPositions[taskID] = positions[i]
+	}
+	if err := r.UpdatePositions(ctx, planID, taskPositions); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// List retrieves a page of tasks with optional filtering.
+func (r *TaskRepository) List(ctx context.Context, planID string, status string, tags []string, limit int, cursor string) ([]models.TaskListResult, models.Page, error) {
 	if limit <= 0 {
 		limit = 50
 	}
+	c, err := models.DecodeListCursor(cursor)
+	if err != nil {
+		return nil, models.Page{}, err
+	}
 
-	var cypher string
-	hasPosition := false
-
-	if planID != "" {
-		hasPosition = true
-		whereClauses := []string{}
-		if status != "" {
-			whereClauses = append(whereClauses, fmt.Sprintf("t.status = '%s'", EscapeCypherString(status)))
-		}
-		if len(tags) > 0 {
-			tagChecks := make([]string, len(tags))
-			for i, tag := range tags {
-				tagChecks[i] = fmt.Sprintf("'%s' IN t.tags", EscapeCypherString(tag))
-			}
-			whereClauses = append(whereClauses, "("+joinStrings(tagChecks, " OR ")+")")
-		}
+	params := map[string]any{}
+	whereClauses := []string{}
+	if status != "" {
+		whereClauses = append(whereClauses, "t.status = $status")
+		params["status"] = status
+	}
+	if len(tags) > 0 {
+		whereClauses = append(whereClauses, tagsFilter("t", tags, params))
+	}
 
-		whereClause := ""
-		if len(whereClauses) > 0 {
-			whereClause = "AND " + joinStrings(whereClauses, " AND ")
-		}
+	hasPosition := planID != ""
+	match := `MATCH (t:Task)`
+	if hasPosition {
+		params["plan_id"] = planID
+		match = `MATCH (t:Task)-[r:PART_OF]->(p:Plan {id: $plan_id})`
+	}
 
+	var page models.Page
+	if page.Total, err = r.client.count(ctx, nil, match+` `+whereSQL(whereClauses)+` RETURN count(t)`, params); err != nil {
+		return nil, models.Page{}, fmt.Errorf("list failed: %w", err)
+	}
+
+	// One extra row tells whether there is a next page
+	var cypher string
+	if hasPosition {
+		if !c.IsZero() {
+			whereClauses = append(whereClauses, positionKeyset(c, params))
+		}
 		cypher = fmt.Sprintf(`
-			MATCH (t:Task)-[r:PART_OF]->(p:Plan {id: '%s'})
-			WHERE true %s
+			%s
+			%s
 			RETURN t, r.position
-			ORDER BY r.position ASC
+			ORDER BY r.position ASC, t.id ASC
 			LIMIT %d`,
-			EscapeCypherString(planID), whereClause, limit)
+			match, whereSQL(whereClauses), limit+1)
 	} else {
-		whereClauses := []string{}
-		if status != "" {
-			whereClauses = append(whereClauses, fmt.Sprintf("t.status = '%s'", EscapeCypherString(status)))
-		}
-		if len(tags) > 0 {
-			tagChecks := make([]string, len(tags))
-			for i, tag := range tags {
-				tagChecks[i] = fmt.Sprintf("'%s' IN t.tags", EscapeCypherString(tag))
-			}
-			whereClauses = append(whereClauses, "("+joinStrings(tagChecks, " OR ")+")")
-		}
-
-		whereClause := ""
-		if len(whereClauses) > 0 {
-			whereClause = "WHERE " + joinStrings(whereClauses, " AND ")
+		if !c.IsZero() {
+			whereClauses = append(whereClauses, recencyKeyset("t", c, params))
 		}
-
 		cypher = fmt.Sprintf(`
-			MATCH (t:Task)
+			%s
 			%s
 			RETURN t, null
-			ORDER BY t.updated_at DESC
+			ORDER BY t.updated_at DESC, t.id DESC
 			LIMIT %d`,
-			whereClause, limit)
+			match, whereSQL(whereClauses), limit+1)
 	}
 
-	rows, err := r.client.execCypher(ctx, nil, cypher, "t agtype, position agtype")
+	rows, err := r.client.execCypher(ctx, nil, cypher, "t agtype, position agtype", params)
 	if err != nil {
-		return nil, fmt.Errorf("list failed: %w", err)
+		return nil, models.Page{}, fmt.Errorf("list failed: %w", err)
 	}
 	defer rows.Close()
 
@@ -431,14 +714,23 @@ func (r *TaskRepository) List(ctx context.Context, planID string, status string,
 		tasks = append(tasks, taskResult)
 	}
 
-	return tasks, nil
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+		last := tasks[limit-1]
+		next := models.Cursor{UpdatedAt: last.Task.UpdatedAt, ID: last.Task.ID}
+		if last.Position != nil {
+			next = models.Cursor{Position: *last.Position, ID: last.Task.ID}
+		}
+		page.NextCursor = next.Encode()
+	}
+	return tasks, page, nil
 }
 
 // Helper methods
 
 func (r *TaskRepository) planExists(ctx context.Context, tx *sql.Tx, planID string) (bool, error) {
-	cypher := fmt.Sprintf(`MATCH (p:Plan {id: '%s'}) RETURN count(p) > 0`, EscapeCypherString(planID))
-	rows, err := r.client.execCypher(ctx, tx, cypher, "exists agtype")
+	cypher := `MATCH (p:Plan {id: $id}) RETURN count(p) > 0`
+	rows, err := r.client.execCypher(ctx, tx, cypher, "exists agtype", map[string]any{"id": planID})
 	if err != nil {
 		return false, err
 	}
@@ -456,14 +748,13 @@ func (r *TaskRepository) planExists(ctx context.Context, tx *sql.Tx, planID stri
 }
 
 func (r *TaskRepository) createTaskToPlanRelationship(ctx context.Context, tx *sql.Tx, taskID, planID string, position float64) error {
+	params := map[string]any{"task_id": taskID, "plan_id": planID, "position": position}
+
 	// Check if relationship exists
-	checkCypher := fmt.Sprintf(
-		`MATCH (t:Task {id: '%s'})-[r:PART_OF]->(p:Plan {id: '%s'})
-		 RETURN r`,
-		EscapeCypherString(taskID),
-		EscapeCypherString(planID))
+	checkCypher := `MATCH (t:Task {id: $task_id})-[r:PART_OF]->(p:Plan {id: $plan_id})
+		 RETURN r`
 
-	checkRows, err := r.client.execCypher(ctx, tx, checkCypher, "r agtype")
+	checkRows, err := r.client.execCypher(ctx, tx, checkCypher, "r agtype", params)
 	if err != nil {
 		return err
 	}
@@ -473,14 +764,10 @@ func (r *TaskRepository) createTaskToPlanRelationship(ctx context.Context, tx *s
 
 	if exists {
 		// Update position
-		updateCypher := fmt.Sprintf(
-			`MATCH (t:Task {id: '%s'})-[r:PART_OF]->(p:Plan {id: '%s'})
-			 SET r.position = %f
-			 RETURN r`,
-			EscapeCypherString(taskID),
-			EscapeCypherString(planID),
-			position)
-		updateRows, err := r.client.execCypher(ctx, tx, updateCypher, "r agtype")
+		updateCypher := `MATCH (t:Task {id: $task_id})-[r:PART_OF]->(p:Plan {id: $plan_id})
+			 SET r.position = $position
+			 RETURN r`
+		updateRows, err := r.client.execCypher(ctx, tx, updateCypher, "r agtype", params)
 		if err != nil {
 			return err
 		}
@@ -489,60 +776,12 @@ func (r *TaskRepository) createTaskToPlanRelationship(ctx context.Context, tx *s
 	}
 
 	// Create relationship with position
-	createCypher := fmt.Sprintf(
-		`MATCH (t:Task {id: '%s'}), (p:Plan {id: '%s'})
-		 CREATE (t)-[r:PART_OF {position: %f}]->(p)
-		 RETURN r`,
-		EscapeCypherString(taskID),
-		EscapeCypherString(planID),
-		position)
-
-	createRows, err := r.client.execCypher(ctx, tx, createCypher, "r agtype")
-	if err != nil {
-		return err
-	}
-	createRows.Close()
-
-	return nil
-}
+	params["created_at"] = time.Now().UTC().Format(time.RFC3339)
+	createCypher := `MATCH (t:Task {id: $task_id}), (p:Plan {id: $plan_id})
+		 CREATE (t)-[r:PART_OF {position: $position, created_at: $created_at}]->(p)
+		 RETURN r`
 
-func (r *TaskRepository) createRelationshipFromTask(ctx context.Context, tx *sql.Tx, fromID, toID string, relType models.RelationType) error {
-	if err := ValidateRelationType(relType); err != nil {
-		return err
-	}
-
-	// Check if exists
-	checkCypher := fmt.Sprintf(
-		`MATCH (a:Task {id: '%s'})-[r:%s]->(b)
-		 WHERE b.id = '%s'
-		 RETURN r`,
-		EscapeCypherString(fromID),
-		relType,
-		EscapeCypherString(toID))
-
-	rows, err := r.client.execCypher(ctx, tx, checkCypher, "r agtype")
-	if err != nil {
-		return err
-	}
-	exists := rows.Next()
-	rows.Close()
-
-	if exists {
-		return nil
-	}
-
-	// Create - use label() function for AGE-compatible label filtering
-	createCypher := fmt.Sprintf(
-		`MATCH (a:Task {id: '%s'}), (b)
-		 WHERE b.id = '%s' AND %s
-		 CREATE (a)-[r:%s]->(b)
-		 RETURN r`,
-		EscapeCypherString(fromID),
-		EscapeCypherString(toID),
-		NodeLabelPredicate("b"),
-		relType)
-
-	createRows, err := r.client.execCypher(ctx, tx, createCypher, "r agtype")
+	createRows, err := r.client.execCypher(ctx, tx, createCypher, "r agtype", params)
 	if err != nil {
 		return err
 	}
@@ -552,12 +791,10 @@ func (r *TaskRepository) createRelationshipFromTask(ctx context.Context, tx *sql
 }
 
 func (r *TaskRepository) getMaxPosition(ctx context.Context, tx *sql.Tx, planID string) (float64, error) {
-	cypher := fmt.Sprintf(
-		`MATCH (t:Task)-[r:PART_OF]->(p:Plan {id: '%s'})
-		 RETURN max(r.position)`,
-		EscapeCypherString(planID))
+	cypher := `MATCH (t:Task)-[r:PART_OF]->(p:Plan {id: $id})
+		 RETURN max(r.position)`
 
-	rows, err := r.client.execCypher(ctx, tx, cypher, "max_pos agtype")
+	rows, err := r.client.execCypher(ctx, tx, cypher, "max_pos agtype", map[string]any{"id": planID})
 	if err != nil {
 		return 0, err
 	}
@@ -580,13 +817,10 @@ func (r *TaskRepository) getMaxPosition(ctx context.Context, tx *sql.Tx, planID
 }
 
 func (r *TaskRepository) getTaskPosition(ctx context.Context, tx *sql.Tx, taskID, planID string) (float64, error) {
-	cypher := fmt.Sprintf(
-		`MATCH (t:Task {id: '%s'})-[r:PART_OF]->(p:Plan {id: '%s'})
-		 RETURN r.position`,
-		EscapeCypherString(taskID),
-		EscapeCypherString(planID))
+	cypher := `MATCH (t:Task {id: $task_id})-[r:PART_OF]->(p:Plan {id: $plan_id})
+		 RETURN r.position`
 
-	rows, err := r.client.execCypher(ctx, tx, cypher, "position agtype")
+	rows, err := r.client.execCypher(ctx, tx, cypher, "position agtype", map[string]any{"task_id": taskID, "plan_id": planID})
 	if err != nil {
 		return 0, err
 	}
@@ -614,17 +848,16 @@ func (r *TaskRepository) getAdjacentPositions(ctx context.Context, tx *sql.Tx, t
 		return 0, 0, err
 	}
 
+	params := map[string]any{"plan_id": planID, "position": currentPos}
+
 	// Get before position
-	beforeCypher := fmt.Sprintf(
-		`MATCH (t:Task)-[r:PART_OF]->(p:Plan {id: '%s'})
-		 WHERE r.position < %f
+	beforeCypher := `MATCH (t:Task)-[r:PART_OF]->(p:Plan {id: $plan_id})
+		 WHERE r.position < $position
 		 RETURN r.position
 		 ORDER BY r.position DESC
-		 LIMIT 1`,
-		EscapeCypherString(planID),
-		currentPos)
+		 LIMIT 1`
 
-	beforeRows, err := r.client.execCypher(ctx, tx, beforeCypher, "position agtype")
+	beforeRows, err := r.client.execCypher(ctx, tx, beforeCypher, "position agtype", params)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -637,16 +870,13 @@ func (r *TaskRepository) getAdjacentPositions(ctx context.Context, tx *sql.Tx, t
 	beforeRows.Close()
 
 	// Get after position
-	afterCypher := fmt.Sprintf(
-		`MATCH (t:Task)-[r:PART_OF]->(p:Plan {id: '%s'})
-		 WHERE r.position > %f
+	afterCypher := `MATCH (t:Task)-[r:PART_OF]->(p:Plan {id: $plan_id})
+		 WHERE r.position > $position
 		 RETURN r.position
 		 ORDER BY r.position ASC
-		 LIMIT 1`,
-		EscapeCypherString(planID),
-		currentPos)
+		 LIMIT 1`
 
-	afterRows, err := r.client.execCypher(ctx, tx, afterCypher, "position agtype")
+	afterRows, err := r.client.execCypher(ctx, tx, afterCypher, "position agtype", params)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -705,9 +935,9 @@ func (r *TaskRepository) calculateNewTaskPosition(ctx context.Context, tx *sql.T
 		return appendPosition(maxPos), nil
 	}
 
-	positions := CalculateInsertPositions(afterPos, beforePos, 1)
+	positions := models.CalculateInsertPositions(afterPos, beforePos, 1)
 	if len(positions) == 0 {
-		return DefaultPositionIncrement, nil
+		return models.DefaultPositionIncrement, nil
 	}
 	return positions[0], nil
 }
@@ -715,37 +945,5 @@ func (r *TaskRepository) calculateNewTaskPosition(ctx context.Context, tx *sql.T
 func appendPosition(maxPos float64) float64 {
 	nanoComponent := float64(time.Now().UnixNano()%1e9) / 1e9
 	jitter := rand.Float64() * 0.0001
-	return maxPos + DefaultPositionIncrement + nanoComponent + jitter
-}
-
-// CalculateInsertPositions calculates position values for inserting tasks.
-func CalculateInsertPositions(afterPos, beforePos float64, count int) []float64 {
-	if count <= 0 {
-		return nil
-	}
-
-	positions := make([]float64, count)
-
-	switch {
-	case afterPos == 0 && beforePos == 0:
-		for i := 0; i < count; i++ {
-			positions[i] = DefaultPositionIncrement * float64(i+1)
-		}
-	case beforePos == 0:
-		for i := 0; i < count; i++ {
-			positions[i] = afterPos + DefaultPositionIncrement*float64(i+1)
-		}
-	case afterPos == 0:
-		gap := beforePos / float64(count+1)
-		for i := 0; i < count; i++ {
-			positions[i] = gap * float64(i+1)
-		}
-	default:
-		gap := (beforePos - afterPos) / float64(count+1)
-		for i := 0; i < count; i++ {
-			positions[i] = afterPos + gap*float64(i+1)
-		}
-	}
-
-	return positions
+	return maxPos + models.DefaultPositionIncrement + nanoComponent + jitter
 }
@@ -0,0 +1,491 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/embedding"
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/store"
+	"github.com/lib/pq"
+)
+
+// trashTable holds what each delete removed from the graph, as a JSON
+// models.TrashEntry keyed by the deleted node's ID, until it is restored or
+// purged. The nodes themselves are removed from the graph and from the
+// search, embeddings and revisions tables, so nothing else needs to skip them.
+const trashTable = "public.associate_trash"
+
+// createTrashTable creates the trash table.
+func createTrashTable(ctx context.Context, tx *sql.Tx, graphName string) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+trashTable+` (
+		id TEXT PRIMARY KEY,
+		deleted_at TIMESTAMPTZ NOT NULL,
+		entry JSONB NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create trash table: %w", err)
+	}
+	return nil
+}
+
+// nodeProperties returns the properties of the node with the given label and
+// ID, or nil if there is none.
+func (c *Client) nodeProperties(ctx context.Context, tx *sql.Tx, label, id string) (map[string]any, error) {
+	rows, err := c.execCypher(ctx, tx, fmt.Sprintf(`MATCH (n:%s {id: $id}) RETURN n`, label), "n agtype", map[string]any{"id": id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", label, err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var agtypeStr string
+	if err := rows.Scan(&agtypeStr); err != nil {
+		return nil, err
+	}
+	return parseAGTypeProperties(agtypeStr)
+}
+
+// trashEntry returns what deleting the nodes with the given IDs, the first of
+// which is the node being deleted, moves to the trash: the nodes, their
+// revisions and every relationship touching them. Missing nodes are skipped,
+// and it returns nil if all of them are.
+func (c *Client) trashEntry(ctx context.Context, tx *sql.Tx, ids ...string) (*models.TrashEntry, error) {
+	var nodes []models.TrashedNode
+	var edges []models.Edge
+	for _, id := range ids {
+		label, err := c.nodeLabel(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if label == "" {
+			continue
+		}
+		props, err := c.nodeProperties(ctx, tx, label, id)
+		if err != nil || props == nil {
+			return nil, err
+		}
+		tn := models.TrashedNode{Kind: models.NodeKind(label)}
+		switch label {
+		case "Plan":
+			p := propsToPlan(props)
+			tn.Plan = &p
+		case "Task":
+			t := propsToTask(props)
+			tn.Task = &t
+		default:
+			m := propsToMemory(props)
+			tn.Memory = &m
+		}
+		if tn.Revisions, err = nodeRevisions(ctx, tx, id); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, tn)
+
+		nodeEdges, err := c.listEdges(ctx, tx, id, "", "both")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read relationships: %w", err)
+		}
+		edges = append(edges, nodeEdges...)
+	}
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	entry := models.NewTrashEntry(nodes, edges, time.Now().UTC())
+	return &entry, nil
+}
+
+// trashNodes moves the nodes with the given IDs, the first of which is the
+// node being deleted, to the trash along with their revisions and every
+// relationship touching them, then deletes them. Missing nodes are skipped.
+func (c *Client) trashNodes(ctx context.Context, tx *sql.Tx, ids ...string) error {
+	entry, err := c.trashEntry(ctx, tx, ids...)
+	if err != nil || entry == nil {
+		return err
+	}
+
+	if err := saveTrashEntry(ctx, tx, *entry); err != nil {
+		return err
+	}
+	for _, tn := range entry.Nodes {
+		cypher := fmt.Sprintf(`MATCH (n:%s {id: $id}) DETACH DELETE n RETURN true`, tn.Kind)
+		rows, err := c.execCypher(ctx, tx, cypher, "result agtype", map[string]any{"id": tn.ID()})
+		if err != nil {
+			return fmt.Errorf("delete failed: %w", err)
+		}
+		rows.Close()
+	}
+	trashed := entry.NodeIDs()
+	if err := removeSearchText(ctx, tx, trashed...); err != nil {
+		return err
+	}
+	if err := removeEmbeddings(ctx, tx, trashed...); err != nil {
+		return err
+	}
+	return removeRevisions(ctx, tx, trashed...)
+}
+
+// previewTrash returns what deleting the node with the given ID and label
+// moves to the trash, or nil if there is no such node.
+func (c *Client) previewTrash(ctx context.Context, id, label string) (*models.TrashEntry, error) {
+	tx, err := c.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Nothing is written, so the transaction is only ever rolled back
+	defer tx.Rollback()
+
+	if l, err := c.nodeLabel(ctx, tx, id); err != nil || l != label {
+		return nil, err
+	}
+	return c.trashEntry(ctx, tx, id)
+}
+
+// nodeRevisions returns the revisions of a node, oldest first.
+func nodeRevisions(ctx context.Context, tx *sql.Tx, id string) ([]models.Revision, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT `+revisionColumns+` FROM `+revisionsTable+` WHERE node_id = $1 ORDER BY version`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []models.Revision
+	for rows.Next() {
+		rev, err := scanRevision(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *rev)
+	}
+	return revisions, rows.Err()
+}
+
+// saveTrashEntry stores entry, replacing any earlier entry with its ID.
+func saveTrashEntry(ctx context.Context, tx *sql.Tx, entry models.TrashEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO `+trashTable+` (id, deleted_at, entry) VALUES ($1, $2, $3)
+		 ON CONFLICT (id) DO UPDATE SET deleted_at = EXCLUDED.deleted_at, entry = EXCLUDED.entry`,
+		entry.ID, entry.DeletedAt, string(b))
+	if err != nil {
+		return fmt.Errorf("failed to save trash entry: %w", err)
+	}
+	return nil
+}
+
+// listTrash returns the trash entries, most recently deleted first.
+func (c *Client) listTrash(ctx context.Context) ([]models.TrashEntry, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT entry FROM `+trashTable+` ORDER BY deleted_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.TrashEntry
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var entry models.TrashEntry
+		if err := json.Unmarshal(b, &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode trash entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// lockTrashEntry returns the trash entry with the given ID, locked until tx
+// ends, or nil if there is none.
+func lockTrashEntry(ctx context.Context, tx *sql.Tx, id string) (*models.TrashEntry, error) {
+	var b []byte
+	err := tx.QueryRowContext(ctx, `SELECT entry FROM `+trashTable+` WHERE id = $1 FOR UPDATE`, id).Scan(&b)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trash entry: %w", err)
+	}
+	var entry models.TrashEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode trash entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// lockTrashHolding returns the trash entries, other than the one with the
+// given ID, that hold any of the nodes with the given IDs, locked until tx
+// ends.
+func lockTrashHolding(ctx context.Context, tx *sql.Tx, id string, nodeIDs []string) ([]models.TrashEntry, error) {
+	if len(nodeIDs) == 0 {
+		return nil, nil
+	}
+	rows, err := tx.QueryContext(ctx,
+		`SELECT t.entry FROM `+trashTable+` t
+		 WHERE t.id <> $1 AND EXISTS (
+			SELECT 1 FROM jsonb_array_elements(t.entry->'nodes') n
+			WHERE COALESCE(n->'memory'->>'id', n->'plan'->>'id', n->'task'->>'id') = ANY($2))
+		 ORDER BY t.deleted_at DESC
+		 FOR UPDATE`,
+		id, pq.Array(nodeIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trash: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.TrashEntry
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var entry models.TrashEntry
+		if err := json.Unmarshal(b, &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode trash entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// restoreNode re-creates a node kept in the trash, with its revisions, search
+// text and embedding.
+func (c *Client) restoreNode(ctx context.Context, tx *sql.Tx, tn models.TrashedNode) error {
+	var cypher, label, text string
+	var params map[string]any
+	var index func() error
+	switch {
+	case tn.Plan != nil:
+		p := *tn.Plan
+		label, text = "Plan", embedding.PlanText(p.Name, p.Description)
+		cypher = `CREATE (n:Plan {
+			id: $id,
+			node_type: 'Plan',
+			name: $name,
+			description: $description,
+			status: $status,
+			metadata: $metadata,
+			tags: $tags,
+			created_at: $created_at,
+			updated_at: $updated_at,
+			version: $version
+		}) RETURN n`
+		params = map[string]any{
+			"id":          p.ID,
+			"name":        p.Name,
+			"description": p.Description,
+			"status":      string(p.Status),
+			"metadata":    metadataToJSON(p.Metadata),
+			"tags":        tagsParam(p.Tags),
+			"created_at":  p.CreatedAt.Format(time.RFC3339),
+			"updated_at":  p.UpdatedAt.Format(time.RFC3339),
+			"version":     p.Version,
+		}
+		index = func() error { return indexPlan(ctx, tx, p) }
+	case tn.Task != nil:
+		t := *tn.Task
+		label, text = "Task", t.Content
+		cypher = `CREATE (n:Task {
+			id: $id,
+			node_type: 'Task',
+			content: $content,
+			status: $status,
+			metadata: $metadata,
+			tags: $tags,
+			created_at: $created_at,
+			updated_at: $updated_at,
+			version: $version
+		}) RETURN n`
+		params = map[string]any{
+			"id":         t.ID,
+			"content":    t.Content,
+			"status":     string(t.Status),
+			"metadata":   metadataToJSON(t.Metadata),
+			"tags":       tagsParam(t.Tags),
+			"created_at": t.CreatedAt.Format(time.RFC3339),
+			"updated_at": t.UpdatedAt.Format(time.RFC3339),
+			"version":    t.Version,
+		}
+		index = func() error { return indexTask(ctx, tx, t) }
+	case tn.Memory != nil:
+		m := *tn.Memory
+		label, text = "Memory", m.Content
+		cypher = `CREATE (n:Memory {
+			id: $id,
+			node_type: 'Memory',
+			type: $type,
+			content: $content,
+			metadata: $metadata,
+			tags: $tags,
+			created_at: $created_at,
+			updated_at: $updated_at,
+			version: $version
+		}) RETURN n`
+		params = map[string]any{
+			"id":         m.ID,
+			"type":       string(m.Type),
+			"content":    m.Content,
+			"metadata":   metadataToJSON(m.Metadata),
+			"tags":       tagsParam(m.Tags),
+			"created_at": m.CreatedAt.Format(time.RFC3339),
+			"updated_at": m.UpdatedAt.Format(time.RFC3339),
+			"version":    m.Version,
+		}
+		index = func() error { return indexMemory(ctx, tx, m) }
+	default:
+		return fmt.Errorf("trash entry has no node")
+	}
+
+	if err := c.execCypherNoReturn(ctx, tx, cypher, params); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", tn.ID(), err)
+	}
+	for _, rev := range tn.Revisions {
+		if err := recordRevision(ctx, tx, rev); err != nil {
+			return err
+		}
+	}
+	if err := index(); err != nil {
+		return err
+	}
+	return c.saveEmbedding(ctx, tx, tn.ID(), label, text)
+}
+
+// restoreEdge re-creates a relationship exactly as described, position
+// included.
+func (c *Client) restoreEdge(ctx context.Context, tx *sql.Tx, e models.Edge) error {
+	if err := models.ValidateRelationType(e.Type); err != nil {
+		return err
+	}
+	params := map[string]any{"from_id": e.FromID, "to_id": e.ToID}
+	var keys []string
+	if !e.CreatedAt.IsZero() {
+		keys = append(keys, "created_at")
+		params["created_at"] = e.CreatedAt.Format(time.RFC3339)
+	}
+	if e.Reason != "" {
+		keys = append(keys, "reason")
+		params["reason"] = e.Reason
+	}
+	if e.Weight != nil {
+		keys = append(keys, "weight")
+		params["weight"] = *e.Weight
+	}
+	if e.Metadata != nil {
+		keys = append(keys, "metadata")
+		params["metadata"] = metadataToJSON(e.Metadata)
+	}
+	if e.Position != nil {
+		keys = append(keys, "position")
+		params["position"] = *e.Position
+	}
+	var props string
+	if len(keys) > 0 {
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = fmt.Sprintf("%s: $%s", k, k)
+		}
+		props = " {" + strings.Join(pairs, ", ") + "}"
+	}
+
+	err := c.execCypherNoReturn(ctx, tx, fmt.Sprintf(
+		`MATCH (a), (b)
+		 WHERE a.id = $from_id AND b.id = $to_id AND %s AND %s
+		 CREATE (a)-[r:%s%s]->(b)
+		 RETURN r`,
+		NodeLabelPredicate("a"), NodeLabelPredicate("b"), e.Type, props), params)
+	if err != nil {
+		return fmt.Errorf("failed to restore relationship %s: %w", e.ID, err)
+	}
+	return nil
+}
+
+// ListTrash lists the trash, most recently deleted first
+func (r *Repository) ListTrash(ctx context.Context) ([]models.TrashEntry, error) {
+	return r.client.listTrash(ctx)
+}
+
+// Restore brings back a deleted node, the nodes deleted with it and their
+// relationships
+func (r *Repository) Restore(ctx context.Context, id string) (*models.TrashEntry, error) {
+	tx, err := r.client.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entry, err := lockTrashEntry(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("not in trash: %s", id)
+	}
+	ids := store.RestoreIDs(*entry)
+	existing, err := r.client.existingNodes(ctx, tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, nodeID := range ids {
+		if !existing[nodeID] && !entry.Contains(nodeID) {
+			missing = append(missing, nodeID)
+		}
+	}
+	trash, err := lockTrashHolding(ctx, tx, id, missing)
+	if err != nil {
+		return nil, err
+	}
+	restoration, err := store.PlanRestore(*entry, existing, trash)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tn := range entry.Nodes {
+		if err := r.client.restoreNode(ctx, tx, tn); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range restoration.Edges {
+		if err := r.client.restoreEdge(ctx, tx, e); err != nil {
+			return nil, err
+		}
+	}
+	for _, other := range trash {
+		if edges := restoration.Deferred[other.ID]; len(edges) > 0 {
+			other.Edges = append(other.Edges, edges...)
+			if err := saveTrashEntry(ctx, tx, other); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+trashTable+` WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to remove trash entry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	entry.SkippedEdges = restoration.Skipped
+	return entry, nil
+}
+
+// PurgeTrash permanently removes the trash entries deleted before the given time
+func (r *Repository) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	res, err := r.client.db.ExecContext(ctx, `DELETE FROM `+trashTable+` WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
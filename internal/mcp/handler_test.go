@@ -0,0 +1,528 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Thomas-Fitz/associate/internal/mcp/tools"
+	"github.com/Thomas-Fitz/associate/internal/memstore"
+	"github.com/Thomas-Fitz/associate/internal/models"
+)
+
+// newTestHandler returns a tool handler backed by a fresh in-memory store.
+func newTestHandler() *tools.Handler {
+	s := memstore.New()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return tools.NewHandler(memstore.NewRepository(s), memstore.NewPlanRepository(s), memstore.NewTaskRepository(s), logger)
+}
+
+func TestNewServer_InMemory(t *testing.T) {
+	s := memstore.New()
+	server := NewServer(memstore.NewRepository(s), memstore.NewPlanRepository(s), memstore.NewTaskRepository(s), nil)
+	if server.HTTPHandler() == nil {
+		t.Error("HTTPHandler should not be nil")
+	}
+}
+
+func TestHandler_MemoryRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler()
+
+	_, added, err := h.HandleAdd(ctx, nil, tools.AddInput{Content: "hermetic memory", Type: "Note", Tags: []string{"unit"}})
+	if err != nil {
+		t.Fatalf("HandleAdd: %v", err)
+	}
+
+	_, found, err := h.HandleSearch(ctx, nil, tools.SearchInput{Query: "HERMETIC"})
+	if err != nil {
+		t.Fatalf("HandleSearch: %v", err)
+	}
+	if found.Count != 1 || found.Results[0].ID != added.ID {
+		t.Errorf("HandleSearch: got %+v", found)
+	}
+
+	if _, _, err := h.HandleDelete(ctx, nil, tools.DeleteInput{ID: added.ID}); err != nil {
+		t.Fatalf("HandleDelete: %v", err)
+	}
+	_, found, err = h.HandleSearch(ctx, nil, tools.SearchInput{Query: "hermetic"})
+	if err != nil {
+		t.Fatalf("HandleSearch: %v", err)
+	}
+	if found.Count != 0 {
+		t.Errorf("HandleSearch after delete: got %d results", found.Count)
+	}
+}
+
+func TestHandler_BestEffortRelationships(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler()
+
+	_, target, err := h.HandleAdd(ctx, nil, tools.AddInput{Content: "target", Type: "Note"})
+	if err != nil {
+		t.Fatalf("HandleAdd: %v", err)
+	}
+	input := tools.AddInput{Content: "source", Type: "Note", RelatedTo: []string{target.ID, "missing"}}
+
+	if _, _, err := h.HandleAdd(ctx, nil, input); err == nil {
+		t.Fatal("HandleAdd with a missing target should fail")
+	}
+	if _, found, _ := h.HandleSearch(ctx, nil, tools.SearchInput{Query: "source"}); found.Count != 0 {
+		t.Errorf("failed HandleAdd created %d memories", found.Count)
+	}
+
+	input.BestEffort = true
+	_, added, err := h.HandleAdd(ctx, nil, input)
+	if err != nil {
+		t.Fatalf("HandleAdd best effort: %v", err)
+	}
+	results := added.RelationshipResults
+	if len(results) != 2 || !results[0].OK || results[1].OK || results[1].ToID != "missing" || results[1].Error == "" {
+		t.Errorf("relationship results: got %+v", results)
+	}
+	_, got, err := h.HandleGet(ctx, nil, tools.GetInput{ID: added.ID})
+	if err != nil {
+		t.Fatalf("HandleGet: %v", err)
+	}
+	if len(got.Related) != 1 || got.Related[0].ID != target.ID {
+		t.Errorf("related: got %+v", got.Related)
+	}
+}
+
+func TestHandler_BestEffortSkipsDependencyCycles(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler()
+
+	_, plan, err := h.HandleCreatePlan(ctx, nil, tools.CreatePlanInput{Name: "Release"})
+	if err != nil {
+		t.Fatalf("HandleCreatePlan: %v", err)
+	}
+	_, build, err := h.HandleCreateTask(ctx, nil, tools.CreateTaskInput{Content: "build", PlanIDs: []string{plan.ID}})
+	if err != nil {
+		t.Fatalf("HandleCreateTask: %v", err)
+	}
+	_, deploy, err := h.HandleCreateTask(ctx, nil, tools.CreateTaskInput{Content: "deploy", PlanIDs: []string{plan.ID}, DependsOn: []string{build.ID}})
+	if err != nil {
+		t.Fatalf("HandleCreateTask: %v", err)
+	}
+	_, docs, err := h.HandleCreateTask(ctx, nil, tools.CreateTaskInput{Content: "docs", PlanIDs: []string{plan.ID}})
+	if err != nil {
+		t.Fatalf("HandleCreateTask: %v", err)
+	}
+
+	input := tools.UpdateTaskInput{ID: build.ID, DependsOn: []string{deploy.ID}, RelatedTo: []string{docs.ID}}
+	if _, _, err := h.HandleUpdateTask(ctx, nil, input); !errors.Is(err, models.ErrDependencyCycle) {
+		t.Fatalf("HandleUpdateTask closing a cycle: got %v, want ErrDependencyCycle", err)
+	}
+
+	input.BestEffort = true
+	_, updated, err := h.HandleUpdateTask(ctx, nil, input)
+	if err != nil {
+		t.Fatalf("HandleUpdateTask best effort: %v", err)
+	}
+	results := updated.RelationshipResults
+	if len(results) != 2 || !results[0].OK || results[1].OK || results[1].ToID != deploy.ID || !strings.Contains(results[1].Error, "cycle") {
+		t.Errorf("relationship results: got %+v", results)
+	}
+	_, listed, err := h.HandleListRelationships(ctx, nil, tools.ListRelationshipsInput{ID: build.ID, Direction: "outgoing"})
+	if err != nil {
+		t.Fatalf("HandleListRelationships: %v", err)
+	}
+	if listed.Count != 2 || listed.Edges[0].Type != string(models.RelPartOf) || listed.Edges[1].ToID != docs.ID {
+		t.Errorf("relationships: got %+v, want PART_OF and RELATES_TO only", listed.Edges)
+	}
+}
+
+func TestHandler_RestoreRevision(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler()
+
+	_, added, err := h.HandleAdd(ctx, nil, tools.AddInput{Content: "deploy with\nblue-green switch", Type: "Note", Tags: []string{"ops"}})
+	if err != nil {
+		t.Fatalf("HandleAdd: %v", err)
+	}
+	content := "deploy"
+	if _, _, err := h.HandleUpdate(ctx, nil, tools.UpdateInput{ID: added.ID, Content: &content, Tags: []string{}, Actor: "cleanup-bot"}); err != nil {
+		t.Fatalf("HandleUpdate: %v", err)
+	}
+
+	_, listed, err := h.HandleListRevisions(ctx, nil, tools.ListRevisionsInput{ID: added.ID})
+	if err != nil {
+		t.Fatalf("HandleListRevisions: %v", err)
+	}
+	if listed.Count != 2 || listed.Revisions[0].Actor != "cleanup-bot" {
+		t.Fatalf("HandleListRevisions: got %+v", listed)
+	}
+
+	_, diff, err := h.HandleDiffRevisions(ctx, nil, tools.DiffRevisionsInput{ID: added.ID, FromVersion: 1})
+	if err != nil {
+		t.Fatalf("HandleDiffRevisions: %v", err)
+	}
+	if diff.ToVersion != 2 || len(diff.Changes) != 2 || diff.Changes[0].Field != "content" || diff.Changes[1].Field != "tags" {
+		t.Errorf("HandleDiffRevisions: got %+v", diff)
+	}
+
+	_, restored, err := h.HandleRestoreRevision(ctx, nil, tools.RestoreRevisionInput{ID: added.ID, Version: 1, ExpectedVersion: 2})
+	if err != nil {
+		t.Fatalf("HandleRestoreRevision: %v", err)
+	}
+	if restored.Version != 3 || restored.Kind != "Memory" {
+		t.Errorf("HandleRestoreRevision: got %+v", restored)
+	}
+	_, got, err := h.HandleGet(ctx, nil, tools.GetInput{ID: added.ID})
+	if err != nil {
+		t.Fatalf("HandleGet: %v", err)
+	}
+	if got.Content != "deploy with\nblue-green switch" || len(got.Tags) != 1 || got.Version != 3 {
+		t.Errorf("after restore: got %+v", got)
+	}
+}
+
+func TestHandler_Trash(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler()
+
+	_, plan, err := h.HandleCreatePlan(ctx, nil, tools.CreatePlanInput{Name: "Release"})
+	if err != nil {
+		t.Fatalf("HandleCreatePlan: %v", err)
+	}
+	for _, content := range []string{"build", "ship"} {
+		if _, _, err := h.HandleCreateTask(ctx, nil, tools.CreateTaskInput{Content: content, PlanIDs: []string{plan.ID}}); err != nil {
+			t.Fatalf("HandleCreateTask: %v", err)
+		}
+	}
+	_, preview, err := h.HandleDeletePlan(ctx, nil, tools.DeletePlanInput{ID: plan.ID, DryRun: true})
+	if err != nil {
+		t.Fatalf("HandleDeletePlan dry run: %v", err)
+	}
+	if preview.Deleted || preview.WouldDelete == nil || len(preview.WouldDelete.Nodes) != 3 || len(preview.WouldDelete.Relationships) != 2 {
+		t.Fatalf("HandleDeletePlan dry run: got %+v", preview)
+	}
+	if _, trash, _ := h.HandleListTrash(ctx, nil, tools.ListTrashInput{}); trash.Count != 0 {
+		t.Fatalf("HandleListTrash after dry run: got %+v", trash)
+	}
+	if _, _, err := h.HandleDeletePlan(ctx, nil, tools.DeletePlanInput{ID: plan.ID}); err != nil {
+		t.Fatalf("HandleDeletePlan: %v", err)
+	}
+
+	_, trash, err := h.HandleListTrash(ctx, nil, tools.ListTrashInput{})
+	if err != nil {
+		t.Fatalf("HandleListTrash: %v", err)
+	}
+	if trash.Count != 1 || trash.Items[0].ID != plan.ID || trash.Items[0].Title != "Release" || len(trash.Items[0].Nodes) != 3 {
+		t.Fatalf("HandleListTrash: got %+v", trash)
+	}
+
+	_, restored, err := h.HandleRestore(ctx, nil, tools.RestoreInput{ID: plan.ID})
+	if err != nil {
+		t.Fatalf("HandleRestore: %v", err)
+	}
+	if restored.Kind != "Plan" || len(restored.Restored) != 3 {
+		t.Errorf("HandleRestore: got %+v", restored)
+	}
+	_, got, err := h.HandleGetPlan(ctx, nil, tools.GetPlanInput{ID: plan.ID})
+	if err != nil {
+		t.Fatalf("HandleGetPlan: %v", err)
+	}
+	if len(got.Tasks) != 2 || got.Tasks[0].Content != "build" || got.Tasks[1].Content != "ship" {
+		t.Errorf("restored plan tasks: got %+v", got.Tasks)
+	}
+	if _, trash, _ := h.HandleListTrash(ctx, nil, tools.ListTrashInput{}); trash.Count != 0 || trash.Items == nil {
+		t.Errorf("HandleListTrash after restore: got %+v", trash)
+	}
+}
+
+func TestHandler_PlanWithTasks(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler()
+
+	_, plan, err := h.HandleCreatePlan(ctx, nil, tools.CreatePlanInput{Name: "Hermetic plan"})
+	if err != nil {
+		t.Fatalf("HandleCreatePlan: %v", err)
+	}
+	_, first, err := h.HandleCreateTask(ctx, nil, tools.CreateTaskInput{Content: "first", PlanIDs: []string{plan.ID}})
+	if err != nil {
+		t.Fatalf("HandleCreateTask: %v", err)
+	}
+	_, second, err := h.HandleCreateTask(ctx, nil, tools.CreateTaskInput{Content: "second", PlanIDs: []string{plan.ID}, DependsOn: []string{first.ID}})
+	if err != nil {
+		t.Fatalf("HandleCreateTask: %v", err)
+	}
+
+	_, got, err := h.HandleGetPlan(ctx, nil, tools.GetPlanInput{ID: plan.ID})
+	if err != nil {
+		t.Fatalf("HandleGetPlan: %v", err)
+	}
+	if len(got.Tasks) != 2 || got.Tasks[0].ID != first.ID || got.Tasks[1].ID != second.ID {
+		t.Fatalf("HandleGetPlan tasks: got %+v", got.Tasks)
+	}
+	if len(got.Tasks[1].DependsOn) != 1 || got.Tasks[1].DependsOn[0] != first.ID {
+		t.Errorf("second.DependsOn: got %v", got.Tasks[1].DependsOn)
+	}
+
+	if _, _, err := h.HandleCreateTask(ctx, nil, tools.CreateTaskInput{Content: "orphan", PlanIDs: []string{"missing-plan"}}); err == nil {
+		t.Error("HandleCreateTask with missing plan should fail")
+	}
+	_, _, err = h.HandleUpdateTask(ctx, nil, tools.UpdateTaskInput{ID: first.ID, DependsOn: []string{second.ID}})
+	if !errors.Is(err, models.ErrDependencyCycle) {
+		t.Errorf("HandleUpdateTask closing a cycle: got %v", err)
+	}
+	_, valid, err := h.HandleValidatePlan(ctx, nil, tools.ValidatePlanInput{ID: plan.ID})
+	if err != nil {
+		t.Fatalf("HandleValidatePlan: %v", err)
+	}
+	if !valid.Valid || len(valid.Cycles) != 0 {
+		t.Errorf("HandleValidatePlan: got %+v", valid)
+	}
+}
+
+func TestHandler_NextTasks(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler()
+
+	_, plan, err := h.HandleCreatePlan(ctx, nil, tools.CreatePlanInput{Name: "Release"})
+	if err != nil {
+		t.Fatalf("HandleCreatePlan: %v", err)
+	}
+	_, draft, err := h.HandleCreatePlan(ctx, nil, tools.CreatePlanInput{Name: "Someday", Status: "draft"})
+	if err != nil {
+		t.Fatalf("HandleCreatePlan: %v", err)
+	}
+	create := func(input tools.CreateTaskInput) tools.CreateTaskOutput {
+		t.Helper()
+		_, out, err := h.HandleCreateTask(ctx, nil, input)
+		if err != nil {
+			t.Fatalf("HandleCreateTask: %v", err)
+		}
+		return out
+	}
+	build := create(tools.CreateTaskInput{Content: "build", PlanIDs: []string{plan.ID}})
+	test := create(tools.CreateTaskInput{Content: "test", PlanIDs: []string{plan.ID}, DependsOn: []string{build.ID}})
+	docs := create(tools.CreateTaskInput{Content: "docs", PlanIDs: []string{plan.ID}})
+	create(tools.CreateTaskInput{Content: "idea", PlanIDs: []string{draft.ID}})
+	if _, _, err := h.HandleUpdateTask(ctx, nil, tools.UpdateTaskInput{ID: build.ID, Blocks: []string{docs.ID}}); err != nil {
+		t.Fatalf("HandleUpdateTask: %v", err)
+	}
+
+	next := func(planID string) tools.NextTasksOutput {
+		t.Helper()
+		_, out, err := h.HandleNextTasks(ctx, nil, tools.NextTasksInput{PlanID: planID})
+		if err != nil {
+			t.Fatalf("HandleNextTasks: %v", err)
+		}
+		return out
+	}
+	// Only the active plan is considered, and build holds up the rest
+	out := next("")
+	if out.Count != 1 || out.Tasks[0].ID != build.ID {
+		t.Fatalf("next tasks: got %+v", out.Tasks)
+	}
+	if len(out.Excluded) != 2 || out.Excluded[0].ID != test.ID || out.Excluded[1].ID != docs.ID {
+		t.Fatalf("excluded: got %+v", out.Excluded)
+	}
+	if reason := out.Excluded[1].Reason; reason != "blocked by "+build.ID+" (pending)" {
+		t.Errorf("docs reason: got %q", reason)
+	}
+
+	completed := "completed"
+	if _, _, err := h.HandleUpdateTask(ctx, nil, tools.UpdateTaskInput{ID: build.ID, Status: &completed}); err != nil {
+		t.Fatalf("HandleUpdateTask: %v", err)
+	}
+	out = next(plan.ID)
+	if out.Count != 2 || out.Tasks[0].ID != test.ID || out.Tasks[1].ID != docs.ID || len(out.Excluded) != 0 {
+		t.Errorf("next tasks after build: got %+v, excluded %+v", out.Tasks, out.Excluded)
+	}
+
+	// A cancelled dependency no longer holds a task up
+	notes := create(tools.CreateTaskInput{Content: "notes", PlanIDs: []string{plan.ID}, DependsOn: []string{docs.ID}})
+	cancelled := "cancelled"
+	if _, _, err := h.HandleUpdateTask(ctx, nil, tools.UpdateTaskInput{ID: docs.ID, Status: &cancelled}); err != nil {
+		t.Fatalf("HandleUpdateTask: %v", err)
+	}
+	out = next(plan.ID)
+	if out.Count != 2 || out.Tasks[0].ID != test.ID || out.Tasks[1].ID != notes.ID || len(out.Excluded) != 0 {
+		t.Errorf("next tasks after cancelling docs: got %+v, excluded %+v", out.Tasks, out.Excluded)
+	}
+
+	if _, _, err := h.HandleNextTasks(ctx, nil, tools.NextTasksInput{PlanID: "missing-plan"}); err == nil {
+		t.Error("HandleNextTasks with a missing plan should fail")
+	}
+}
+
+func TestHandler_StatusRules(t *testing.T) {
+	ctx := context.Background()
+	s := memstore.New()
+	tasks := memstore.NewTaskRepository(s)
+	tasks.SetStatusRules(true)
+	h := tools.NewHandler(memstore.NewRepository(s), memstore.NewPlanRepository(s), tasks, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	_, plan, err := h.HandleCreatePlan(ctx, nil, tools.CreatePlanInput{Name: "Release"})
+	if err != nil {
+		t.Fatalf("HandleCreatePlan: %v", err)
+	}
+	_, build, err := h.HandleCreateTask(ctx, nil, tools.CreateTaskInput{Content: "build", PlanIDs: []string{plan.ID}})
+	if err != nil {
+		t.Fatalf("HandleCreateTask: %v", err)
+	}
+	_, deploy, err := h.HandleCreateTask(ctx, nil, tools.CreateTaskInput{Content: "deploy", PlanIDs: []string{plan.ID}})
+	if err != nil {
+		t.Fatalf("HandleCreateTask: %v", err)
+	}
+
+	_, out, err := h.HandleUpdateTask(ctx, nil, tools.UpdateTaskInput{ID: deploy.ID, DependsOn: []string{build.ID}})
+	if err != nil {
+		t.Fatalf("HandleUpdateTask: %v", err)
+	}
+	if out.Status != "blocked" || len(out.StatusChanges) != 1 || out.StatusChanges[0].From != "pending" {
+		t.Errorf("adding a dependency: got %+v", out)
+	}
+
+	completed := "completed"
+	_, out, err = h.HandleUpdateTask(ctx, nil, tools.UpdateTaskInput{ID: build.ID, Status: &completed})
+	if err != nil {
+		t.Fatalf("HandleUpdateTask: %v", err)
+	}
+	if changes := out.StatusChanges; len(changes) != 1 || changes[0].ID != deploy.ID || changes[0].To != "pending" {
+		t.Errorf("completing build: got %+v", changes)
+	}
+}
+
+func TestHandler_PlanProgress(t *testing.T) {
+	ctx := context.Background()
+	s := memstore.New()
+	tasks := memstore.NewTaskRepository(s)
+	tasks.SetPlanCompletion(true)
+	h := tools.NewHandler(memstore.NewRepository(s), memstore.NewPlanRepository(s), tasks, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	_, plan, err := h.HandleCreatePlan(ctx, nil, tools.CreatePlanInput{Name: "Release"})
+	if err != nil {
+		t.Fatalf("HandleCreatePlan: %v", err)
+	}
+	_, build, err := h.HandleCreateTask(ctx, nil, tools.CreateTaskInput{Content: "build", PlanIDs: []string{plan.ID}})
+	if err != nil {
+		t.Fatalf("HandleCreateTask: %v", err)
+	}
+	_, deploy, err := h.HandleCreateTask(ctx, nil, tools.CreateTaskInput{Content: "deploy", PlanIDs: []string{plan.ID}})
+	if err != nil {
+		t.Fatalf("HandleCreateTask: %v", err)
+	}
+
+	completed := "completed"
+	_, out, err := h.HandleUpdateTask(ctx, nil, tools.UpdateTaskInput{ID: build.ID, Status: &completed})
+	if err != nil {
+		t.Fatalf("HandleUpdateTask: %v", err)
+	}
+	if len(out.PlanStatusChanges) != 0 {
+		t.Errorf("completing build: got plan changes %+v", out.PlanStatusChanges)
+	}
+
+	_, got, err := h.HandleGetPlan(ctx, nil, tools.GetPlanInput{ID: plan.ID})
+	if err != nil {
+		t.Fatalf("HandleGetPlan: %v", err)
+	}
+	if p := got.Progress; p.Total != 2 || p.ByStatus["completed"] != 1 || p.ByStatus["pending"] != 1 || p.PercentComplete != 50 {
+		t.Errorf("get_plan progress: got %+v", p)
+	}
+	_, list, err := h.HandleListPlans(ctx, nil, tools.ListPlansInput{})
+	if err != nil {
+		t.Fatalf("HandleListPlans: %v", err)
+	}
+	if len(list.Plans) != 1 || !reflect.DeepEqual(list.Plans[0].Progress, got.Progress) {
+		t.Errorf("list_plans progress: got %+v, want %+v", list.Plans, got.Progress)
+	}
+
+	_, out, err = h.HandleUpdateTask(ctx, nil, tools.UpdateTaskInput{ID: deploy.ID, Status: &completed})
+	if err != nil {
+		t.Fatalf("HandleUpdateTask: %v", err)
+	}
+	if changes := out.PlanStatusChanges; len(changes) != 1 || changes[0].ID != plan.ID || changes[0].From != "active" || changes[0].To != "completed" {
+		t.Errorf("completing deploy: got %+v", changes)
+	}
+
+	pending := "pending"
+	_, out, err = h.HandleUpdateTask(ctx, nil, tools.UpdateTaskInput{ID: build.ID, Status: &pending})
+	if err != nil {
+		t.Fatalf("HandleUpdateTask: %v", err)
+	}
+	if changes := out.PlanStatusChanges; len(changes) != 1 || changes[0].To != "active" {
+		t.Errorf("reopening build: got %+v", changes)
+	}
+}
+
+func TestHandler_SchedulePlan(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler()
+
+	_, plan, err := h.HandleCreatePlan(ctx, nil, tools.CreatePlanInput{Name: "Release"})
+	if err != nil {
+		t.Fatalf("HandleCreatePlan: %v", err)
+	}
+	_, deploy, err := h.HandleCreateTask(ctx, nil, tools.CreateTaskInput{Content: "deploy", PlanIDs: []string{plan.ID}})
+	if err != nil {
+		t.Fatalf("HandleCreateTask: %v", err)
+	}
+	_, build, err := h.HandleCreateTask(ctx, nil, tools.CreateTaskInput{Content: "build", PlanIDs: []string{plan.ID}, Blocks: []string{deploy.ID}, Metadata: map[string]any{"estimate": 3}})
+	if err != nil {
+		t.Fatalf("HandleCreateTask: %v", err)
+	}
+
+	schedule := func(normalize bool) tools.SchedulePlanOutput {
+		t.Helper()
+		_, out, err := h.HandleSchedulePlan(ctx, nil, tools.SchedulePlanInput{ID: plan.ID, Normalize: normalize})
+		if err != nil {
+			t.Fatalf("HandleSchedulePlan: %v", err)
+		}
+		return out
+	}
+	out := schedule(false)
+	if out.PositionsAgree || len(out.Tasks) != 2 || out.Tasks[0].ID != build.ID || out.Duration != 4 {
+		t.Fatalf("HandleSchedulePlan: got %+v", out)
+	}
+
+	if out = schedule(true); !out.Normalized || out.Tasks[0].Position >= out.Tasks[1].Position {
+		t.Fatalf("HandleSchedulePlan normalize: got %+v", out)
+	}
+	if out = schedule(false); !out.PositionsAgree {
+		t.Errorf("positions should agree after normalizing: got %+v", out.Tasks)
+	}
+
+	if _, _, err := h.HandleSchedulePlan(ctx, nil, tools.SchedulePlanInput{ID: "missing-plan"}); err == nil {
+		t.Error("HandleSchedulePlan with a missing plan should fail")
+	}
+}
+
+func TestHandler_SearchAll(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandler()
+
+	_, plan, err := h.HandleCreatePlan(ctx, nil, tools.CreatePlanInput{Name: "Auth overhaul"})
+	if err != nil {
+		t.Fatalf("HandleCreatePlan: %v", err)
+	}
+	_, task, err := h.HandleCreateTask(ctx, nil, tools.CreateTaskInput{Content: "Handle token refresh", PlanIDs: []string{plan.ID}})
+	if err != nil {
+		t.Fatalf("HandleCreateTask: %v", err)
+	}
+	if _, _, err := h.HandleAdd(ctx, nil, tools.AddInput{Content: "Token refresh runs every hour"}); err != nil {
+		t.Fatalf("HandleAdd: %v", err)
+	}
+
+	_, found, err := h.HandleSearchAll(ctx, nil, tools.SearchAllInput{Query: "token refresh", Kinds: []string{"task"}})
+	if err != nil {
+		t.Fatalf("HandleSearchAll: %v", err)
+	}
+	if found.Count != 1 || found.Results[0].ID != task.ID || found.Results[0].Kind != "Task" || found.Results[0].Status != "pending" {
+		t.Errorf("HandleSearchAll tasks: got %+v", found)
+	}
+
+	if _, _, err := h.HandleSearchAll(ctx, nil, tools.SearchAllInput{Query: "token", Kinds: []string{"project"}}); err == nil {
+		t.Error("HandleSearchAll with an unknown kind should fail")
+	}
+	if _, _, err := h.HandleSearchAll(ctx, nil, tools.SearchAllInput{}); err == nil {
+		t.Error("HandleSearchAll without a query should fail")
+	}
+}
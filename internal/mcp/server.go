@@ -5,8 +5,8 @@ import (
 	"log/slog"
 	"net/http"
 
-	"github.com/Thomas-Fitz/associate/internal/graph"
 	"github.com/Thomas-Fitz/associate/internal/mcp/tools"
+	"github.com/Thomas-Fitz/associate/internal/store"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -18,15 +18,15 @@ const (
 // Server wraps the MCP server with Associate-specific configuration
 type Server struct {
 	mcpServer *mcp.Server
-	repo      *graph.Repository
-	planRepo  *graph.PlanRepository
-	taskRepo  *graph.TaskRepository
+	repo      store.MemoryStore
+	planRepo  store.PlanStore
+	taskRepo  store.TaskStore
 	logger    *slog.Logger
 	handler   *tools.Handler
 }
 
-// NewServer creates a new Associate MCP server
-func NewServer(repo *graph.Repository, planRepo *graph.PlanRepository, taskRepo *graph.TaskRepository, logger *slog.Logger) *Server {
+// NewServer creates a new Associate MCP server backed by the given stores
+func NewServer(repo store.MemoryStore, planRepo store.PlanStore, taskRepo store.TaskStore, logger *slog.Logger) *Server {
 	if logger == nil {
 		logger = slog.Default()
 	}
@@ -56,11 +56,20 @@ func NewServer(repo *graph.Repository, planRepo *graph.PlanRepository, taskRepo
 func (s *Server) registerTools() {
 	// Memory tools
 	mcp.AddTool(s.mcpServer, tools.SearchTool(), s.handler.HandleSearch)
+	mcp.AddTool(s.mcpServer, tools.SearchAllTool(), s.handler.HandleSearchAll)
 	mcp.AddTool(s.mcpServer, tools.GetTool(), s.handler.HandleGet)
 	mcp.AddTool(s.mcpServer, tools.AddTool(), s.handler.HandleAdd)
 	mcp.AddTool(s.mcpServer, tools.UpdateTool(), s.handler.HandleUpdate)
 	mcp.AddTool(s.mcpServer, tools.DeleteTool(), s.handler.HandleDelete)
 	mcp.AddTool(s.mcpServer, tools.GetRelatedTool(), s.handler.HandleGetRelated)
+	mcp.AddTool(s.mcpServer, tools.ListRelationshipsTool(), s.handler.HandleListRelationships)
+	mcp.AddTool(s.mcpServer, tools.DeleteRelationshipTool(), s.handler.HandleDeleteRelationship)
+	mcp.AddTool(s.mcpServer, tools.ReplaceRelationshipsTool(), s.handler.HandleReplaceRelationships)
+	mcp.AddTool(s.mcpServer, tools.ListRevisionsTool(), s.handler.HandleListRevisions)
+	mcp.AddTool(s.mcpServer, tools.DiffRevisionsTool(), s.handler.HandleDiffRevisions)
+	mcp.AddTool(s.mcpServer, tools.RestoreRevisionTool(), s.handler.HandleRestoreRevision)
+	mcp.AddTool(s.mcpServer, tools.ListTrashTool(), s.handler.HandleListTrash)
+	mcp.AddTool(s.mcpServer, tools.RestoreTool(), s.handler.HandleRestore)
 
 	// Plan tools
 	mcp.AddTool(s.mcpServer, tools.CreatePlanTool(), s.handler.HandleCreatePlan)
@@ -68,6 +77,8 @@ func (s *Server) registerTools() {
 	mcp.AddTool(s.mcpServer, tools.UpdatePlanTool(), s.handler.HandleUpdatePlan)
 	mcp.AddTool(s.mcpServer, tools.DeletePlanTool(), s.handler.HandleDeletePlan)
 	mcp.AddTool(s.mcpServer, tools.ListPlansTool(), s.handler.HandleListPlans)
+	mcp.AddTool(s.mcpServer, tools.ValidatePlanTool(), s.handler.HandleValidatePlan)
+	mcp.AddTool(s.mcpServer, tools.SchedulePlanTool(), s.handler.HandleSchedulePlan)
 
 	// Task tools
 	mcp.AddTool(s.mcpServer, tools.CreateTaskTool(), s.handler.HandleCreateTask)
@@ -76,6 +87,7 @@ func (s *Server) registerTools() {
 	mcp.AddTool(s.mcpServer, tools.DeleteTaskTool(), s.handler.HandleDeleteTask)
 	mcp.AddTool(s.mcpServer, tools.ListTasksTool(), s.handler.HandleListTasks)
 	mcp.AddTool(s.mcpServer, tools.ReorderTasksTool(), s.handler.HandleReorderTasks)
+	mcp.AddTool(s.mcpServer, tools.NextTasksTool(), s.handler.HandleNextTasks)
 }
 
 // HTTPHandler returns an http.Handler for the MCP server
@@ -1,42 +1,12 @@
 package mcp
 
 import (
-	"context"
 	"testing"
 	"time"
 
 	"github.com/Thomas-Fitz/associate/internal/mcp/tools"
-	"github.com/Thomas-Fitz/associate/internal/models"
 )
 
-// MockRepository implements a mock for testing
-type MockRepository struct {
-	SearchFunc func(ctx context.Context, query string, limit int) ([]models.SearchResult, error)
-	AddFunc    func(ctx context.Context, mem models.Memory, rels []models.Relationship) (*models.Memory, error)
-	UpdateFunc func(ctx context.Context, id string, content *string, metadata map[string]string, tags []string, rels []models.Relationship) (*models.Memory, error)
-}
-
-func (m *MockRepository) Search(ctx context.Context, query string, limit int) ([]models.SearchResult, error) {
-	if m.SearchFunc != nil {
-		return m.SearchFunc(ctx, query, limit)
-	}
-	return nil, nil
-}
-
-func (m *MockRepository) Add(ctx context.Context, mem models.Memory, rels []models.Relationship) (*models.Memory, error) {
-	if m.AddFunc != nil {
-		return m.AddFunc(ctx, mem, rels)
-	}
-	return &mem, nil
-}
-
-func (m *MockRepository) Update(ctx context.Context, id string, content *string, metadata map[string]string, tags []string, rels []models.Relationship) (*models.Memory, error) {
-	if m.UpdateFunc != nil {
-		return m.UpdateFunc(ctx, id, content, metadata, tags, rels)
-	}
-	return &models.Memory{ID: id}, nil
-}
-
 func TestSearchInput_Validation(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -11,27 +11,32 @@ import (
 // AddInput defines the input for the add tool.
 // Metadata accepts any JSON values; non-string values are serialized to JSON strings.
 type AddInput struct {
-	Content    string         `json:"content" jsonschema:"The content of the memory to store"`
-	Type       string         `json:"type,omitempty" jsonschema:"Type of memory: Note, Repository, or Memory (default). For tasks use create_task, for plans use create_plan."`
-	Metadata   map[string]any `json:"metadata,omitempty" jsonschema:"Key-value metadata to attach to the memory. Values can be strings or will be JSON-serialized."`
-	Tags       []string       `json:"tags,omitempty" jsonschema:"Tags for categorizing the memory"`
-	RelatedTo  []string       `json:"related_to,omitempty" jsonschema:"IDs of existing nodes to connect to using RELATES_TO"`
-	PartOf     []string       `json:"part_of,omitempty" jsonschema:"IDs of existing nodes this is part of using PART_OF"`
-	References []string       `json:"references,omitempty" jsonschema:"IDs of existing nodes this references using REFERENCES"`
-	DependsOn  []string       `json:"depends_on,omitempty" jsonschema:"IDs of existing nodes this depends on using DEPENDS_ON"`
-	Blocks     []string       `json:"blocks,omitempty" jsonschema:"IDs of existing nodes this blocks using BLOCKS"`
-	Follows    []string       `json:"follows,omitempty" jsonschema:"IDs of existing nodes this follows in sequence using FOLLOWS"`
-	Implements []string       `json:"implements,omitempty" jsonschema:"IDs of existing nodes this implements using IMPLEMENTS"`
+	Content       string              `json:"content" jsonschema:"The content of the memory to store"`
+	Type          string              `json:"type,omitempty" jsonschema:"Type of memory: Note, Repository, or Memory (default). For tasks use create_task, for plans use create_plan."`
+	Metadata      map[string]any      `json:"metadata,omitempty" jsonschema:"Key-value metadata to attach to the memory. Values can be strings or will be JSON-serialized."`
+	Tags          []string            `json:"tags,omitempty" jsonschema:"Tags for categorizing the memory"`
+	RelatedTo     []string            `json:"related_to,omitempty" jsonschema:"IDs of existing nodes to connect to using RELATES_TO"`
+	PartOf        []string            `json:"part_of,omitempty" jsonschema:"IDs of existing nodes this is part of using PART_OF"`
+	References    []string            `json:"references,omitempty" jsonschema:"IDs of existing nodes this references using REFERENCES"`
+	DependsOn     []string            `json:"depends_on,omitempty" jsonschema:"IDs of existing nodes this depends on using DEPENDS_ON"`
+	Blocks        []string            `json:"blocks,omitempty" jsonschema:"IDs of existing nodes this blocks using BLOCKS"`
+	Follows       []string            `json:"follows,omitempty" jsonschema:"IDs of existing nodes this follows in sequence using FOLLOWS"`
+	Implements    []string            `json:"implements,omitempty" jsonschema:"IDs of existing nodes this implements using IMPLEMENTS"`
+	Relationships []RelationshipInput `json:"relationships,omitempty" jsonschema:"Relationships to create, each with to_id and type and an optional reason (why it exists), weight (0-1 confidence) and metadata. Giving an existing relationship updates those details."`
+	BestEffort    bool                `json:"best_effort,omitempty" jsonschema:"If true, relationships that cannot be created (unknown type or target, or one that would close a DEPENDS_ON/BLOCKS cycle) are skipped and reported in relationship_results instead of failing the whole call"`
+	Actor         string              `json:"actor,omitempty" jsonschema:"Who is making the change, recorded in the revision history (default: the MCP client's name)"`
 }
 
 // AddOutput defines the output for the add tool.
 type AddOutput struct {
-	ID        string            `json:"id"`
-	Type      string            `json:"type"`
-	Content   string            `json:"content"`
-	Metadata  map[string]string `json:"metadata,omitempty"`
-	Tags      []string          `json:"tags,omitempty"`
-	CreatedAt string            `json:"created_at"`
+	ID                  string               `json:"id"`
+	Type                string               `json:"type"`
+	Content             string               `json:"content"`
+	Metadata            map[string]string    `json:"metadata,omitempty"`
+	Tags                []string             `json:"tags,omitempty"`
+	CreatedAt           string               `json:"created_at"`
+	Version             int64                `json:"version"`
+	RelationshipResults []RelationshipResult `json:"relationship_results,omitempty"`
 }
 
 // AddTool returns the tool definition for add_memory.
@@ -61,8 +66,25 @@ func (h *Handler) HandleAdd(ctx context.Context, req *mcp.CallToolRequest, input
 		input.RelatedTo, input.PartOf, input.References,
 		input.DependsOn, input.Blocks, input.Follows, input.Implements,
 	)
+	detailed, err := parseRelationships(input.Relationships)
+	if err != nil {
+		return nil, AddOutput{}, err
+	}
+	rels = append(rels, detailed...)
+	var results []RelationshipResult
+	if input.BestEffort {
+		rels, results, err = h.bestEffortRelationships(ctx, "", rels)
+		if err != nil {
+			return nil, AddOutput{}, err
+		}
+	}
 
-	created, err := h.Repo.Add(ctx, mem, rels)
+	ctx = withActor(ctx, req, input.Actor)
+	var created *models.Memory
+	rels, err = skipCycles(input.BestEffort, rels, results, func(rels []models.Relationship) (err error) {
+		created, err = h.Repo.Add(ctx, mem, rels)
+		return err
+	})
 	if err != nil {
 		h.Logger.Error("add_memory failed", "type", input.Type, "error", err)
 		return nil, AddOutput{}, fmt.Errorf("failed to add memory: %w", err)
@@ -70,11 +92,13 @@ func (h *Handler) HandleAdd(ctx context.Context, req *mcp.CallToolRequest, input
 
 	h.Logger.Info("add_memory complete", "id", created.ID, "type", created.Type, "relationships", len(rels))
 	return nil, AddOutput{
-		ID:        created.ID,
-		Type:      string(created.Type),
-		Content:   created.Content,
-		Metadata:  created.Metadata,
-		Tags:      created.Tags,
-		CreatedAt: created.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		ID:                  created.ID,
+		Type:                string(created.Type),
+		Content:             created.Content,
+		Metadata:            created.Metadata,
+		Tags:                created.Tags,
+		CreatedAt:           created.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		Version:             created.Version,
+		RelationshipResults: results,
 	}, nil
 }
@@ -3,23 +3,27 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
+	"time"
 
-	"github.com/Thomas-Fitz/associate/internal/graph"
 	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/store"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // Handler provides the dependencies needed by tool handlers.
 type Handler struct {
-	Repo     *graph.Repository
-	PlanRepo *graph.PlanRepository
-	TaskRepo *graph.TaskRepository
+	Repo     store.MemoryStore
+	PlanRepo store.PlanStore
+	TaskRepo store.TaskStore
 	Logger   *slog.Logger
 }
 
 // NewHandler creates a new Handler with the given dependencies.
-func NewHandler(repo *graph.Repository, planRepo *graph.PlanRepository, taskRepo *graph.TaskRepository, logger *slog.Logger) *Handler {
+func NewHandler(repo store.MemoryStore, planRepo store.PlanStore, taskRepo store.TaskStore, logger *slog.Logger) *Handler {
 	return &Handler{
 		Repo:     repo,
 		PlanRepo: planRepo,
@@ -34,6 +38,7 @@ type RelatedMemory struct {
 	Type         string `json:"type"`
 	RelationType string `json:"relationship_type"`
 	Direction    string `json:"direction"` // "incoming" or "outgoing"
+	RelationshipDetails
 }
 
 // RelatedMemoryFull contains full info about a related memory.
@@ -44,10 +49,174 @@ type RelatedMemoryFull struct {
 	Metadata     map[string]string `json:"metadata,omitempty"`
 	Tags         []string          `json:"tags,omitempty"`
 	RelationType string            `json:"relationship_type"`
-	Direction    string            `json:"direction"`
+	Direction    string            `json:"direction"` // Of the last edge: "outgoing" if it points to this node
 	Depth        int               `json:"depth"`
+	Path         []string          `json:"path"`  // Node IDs from the queried node to this one
+	Edges        []PathEdge        `json:"edges"` // Relationships along path, in order
 	CreatedAt    string            `json:"created_at"`
 	UpdatedAt    string            `json:"updated_at"`
+	Version      int64             `json:"version"`
+}
+
+// PathEdge is a relationship on the path to a related node.
+type PathEdge struct {
+	FromID string `json:"from_id"`
+	ToID   string `json:"to_id"`
+	Type   string `json:"type"`
+	RelationshipDetails
+}
+
+// EdgeItem is a stored relationship between two nodes.
+type EdgeItem struct {
+	ID     string `json:"id"`
+	FromID string `json:"from_id"`
+	ToID   string `json:"to_id"`
+	Type   string `json:"type"`
+	RelationshipDetails
+	Position *float64 `json:"position,omitempty"`
+}
+
+// RelationshipDetails are the optional properties of a relationship.
+type RelationshipDetails struct {
+	Reason    string            `json:"reason,omitempty"`
+	Weight    *float64          `json:"weight,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt string            `json:"created_at,omitempty"`
+}
+
+// RelationshipInput is an outgoing relationship to create, with optional details.
+type RelationshipInput struct {
+	ToID     string            `json:"to_id" jsonschema:"required,The ID of the node the relationship points to"`
+	Type     string            `json:"type" jsonschema:"required,The relationship type (RELATES_TO, PART_OF, REFERENCES, DEPENDS_ON, BLOCKS, FOLLOWS, IMPLEMENTS)"`
+	Reason   string            `json:"reason,omitempty" jsonschema:"Why the relationship exists"`
+	Weight   *float64          `json:"weight,omitempty" jsonschema:"Strength or confidence of the relationship, typically from 0 to 1"`
+	Metadata map[string]string `json:"metadata,omitempty" jsonschema:"Key-value metadata to attach to the relationship"`
+}
+
+// toRelationshipDetails converts relationship properties for output.
+func toRelationshipDetails(p models.RelationshipProperties) RelationshipDetails {
+	d := RelationshipDetails{Reason: p.Reason, Weight: p.Weight, Metadata: p.Metadata}
+	if !p.CreatedAt.IsZero() {
+		d.CreatedAt = p.CreatedAt.Format("2006-01-02T15:04:05Z")
+	}
+	return d
+}
+
+// toEdgeItems converts edges for output, as an empty slice rather than nil so
+// that JSON serializes them as [] not null.
+func toEdgeItems(edges []models.Edge) []EdgeItem {
+	items := make([]EdgeItem, len(edges))
+	for i, e := range edges {
+		items[i] = EdgeItem{
+			ID:                  e.ID,
+			FromID:              e.FromID,
+			ToID:                e.ToID,
+			Type:                string(e.Type),
+			RelationshipDetails: toRelationshipDetails(e.RelationshipProperties),
+			Position:            e.Position,
+		}
+	}
+	return items
+}
+
+// withActor returns ctx attributing the changes made with it to actor or, if
+// it is empty, to the name of the MCP client making the request.
+func withActor(ctx context.Context, req *mcp.CallToolRequest, actor string) context.Context {
+	if actor == "" && req != nil && req.Session != nil {
+		if params := req.Session.InitializeParams(); params != nil && params.ClientInfo != nil {
+			actor = params.ClientInfo.Name
+		}
+	}
+	return store.WithActor(ctx, actor)
+}
+
+// RelationshipResult reports whether a relationship given in best-effort mode
+// was created.
+type RelationshipResult struct {
+	ToID  string `json:"to_id"`
+	Type  string `json:"type"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// bestEffortRelationships drops the relationships from fromID that cannot be
+// created, because their type is unknown or their target does not exist, and
+// reports the outcome of each. fromID is empty for a node not yet created.
+func (h *Handler) bestEffortRelationships(ctx context.Context, fromID string, rels []models.Relationship) ([]models.Relationship, []RelationshipResult, error) {
+	ids := make([]string, len(rels))
+	for i, rel := range rels {
+		ids[i] = rel.ToID
+	}
+	existing, err := h.Repo.ExistingNodes(ctx, ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check relationship targets: %w", err)
+	}
+
+	var kept []models.Relationship
+	results := make([]RelationshipResult, len(rels))
+	for i, rel := range rels {
+		results[i] = RelationshipResult{ToID: rel.ToID, Type: string(rel.Type)}
+		if err := models.ValidateRelationType(rel.Type); err != nil {
+			results[i].Error = err.Error()
+		} else if !existing[rel.ToID] && rel.ToID != fromID {
+			results[i].Error = fmt.Sprintf("%v: %s", models.ErrNodeNotFound, rel.ToID)
+		} else {
+			results[i].OK = true
+			kept = append(kept, rel)
+		}
+	}
+	return kept, results, nil
+}
+
+// skipCycles calls apply with rels. In best-effort mode, while apply fails
+// because one of them would close a cycle of DEPENDS_ON and BLOCKS
+// relationships, it reports that relationship in results and calls apply
+// again without it; the stores check cycles in the transaction that writes
+// the relationships, so the failed call changed nothing. It returns the
+// relationships apply last got.
+func skipCycles(bestEffort bool, rels []models.Relationship, results []RelationshipResult, apply func(rels []models.Relationship) error) ([]models.Relationship, error) {
+	for {
+		err := apply(rels)
+		var relErr *models.RelationshipError
+		if !bestEffort || !errors.Is(err, models.ErrDependencyCycle) || !errors.As(err, &relErr) {
+			return rels, err
+		}
+		i := slices.IndexFunc(rels, func(rel models.Relationship) bool {
+			return rel.ToID == relErr.ToID && rel.Type == relErr.Type
+		})
+		if i < 0 {
+			return rels, err
+		}
+		rels = slices.Delete(slices.Clone(rels), i, i+1)
+		for j := range results {
+			if results[j].OK && results[j].ToID == relErr.ToID && results[j].Type == string(relErr.Type) {
+				results[j].OK = false
+				results[j].Error = relErr.Err.Error()
+				break
+			}
+		}
+	}
+}
+
+// parseRelationships converts relationship inputs, which must each have a
+// to_id and a type.
+func parseRelationships(inputs []RelationshipInput) ([]models.Relationship, error) {
+	rels := make([]models.Relationship, 0, len(inputs))
+	for _, r := range inputs {
+		if r.ToID == "" || r.Type == "" {
+			return nil, fmt.Errorf("each relationship needs a to_id and a type")
+		}
+		rels = append(rels, models.Relationship{
+			ToID: r.ToID,
+			Type: models.RelationType(r.Type),
+			RelationshipProperties: models.RelationshipProperties{
+				Reason:   r.Reason,
+				Weight:   r.Weight,
+				Metadata: r.Metadata,
+			},
+		})
+	}
+	return rels, nil
 }
 
 // ConvertMetadata converts a map[string]any to map[string]string.
@@ -79,6 +248,22 @@ func convertMetadata(m map[string]any) map[string]string {
 	return result
 }
 
+// parseTimestamp parses an RFC 3339 timestamp or a YYYY-MM-DD date, taken
+// as midnight UTC. An empty string yields the zero time.
+func parseTimestamp(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time or YYYY-MM-DD date", s)
+	}
+	return t, nil
+}
+
 // buildRelationships builds a slice of relationships from the input slices.
 // Nil slices are safely handled - range over nil iterates zero times.
 func buildRelationships(
@@ -111,6 +296,5 @@ func buildRelationships(
 
 // Unused import placeholders to satisfy compiler during incremental development.
 var (
-	_ context.Context
 	_ *mcp.CallToolRequest
 )
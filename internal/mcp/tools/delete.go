@@ -9,26 +9,41 @@ import (
 
 // DeleteInput defines the input for the delete tool.
 type DeleteInput struct {
-ID string `json:"id" jsonschema:"The ID of the memory to delete"`
+ID     string `json:"id" jsonschema:"The ID of the memory to delete"`
+DryRun bool   `json:"dry_run,omitempty" jsonschema:"If true, nothing is deleted and would_delete lists the memory and relationships that would be moved to the trash"`
 }
 
 // DeleteOutput defines the output for the delete tool.
 type DeleteOutput struct {
-ID      string `json:"id"`
-Deleted bool   `json:"deleted"`
+ID          string         `json:"id"`
+Deleted     bool           `json:"deleted"`
+DryRun      bool           `json:"dry_run,omitempty"`
+WouldDelete *DeletePreview `json:"would_delete,omitempty"`
 }
 
 // DeleteTool returns the tool definition for delete_memory.
 func DeleteTool() *mcp.Tool {
 return &mcp.Tool{
 Name:        "delete_memory",
-Description: "Permanently delete a memory and its relationships. Cannot be undone. Returns the deleted ID and confirmation boolean.",
+Description: "Delete a memory and its relationships by moving them to the trash, where list_trash shows them until they are purged after the retention period. Use restore to bring them back. Set dry_run to see what would be removed without deleting anything. Returns the deleted ID and confirmation boolean.",
 }
 }
 
 // HandleDelete handles the delete_memory tool call.
 func (h *Handler) HandleDelete(ctx context.Context, req *mcp.CallToolRequest, input DeleteInput) (*mcp.CallToolResult, DeleteOutput, error) {
-h.Logger.Info("delete_memory", "id", input.ID)
+h.Logger.Info("delete_memory", "id", input.ID, "dry_run", input.DryRun)
+
+if input.DryRun {
+entry, err := h.Repo.PreviewDelete(ctx, input.ID)
+if err != nil {
+h.Logger.Error("delete_memory preview failed", "id", input.ID, "error", err)
+return nil, DeleteOutput{}, fmt.Errorf("failed to preview delete: %w", err)
+}
+if entry == nil {
+return nil, DeleteOutput{}, fmt.Errorf("memory not found: %s", input.ID)
+}
+return nil, DeleteOutput{ID: input.ID, DryRun: true, WouldDelete: toDeletePreview(*entry)}, nil
+}
 
 err := h.Repo.Delete(ctx, input.ID)
 if err != nil {
@@ -22,13 +22,14 @@ Tags      []string          `json:"tags,omitempty"`
 Related   []RelatedMemory   `json:"related,omitempty"`
 CreatedAt string            `json:"created_at"`
 UpdatedAt string            `json:"updated_at"`
+Version   int64             `json:"version"`
 }
 
 // GetTool returns the tool definition for get_memory.
 func GetTool() *mcp.Tool {
 return &mcp.Tool{
 Name:        "get_memory",
-Description: "Retrieve a single memory by id. Returns full details: type (Note, Task, Project, Repository, Memory), content (string), metadata (json), tags (array), and related (array) memories including relationship types and directions.",
+Description: "Retrieve a single memory by id. Returns full details: type (Note, Task, Project, Repository, Memory), content (string), metadata (json), tags (array), and related (array) memories including relationship types and directions, and each relationship's created_at, reason, weight and metadata when set.",
 }
 }
 
@@ -54,6 +55,7 @@ Metadata:  mem.Metadata,
 Tags:      mem.Tags,
 CreatedAt: mem.CreatedAt.Format("2006-01-02T15:04:05Z"),
 UpdatedAt: mem.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+Version:   mem.Version,
 }
 
 for _, r := range related {
@@ -62,6 +64,7 @@ ID:           r.ID,
 Type:         string(r.Type),
 RelationType: r.RelationType,
 Direction:    r.Direction,
+RelationshipDetails: toRelationshipDetails(r.RelationshipProperties),
 })
 }
 
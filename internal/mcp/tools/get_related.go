@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/Thomas-Fitz/associate/internal/models"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -26,7 +27,7 @@ type GetRelatedOutput struct {
 func GetRelatedTool() *mcp.Tool {
 	return &mcp.Tool{
 		Name:        "get_related",
-		Description: "Retrieve nodes related to any node (memory, plan, or task) by ID. Traverses relationships across all node types. Filter by relationship_type, direction (incoming/outgoing/both), and depth (1-5).",
+		Description: "Retrieve nodes related to any node (memory, plan, or task) by ID. Traverses relationships across all node types. Filter by relationship_type, direction (incoming/outgoing/both), and depth (1-5). Each node is reached by a shortest path and returned with: depth, path (node IDs from id to the node), edges (from_id, to_id, type, and created_at, reason, weight and metadata when set, of each relationship on the path, as stored), and relationship_type and direction (outgoing if the last edge points to the node, else incoming) of the last edge.",
 	}
 }
 
@@ -39,8 +40,8 @@ func (h *Handler) HandleGetRelated(ctx context.Context, req *mcp.CallToolRequest
 	if depth <= 0 {
 		depth = 1
 	}
-	if depth > 5 {
-		depth = 5
+	if depth > models.MaxRelatedDepth {
+		depth = models.MaxRelatedDepth
 	}
 
 	direction := input.Direction
@@ -61,6 +62,15 @@ func (h *Handler) HandleGetRelated(ctx context.Context, req *mcp.CallToolRequest
 	}
 
 	for i, r := range related {
+		edges := make([]PathEdge, len(r.Edges))
+		for j, e := range r.Edges {
+			edges[j] = PathEdge{
+				FromID:              e.FromID,
+				ToID:                e.ToID,
+				Type:                string(e.Type),
+				RelationshipDetails: toRelationshipDetails(e.RelationshipProperties),
+			}
+		}
 		output.Related[i] = RelatedMemoryFull{
 			ID:           r.Memory.ID,
 			Type:         string(r.Memory.Type),
@@ -70,8 +80,11 @@ func (h *Handler) HandleGetRelated(ctx context.Context, req *mcp.CallToolRequest
 			RelationType: r.RelationType,
 			Direction:    r.Direction,
 			Depth:        r.Depth,
+			Path:         r.Path,
+			Edges:        edges,
 			CreatedAt:    r.Memory.CreatedAt.Format("2006-01-02T15:04:05Z"),
 			UpdatedAt:    r.Memory.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+			Version:      r.Memory.Version,
 		}
 	}
 
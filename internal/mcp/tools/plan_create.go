@@ -10,24 +10,29 @@ import (
 
 // CreatePlanInput defines the input for the create_plan tool.
 type CreatePlanInput struct {
-	Name        string         `json:"name" jsonschema:"required,The name/title of the plan"`
-	Description string         `json:"description,omitempty" jsonschema:"A detailed description of the plan"`
-	Status      string         `json:"status,omitempty" jsonschema:"Plan status: draft, active, completed, archived (default: active)"`
-	Metadata    map[string]any `json:"metadata,omitempty" jsonschema:"Key-value metadata to attach to the plan"`
-	Tags        []string       `json:"tags,omitempty" jsonschema:"Tags for categorizing the plan"`
-	RelatedTo   []string       `json:"related_to,omitempty" jsonschema:"IDs of existing nodes to connect using RELATES_TO"`
-	References  []string       `json:"references,omitempty" jsonschema:"IDs of existing nodes this references using REFERENCES"`
+	Name          string              `json:"name" jsonschema:"required,The name/title of the plan"`
+	Description   string              `json:"description,omitempty" jsonschema:"A detailed description of the plan"`
+	Status        string              `json:"status,omitempty" jsonschema:"Plan status: draft, active, completed, archived (default: active)"`
+	Metadata      map[string]any      `json:"metadata,omitempty" jsonschema:"Key-value metadata to attach to the plan"`
+	Tags          []string            `json:"tags,omitempty" jsonschema:"Tags for categorizing the plan"`
+	RelatedTo     []string            `json:"related_to,omitempty" jsonschema:"IDs of existing nodes to connect using RELATES_TO"`
+	References    []string            `json:"references,omitempty" jsonschema:"IDs of existing nodes this references using REFERENCES"`
+	Relationships []RelationshipInput `json:"relationships,omitempty" jsonschema:"Relationships to create, each with to_id and type and an optional reason (why it exists), weight (0-1 confidence) and metadata. Giving an existing relationship updates those details."`
+	BestEffort    bool                `json:"best_effort,omitempty" jsonschema:"If true, relationships that cannot be created (unknown type or target, or one that would close a DEPENDS_ON/BLOCKS cycle) are skipped and reported in relationship_results instead of failing the whole call"`
+	Actor         string              `json:"actor,omitempty" jsonschema:"Who is making the change, recorded in the revision history (default: the MCP client's name)"`
 }
 
 // CreatePlanOutput defines the output for the create_plan tool.
 type CreatePlanOutput struct {
-	ID          string            `json:"id"`
-	Name        string            `json:"name"`
-	Description string            `json:"description,omitempty"`
-	Status      string            `json:"status"`
-	Metadata    map[string]string `json:"metadata,omitempty"`
-	Tags        []string          `json:"tags,omitempty"`
-	CreatedAt   string            `json:"created_at"`
+	ID                  string               `json:"id"`
+	Name                string               `json:"name"`
+	Description         string               `json:"description,omitempty"`
+	Status              string               `json:"status"`
+	Metadata            map[string]string    `json:"metadata,omitempty"`
+	Tags                []string             `json:"tags,omitempty"`
+	CreatedAt           string               `json:"created_at"`
+	Version             int64                `json:"version"`
+	RelationshipResults []RelationshipResult `json:"relationship_results,omitempty"`
 }
 
 // CreatePlanTool returns the tool definition for create_plan.
@@ -67,8 +72,25 @@ func (h *Handler) HandleCreatePlan(ctx context.Context, req *mcp.CallToolRequest
 		input.RelatedTo, nil, input.References,
 		nil, nil, nil, nil,
 	)
+	detailed, err := parseRelationships(input.Relationships)
+	if err != nil {
+		return nil, CreatePlanOutput{}, err
+	}
+	rels = append(rels, detailed...)
+	var results []RelationshipResult
+	if input.BestEffort {
+		rels, results, err = h.bestEffortRelationships(ctx, "", rels)
+		if err != nil {
+			return nil, CreatePlanOutput{}, err
+		}
+	}
 
-	created, err := h.PlanRepo.Add(ctx, plan, rels)
+	ctx = withActor(ctx, req, input.Actor)
+	var created *models.Plan
+	_, err = skipCycles(input.BestEffort, rels, results, func(rels []models.Relationship) (err error) {
+		created, err = h.PlanRepo.Add(ctx, plan, rels)
+		return err
+	})
 	if err != nil {
 		h.Logger.Error("create_plan failed", "name", input.Name, "error", err)
 		return nil, CreatePlanOutput{}, fmt.Errorf("failed to create plan: %w", err)
@@ -76,12 +98,14 @@ func (h *Handler) HandleCreatePlan(ctx context.Context, req *mcp.CallToolRequest
 
 	h.Logger.Info("create_plan complete", "id", created.ID, "name", created.Name)
 	return nil, CreatePlanOutput{
-		ID:          created.ID,
-		Name:        created.Name,
-		Description: created.Description,
-		Status:      string(created.Status),
-		Metadata:    created.Metadata,
-		Tags:        created.Tags,
-		CreatedAt:   created.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		ID:                  created.ID,
+		Name:                created.Name,
+		Description:         created.Description,
+		Status:              string(created.Status),
+		Metadata:            created.Metadata,
+		Tags:                created.Tags,
+		CreatedAt:           created.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		Version:             created.Version,
+		RelationshipResults: results,
 	}, nil
 }
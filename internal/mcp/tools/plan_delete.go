@@ -4,38 +4,63 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/Thomas-Fitz/associate/internal/models"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // DeletePlanInput defines the input for the delete_plan tool.
 type DeletePlanInput struct {
-	ID string `json:"id" jsonschema:"required,The ID of the plan to delete"`
+	ID           string `json:"id" jsonschema:"required,The ID of the plan to delete"`
+	OrphanTasks  string `json:"orphan_tasks,omitempty" jsonschema:"What to do with tasks that belong to no other plan: delete (default) moves them to the trash with the plan, detach keeps them in no plan, move appends them in order to target_plan_id"`
+	TargetPlanID string `json:"target_plan_id,omitempty" jsonschema:"The plan orphan tasks move to, required when orphan_tasks is move"`
+	DryRun       bool   `json:"dry_run,omitempty" jsonschema:"If true, nothing is deleted or moved and would_delete lists the plan, tasks and relationships that would be moved to the trash"`
 }
 
 // DeletePlanOutput defines the output for the delete_plan tool.
 type DeletePlanOutput struct {
-	ID           string `json:"id"`
-	Deleted      bool   `json:"deleted"`
-	TasksDeleted int    `json:"tasks_deleted"`
+	ID                string                 `json:"id"`
+	Deleted           bool                   `json:"deleted"`
+	TasksDeleted      int                    `json:"tasks_deleted"`
+	DryRun            bool                   `json:"dry_run,omitempty"`
+	WouldDelete       *DeletePreview         `json:"would_delete,omitempty"`
+	StatusChanges     []StatusChangeItem     `json:"status_changes,omitempty"`
+	PlanStatusChanges []PlanStatusChangeItem `json:"plan_status_changes,omitempty"`
 }
 
 // DeletePlanTool returns the tool definition for delete_plan.
 func DeletePlanTool() *mcp.Tool {
 	return &mcp.Tool{
 		Name:        "delete_plan",
-		Description: "Delete a plan and cascade delete tasks that only belong to this plan. Tasks that are PART_OF other plans are preserved (only the relationship to this plan is removed).",
+		Description: "Delete a plan and cascade delete tasks that only belong to this plan. Tasks that are PART_OF other plans are preserved (only the relationship to this plan is removed). Set orphan_tasks to detach to keep the tasks that only belong to this plan, or to move with a target_plan_id to append them to another plan in their current order. The plan, its deleted tasks and their relationships move to the trash, where list_trash shows them until they are purged after the retention period; restore brings them back with their task order. Set dry_run to see exactly which nodes and relationships would be removed without deleting anything. When the server runs with status rules on, blocked tasks that waited for the deleted tasks are moved back to pending once all they still wait for are completed or cancelled; status_changes lists the tasks changed that way. When the server runs with plan completion on and tasks are moved, the target plan is moved back to active if a moved task is unfinished, or to completed if all its tasks now are; plan_status_changes lists it when it changes that way.",
 	}
 }
 
 // HandleDeletePlan handles the delete_plan tool call.
 func (h *Handler) HandleDeletePlan(ctx context.Context, req *mcp.CallToolRequest, input DeletePlanInput) (*mcp.CallToolResult, DeletePlanOutput, error) {
-	h.Logger.Info("delete_plan", "id", input.ID)
+	h.Logger.Info("delete_plan", "id", input.ID, "orphan_tasks", input.OrphanTasks, "dry_run", input.DryRun)
 
 	if input.ID == "" {
 		return nil, DeletePlanOutput{}, fmt.Errorf("id is required")
 	}
+	opts := models.PlanDeleteOptions{OrphanTasks: models.OrphanTasks(input.OrphanTasks), TargetPlanID: input.TargetPlanID}
 
-	tasksDeleted, err := h.PlanRepo.Delete(ctx, input.ID)
+	if input.DryRun {
+		entry, err := h.PlanRepo.PreviewDelete(ctx, input.ID, opts)
+		if err != nil {
+			h.Logger.Error("delete_plan preview failed", "id", input.ID, "error", err)
+			return nil, DeletePlanOutput{}, fmt.Errorf("failed to preview delete: %w", err)
+		}
+		if entry == nil {
+			return nil, DeletePlanOutput{}, fmt.Errorf("plan not found: %s", input.ID)
+		}
+		return nil, DeletePlanOutput{
+			ID:          input.ID,
+			DryRun:      true,
+			WouldDelete: toDeletePreview(*entry),
+		}, nil
+	}
+
+	tasksDeleted, changes, err := h.PlanRepo.Delete(ctx, input.ID, opts)
 	if err != nil {
 		h.Logger.Error("delete_plan failed", "id", input.ID, "error", err)
 		return nil, DeletePlanOutput{}, fmt.Errorf("failed to delete plan: %w", err)
@@ -43,8 +68,10 @@ func (h *Handler) HandleDeletePlan(ctx context.Context, req *mcp.CallToolRequest
 
 	h.Logger.Info("delete_plan complete", "id", input.ID, "tasks_deleted", tasksDeleted)
 	return nil, DeletePlanOutput{
-		ID:           input.ID,
-		Deleted:      true,
-		TasksDeleted: tasksDeleted,
+		ID:                input.ID,
+		Deleted:           true,
+		TasksDeleted:      tasksDeleted,
+		StatusChanges:     toStatusChangeItems(changes),
+		PlanStatusChanges: toPlanStatusChangeItems(changes),
 	}, nil
 }
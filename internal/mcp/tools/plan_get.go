@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/Thomas-Fitz/associate/internal/models"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -17,11 +18,36 @@ type TaskSummary struct {
 	ID        string   `json:"id"`
 	Content   string   `json:"content"`
 	Status    string   `json:"status"`
+	Version   int64    `json:"version"`
 	Position  float64  `json:"position"`
 	DependsOn []string `json:"depends_on,omitempty"`
 	Blocks    []string `json:"blocks,omitempty"`
 }
 
+// PlanProgressItem sums up how far along a plan's tasks are.
+type PlanProgressItem struct {
+	Total           int            `json:"total"`
+	ByStatus        map[string]int `json:"by_status"`
+	Blocked         int            `json:"blocked"`
+	PercentComplete float64        `json:"percent_complete"`
+	LastActivity    string         `json:"last_activity"`
+}
+
+// toPlanProgressItem converts the progress of a plan.
+func toPlanProgressItem(p models.PlanProgress) *PlanProgressItem {
+	byStatus := make(map[string]int, len(p.ByStatus))
+	for status, count := range p.ByStatus {
+		byStatus[string(status)] = count
+	}
+	return &PlanProgressItem{
+		Total:           p.Total,
+		ByStatus:        byStatus,
+		Blocked:         p.Blocked,
+		PercentComplete: p.PercentComplete,
+		LastActivity:    p.LastActivity.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
 // GetPlanOutput defines the output for the get_plan tool.
 type GetPlanOutput struct {
 	ID          string            `json:"id"`
@@ -31,15 +57,17 @@ type GetPlanOutput struct {
 	Metadata    map[string]string `json:"metadata,omitempty"`
 	Tags        []string          `json:"tags,omitempty"`
 	Tasks       []TaskSummary     `json:"tasks,omitempty"`
+	Progress    *PlanProgressItem `json:"progress"`
 	CreatedAt   string            `json:"created_at"`
 	UpdatedAt   string            `json:"updated_at"`
+	Version     int64             `json:"version"`
 }
 
 // GetPlanTool returns the tool definition for get_plan.
 func GetPlanTool() *mcp.Tool {
 	return &mcp.Tool{
 		Name:        "get_plan",
-		Description: "Retrieve a plan by ID, including all its tasks. Returns full plan details with task summaries (id, content, status).",
+		Description: "Retrieve a plan by ID, including all its tasks. Returns full plan details with task summaries (id, content, status) and progress: task counts by status, the blocked count, percent_complete (completed tasks out of those not cancelled) and last_activity (the latest update to the plan or one of its tasks).",
 	}
 }
 
@@ -63,11 +91,14 @@ func (h *Handler) HandleGetPlan(ctx context.Context, req *mcp.CallToolRequest, i
 
 	// Convert tasks to summaries (tasks are already ordered by position from repository)
 	var taskSummaries []TaskSummary
+	progress := models.NewPlanProgress(plan.UpdatedAt)
 	for _, t := range tasks {
+		progress.Add(t.Task.Status, 1, t.Task.UpdatedAt)
 		taskSummaries = append(taskSummaries, TaskSummary{
 			ID:        t.Task.ID,
 			Content:   t.Task.Content,
 			Status:    string(t.Task.Status),
+			Version:   t.Task.Version,
 			Position:  t.Position,
 			DependsOn: t.DependsOn,
 			Blocks:    t.Blocks,
@@ -83,7 +114,9 @@ func (h *Handler) HandleGetPlan(ctx context.Context, req *mcp.CallToolRequest, i
 		Metadata:    plan.Metadata,
 		Tags:        plan.Tags,
 		Tasks:       taskSummaries,
+		Progress:    toPlanProgressItem(progress),
 		CreatedAt:   plan.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		UpdatedAt:   plan.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		Version:     plan.Version,
 	}, nil
 }
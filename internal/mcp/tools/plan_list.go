@@ -13,28 +13,33 @@ type ListPlansInput struct {
 	Status string   `json:"status,omitempty" jsonschema:"Filter by status: draft, active, completed, archived"`
 	Tags   []string `json:"tags,omitempty" jsonschema:"Filter by tags (plans matching any of the tags are returned)"`
 	Limit  int      `json:"limit,omitempty" jsonschema:"Maximum number of plans to return (default: 50)"`
+	Cursor string   `json:"cursor,omitempty" jsonschema:"next_cursor from the previous page, to fetch the page after it"`
 }
 
 // PlanSummary contains summary info about a plan.
 type PlanSummary struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	Status      string `json:"status"`
-	UpdatedAt   string `json:"updated_at"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Status      string            `json:"status"`
+	Progress    *PlanProgressItem `json:"progress"`
+	UpdatedAt   string            `json:"updated_at"`
+	Version     int64             `json:"version"`
 }
 
 // ListPlansOutput defines the output for the list_plans tool.
 type ListPlansOutput struct {
-	Plans []PlanSummary `json:"plans"`
-	Count int           `json:"count"`
+	Plans      []PlanSummary `json:"plans"`
+	Count      int           `json:"count"`
+	Total      int           `json:"total"`
+	NextCursor string        `json:"next_cursor,omitempty"`
 }
 
 // ListPlansTool returns the tool definition for list_plans.
 func ListPlansTool() *mcp.Tool {
 	return &mcp.Tool{
 		Name:        "list_plans",
-		Description: "List plans with optional filtering by status and tags. Returns plan summaries ordered by most recently updated.",
+		Description: "List plans with optional filtering by status and tags. Returns plan summaries ordered by most recently updated, each with its progress as get_plan gives it. total is the number of matching plans; when more remain, pass next_cursor back as cursor, with the same filters, for the next page.",
 	}
 }
 
@@ -47,28 +52,47 @@ func (h *Handler) HandleListPlans(ctx context.Context, req *mcp.CallToolRequest,
 		return nil, ListPlansOutput{}, fmt.Errorf("invalid status: %s (must be one of: draft, active, completed, archived)", input.Status)
 	}
 
-	plans, err := h.PlanRepo.List(ctx, input.Status, input.Tags, input.Limit)
+	plans, page, err := h.PlanRepo.List(ctx, input.Status, input.Tags, input.Limit, input.Cursor)
 	if err != nil {
 		h.Logger.Error("list_plans failed", "error", err)
 		return nil, ListPlansOutput{}, fmt.Errorf("failed to list plans: %w", err)
 	}
 
+	ids := make([]string, 0, len(plans))
+	for _, p := range plans {
+		ids = append(ids, p.ID)
+	}
+	progress, err := h.PlanRepo.Progress(ctx, ids)
+	if err != nil {
+		h.Logger.Error("list_plans failed", "error", err)
+		return nil, ListPlansOutput{}, fmt.Errorf("failed to get plan progress: %w", err)
+	}
+
 	// Convert to summaries
 	// Initialize as empty slice (not nil) to ensure JSON serializes as [] not null
 	summaries := make([]PlanSummary, 0, len(plans))
 	for _, p := range plans {
+		// A plan deleted since it was listed has no progress
+		planProgress, ok := progress[p.ID]
+		if !ok {
+			planProgress = models.NewPlanProgress(p.UpdatedAt)
+		}
 		summaries = append(summaries, PlanSummary{
 			ID:          p.ID,
 			Name:        p.Name,
 			Description: p.Description,
 			Status:      string(p.Status),
+			Progress:    toPlanProgressItem(planProgress),
 			UpdatedAt:   p.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+			Version:     p.Version,
 		})
 	}
 
 	h.Logger.Info("list_plans complete", "count", len(summaries))
 	return nil, ListPlansOutput{
-		Plans: summaries,
-		Count: len(summaries),
+		Plans:      summaries,
+		Count:      len(summaries),
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
 	}, nil
 }
@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Thomas-Fitz/associate/internal/store"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// SchedulePlanInput defines the input for the schedule_plan tool.
+type SchedulePlanInput struct {
+	ID        string `json:"id" jsonschema:"required,The ID of the plan to schedule"`
+	Normalize bool   `json:"normalize,omitempty" jsonschema:"If true, rewrite the positions of the plan's tasks, evenly spaced, to follow the scheduled order"`
+}
+
+// ScheduledTaskItem is a task's place in a plan's schedule.
+type ScheduledTaskItem struct {
+	ID       string  `json:"id"`
+	Content  string  `json:"content"`
+	Status   string  `json:"status"`
+	Position float64 `json:"position"`
+	Layer    int     `json:"layer"`
+	Estimate float64 `json:"estimate"`
+	Start    float64 `json:"start"`
+	Finish   float64 `json:"finish"`
+}
+
+// SchedulePlanOutput defines the output for the schedule_plan tool.
+type SchedulePlanOutput struct {
+	ID             string              `json:"id"`
+	Tasks          []ScheduledTaskItem `json:"tasks"`
+	Layers         [][]string          `json:"layers"`
+	CriticalPath   []string            `json:"critical_path"`
+	Duration       float64             `json:"duration"`
+	PositionsAgree bool                `json:"positions_agree"`
+	Normalized     bool                `json:"normalized,omitempty"`
+}
+
+// SchedulePlanTool returns the tool definition for schedule_plan.
+func SchedulePlanTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        "schedule_plan",
+		Description: "Schedule a plan's tasks by the DEPENDS_ON and BLOCKS relationships between them (relationships to other plans' tasks are ignored). Returns the tasks in topological order, each after the tasks it waits for and otherwise in position order; the layers of tasks that can run in parallel, each waiting only for earlier layers; and the critical path, the chain of tasks that takes longest. Durations come from each task's \"estimate\" metadata, a non-negative number in any unit, or 1 without one; start and finish are the earliest a task can start and finish. positions_agree tells whether position order already respects the dependencies; normalize: true rewrites the positions to follow the schedule. Fails, naming the cycle, if tasks wait for each other in a cycle.",
+	}
+}
+
+// HandleSchedulePlan handles the schedule_plan tool call.
+func (h *Handler) HandleSchedulePlan(ctx context.Context, req *mcp.CallToolRequest, input SchedulePlanInput) (*mcp.CallToolResult, SchedulePlanOutput, error) {
+	h.Logger.Info("schedule_plan", "id", input.ID, "normalize", input.Normalize)
+
+	if input.ID == "" {
+		return nil, SchedulePlanOutput{}, fmt.Errorf("id is required")
+	}
+
+	plan, tasks, err := h.PlanRepo.GetWithTasks(ctx, input.ID)
+	if err != nil {
+		h.Logger.Error("schedule_plan failed", "id", input.ID, "error", err)
+		return nil, SchedulePlanOutput{}, fmt.Errorf("failed to get plan: %w", err)
+	}
+	if plan == nil {
+		return nil, SchedulePlanOutput{}, fmt.Errorf("plan not found: %s", input.ID)
+	}
+
+	schedule, err := store.Schedule(ctx, tasks)
+	if err != nil {
+		return nil, SchedulePlanOutput{}, fmt.Errorf("failed to schedule plan: %w", err)
+	}
+
+	// Initialize as empty slices (not nil) to ensure JSON serializes as [] not null
+	output := SchedulePlanOutput{
+		ID:             input.ID,
+		Tasks:          make([]ScheduledTaskItem, 0, len(schedule.Tasks)),
+		Layers:         schedule.Layers,
+		CriticalPath:   schedule.CriticalPath,
+		Duration:       schedule.Duration,
+		PositionsAgree: true,
+	}
+	if output.Layers == nil {
+		output.Layers = [][]string{}
+	}
+	if output.CriticalPath == nil {
+		output.CriticalPath = []string{}
+	}
+	for i, st := range schedule.Tasks {
+		if st.Task.ID != tasks[i].Task.ID {
+			output.PositionsAgree = false
+		}
+		output.Tasks = append(output.Tasks, ScheduledTaskItem{
+			ID:       st.Task.ID,
+			Content:  st.Task.Content,
+			Status:   string(st.Task.Status),
+			Position: st.Position,
+			Layer:    st.Layer,
+			Estimate: st.Estimate,
+			Start:    st.Start,
+			Finish:   st.Finish,
+		})
+	}
+
+	if input.Normalize && len(output.Tasks) > 0 {
+		ids := make([]string, len(output.Tasks))
+		for i, item := range output.Tasks {
+			ids[i] = item.ID
+		}
+		positions, err := h.TaskRepo.NormalizePositions(ctx, input.ID, ids)
+		if err != nil {
+			h.Logger.Error("schedule_plan failed to update positions", "id", input.ID, "error", err)
+			return nil, SchedulePlanOutput{}, fmt.Errorf("failed to update positions: %w", err)
+		}
+		for i := range output.Tasks {
+			output.Tasks[i].Position = positions[i]
+		}
+		output.Normalized = true
+	}
+
+	h.Logger.Info("schedule_plan complete", "id", input.ID, "tasks", len(output.Tasks), "duration", output.Duration)
+	return nil, output, nil
+}
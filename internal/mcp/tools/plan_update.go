@@ -10,32 +10,38 @@ import (
 
 // UpdatePlanInput defines the input for the update_plan tool.
 type UpdatePlanInput struct {
-	ID          string         `json:"id" jsonschema:"required,The ID of the plan to update"`
-	Name        *string        `json:"name,omitempty" jsonschema:"New name for the plan"`
-	Description *string        `json:"description,omitempty" jsonschema:"New description for the plan"`
-	Status      *string        `json:"status,omitempty" jsonschema:"New status: draft, active, completed, archived"`
-	Metadata    map[string]any `json:"metadata,omitempty" jsonschema:"New metadata (replaces existing)"`
-	Tags        []string       `json:"tags,omitempty" jsonschema:"New tags (replaces existing)"`
-	RelatedTo   []string       `json:"related_to,omitempty" jsonschema:"IDs of nodes to connect using RELATES_TO"`
-	References  []string       `json:"references,omitempty" jsonschema:"IDs of nodes to connect using REFERENCES"`
+	ID              string              `json:"id" jsonschema:"required,The ID of the plan to update"`
+	Name            *string             `json:"name,omitempty" jsonschema:"New name for the plan"`
+	Description     *string             `json:"description,omitempty" jsonschema:"New description for the plan"`
+	Status          *string             `json:"status,omitempty" jsonschema:"New status: draft, active, completed, archived"`
+	Metadata        map[string]any      `json:"metadata,omitempty" jsonschema:"New metadata (replaces existing)"`
+	Tags            []string            `json:"tags,omitempty" jsonschema:"New tags (replaces existing)"`
+	RelatedTo       []string            `json:"related_to,omitempty" jsonschema:"IDs of nodes to connect using RELATES_TO"`
+	References      []string            `json:"references,omitempty" jsonschema:"IDs of nodes to connect using REFERENCES"`
+	Relationships   []RelationshipInput `json:"relationships,omitempty" jsonschema:"Relationships to create, each with to_id and type and an optional reason (why it exists), weight (0-1 confidence) and metadata. Giving an existing relationship updates those details."`
+	BestEffort      bool                `json:"best_effort,omitempty" jsonschema:"If true, relationships that cannot be created (unknown type or target, or one that would close a DEPENDS_ON/BLOCKS cycle) are skipped and reported in relationship_results instead of failing the whole call"`
+	ExpectedVersion int64               `json:"expected_version,omitempty" jsonschema:"The version this update was based on. If the plan has changed since, the update fails with a version conflict; get it again and retry"`
+	Actor           string              `json:"actor,omitempty" jsonschema:"Who is making the change, recorded in the revision history (default: the MCP client's name)"`
 }
 
 // UpdatePlanOutput defines the output for the update_plan tool.
 type UpdatePlanOutput struct {
-	ID          string            `json:"id"`
-	Name        string            `json:"name"`
-	Description string            `json:"description,omitempty"`
-	Status      string            `json:"status"`
-	Metadata    map[string]string `json:"metadata,omitempty"`
-	Tags        []string          `json:"tags,omitempty"`
-	UpdatedAt   string            `json:"updated_at"`
+	ID                  string               `json:"id"`
+	Name                string               `json:"name"`
+	Description         string               `json:"description,omitempty"`
+	Status              string               `json:"status"`
+	Metadata            map[string]string    `json:"metadata,omitempty"`
+	Tags                []string             `json:"tags,omitempty"`
+	UpdatedAt           string               `json:"updated_at"`
+	Version             int64                `json:"version"`
+	RelationshipResults []RelationshipResult `json:"relationship_results,omitempty"`
 }
 
 // UpdatePlanTool returns the tool definition for update_plan.
 func UpdatePlanTool() *mcp.Tool {
 	return &mcp.Tool{
 		Name:        "update_plan",
-		Description: "Update an existing plan. Only provided fields are updated. Can update name, description, status, metadata, tags, and add new relationships.",
+		Description: "Update an existing plan. Only provided fields are updated. Can update name, description, status, metadata, tags, and add new relationships. Pass the version you read as expected_version to fail instead of overwriting a concurrent change.",
 	}
 }
 
@@ -67,8 +73,25 @@ func (h *Handler) HandleUpdatePlan(ctx context.Context, req *mcp.CallToolRequest
 		input.RelatedTo, nil, input.References,
 		nil, nil, nil, nil,
 	)
+	detailed, err := parseRelationships(input.Relationships)
+	if err != nil {
+		return nil, UpdatePlanOutput{}, err
+	}
+	rels = append(rels, detailed...)
+	var results []RelationshipResult
+	if input.BestEffort {
+		rels, results, err = h.bestEffortRelationships(ctx, input.ID, rels)
+		if err != nil {
+			return nil, UpdatePlanOutput{}, err
+		}
+	}
 
-	updated, err := h.PlanRepo.Update(ctx, input.ID, input.Name, input.Description, status, metadata, input.Tags, rels)
+	ctx = withActor(ctx, req, input.Actor)
+	var updated *models.Plan
+	_, err = skipCycles(input.BestEffort, rels, results, func(rels []models.Relationship) (err error) {
+		updated, err = h.PlanRepo.Update(ctx, input.ID, input.ExpectedVersion, input.Name, input.Description, status, metadata, input.Tags, rels)
+		return err
+	})
 	if err != nil {
 		h.Logger.Error("update_plan failed", "id", input.ID, "error", err)
 		return nil, UpdatePlanOutput{}, fmt.Errorf("failed to update plan: %w", err)
@@ -76,12 +99,14 @@ func (h *Handler) HandleUpdatePlan(ctx context.Context, req *mcp.CallToolRequest
 
 	h.Logger.Info("update_plan complete", "id", updated.ID)
 	return nil, UpdatePlanOutput{
-		ID:          updated.ID,
-		Name:        updated.Name,
-		Description: updated.Description,
-		Status:      string(updated.Status),
-		Metadata:    updated.Metadata,
-		Tags:        updated.Tags,
-		UpdatedAt:   updated.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		ID:                  updated.ID,
+		Name:                updated.Name,
+		Description:         updated.Description,
+		Status:              string(updated.Status),
+		Metadata:            updated.Metadata,
+		Tags:                updated.Tags,
+		UpdatedAt:           updated.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		Version:             updated.Version,
+		RelationshipResults: results,
 	}, nil
 }
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ValidatePlanInput defines the input for the validate_plan tool.
+type ValidatePlanInput struct {
+	ID string `json:"id" jsonschema:"required,The ID of the plan to validate"`
+}
+
+// ValidatePlanOutput defines the output for the validate_plan tool.
+type ValidatePlanOutput struct {
+	ID     string     `json:"id"`
+	Valid  bool       `json:"valid"`
+	Cycles [][]string `json:"cycles"`
+}
+
+// ValidatePlanTool returns the tool definition for validate_plan.
+func ValidatePlanTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        "validate_plan",
+		Description: "Check a plan for dependency cycles: DEPENDS_ON and BLOCKS relationships that leave tasks waiting for each other, so the plan can never be finished. Each cycle is listed as the IDs along it, each waiting for the next (A DEPENDS_ON B or B BLOCKS A means A waits for B), ending where it started. Cycles through tasks of other plans that this plan's tasks wait on are included. New relationships that would close a cycle are already rejected, so cycles only come from older data. Returns valid: true when there are none.",
+	}
+}
+
+// HandleValidatePlan handles the validate_plan tool call.
+func (h *Handler) HandleValidatePlan(ctx context.Context, req *mcp.CallToolRequest, input ValidatePlanInput) (*mcp.CallToolResult, ValidatePlanOutput, error) {
+	h.Logger.Info("validate_plan", "id", input.ID)
+
+	if input.ID == "" {
+		return nil, ValidatePlanOutput{}, fmt.Errorf("id is required")
+	}
+
+	cycles, err := h.PlanRepo.DependencyCycles(ctx, input.ID)
+	if err != nil {
+		h.Logger.Error("validate_plan failed", "id", input.ID, "error", err)
+		return nil, ValidatePlanOutput{}, fmt.Errorf("failed to validate plan: %w", err)
+	}
+
+	// Initialize as empty slice (not nil) to ensure JSON serializes as [] not null
+	if cycles == nil {
+		cycles = [][]string{}
+	}
+
+	h.Logger.Info("validate_plan complete", "id", input.ID, "cycles", len(cycles))
+	return nil, ValidatePlanOutput{ID: input.ID, Valid: len(cycles) == 0, Cycles: cycles}, nil
+}
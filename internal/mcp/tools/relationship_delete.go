@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// DeleteRelationshipInput defines the input for the delete_relationship tool.
+type DeleteRelationshipInput struct {
+	FromID       string `json:"from_id" jsonschema:"required,The ID of the node the relationship starts at"`
+	ToID         string `json:"to_id" jsonschema:"required,The ID of the node the relationship points to"`
+	RelationType string `json:"relationship_type" jsonschema:"required,The relationship type (RELATES_TO, PART_OF, REFERENCES, DEPENDS_ON, BLOCKS, FOLLOWS, IMPLEMENTS)"`
+}
+
+// DeleteRelationshipOutput defines the output for the delete_relationship tool.
+type DeleteRelationshipOutput struct {
+	ID      string `json:"id"`
+	Deleted bool   `json:"deleted"`
+}
+
+// DeleteRelationshipTool returns the tool definition for delete_relationship.
+func DeleteRelationshipTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        "delete_relationship",
+		Description: "Delete one relationship, identified by from_id, to_id and relationship_type, leaving both nodes and their other relationships in place. Fails if there is no such relationship. Returns the id of the deleted edge.",
+	}
+}
+
+// HandleDeleteRelationship handles the delete_relationship tool call.
+func (h *Handler) HandleDeleteRelationship(ctx context.Context, req *mcp.CallToolRequest, input DeleteRelationshipInput) (*mcp.CallToolResult, DeleteRelationshipOutput, error) {
+	h.Logger.Info("delete_relationship", "from_id", input.FromID, "to_id", input.ToID, "rel_type", input.RelationType)
+
+	if input.FromID == "" || input.ToID == "" {
+		return nil, DeleteRelationshipOutput{}, fmt.Errorf("from_id and to_id are required")
+	}
+	if input.RelationType == "" {
+		return nil, DeleteRelationshipOutput{}, fmt.Errorf("relationship_type is required")
+	}
+
+	relType := models.RelationType(input.RelationType)
+	if err := h.Repo.DeleteRelationship(ctx, input.FromID, input.ToID, relType); err != nil {
+		h.Logger.Error("delete_relationship failed", "from_id", input.FromID, "to_id", input.ToID, "error", err)
+		return nil, DeleteRelationshipOutput{}, fmt.Errorf("failed to delete relationship: %w", err)
+	}
+
+	id := models.EdgeID(input.FromID, input.ToID, relType)
+	h.Logger.Info("delete_relationship complete", "id", id)
+	return nil, DeleteRelationshipOutput{
+		ID:      id,
+		Deleted: true,
+	}, nil
+}
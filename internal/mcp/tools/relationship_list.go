@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ListRelationshipsInput defines the input for the list_relationships tool.
+type ListRelationshipsInput struct {
+	ID           string `json:"id" jsonschema:"required,The ID of the node (memory, plan, or task) to list relationships for"`
+	RelationType string `json:"relationship_type,omitempty" jsonschema:"Filter by relationship type (RELATES_TO, PART_OF, REFERENCES, DEPENDS_ON, BLOCKS, FOLLOWS, IMPLEMENTS)"`
+	Direction    string `json:"direction,omitempty" jsonschema:"Filter by direction: incoming, outgoing, or both (default: both)"`
+}
+
+// ListRelationshipsOutput defines the output for the list_relationships tool.
+type ListRelationshipsOutput struct {
+	ID    string     `json:"id"`
+	Edges []EdgeItem `json:"edges"`
+	Count int        `json:"count"`
+}
+
+// ListRelationshipsTool returns the tool definition for list_relationships.
+func ListRelationshipsTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        "list_relationships",
+		Description: "List the relationships of a node (memory, plan, or task) by ID, oldest first. Filter by relationship_type and direction (incoming/outgoing/both). Each edge is returned with: id (identifies the edge), from_id, to_id, type, created_at, reason, weight and metadata when set, and position for a task's PART_OF edge. Use delete_relationship to remove an edge.",
+	}
+}
+
+// HandleListRelationships handles the list_relationships tool call.
+func (h *Handler) HandleListRelationships(ctx context.Context, req *mcp.CallToolRequest, input ListRelationshipsInput) (*mcp.CallToolResult, ListRelationshipsOutput, error) {
+	h.Logger.Info("list_relationships", "id", input.ID, "rel_type", input.RelationType, "direction", input.Direction)
+
+	if input.ID == "" {
+		return nil, ListRelationshipsOutput{}, fmt.Errorf("id is required")
+	}
+
+	direction := input.Direction
+	switch direction {
+	case "":
+		direction = "both"
+	case "incoming", "outgoing", "both":
+	default:
+		return nil, ListRelationshipsOutput{}, fmt.Errorf("invalid direction: %s (must be one of: incoming, outgoing, both)", direction)
+	}
+
+	edges, err := h.Repo.ListRelationships(ctx, input.ID, input.RelationType, direction)
+	if err != nil {
+		h.Logger.Error("list_relationships failed", "id", input.ID, "error", err)
+		return nil, ListRelationshipsOutput{}, fmt.Errorf("failed to list relationships: %w", err)
+	}
+
+	h.Logger.Info("list_relationships complete", "id", input.ID, "results", len(edges))
+	return nil, ListRelationshipsOutput{
+		ID:    input.ID,
+		Edges: toEdgeItems(edges),
+		Count: len(edges),
+	}, nil
+}
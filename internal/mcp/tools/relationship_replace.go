@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ReplaceRelationshipsInput defines the input for the replace_relationships tool.
+type ReplaceRelationshipsInput struct {
+	ID            string              `json:"id" jsonschema:"required,The ID of the node (memory, plan, or task) whose outgoing relationships to replace"`
+	Relationships []RelationshipInput `json:"relationships" jsonschema:"The complete set of outgoing relationships the node should have; an empty list removes them all"`
+}
+
+// ReplaceRelationshipsOutput defines the output for the replace_relationships tool.
+type ReplaceRelationshipsOutput struct {
+	ID    string     `json:"id"`
+	Edges []EdgeItem `json:"edges"`
+	Count int        `json:"count"`
+}
+
+// ReplaceRelationshipsTool returns the tool definition for replace_relationships.
+func ReplaceRelationshipsTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        "replace_relationships",
+		Description: "Replace all outgoing relationships of a node (memory, plan, or task) with the given list of {to_id, type, reason, weight, metadata}, in one step: relationships already present are kept (with any reason, weight or metadata given updated), missing ones are created, and the rest are deleted. Incoming relationships are not changed. A task's PART_OF relationship to its plan is kept and cannot be given. If any target does not exist, nothing changes. Returns the node's outgoing edges afterwards.",
+	}
+}
+
+// HandleReplaceRelationships handles the replace_relationships tool call.
+func (h *Handler) HandleReplaceRelationships(ctx context.Context, req *mcp.CallToolRequest, input ReplaceRelationshipsInput) (*mcp.CallToolResult, ReplaceRelationshipsOutput, error) {
+	h.Logger.Info("replace_relationships", "id", input.ID, "relationships", len(input.Relationships))
+
+	if input.ID == "" {
+		return nil, ReplaceRelationshipsOutput{}, fmt.Errorf("id is required")
+	}
+
+	rels, err := parseRelationships(input.Relationships)
+	if err != nil {
+		return nil, ReplaceRelationshipsOutput{}, err
+	}
+
+	edges, err := h.Repo.ReplaceRelationships(ctx, input.ID, rels)
+	if err != nil {
+		h.Logger.Error("replace_relationships failed", "id", input.ID, "error", err)
+		return nil, ReplaceRelationshipsOutput{}, fmt.Errorf("failed to replace relationships: %w", err)
+	}
+
+	h.Logger.Info("replace_relationships complete", "id", input.ID, "edges", len(edges))
+	return nil, ReplaceRelationshipsOutput{
+		ID:    input.ID,
+		Edges: toEdgeItems(edges),
+		Count: len(edges),
+	}, nil
+}
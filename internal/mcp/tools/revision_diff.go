@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// DiffRevisionsInput defines the input for the diff_revisions tool.
+type DiffRevisionsInput struct {
+	ID          string `json:"id" jsonschema:"required,The ID of the memory, plan, or task"`
+	FromVersion int64  `json:"from_version" jsonschema:"required,The older version to compare"`
+	ToVersion   int64  `json:"to_version,omitempty" jsonschema:"The newer version to compare (default: the latest revision)"`
+}
+
+// DiffRevisionsOutput defines the output for the diff_revisions tool.
+type DiffRevisionsOutput struct {
+	ID          string               `json:"id"`
+	FromVersion int64                `json:"from_version"`
+	ToVersion   int64                `json:"to_version"`
+	Changes     []models.FieldChange `json:"changes"`
+}
+
+// DiffRevisionsTool returns the tool definition for diff_revisions.
+func DiffRevisionsTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        "diff_revisions",
+		Description: "Show what changed in a memory, plan, or task between two versions from list_revisions: from_version and to_version (default: the latest). Returns one change per changed field (content, name, description, status, metadata, tags). Single-line values are given as from and to; multi-line values as diff, a list of lines prefixed with - (removed), + (added) or a space (unchanged).",
+	}
+}
+
+// HandleDiffRevisions handles the diff_revisions tool call.
+func (h *Handler) HandleDiffRevisions(ctx context.Context, req *mcp.CallToolRequest, input DiffRevisionsInput) (*mcp.CallToolResult, DiffRevisionsOutput, error) {
+	h.Logger.Info("diff_revisions", "id", input.ID, "from_version", input.FromVersion, "to_version", input.ToVersion)
+
+	if input.ID == "" {
+		return nil, DiffRevisionsOutput{}, fmt.Errorf("id is required")
+	}
+	if input.FromVersion <= 0 {
+		return nil, DiffRevisionsOutput{}, fmt.Errorf("from_version is required")
+	}
+
+	toVersion := input.ToVersion
+	if toVersion == 0 {
+		revisions, err := h.Repo.ListRevisions(ctx, input.ID)
+		if err != nil {
+			return nil, DiffRevisionsOutput{}, fmt.Errorf("failed to list revisions: %w", err)
+		}
+		if len(revisions) > 0 {
+			toVersion = revisions[0].Version
+		}
+	}
+
+	from, err := h.revision(ctx, input.ID, input.FromVersion)
+	if err != nil {
+		return nil, DiffRevisionsOutput{}, err
+	}
+	to, err := h.revision(ctx, input.ID, toVersion)
+	if err != nil {
+		return nil, DiffRevisionsOutput{}, err
+	}
+
+	changes := models.DiffRevisions(*from, *to)
+	if changes == nil {
+		changes = []models.FieldChange{}
+	}
+
+	h.Logger.Info("diff_revisions complete", "id", input.ID, "changes", len(changes))
+	return nil, DiffRevisionsOutput{
+		ID:          input.ID,
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Changes:     changes,
+	}, nil
+}
+
+// revision returns the revision of a node at the given version, or an error
+// if there is none.
+func (h *Handler) revision(ctx context.Context, id string, version int64) (*models.Revision, error) {
+	rev, err := h.Repo.GetRevision(ctx, id, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revision: %w", err)
+	}
+	if rev == nil {
+		return nil, fmt.Errorf("revision not found: %s at version %d", id, version)
+	}
+	return rev, nil
+}
@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ListRevisionsInput defines the input for the list_revisions tool.
+type ListRevisionsInput struct {
+	ID string `json:"id" jsonschema:"required,The ID of the memory, plan, or task to list revisions for"`
+}
+
+// RevisionItem is the state of a node at one of its versions.
+type RevisionItem struct {
+	Version     int64             `json:"version"`
+	Kind        string            `json:"kind"`
+	Type        string            `json:"type,omitempty"`
+	Content     string            `json:"content,omitempty"`
+	Name        string            `json:"name,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Status      string            `json:"status,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Actor       string            `json:"actor,omitempty"`
+	CreatedAt   string            `json:"created_at"`
+}
+
+// ListRevisionsOutput defines the output for the list_revisions tool.
+type ListRevisionsOutput struct {
+	ID        string         `json:"id"`
+	Revisions []RevisionItem `json:"revisions"`
+	Count     int            `json:"count"`
+}
+
+// ListRevisionsTool returns the tool definition for list_revisions.
+func ListRevisionsTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        "list_revisions",
+		Description: "List the revision history of a memory, plan, or task by ID, newest first. Each revision is the node's state at one version: content (memories and tasks), name and description (plans), status, metadata and tags, with the actor who made the change and created_at, when it was made. Use diff_revisions to compare two versions and restore_revision to roll back.",
+	}
+}
+
+// HandleListRevisions handles the list_revisions tool call.
+func (h *Handler) HandleListRevisions(ctx context.Context, req *mcp.CallToolRequest, input ListRevisionsInput) (*mcp.CallToolResult, ListRevisionsOutput, error) {
+	h.Logger.Info("list_revisions", "id", input.ID)
+
+	if input.ID == "" {
+		return nil, ListRevisionsOutput{}, fmt.Errorf("id is required")
+	}
+
+	revisions, err := h.Repo.ListRevisions(ctx, input.ID)
+	if err != nil {
+		h.Logger.Error("list_revisions failed", "id", input.ID, "error", err)
+		return nil, ListRevisionsOutput{}, fmt.Errorf("failed to list revisions: %w", err)
+	}
+
+	// Initialize as empty slice (not nil) to ensure JSON serializes as [] not null
+	items := make([]RevisionItem, 0, len(revisions))
+	for _, rev := range revisions {
+		items = append(items, toRevisionItem(rev))
+	}
+
+	h.Logger.Info("list_revisions complete", "id", input.ID, "results", len(items))
+	return nil, ListRevisionsOutput{ID: input.ID, Revisions: items, Count: len(items)}, nil
+}
+
+func toRevisionItem(rev models.Revision) RevisionItem {
+	return RevisionItem{
+		Version:     rev.Version,
+		Kind:        string(rev.Kind),
+		Type:        string(rev.Type),
+		Content:     rev.Content,
+		Name:        rev.Name,
+		Description: rev.Description,
+		Status:      rev.Status,
+		Metadata:    rev.Metadata,
+		Tags:        rev.Tags,
+		Actor:       rev.Actor,
+		CreatedAt:   rev.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// RestoreRevisionInput defines the input for the restore_revision tool.
+type RestoreRevisionInput struct {
+	ID              string `json:"id" jsonschema:"required,The ID of the memory, plan, or task to restore"`
+	Version         int64  `json:"version" jsonschema:"required,The version to restore, from list_revisions"`
+	ExpectedVersion int64  `json:"expected_version,omitempty" jsonschema:"The current version. If the node has changed since, the restore fails with a version conflict"`
+	Actor           string `json:"actor,omitempty" jsonschema:"Who is making the change, recorded in the revision history (default: the MCP client's name)"`
+}
+
+// RestoreRevisionOutput defines the output for the restore_revision tool.
+type RestoreRevisionOutput struct {
+	ID                string                 `json:"id"`
+	Kind              string                 `json:"kind"`
+	RestoredVersion   int64                  `json:"restored_version"`
+	Version           int64                  `json:"version"`
+	StatusChanges     []StatusChangeItem     `json:"status_changes,omitempty"`
+	PlanStatusChanges []PlanStatusChangeItem `json:"plan_status_changes,omitempty"`
+}
+
+// RestoreRevisionTool returns the tool definition for restore_revision.
+func RestoreRevisionTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        "restore_revision",
+		Description: "Roll a memory, plan, or task back to an earlier version from list_revisions. Its content, name, description, status, metadata and tags are set to those of that version; relationships are left as they are. The restore is itself recorded as a new version, so it can be undone. Returns the new version.",
+	}
+}
+
+// HandleRestoreRevision handles the restore_revision tool call.
+func (h *Handler) HandleRestoreRevision(ctx context.Context, req *mcp.CallToolRequest, input RestoreRevisionInput) (*mcp.CallToolResult, RestoreRevisionOutput, error) {
+	h.Logger.Info("restore_revision", "id", input.ID, "version", input.Version)
+
+	if input.ID == "" {
+		return nil, RestoreRevisionOutput{}, fmt.Errorf("id is required")
+	}
+	if input.Version <= 0 {
+		return nil, RestoreRevisionOutput{}, fmt.Errorf("version is required")
+	}
+
+	rev, err := h.revision(ctx, input.ID, input.Version)
+	if err != nil {
+		return nil, RestoreRevisionOutput{}, err
+	}
+
+	// Empty, rather than nil, metadata and tags clear those of the current version
+	metadata := rev.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	tags := rev.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	ctx = withActor(ctx, req, input.Actor)
+	var version int64
+	var changes models.StatusChanges
+	switch rev.Kind {
+	case models.KindPlan:
+		plan, err := h.PlanRepo.Update(ctx, input.ID, input.ExpectedVersion, &rev.Name, &rev.Description, &rev.Status, metadata, tags, nil)
+		if err != nil {
+			return nil, RestoreRevisionOutput{}, fmt.Errorf("failed to restore plan: %w", err)
+		}
+		version = plan.Version
+	case models.KindTask:
+		task, taskChanges, err := h.TaskRepo.Update(ctx, input.ID, input.ExpectedVersion, &rev.Content, &rev.Status, metadata, tags, nil, nil)
+		if err != nil {
+			return nil, RestoreRevisionOutput{}, fmt.Errorf("failed to restore task: %w", err)
+		}
+		version = task.Version
+		changes = taskChanges
+	default:
+		mem, err := h.Repo.Update(ctx, input.ID, input.ExpectedVersion, &rev.Content, metadata, tags, nil)
+		if err != nil {
+			return nil, RestoreRevisionOutput{}, fmt.Errorf("failed to restore memory: %w", err)
+		}
+		version = mem.Version
+	}
+
+	h.Logger.Info("restore_revision complete", "id", input.ID, "restored_version", input.Version, "version", version)
+	return nil, RestoreRevisionOutput{
+		ID:                input.ID,
+		Kind:              string(rev.Kind),
+		RestoredVersion:   input.Version,
+		Version:           version,
+		StatusChanges:     toStatusChangeItems(changes),
+		PlanStatusChanges: toPlanStatusChangeItems(changes),
+	}, nil
+}
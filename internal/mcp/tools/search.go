@@ -3,20 +3,37 @@ package tools
 import (
 "context"
 "fmt"
+"time"
 
+"github.com/Thomas-Fitz/associate/internal/models"
 "github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // SearchInput defines the input for the search tool.
 type SearchInput struct {
-Query string `json:"query" jsonschema:"The search query to find matching memories"`
-Limit int    `json:"limit,omitempty" jsonschema:"Maximum number of results to return (default 10)"`
+Query         string         `json:"query" jsonschema:"The search query. Words may match with typos or in any order; use \"double quotes\" for an exact phrase and -word to exclude a word. May be empty when filtering"`
+Limit         int            `json:"limit,omitempty" jsonschema:"Maximum number of results to return (default 10)"`
+Mode          string         `json:"mode,omitempty" jsonschema:"text (default) for keyword search, semantic to match by meaning using embeddings, or hybrid to boost keyword matches by graph links and recency"`
+AnchorID      string         `json:"anchor_id,omitempty" jsonschema:"Hybrid mode only: ID of the memory, plan or task being worked on; memories linked to it rank first"`
+Type          string         `json:"type,omitempty" jsonschema:"Only memories of this type (e.g. Note, Repository, Memory)"`
+Tags          []string       `json:"tags,omitempty" jsonschema:"Only memories with these tags"`
+TagMatch      string         `json:"tag_match,omitempty" jsonschema:"any (default): at least one of tags; all: every tag"`
+Metadata      map[string]any `json:"metadata,omitempty" jsonschema:"Only memories whose metadata has each of these key/value pairs"`
+CreatedAfter  string         `json:"created_after,omitempty" jsonschema:"Only memories created at or after this time (RFC 3339 or YYYY-MM-DD)"`
+CreatedBefore string         `json:"created_before,omitempty" jsonschema:"Only memories created before this time (RFC 3339 or YYYY-MM-DD)"`
+UpdatedAfter  string         `json:"updated_after,omitempty" jsonschema:"Only memories updated at or after this time (RFC 3339 or YYYY-MM-DD)"`
+UpdatedBefore string         `json:"updated_before,omitempty" jsonschema:"Only memories updated before this time (RFC 3339 or YYYY-MM-DD)"`
+Sort          string         `json:"sort,omitempty" jsonschema:"relevance (default with a query), created_at, or updated_at (default without a query)"`
+Order         string         `json:"order,omitempty" jsonschema:"desc (default: highest score or newest first) or asc"`
+Cursor        string         `json:"cursor,omitempty" jsonschema:"next_cursor from the previous page, to fetch the page after it"`
 }
 
 // SearchOutput defines the output for the search tool.
 type SearchOutput struct {
-Results []SearchResultItem `json:"results"`
-Count   int                `json:"count"`
+Results    []SearchResultItem `json:"results"`
+Count      int                `json:"count"`
+Total      int                `json:"total"`
+NextCursor string             `json:"next_cursor,omitempty"`
 }
 
 // SearchResultItem represents a single search result.
@@ -25,34 +42,69 @@ ID        string            `json:"id"`
 Type      string            `json:"type"`
 Content   string            `json:"content"`
 Score     float64           `json:"score"`
+Snippet   string            `json:"snippet,omitempty"`
 Metadata  map[string]string `json:"metadata,omitempty"`
 Tags      []string          `json:"tags,omitempty"`
 Related   []string          `json:"related,omitempty"`
 CreatedAt string            `json:"created_at"`
 UpdatedAt string            `json:"updated_at"`
+Version   int64             `json:"version"`
 }
 
 // SearchTool returns the tool definition for search_memories.
 func SearchTool() *mcp.Tool {
 return &mcp.Tool{
 Name:        "search_memories",
-Description: "Search memories with parameters query (string) and limit (int, default 10). Returns matching results with: id, type (Note, Task, Project, Repository, Memory), content (string), score (float), metadata (json), tags (array), and related (array) memory IDs.",
+Description: "Search memories with parameters query (string), limit (int, default 10) and mode (text, semantic or hybrid, default text), and anchor_id (string, hybrid only). Text mode is ranked full-text search with stemming and typo tolerance; \"quoted phrases\" must match exactly and -word excludes a word. Semantic mode ranks memories by embedding similarity to the query, finding related wording that shares no keywords. Hybrid mode re-ranks text matches by links to anchor_id (directly or through one other node), number of links, and recency. Returns results sorted by relevance with: id, type (Note, Task, Project, Repository, Memory), content (string), score (float, 0-1, higher is better), snippet (matched excerpt with **highlighted** words), metadata (json), tags (array), and related (array) memory IDs. total is the number of matches across all pages; when more remain, pass next_cursor back as cursor, with the same other parameters, for the next page.",
 }
 }
 
 // HandleSearch handles the search_memories tool call.
 func (h *Handler) HandleSearch(ctx context.Context, req *mcp.CallToolRequest, input SearchInput) (*mcp.CallToolResult, SearchOutput, error) {
-h.Logger.Info("search_memories", "query", input.Query, "limit", input.Limit)
+h.Logger.Info("search_memories", "query", input.Query, "limit", input.Limit, "mode", input.Mode, "anchor_id", input.AnchorID)
 
-results, err := h.Repo.Search(ctx, input.Query, input.Limit)
+filter := models.SearchFilter{
+Type:     models.MemoryType(input.Type),
+Tags:     input.Tags,
+TagMatch: models.TagMatch(input.TagMatch),
+Metadata: convertMetadata(input.Metadata),
+}
+for _, bound := range []struct {
+name  string
+value string
+dest  *time.Time
+}{
+{"created_after", input.CreatedAfter, &filter.CreatedAfter},
+{"created_before", input.CreatedBefore, &filter.CreatedBefore},
+{"updated_after", input.UpdatedAfter, &filter.UpdatedAfter},
+{"updated_before", input.UpdatedBefore, &filter.UpdatedBefore},
+} {
+t, err := parseTimestamp(bound.value)
+if err != nil {
+return nil, SearchOutput{}, fmt.Errorf("invalid %s: %w", bound.name, err)
+}
+*bound.dest = t
+}
+
+results, page, err := h.Repo.Search(ctx, input.Query, models.SearchOptions{
+Mode:     models.SearchMode(input.Mode),
+Limit:    input.Limit,
+AnchorID: input.AnchorID,
+Filter:   filter,
+Sort:     models.SearchSort(input.Sort),
+Order:    models.SortOrder(input.Order),
+Cursor:   input.Cursor,
+})
 if err != nil {
 h.Logger.Error("search_memories failed", "query", input.Query, "error", err)
 return nil, SearchOutput{}, fmt.Errorf("search failed: %w", err)
 }
 
 output := SearchOutput{
-Results: make([]SearchResultItem, len(results)),
-Count:   len(results),
+Results:    make([]SearchResultItem, len(results)),
+Count:      len(results),
+Total:      page.Total,
+NextCursor: page.NextCursor,
 }
 
 for i, r := range results {
@@ -61,11 +113,13 @@ ID:        r.Memory.ID,
 Type:      string(r.Memory.Type),
 Content:   r.Memory.Content,
 Score:     r.Score,
+Snippet:   r.Snippet,
 Metadata:  r.Memory.Metadata,
 Tags:      r.Memory.Tags,
 Related:   r.Related,
 CreatedAt: r.Memory.CreatedAt.Format("2006-01-02T15:04:05Z"),
 UpdatedAt: r.Memory.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+Version:   r.Memory.Version,
 }
 }
 
@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// SearchAllInput defines the input for the search tool.
+type SearchAllInput struct {
+	Query string   `json:"query" jsonschema:"The search query. Words may match with typos or in any order; use \"double quotes\" for an exact phrase and -word to exclude a word"`
+	Kinds []string `json:"kinds,omitempty" jsonschema:"Only these node kinds: memory, plan, task (default: all)"`
+	Mode  string   `json:"mode,omitempty" jsonschema:"text (default) for keyword search, or semantic to match by meaning using embeddings"`
+	Limit int      `json:"limit,omitempty" jsonschema:"Maximum number of results to return (default 10)"`
+}
+
+// SearchAllOutput defines the output for the search tool.
+type SearchAllOutput struct {
+	Results []SearchAllResultItem `json:"results"`
+	Count   int                   `json:"count"`
+}
+
+// SearchAllResultItem represents a memory, plan or task found by the search tool.
+type SearchAllResultItem struct {
+	Kind      string  `json:"kind"`
+	ID        string  `json:"id"`
+	Title     string  `json:"title"`
+	Snippet   string  `json:"snippet,omitempty"`
+	Status    string  `json:"status,omitempty"`
+	Score     float64 `json:"score"`
+	UpdatedAt string  `json:"updated_at"`
+}
+
+// SearchAllTool returns the tool definition for search.
+func SearchAllTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        "search",
+		Description: "Search memories, plans and tasks in one call with parameters query (string), kinds (array of memory, plan, task; default all), mode (text or semantic, default text) and limit (int, default 10). Plans match on name and description; memories and tasks on content. Returns results sorted by relevance with: kind (Memory, Plan or Task), id, title (plan name, or first line of content), snippet (matched excerpt with **highlighted** words), status (plans and tasks only), score (float, 0-1, higher is better) and updated_at. Use get_plan, get_task or get_memory for full details.",
+	}
+}
+
+// HandleSearchAll handles the search tool call.
+func (h *Handler) HandleSearchAll(ctx context.Context, req *mcp.CallToolRequest, input SearchAllInput) (*mcp.CallToolResult, SearchAllOutput, error) {
+	h.Logger.Info("search", "query", input.Query, "kinds", input.Kinds, "mode", input.Mode, "limit", input.Limit)
+
+	if strings.TrimSpace(input.Query) == "" {
+		return nil, SearchAllOutput{}, fmt.Errorf("query is required")
+	}
+
+	kinds := make([]models.NodeKind, 0, len(input.Kinds))
+	for _, k := range input.Kinds {
+		kind, err := parseNodeKind(k)
+		if err != nil {
+			return nil, SearchAllOutput{}, err
+		}
+		kinds = append(kinds, kind)
+	}
+
+	results, err := h.Repo.SearchAll(ctx, input.Query, models.NodeSearchOptions{
+		Kinds: kinds,
+		Mode:  models.SearchMode(input.Mode),
+		Limit: input.Limit,
+	})
+	if err != nil {
+		h.Logger.Error("search failed", "query", input.Query, "error", err)
+		return nil, SearchAllOutput{}, fmt.Errorf("search failed: %w", err)
+	}
+
+	// Initialize as empty slice (not nil) to ensure JSON serializes as [] not null
+	items := make([]SearchAllResultItem, 0, len(results))
+	for _, r := range results {
+		items = append(items, SearchAllResultItem{
+			Kind:      string(r.Kind),
+			ID:        r.ID,
+			Title:     r.Title,
+			Snippet:   r.Snippet,
+			Status:    r.Status,
+			Score:     r.Score,
+			UpdatedAt: r.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+
+	h.Logger.Info("search complete", "results", len(items))
+	return nil, SearchAllOutput{Results: items, Count: len(items)}, nil
+}
+
+// parseNodeKind matches a node kind name case-insensitively.
+func parseNodeKind(s string) (models.NodeKind, error) {
+	for _, kind := range models.NodeKinds {
+		if strings.EqualFold(s, string(kind)) {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("invalid kind: %s (must be one of: memory, plan, task)", s)
+}
@@ -10,35 +10,42 @@ import (
 
 // CreateTaskInput defines the input for the create_task tool.
 type CreateTaskInput struct {
-	Content      string         `json:"content" jsonschema:"required,The content/description of the task"`
-	PlanIDs      []string       `json:"plan_ids" jsonschema:"required,IDs of plans this task belongs to (at least one required, creates PART_OF relationships)"`
-	Status       string         `json:"status,omitempty" jsonschema:"Task status: pending, in_progress, completed, cancelled, blocked (default: pending)"`
-	Metadata     map[string]any `json:"metadata,omitempty" jsonschema:"Key-value metadata to attach to the task"`
-	Tags         []string       `json:"tags,omitempty" jsonschema:"Tags for categorizing the task"`
-	AfterTaskID  *string        `json:"after_task_id,omitempty" jsonschema:"ID of task to position this task after (within each plan). If not specified, appends to end."`
-	BeforeTaskID *string        `json:"before_task_id,omitempty" jsonschema:"ID of task to position this task before (within each plan). Takes precedence for positioning if both after and before are specified."`
-	DependsOn    []string       `json:"depends_on,omitempty" jsonschema:"IDs of tasks this depends on using DEPENDS_ON"`
-	Blocks       []string       `json:"blocks,omitempty" jsonschema:"IDs of tasks this blocks using BLOCKS"`
-	Follows      []string       `json:"follows,omitempty" jsonschema:"IDs of tasks this follows in sequence using FOLLOWS"`
-	RelatedTo    []string       `json:"related_to,omitempty" jsonschema:"IDs of nodes to connect using RELATES_TO"`
-	References   []string       `json:"references,omitempty" jsonschema:"IDs of nodes this references using REFERENCES"`
+	Content       string              `json:"content" jsonschema:"required,The content/description of the task"`
+	PlanIDs       []string            `json:"plan_ids" jsonschema:"required,IDs of plans this task belongs to (at least one required, creates PART_OF relationships)"`
+	Status        string              `json:"status,omitempty" jsonschema:"Task status: pending, in_progress, completed, cancelled, blocked (default: pending)"`
+	Metadata      map[string]any      `json:"metadata,omitempty" jsonschema:"Key-value metadata to attach to the task"`
+	Tags          []string            `json:"tags,omitempty" jsonschema:"Tags for categorizing the task"`
+	AfterTaskID   *string             `json:"after_task_id,omitempty" jsonschema:"ID of task to position this task after (within each plan). If not specified, appends to end."`
+	BeforeTaskID  *string             `json:"before_task_id,omitempty" jsonschema:"ID of task to position this task before (within each plan). Takes precedence for positioning if both after and before are specified."`
+	DependsOn     []string            `json:"depends_on,omitempty" jsonschema:"IDs of tasks this depends on using DEPENDS_ON"`
+	Blocks        []string            `json:"blocks,omitempty" jsonschema:"IDs of tasks this blocks using BLOCKS"`
+	Follows       []string            `json:"follows,omitempty" jsonschema:"IDs of tasks this follows in sequence using FOLLOWS"`
+	RelatedTo     []string            `json:"related_to,omitempty" jsonschema:"IDs of nodes to connect using RELATES_TO"`
+	References    []string            `json:"references,omitempty" jsonschema:"IDs of nodes this references using REFERENCES"`
+	Relationships []RelationshipInput `json:"relationships,omitempty" jsonschema:"Relationships to create, each with to_id and type and an optional reason (why it exists), weight (0-1 confidence) and metadata. Giving an existing relationship updates those details."`
+	BestEffort    bool                `json:"best_effort,omitempty" jsonschema:"If true, relationships that cannot be created (unknown type or target, or one that would close a DEPENDS_ON/BLOCKS cycle) are skipped and reported in relationship_results instead of failing the whole call"`
+	Actor         string              `json:"actor,omitempty" jsonschema:"Who is making the change, recorded in the revision history (default: the MCP client's name)"`
 }
 
 // CreateTaskOutput defines the output for the create_task tool.
 type CreateTaskOutput struct {
-	ID        string            `json:"id"`
-	Content   string            `json:"content"`
-	Status    string            `json:"status"`
-	Metadata  map[string]string `json:"metadata,omitempty"`
-	Tags      []string          `json:"tags,omitempty"`
-	CreatedAt string            `json:"created_at"`
+	ID                  string                 `json:"id"`
+	Content             string                 `json:"content"`
+	Status              string                 `json:"status"`
+	Metadata            map[string]string      `json:"metadata,omitempty"`
+	Tags                []string               `json:"tags,omitempty"`
+	CreatedAt           string                 `json:"created_at"`
+	Version             int64                  `json:"version"`
+	RelationshipResults []RelationshipResult   `json:"relationship_results,omitempty"`
+	StatusChanges       []StatusChangeItem     `json:"status_changes,omitempty"`
+	PlanStatusChanges   []PlanStatusChangeItem `json:"plan_status_changes,omitempty"`
 }
 
 // CreateTaskTool returns the tool definition for create_task.
 func CreateTaskTool() *mcp.Tool {
 	return &mcp.Tool{
 		Name:        "create_task",
-		Description: "Create a new task that belongs to one or more plans. Tasks must be associated with at least one plan via plan_ids. Supports dependencies (depends_on, blocks, follows) and other relationships. Returns the created task with its ID.",
+		Description: "Create a new task that belongs to one or more plans. Tasks must be associated with at least one plan via plan_ids. Supports dependencies (depends_on, blocks, follows) and other relationships. Returns the created task with its ID. When the server runs with status rules on, a pending task that waits for unfinished tasks (through DEPENDS_ON or BLOCKS) is created blocked, and pending tasks it blocks are moved to blocked; status_changes lists the tasks changed that way. When the server runs with plan completion on, a completed plan the task joins unfinished is moved back to active, and an active one completed if all its tasks now are; plan_status_changes lists the plans changed that way.",
 	}
 }
 
@@ -79,8 +86,31 @@ func (h *Handler) HandleCreateTask(ctx context.Context, req *mcp.CallToolRequest
 		input.RelatedTo, nil, input.References,
 		input.DependsOn, input.Blocks, input.Follows, nil,
 	)
+	detailed, err := parseRelationships(input.Relationships)
+	if err != nil {
+		return nil, CreateTaskOutput{}, err
+	}
+	for _, rel := range detailed {
+		if rel.Type == models.RelPartOf {
+			return nil, CreateTaskOutput{}, fmt.Errorf("PART_OF relationships of tasks are set with plan_ids")
+		}
+	}
+	rels = append(rels, detailed...)
+	var results []RelationshipResult
+	if input.BestEffort {
+		rels, results, err = h.bestEffortRelationships(ctx, "", rels)
+		if err != nil {
+			return nil, CreateTaskOutput{}, err
+		}
+	}
 
-	created, err := h.TaskRepo.Add(ctx, task, input.PlanIDs, rels, input.AfterTaskID, input.BeforeTaskID)
+	ctx = withActor(ctx, req, input.Actor)
+	var created *models.Task
+	var changes models.StatusChanges
+	_, err = skipCycles(input.BestEffort, rels, results, func(rels []models.Relationship) (err error) {
+		created, changes, err = h.TaskRepo.Add(ctx, task, input.PlanIDs, rels, input.AfterTaskID, input.BeforeTaskID)
+		return err
+	})
 	if err != nil {
 		h.Logger.Error("create_task failed", "error", err)
 		return nil, CreateTaskOutput{}, fmt.Errorf("failed to create task: %w", err)
@@ -88,11 +118,15 @@ func (h *Handler) HandleCreateTask(ctx context.Context, req *mcp.CallToolRequest
 
 	h.Logger.Info("create_task complete", "id", created.ID, "status", created.Status)
 	return nil, CreateTaskOutput{
-		ID:        created.ID,
-		Content:   created.Content,
-		Status:    string(created.Status),
-		Metadata:  created.Metadata,
-		Tags:      created.Tags,
-		CreatedAt: created.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		ID:                  created.ID,
+		Content:             created.Content,
+		Status:              string(created.Status),
+		Metadata:            created.Metadata,
+		Tags:                created.Tags,
+		CreatedAt:           created.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		Version:             created.Version,
+		RelationshipResults: results,
+		StatusChanges:       toStatusChangeItems(changes),
+		PlanStatusChanges:   toPlanStatusChangeItems(changes),
 	}, nil
 }
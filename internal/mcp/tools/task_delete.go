@@ -9,32 +9,49 @@ import (
 
 // DeleteTaskInput defines the input for the delete_task tool.
 type DeleteTaskInput struct {
-	ID string `json:"id" jsonschema:"required,The ID of the task to delete"`
+	ID     string `json:"id" jsonschema:"required,The ID of the task to delete"`
+	DryRun bool   `json:"dry_run,omitempty" jsonschema:"If true, nothing is deleted and would_delete lists the task and relationships that would be moved to the trash"`
 }
 
 // DeleteTaskOutput defines the output for the delete_task tool.
 type DeleteTaskOutput struct {
-	ID      string `json:"id"`
-	Deleted bool   `json:"deleted"`
+	ID                string                 `json:"id"`
+	Deleted           bool                   `json:"deleted"`
+	DryRun            bool                   `json:"dry_run,omitempty"`
+	WouldDelete       *DeletePreview         `json:"would_delete,omitempty"`
+	StatusChanges     []StatusChangeItem     `json:"status_changes,omitempty"`
+	PlanStatusChanges []PlanStatusChangeItem `json:"plan_status_changes,omitempty"`
 }
 
 // DeleteTaskTool returns the tool definition for delete_task.
 func DeleteTaskTool() *mcp.Tool {
 	return &mcp.Tool{
 		Name:        "delete_task",
-		Description: "Delete a task and all its relationships. This action cannot be undone.",
+		Description: "Delete a task and all its relationships by moving them to the trash, where list_trash shows them until they are purged after the retention period. Use restore to bring them back, position in each plan included. Set dry_run to see what would be removed without deleting anything. When the server runs with status rules on, blocked tasks that waited for this one are moved back to pending once all they still wait for are completed or cancelled; status_changes lists the tasks changed that way. When the server runs with plan completion on, an active plan the task belonged to is moved to completed if all its remaining tasks are completed or cancelled; plan_status_changes lists the plans changed that way.",
 	}
 }
 
 // HandleDeleteTask handles the delete_task tool call.
 func (h *Handler) HandleDeleteTask(ctx context.Context, req *mcp.CallToolRequest, input DeleteTaskInput) (*mcp.CallToolResult, DeleteTaskOutput, error) {
-	h.Logger.Info("delete_task", "id", input.ID)
+	h.Logger.Info("delete_task", "id", input.ID, "dry_run", input.DryRun)
 
 	if input.ID == "" {
 		return nil, DeleteTaskOutput{}, fmt.Errorf("id is required")
 	}
 
-	err := h.TaskRepo.Delete(ctx, input.ID)
+	if input.DryRun {
+		entry, err := h.TaskRepo.PreviewDelete(ctx, input.ID)
+		if err != nil {
+			h.Logger.Error("delete_task preview failed", "id", input.ID, "error", err)
+			return nil, DeleteTaskOutput{}, fmt.Errorf("failed to preview delete: %w", err)
+		}
+		if entry == nil {
+			return nil, DeleteTaskOutput{}, fmt.Errorf("task not found: %s", input.ID)
+		}
+		return nil, DeleteTaskOutput{ID: input.ID, DryRun: true, WouldDelete: toDeletePreview(*entry)}, nil
+	}
+
+	changes, err := h.TaskRepo.Delete(ctx, input.ID)
 	if err != nil {
 		h.Logger.Error("delete_task failed", "id", input.ID, "error", err)
 		return nil, DeleteTaskOutput{}, fmt.Errorf("failed to delete task: %w", err)
@@ -42,7 +59,9 @@ func (h *Handler) HandleDeleteTask(ctx context.Context, req *mcp.CallToolRequest
 
 	h.Logger.Info("delete_task complete", "id", input.ID)
 	return nil, DeleteTaskOutput{
-		ID:      input.ID,
-		Deleted: true,
+		ID:                input.ID,
+		Deleted:           true,
+		StatusChanges:     toStatusChangeItems(changes),
+		PlanStatusChanges: toPlanStatusChangeItems(changes),
 	}, nil
 }
@@ -29,6 +29,7 @@ type GetTaskOutput struct {
 	Plans     []PlanReference   `json:"plans,omitempty"`
 	CreatedAt string            `json:"created_at"`
 	UpdatedAt string            `json:"updated_at"`
+	Version   int64             `json:"version"`
 }
 
 // GetTaskTool returns the tool definition for get_task.
@@ -77,5 +78,6 @@ func (h *Handler) HandleGetTask(ctx context.Context, req *mcp.CallToolRequest, i
 		Plans:     planRefs,
 		CreatedAt: task.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		UpdatedAt: task.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		Version:   task.Version,
 	}, nil
 }
@@ -14,6 +14,7 @@ type ListTasksInput struct {
 	Status string   `json:"status,omitempty" jsonschema:"Filter by status: pending, in_progress, completed, cancelled, blocked"`
 	Tags   []string `json:"tags,omitempty" jsonschema:"Filter by tags (tasks matching any of the tags are returned)"`
 	Limit  int      `json:"limit,omitempty" jsonschema:"Maximum number of tasks to return (default: 50)"`
+	Cursor string   `json:"cursor,omitempty" jsonschema:"next_cursor from the previous page, to fetch the page after it"`
 }
 
 // TaskListSummary contains summary info about a task in list results.
@@ -22,20 +23,23 @@ type TaskListSummary struct {
 	ID       string   `json:"id"`
 	Content  string   `json:"content"`
 	Status   string   `json:"status"`
+	Version  int64    `json:"version"`
 	Position *float64 `json:"position,omitempty"` // Only set when listing within a plan
 }
 
 // ListTasksOutput defines the output for the list_tasks tool.
 type ListTasksOutput struct {
-	Tasks []TaskListSummary `json:"tasks"`
-	Count int               `json:"count"`
+	Tasks      []TaskListSummary `json:"tasks"`
+	Count      int               `json:"count"`
+	Total      int               `json:"total"`
+	NextCursor string            `json:"next_cursor,omitempty"`
 }
 
 // ListTasksTool returns the tool definition for list_tasks.
 func ListTasksTool() *mcp.Tool {
 	return &mcp.Tool{
 		Name:        "list_tasks",
-		Description: "List tasks with optional filtering by plan_id, status, and tags. Returns task summaries ordered by most recently updated.",
+		Description: "List tasks with optional filtering by plan_id, status, and tags. Returns task summaries ordered by most recently updated (by position when filtering by plan_id). total is the number of matching tasks; when more remain, pass next_cursor back as cursor, with the same filters, for the next page.",
 	}
 }
 
@@ -48,7 +52,7 @@ func (h *Handler) HandleListTasks(ctx context.Context, req *mcp.CallToolRequest,
 		return nil, ListTasksOutput{}, fmt.Errorf("invalid status: %s (must be one of: pending, in_progress, completed, cancelled, blocked)", input.Status)
 	}
 
-	tasks, err := h.TaskRepo.List(ctx, input.PlanID, input.Status, input.Tags, input.Limit)
+	tasks, page, err := h.TaskRepo.List(ctx, input.PlanID, input.Status, input.Tags, input.Limit, input.Cursor)
 	if err != nil {
 		h.Logger.Error("list_tasks failed", "error", err)
 		return nil, ListTasksOutput{}, fmt.Errorf("failed to list tasks: %w", err)
@@ -62,13 +66,16 @@ func (h *Handler) HandleListTasks(ctx context.Context, req *mcp.CallToolRequest,
 			ID:       t.Task.ID,
 			Content:  t.Task.Content,
 			Status:   string(t.Task.Status),
+			Version:  t.Task.Version,
 			Position: t.Position,
 		})
 	}
 
 	h.Logger.Info("list_tasks complete", "count", len(summaries))
 	return nil, ListTasksOutput{
-		Tasks: summaries,
-		Count: len(summaries),
+		Tasks:      summaries,
+		Count:      len(summaries),
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
 	}, nil
 }
@@ -0,0 +1,161 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// NextTasksInput defines the input for the next_tasks tool.
+type NextTasksInput struct {
+	PlanID string `json:"plan_id,omitempty" jsonschema:"Plan to pick tasks from (default: all active plans)"`
+}
+
+// NextTaskItem is a task that can be started now.
+type NextTaskItem struct {
+	ID       string   `json:"id"`
+	Content  string   `json:"content"`
+	PlanID   string   `json:"plan_id"`
+	Position float64  `json:"position"`
+	Tags     []string `json:"tags,omitempty"`
+	Version  int64    `json:"version"`
+}
+
+// ExcludedTaskItem is an unfinished task that cannot be started now, and why.
+type ExcludedTaskItem struct {
+	ID        string   `json:"id"`
+	Content   string   `json:"content"`
+	Status    string   `json:"status"`
+	PlanID    string   `json:"plan_id"`
+	Reason    string   `json:"reason"`
+	WaitingOn []string `json:"waiting_on,omitempty"` // IDs of the unfinished tasks it waits for
+}
+
+// NextTasksOutput defines the output for the next_tasks tool.
+type NextTasksOutput struct {
+	Tasks    []NextTaskItem     `json:"tasks"`
+	Excluded []ExcludedTaskItem `json:"excluded"`
+	Count    int                `json:"count"`
+}
+
+// NextTasksTool returns the tool definition for next_tasks.
+func NextTasksTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        "next_tasks",
+		Description: "Find the tasks that can be worked on now: pending tasks whose DEPENDS_ON targets are all completed or cancelled and that no unfinished task (one neither completed nor cancelled) BLOCKS, whichever plans those belong to. Looks at one plan, or at every active plan when plan_id is omitted. Tasks are ordered by plan, then by position; a task in several plans is listed once. The other unfinished tasks (pending, in_progress or blocked) are listed in excluded with the reason they cannot be started and the IDs of the tasks they are waiting on.",
+	}
+}
+
+// HandleNextTasks handles the next_tasks tool call.
+func (h *Handler) HandleNextTasks(ctx context.Context, req *mcp.CallToolRequest, input NextTasksInput) (*mcp.CallToolResult, NextTasksOutput, error) {
+	h.Logger.Info("next_tasks", "plan_id", input.PlanID)
+
+	planIDs := []string{input.PlanID}
+	if input.PlanID == "" {
+		var err error
+		planIDs, err = h.activePlanIDs(ctx)
+		if err != nil {
+			h.Logger.Error("next_tasks failed", "error", err)
+			return nil, NextTasksOutput{}, fmt.Errorf("failed to list plans: %w", err)
+		}
+	}
+
+	// Initialize as empty slices (not nil) to ensure JSON serializes as [] not null
+	output := NextTasksOutput{Tasks: []NextTaskItem{}, Excluded: []ExcludedTaskItem{}}
+	seen := make(map[string]bool)
+	for _, planID := range planIDs {
+		plan, tasks, err := h.PlanRepo.GetWithTasks(ctx, planID)
+		if err != nil {
+			h.Logger.Error("next_tasks failed", "plan_id", planID, "error", err)
+			return nil, NextTasksOutput{}, fmt.Errorf("failed to get plan: %w", err)
+		}
+		if plan == nil {
+			if input.PlanID != "" {
+				return nil, NextTasksOutput{}, fmt.Errorf("plan not found: %s", planID)
+			}
+			continue // Deleted since it was listed
+		}
+		waits, err := h.PlanRepo.Waits(ctx, planID)
+		if err != nil {
+			h.Logger.Error("next_tasks failed", "plan_id", planID, "error", err)
+			return nil, NextTasksOutput{}, fmt.Errorf("failed to get dependencies: %w", err)
+		}
+
+		for _, t := range tasks {
+			if seen[t.Task.ID] {
+				continue
+			}
+			seen[t.Task.ID] = true
+
+			excluded := ExcludedTaskItem{
+				ID:      t.Task.ID,
+				Content: t.Task.Content,
+				Status:  string(t.Task.Status),
+				PlanID:  planID,
+			}
+			switch t.Task.Status {
+			case models.TaskStatusCompleted, models.TaskStatusCancelled:
+				continue
+			case models.TaskStatusInProgress:
+				excluded.Reason = "already in progress"
+			case models.TaskStatusBlocked:
+				excluded.Reason = "status is blocked"
+			}
+			unfinished := models.Unfinished(waits[t.Task.ID])
+			if len(unfinished) > 0 {
+				var reasons []string
+				for _, w := range unfinished {
+					verb := "depends on"
+					if w.Type == models.RelBlocks {
+						verb = "blocked by"
+					}
+					reasons = append(reasons, fmt.Sprintf("%s %s (%s)", verb, w.Task.ID, w.Task.Status))
+					excluded.WaitingOn = append(excluded.WaitingOn, w.Task.ID)
+				}
+				if excluded.Reason == "" {
+					excluded.Reason = strings.Join(reasons, "; ")
+				}
+			}
+			if excluded.Reason != "" {
+				output.Excluded = append(output.Excluded, excluded)
+				continue
+			}
+
+			output.Tasks = append(output.Tasks, NextTaskItem{
+				ID:       t.Task.ID,
+				Content:  t.Task.Content,
+				PlanID:   planID,
+				Position: t.Position,
+				Tags:     t.Task.Tags,
+				Version:  t.Task.Version,
+			})
+		}
+	}
+	output.Count = len(output.Tasks)
+
+	h.Logger.Info("next_tasks complete", "count", output.Count, "excluded", len(output.Excluded))
+	return nil, output, nil
+}
+
+// activePlanIDs returns the IDs of every active plan, most recently updated
+// first.
+func (h *Handler) activePlanIDs(ctx context.Context) ([]string, error) {
+	var ids []string
+	cursor := ""
+	for {
+		plans, page, err := h.PlanRepo.List(ctx, string(models.PlanStatusActive), nil, 0, cursor)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range plans {
+			ids = append(ids, p.ID)
+		}
+		if page.NextCursor == "" {
+			return ids, nil
+		}
+		cursor = page.NextCursor
+	}
+}
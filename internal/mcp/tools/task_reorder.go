@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/Thomas-Fitz/associate/internal/graph"
+	"github.com/Thomas-Fitz/associate/internal/models"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -131,7 +131,7 @@ func (h *Handler) HandleReorderTasks(ctx context.Context, req *mcp.CallToolReque
 	}
 
 	// Calculate new positions for the tasks
-	positions := graph.CalculateInsertPositions(afterPos, beforePos, len(input.TaskIDs))
+	positions := models.CalculateInsertPositions(afterPos, beforePos, len(input.TaskIDs))
 
 	// Build the update map
 	newPositions := make(map[string]float64)
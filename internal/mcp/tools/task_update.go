@@ -10,34 +10,92 @@ import (
 
 // UpdateTaskInput defines the input for the update_task tool.
 type UpdateTaskInput struct {
-	ID         string         `json:"id" jsonschema:"required,The ID of the task to update"`
-	Content    *string        `json:"content,omitempty" jsonschema:"New content for the task"`
-	Status     *string        `json:"status,omitempty" jsonschema:"New status: pending, in_progress, completed, cancelled, blocked"`
-	Metadata   map[string]any `json:"metadata,omitempty" jsonschema:"New metadata (replaces existing)"`
-	Tags       []string       `json:"tags,omitempty" jsonschema:"New tags (replaces existing)"`
-	PlanIDs    []string       `json:"plan_ids,omitempty" jsonschema:"IDs of plans to add this task to (creates PART_OF relationships)"`
-	DependsOn  []string       `json:"depends_on,omitempty" jsonschema:"IDs of tasks to add DEPENDS_ON relationships to"`
-	Blocks     []string       `json:"blocks,omitempty" jsonschema:"IDs of tasks to add BLOCKS relationships to"`
-	Follows    []string       `json:"follows,omitempty" jsonschema:"IDs of tasks to add FOLLOWS relationships to"`
-	RelatedTo  []string       `json:"related_to,omitempty" jsonschema:"IDs of nodes to connect using RELATES_TO"`
-	References []string       `json:"references,omitempty" jsonschema:"IDs of nodes to connect using REFERENCES"`
+	ID              string              `json:"id" jsonschema:"required,The ID of the task to update"`
+	Content         *string             `json:"content,omitempty" jsonschema:"New content for the task"`
+	Status          *string             `json:"status,omitempty" jsonschema:"New status: pending, in_progress, completed, cancelled, blocked"`
+	Metadata        map[string]any      `json:"metadata,omitempty" jsonschema:"New metadata (replaces existing)"`
+	Tags            []string            `json:"tags,omitempty" jsonschema:"New tags (replaces existing)"`
+	PlanIDs         []string            `json:"plan_ids,omitempty" jsonschema:"IDs of plans to add this task to (creates PART_OF relationships)"`
+	DependsOn       []string            `json:"depends_on,omitempty" jsonschema:"IDs of tasks to add DEPENDS_ON relationships to"`
+	Blocks          []string            `json:"blocks,omitempty" jsonschema:"IDs of tasks to add BLOCKS relationships to"`
+	Follows         []string            `json:"follows,omitempty" jsonschema:"IDs of tasks to add FOLLOWS relationships to"`
+	RelatedTo       []string            `json:"related_to,omitempty" jsonschema:"IDs of nodes to connect using RELATES_TO"`
+	References      []string            `json:"references,omitempty" jsonschema:"IDs of nodes to connect using REFERENCES"`
+	Relationships   []RelationshipInput `json:"relationships,omitempty" jsonschema:"Relationships to create, each with to_id and type and an optional reason (why it exists), weight (0-1 confidence) and metadata. Giving an existing relationship updates those details."`
+	BestEffort      bool                `json:"best_effort,omitempty" jsonschema:"If true, relationships that cannot be created (unknown type or target, or one that would close a DEPENDS_ON/BLOCKS cycle) are skipped and reported in relationship_results instead of failing the whole call"`
+	ExpectedVersion int64               `json:"expected_version,omitempty" jsonschema:"The version this update was based on. If the task has changed since, the update fails with a version conflict; get it again and retry"`
+	Actor           string              `json:"actor,omitempty" jsonschema:"Who is making the change, recorded in the revision history (default: the MCP client's name)"`
 }
 
 // UpdateTaskOutput defines the output for the update_task tool.
 type UpdateTaskOutput struct {
-	ID        string            `json:"id"`
-	Content   string            `json:"content"`
-	Status    string            `json:"status"`
-	Metadata  map[string]string `json:"metadata,omitempty"`
-	Tags      []string          `json:"tags,omitempty"`
-	UpdatedAt string            `json:"updated_at"`
+	ID                  string                 `json:"id"`
+	Content             string                 `json:"content"`
+	Status              string                 `json:"status"`
+	Metadata            map[string]string      `json:"metadata,omitempty"`
+	Tags                []string               `json:"tags,omitempty"`
+	UpdatedAt           string                 `json:"updated_at"`
+	Version             int64                  `json:"version"`
+	RelationshipResults []RelationshipResult   `json:"relationship_results,omitempty"`
+	StatusChanges       []StatusChangeItem     `json:"status_changes,omitempty"`
+	PlanStatusChanges   []PlanStatusChangeItem `json:"plan_status_changes,omitempty"`
+}
+
+// StatusChangeItem is a status change the status rules made to a task as a
+// side effect of creating, updating or deleting a task.
+type StatusChangeItem struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Version int64  `json:"version"`
+}
+
+// PlanStatusChangeItem is a status change plan completion made to a plan as a
+// side effect of changing its tasks.
+type PlanStatusChangeItem struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Version int64  `json:"version"`
+}
+
+// toStatusChangeItems converts the task status changes a call made.
+func toStatusChangeItems(changes models.StatusChanges) []StatusChangeItem {
+	var items []StatusChangeItem
+	for _, c := range changes.Tasks {
+		items = append(items, StatusChangeItem{
+			ID:      c.Task.ID,
+			Content: c.Task.Content,
+			From:    string(c.From),
+			To:      string(c.Task.Status),
+			Version: c.Task.Version,
+		})
+	}
+	return items
+}
+
+// toPlanStatusChangeItems converts the plan status changes a call made.
+func toPlanStatusChangeItems(changes models.StatusChanges) []PlanStatusChangeItem {
+	var items []PlanStatusChangeItem
+	for _, c := range changes.Plans {
+		items = append(items, PlanStatusChangeItem{
+			ID:      c.Plan.ID,
+			Name:    c.Plan.Name,
+			From:    string(c.From),
+			To:      string(c.Plan.Status),
+			Version: c.Plan.Version,
+		})
+	}
+	return items
 }
 
 // UpdateTaskTool returns the tool definition for update_task.
 func UpdateTaskTool() *mcp.Tool {
 	return &mcp.Tool{
 		Name:        "update_task",
-		Description: "Update an existing task. Only provided fields are updated. Can update content, status, metadata, tags, add to plans, and add new relationships.",
+		Description: "Update an existing task. Only provided fields are updated. Can update content, status, metadata, tags, add to plans, and add new relationships. Pass the version you read as expected_version to fail instead of overwriting a concurrent change. When the server runs with status rules on, pending tasks are moved to blocked while they wait for unfinished tasks (through DEPENDS_ON or BLOCKS) and back to pending once those are completed or cancelled; status_changes lists the tasks this update changed that way. When the server runs with plan completion on, an active plan is moved to completed once all its tasks are completed or cancelled, and back to active when one is reopened; plan_status_changes lists the plans this update changed that way.",
 	}
 }
 
@@ -69,20 +127,47 @@ func (h *Handler) HandleUpdateTask(ctx context.Context, req *mcp.CallToolRequest
 		input.RelatedTo, nil, input.References,
 		input.DependsOn, input.Blocks, input.Follows, nil,
 	)
+	detailed, err := parseRelationships(input.Relationships)
+	if err != nil {
+		return nil, UpdateTaskOutput{}, err
+	}
+	for _, rel := range detailed {
+		if rel.Type == models.RelPartOf {
+			return nil, UpdateTaskOutput{}, fmt.Errorf("PART_OF relationships of tasks are set with plan_ids")
+		}
+	}
+	rels = append(rels, detailed...)
+	var results []RelationshipResult
+	if input.BestEffort {
+		rels, results, err = h.bestEffortRelationships(ctx, input.ID, rels)
+		if err != nil {
+			return nil, UpdateTaskOutput{}, err
+		}
+	}
 
-	updated, err := h.TaskRepo.Update(ctx, input.ID, input.Content, status, metadata, input.Tags, input.PlanIDs, rels)
+	ctx = withActor(ctx, req, input.Actor)
+	var updated *models.Task
+	var changes models.StatusChanges
+	_, err = skipCycles(input.BestEffort, rels, results, func(rels []models.Relationship) (err error) {
+		updated, changes, err = h.TaskRepo.Update(ctx, input.ID, input.ExpectedVersion, input.Content, status, metadata, input.Tags, input.PlanIDs, rels)
+		return err
+	})
 	if err != nil {
 		h.Logger.Error("update_task failed", "id", input.ID, "error", err)
 		return nil, UpdateTaskOutput{}, fmt.Errorf("failed to update task: %w", err)
 	}
 
-	h.Logger.Info("update_task complete", "id", updated.ID, "status", updated.Status)
+	h.Logger.Info("update_task complete", "id", updated.ID, "status", updated.Status, "status_changes", len(changes.Tasks), "plan_status_changes", len(changes.Plans))
 	return nil, UpdateTaskOutput{
-		ID:        updated.ID,
-		Content:   updated.Content,
-		Status:    string(updated.Status),
-		Metadata:  updated.Metadata,
-		Tags:      updated.Tags,
-		UpdatedAt: updated.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		ID:                  updated.ID,
+		Content:             updated.Content,
+		Status:              string(updated.Status),
+		Metadata:            updated.Metadata,
+		Tags:                updated.Tags,
+		UpdatedAt:           updated.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		Version:             updated.Version,
+		RelationshipResults: results,
+		StatusChanges:       toStatusChangeItems(changes),
+		PlanStatusChanges:   toPlanStatusChangeItems(changes),
 	}, nil
 }
@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ListTrashInput defines the input for the list_trash tool.
+type ListTrashInput struct{}
+
+// TrashedNodeItem is one of the nodes a delete moved to the trash.
+type TrashedNodeItem struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Title string `json:"title"`
+}
+
+// TrashItem is what one delete moved to the trash.
+type TrashItem struct {
+	ID            string            `json:"id"`
+	Kind          string            `json:"kind"`
+	Title         string            `json:"title"`
+	DeletedAt     string            `json:"deleted_at"`
+	Nodes         []TrashedNodeItem `json:"nodes"`
+	Relationships int               `json:"relationships"`
+}
+
+// DeletePreview is what a delete would move to the trash, for dry runs.
+type DeletePreview struct {
+	Nodes         []TrashedNodeItem `json:"nodes"`
+	Relationships []EdgeItem        `json:"relationships"`
+}
+
+// ListTrashOutput defines the output for the list_trash tool.
+type ListTrashOutput struct {
+	Items []TrashItem `json:"items"`
+	Count int         `json:"count"`
+}
+
+// ListTrashTool returns the tool definition for list_trash.
+func ListTrashTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        "list_trash",
+		Description: "List deleted memories, plans, and tasks, most recently deleted first. Each item has the id, kind and title of the deleted node, deleted_at, nodes (the node itself followed by any tasks deleted along with a plan), and the number of relationships that were removed with them. Pass an item's id to restore to bring it back. Items are purged permanently once the retention period has passed.",
+	}
+}
+
+// HandleListTrash handles the list_trash tool call.
+func (h *Handler) HandleListTrash(ctx context.Context, req *mcp.CallToolRequest, input ListTrashInput) (*mcp.CallToolResult, ListTrashOutput, error) {
+	h.Logger.Info("list_trash")
+
+	entries, err := h.Repo.ListTrash(ctx)
+	if err != nil {
+		h.Logger.Error("list_trash failed", "error", err)
+		return nil, ListTrashOutput{}, fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	// Initialize as empty slice (not nil) to ensure JSON serializes as [] not null
+	items := make([]TrashItem, 0, len(entries))
+	for _, entry := range entries {
+		items = append(items, toTrashItem(entry))
+	}
+
+	h.Logger.Info("list_trash complete", "results", len(items))
+	return nil, ListTrashOutput{Items: items, Count: len(items)}, nil
+}
+
+func toTrashItem(entry models.TrashEntry) TrashItem {
+	return TrashItem{
+		ID:            entry.ID,
+		Kind:          string(entry.Kind),
+		Title:         entry.Title,
+		DeletedAt:     entry.DeletedAt.Format("2006-01-02T15:04:05Z"),
+		Nodes:         toTrashedNodeItems(entry.Nodes),
+		Relationships: len(entry.Edges),
+	}
+}
+
+func toDeletePreview(entry models.TrashEntry) *DeletePreview {
+	return &DeletePreview{Nodes: toTrashedNodeItems(entry.Nodes), Relationships: toEdgeItems(entry.Edges)}
+}
+
+func toTrashedNodeItems(nodes []models.TrashedNode) []TrashedNodeItem {
+	items := make([]TrashedNodeItem, 0, len(nodes))
+	for _, n := range nodes {
+		items = append(items, TrashedNodeItem{ID: n.ID(), Kind: string(n.Kind), Title: n.Title()})
+	}
+	return items
+}
@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// RestoreInput defines the input for the restore tool.
+type RestoreInput struct {
+	ID string `json:"id" jsonschema:"required,The ID of the deleted memory, plan, or task, as listed by list_trash"`
+}
+
+// RestoreOutput defines the output for the restore tool.
+type RestoreOutput struct {
+	ID       string            `json:"id"`
+	Kind     string            `json:"kind"`
+	Restored []TrashedNodeItem `json:"restored"`
+	// Relationships not restored because a node at their other end was purged
+	SkippedRelationships []EdgeItem `json:"skipped_relationships"`
+}
+
+// RestoreTool returns the tool definition for restore.
+func RestoreTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        "restore",
+		Description: "Restore a deleted memory, plan, or task from the trash by id. Brings back the node as it was, with its version and revision history, along with any tasks deleted with a plan and their relationships, including each task's position in its plans. Relationships to nodes that are still in the trash come back when those are restored; those to nodes purged from the trash cannot be restored and are listed as skipped. Fails if the id is not in the trash. Returns the id, kind, the nodes restored, and the skipped relationships.",
+	}
+}
+
+// HandleRestore handles the restore tool call.
+func (h *Handler) HandleRestore(ctx context.Context, req *mcp.CallToolRequest, input RestoreInput) (*mcp.CallToolResult, RestoreOutput, error) {
+	h.Logger.Info("restore", "id", input.ID)
+
+	if input.ID == "" {
+		return nil, RestoreOutput{}, fmt.Errorf("id is required")
+	}
+
+	entry, err := h.Repo.Restore(ctx, input.ID)
+	if err != nil {
+		h.Logger.Error("restore failed", "id", input.ID, "error", err)
+		return nil, RestoreOutput{}, fmt.Errorf("failed to restore: %w", err)
+	}
+
+	item := toTrashItem(*entry)
+	h.Logger.Info("restore complete", "id", input.ID, "nodes", len(item.Nodes), "skipped_relationships", len(entry.SkippedEdges))
+	return nil, RestoreOutput{ID: item.ID, Kind: item.Kind, Restored: item.Nodes, SkippedRelationships: toEdgeItems(entry.SkippedEdges)}, nil
+}
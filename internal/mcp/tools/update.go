@@ -4,6 +4,7 @@ import (
 "context"
 "fmt"
 
+"github.com/Thomas-Fitz/associate/internal/models"
 "github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -20,6 +21,10 @@ DependsOn  []string       `json:"depends_on,omitempty" jsonschema:"IDs of memori
 Blocks     []string       `json:"blocks,omitempty" jsonschema:"IDs of memories to connect using BLOCKS"`
 Follows    []string       `json:"follows,omitempty" jsonschema:"IDs of memories to connect using FOLLOWS"`
 Implements []string       `json:"implements,omitempty" jsonschema:"IDs of memories to connect using IMPLEMENTS"`
+Relationships []RelationshipInput `json:"relationships,omitempty" jsonschema:"Relationships to create, each with to_id and type and an optional reason (why it exists), weight (0-1 confidence) and metadata. Giving an existing relationship updates those details."`
+BestEffort bool `json:"best_effort,omitempty" jsonschema:"If true, relationships that cannot be created (unknown type or target, or one that would close a DEPENDS_ON/BLOCKS cycle) are skipped and reported in relationship_results instead of failing the whole call"`
+ExpectedVersion int64 `json:"expected_version,omitempty" jsonschema:"The version this update was based on. If the memory has changed since, the update fails with a version conflict; get it again and retry"`
+Actor string `json:"actor,omitempty" jsonschema:"Who is making the change, recorded in the revision history (default: the MCP client's name)"`
 }
 
 // UpdateOutput defines the output for the update tool.
@@ -30,13 +35,15 @@ Content   string            `json:"content"`
 Metadata  map[string]string `json:"metadata,omitempty"`
 Tags      []string          `json:"tags,omitempty"`
 UpdatedAt string            `json:"updated_at"`
+Version   int64             `json:"version"`
+RelationshipResults []RelationshipResult `json:"relationship_results,omitempty"`
 }
 
 // UpdateTool returns the tool definition for update_memory.
 func UpdateTool() *mcp.Tool {
 return &mcp.Tool{
 Name:        "update_memory",
-Description: "Update an existing memory by id (string). Modify content (string), metadata (json), or tags (array). Add new relationships by passing arrays of existing memory IDs to: \"related_to\", \"part_of\", \"references\", \"depends_on\", \"blocks\", \"follows\", or \"implements\". Returns updated memory with updated_at timestamp.",
+Description: "Update an existing memory by id (string). Modify content (string), metadata (json), or tags (array). Add new relationships by passing arrays of existing memory IDs to: \"related_to\", \"part_of\", \"references\", \"depends_on\", \"blocks\", \"follows\", or \"implements\". Returns updated memory with updated_at timestamp and its new version. Pass the version you read as expected_version to fail instead of overwriting a concurrent change.",
 }
 }
 
@@ -55,8 +62,25 @@ rels := buildRelationships(
 input.RelatedTo, input.PartOf, input.References,
 input.DependsOn, input.Blocks, input.Follows, input.Implements,
 )
+detailed, err := parseRelationships(input.Relationships)
+if err != nil {
+return nil, UpdateOutput{}, err
+}
+rels = append(rels, detailed...)
+var results []RelationshipResult
+if input.BestEffort {
+rels, results, err = h.bestEffortRelationships(ctx, input.ID, rels)
+if err != nil {
+return nil, UpdateOutput{}, err
+}
+}
 
-updated, err := h.Repo.Update(ctx, input.ID, input.Content, metadata, input.Tags, rels)
+ctx = withActor(ctx, req, input.Actor)
+var updated *models.Memory
+rels, err = skipCycles(input.BestEffort, rels, results, func(rels []models.Relationship) (err error) {
+updated, err = h.Repo.Update(ctx, input.ID, input.ExpectedVersion, input.Content, metadata, input.Tags, rels)
+return err
+})
 if err != nil {
 h.Logger.Error("update_memory failed", "id", input.ID, "error", err)
 return nil, UpdateOutput{}, fmt.Errorf("failed to update memory: %w", err)
@@ -70,5 +94,7 @@ Content:   updated.Content,
 Metadata:  updated.Metadata,
 Tags:      updated.Tags,
 UpdatedAt: updated.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+Version:   updated.Version,
+RelationshipResults: results,
 }, nil
 }
@@ -0,0 +1,325 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/embedding"
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.PlanStore = (*PlanRepository)(nil)
+
+// PlanRepository provides CRUD operations for plans.
+type PlanRepository struct {
+	store *Store
+}
+
+// NewPlanRepository creates a new plan repository backed by s
+func NewPlanRepository(s *Store) *PlanRepository {
+	return &PlanRepository{store: s}
+}
+
+// Add creates a new plan and optional relationships
+func (r *PlanRepository) Add(ctx context.Context, plan models.Plan, relationships []models.Relationship) (*models.Plan, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if plan.ID == "" {
+		plan.ID = uuid.New().String()
+	}
+	if _, exists := r.store.nodes[plan.ID]; exists {
+		return nil, fmt.Errorf("failed to create plan: node already exists: %s", plan.ID)
+	}
+	now := time.Now().UTC()
+	plan.CreatedAt = now
+	plan.UpdatedAt = now
+	plan.Version = 1
+
+	if plan.Status == "" {
+		plan.Status = models.PlanStatusActive
+	}
+	if err := r.store.checkRelationships(plan.ID, relationships); err != nil {
+		return nil, err
+	}
+
+	plan = clonePlan(plan)
+	n := &node{label: labelPlan, plan: plan}
+	r.store.embed(ctx, n, embedding.PlanText(plan.Name, plan.Description))
+	r.store.insert(plan.ID, n)
+	n.recordRevision(store.Actor(ctx))
+
+	for _, rel := range relationships {
+		if err := r.store.createRelationship(plan.ID, rel); err != nil {
+			return nil, err
+		}
+	}
+
+	result := clonePlan(plan)
+	return &result, nil
+}
+
+// GetByID retrieves a plan by ID
+func (r *PlanRepository) GetByID(ctx context.Context, id string) (*models.Plan, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	n := r.store.lookup(id, labelPlan)
+	if n == nil {
+		return nil, nil // Not found
+	}
+	plan := clonePlan(n.plan)
+	return &plan, nil
+}
+
+// GetWithTasks retrieves a plan by ID along with all its tasks ordered by position.
+func (r *PlanRepository) GetWithTasks(ctx context.Context, id string) (*models.Plan, []models.TaskInPlan, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	n := r.store.lookup(id, labelPlan)
+	if n == nil {
+		return nil, nil, nil // Not found
+	}
+	plan := clonePlan(n.plan)
+
+	partOf := r.store.planTasks(id)
+	inPlan := make(map[string]bool, len(partOf))
+	for _, e := range partOf {
+		inPlan[e.from] = true
+	}
+
+	var tasks []models.TaskInPlan
+	for _, e := range partOf {
+		t := r.store.nodes[e.from]
+		tip := models.TaskInPlan{
+			Task:     cloneTask(t.task),
+			Position: e.position,
+		}
+		for _, dep := range r.store.edges {
+			if dep.from != e.from || !inPlan[dep.to] {
+				continue
+			}
+			switch dep.relType {
+			case models.RelDependsOn:
+				tip.DependsOn = append(tip.DependsOn, dep.to)
+			case models.RelBlocks:
+				tip.Blocks = append(tip.Blocks, dep.to)
+			}
+		}
+		tasks = append(tasks, tip)
+	}
+
+	return &plan, tasks, nil
+}
+
+// Update modifies an existing plan
+func (r *PlanRepository) Update(ctx context.Context, id string, expectedVersion int64, name *string, description *string, status *string, metadata map[string]string, tags []string, newRelationships []models.Relationship) (*models.Plan, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	n := r.store.lookup(id, labelPlan)
+	if n == nil {
+		return nil, fmt.Errorf("plan not found: %s", id)
+	}
+	if err := models.CheckVersion(id, expectedVersion, n.plan.Version); err != nil {
+		return nil, err
+	}
+	if err := r.store.checkRelationships(id, newRelationships); err != nil {
+		return nil, err
+	}
+
+	n.plan.UpdatedAt = time.Now().UTC()
+	n.plan.Version++
+	if name != nil {
+		n.plan.Name = *name
+	}
+	if description != nil {
+		n.plan.Description = *description
+	}
+	if name != nil || description != nil {
+		r.store.embed(ctx, n, embedding.PlanText(n.plan.Name, n.plan.Description))
+	}
+	if status != nil {
+		n.plan.Status = models.PlanStatus(*status)
+	}
+	if metadata != nil {
+		n.plan.Metadata = cloneMetadata(metadata)
+	}
+	if tags != nil {
+		n.plan.Tags = cloneTags(tags)
+	}
+
+	n.recordRevision(store.Actor(ctx))
+
+	for _, rel := range newRelationships {
+		if err := r.store.createRelationship(id, rel); err != nil {
+			return nil, err
+		}
+	}
+
+	result := clonePlan(n.plan)
+	return &result, nil
+}
+
+// Delete moves a plan to the trash and cascades to tasks not linked to other
+// plans, unless the options keep them.
+func (r *PlanRepository) Delete(ctx context.Context, id string, opts models.PlanDeleteOptions) (int, models.StatusChanges, error) {
+	if err := opts.Validate(id); err != nil {
+		return 0, models.StatusChanges{}, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.store.lookup(id, labelPlan) == nil {
+		return 0, models.StatusChanges{}, nil
+	}
+	ids, kept, err := r.deletion(id, opts)
+	if err != nil {
+		return 0, models.StatusChanges{}, err
+	}
+
+	tasks := NewTaskRepository(r.store)
+	if opts.OrphanTasks == models.OrphanTasksMove {
+		for _, taskID := range kept {
+			tasks.setPlanPosition(taskID, opts.TargetPlanID, tasks.getMaxPosition(opts.TargetPlanID)+models.DefaultPositionIncrement)
+		}
+	}
+	var changes models.StatusChanges
+	if changes.Tasks, err = tasks.trashNodes(ctx, ids...); err != nil {
+		return 0, models.StatusChanges{}, err
+	}
+	if r.store.planCompletion && opts.OrphanTasks == models.OrphanTasksMove && len(kept) > 0 {
+		changes.Plans = tasks.applyPlanCompletion(ctx, []string{opts.TargetPlanID})
+	}
+	return len(ids) - 1, changes, nil
+}
+
+// PreviewDelete returns what Delete would move to the trash
+func (r *PlanRepository) PreviewDelete(ctx context.Context, id string, opts models.PlanDeleteOptions) (*models.TrashEntry, error) {
+	if err := opts.Validate(id); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if r.store.lookup(id, labelPlan) == nil {
+		return nil, nil
+	}
+	ids, _, err := r.deletion(id, opts)
+	if err != nil {
+		return nil, err
+	}
+	entry := r.store.trashEntry(ids...)
+	return &entry, nil
+}
+
+// DependencyCycles returns the dependency cycles the plan's tasks are on or wait on
+func (r *PlanRepository) DependencyCycles(ctx context.Context, id string) ([][]string, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if r.store.lookup(id, labelPlan) == nil {
+		return nil, fmt.Errorf("plan not found: %s", id)
+	}
+	var ids []string
+	for _, e := range r.store.planTasks(id) {
+		ids = append(ids, e.from)
+	}
+	return store.FindCycles(ctx, ids, r.store.dependencyEdges(nil))
+}
+
+// Waits returns the tasks that each of the plan's tasks waits for
+func (r *PlanRepository) Waits(ctx context.Context, id string) (map[string][]models.Wait, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if r.store.lookup(id, labelPlan) == nil {
+		return nil, fmt.Errorf("plan not found: %s", id)
+	}
+	var ids []string
+	for _, e := range r.store.planTasks(id) {
+		ids = append(ids, e.from)
+	}
+	return r.store.waits(ids), nil
+}
+
+// Progress returns the progress of each of the given plans
+func (r *PlanRepository) Progress(ctx context.Context, ids []string) (map[string]models.PlanProgress, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.planProgress(ids), nil
+}
+
+// deletion returns the IDs of the nodes deleting a plan moves to the trash,
+// the plan first, and of the tasks belonging to no other plan that the options
+// keep, in position order. Callers must hold a lock.
+func (r *PlanRepository) deletion(id string, opts models.PlanDeleteOptions) (trashed, kept []string, err error) {
+	if opts.OrphanTasks == models.OrphanTasksMove && r.store.lookup(opts.TargetPlanID, labelPlan) == nil {
+		return nil, nil, fmt.Errorf("target plan not found: %s", opts.TargetPlanID)
+	}
+
+	var orphans []string
+	for _, e := range r.store.planTasks(id) {
+		hasOther := false
+		for _, other := range r.store.edges {
+			if other.from == e.from && other.relType == models.RelPartOf && other.to != id && r.store.lookup(other.to, labelPlan) != nil {
+				hasOther = true
+				break
+			}
+		}
+		if !hasOther {
+			orphans = append(orphans, e.from)
+		}
+	}
+
+	if opts.OrphanTasks == models.OrphanTasksDetach || opts.OrphanTasks == models.OrphanTasksMove {
+		return []string{id}, orphans, nil
+	}
+	return append([]string{id}, orphans...), nil, nil
+}
+
+// List retrieves plans with optional filtering
+func (r *PlanRepository) List(ctx context.Context, status string, tags []string, limit int, cursor string) ([]models.Plan, models.Page, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	c, err := models.DecodeListCursor(cursor)
+	if err != nil {
+		return nil, models.Page{}, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var matched []models.Plan
+	for _, n := range r.store.sortedNodes(labelPlan) {
+		if status != "" && string(n.plan.Status) != status {
+			continue
+		}
+		if len(tags) > 0 && !hasAnyTag(n.plan.Tags, tags) {
+			continue
+		}
+		matched = append(matched, n.plan)
+	}
+	keyOf := func(p models.Plan) models.Cursor {
+		return models.Cursor{UpdatedAt: p.UpdatedAt, ID: p.ID}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return byRecency(keyOf(matched[j]), keyOf(matched[i]))
+	})
+
+	page, info := listPage(matched, c, limit, keyOf, byRecency)
+	var plans []models.Plan
+	for _, p := range page {
+		plans = append(plans, clonePlan(p))
+	}
+	return plans, info, nil
+}
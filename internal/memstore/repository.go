@@ -0,0 +1,564 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/embedding"
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/ranking"
+	"github.com/Thomas-Fitz/associate/internal/store"
+	"github.com/Thomas-Fitz/associate/internal/textsearch"
+	"github.com/google/uuid"
+)
+
+var _ store.MemoryStore = (*Repository)(nil)
+
+// Repository provides CRUD operations for memories.
+type Repository struct {
+	store *Store
+}
+
+// NewRepository creates a new memory repository backed by s
+func NewRepository(s *Store) *Repository {
+	return &Repository{store: s}
+}
+
+// searchRelationTypes are the edge types followed when collecting related IDs for search hits.
+var searchRelationTypes = map[models.RelationType]bool{
+	models.RelRelatesTo:  true,
+	models.RelPartOf:     true,
+	models.RelReferences: true,
+	models.RelDependsOn:  true,
+	models.RelBlocks:     true,
+	models.RelFollows:    true,
+	models.RelImplements: true,
+}
+
+// Search ranks memories against the query and returns the best matches,
+// highest score first. Text mode ranks with internal/textsearch; semantic
+// mode ranks by cosine similarity of embeddings; hybrid mode re-ranks text
+// matches with graph proximity and recency. Only memories passing the
+// filter are scored.
+func (r *Repository) Search(ctx context.Context, query string, opts models.SearchOptions) ([]models.SearchResult, models.Page, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, models.Page{}, err
+	}
+	opts = opts.WithDefaults(query)
+	if models.ListOnly(query) && opts.Filter.Empty() && opts.AnchorID == "" {
+		return nil, models.Page{}, nil
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	q := textsearch.Parse(query)
+	var hits []searchHit
+	switch {
+	case models.ListOnly(query):
+		for _, n := range r.store.sortedNodes(labelMemory) {
+			if opts.Filter.Matches(n.memory) {
+				hits = append(hits, searchHit{node: n})
+			}
+		}
+	case opts.Mode == models.SearchModeSemantic:
+		var err error
+		if hits, err = r.semanticHits(ctx, query, opts.Filter); err != nil {
+			return nil, models.Page{}, err
+		}
+	default:
+		for _, n := range r.store.sortedNodes(labelMemory) {
+			if !opts.Filter.Matches(n.memory) {
+				continue
+			}
+			if score, ok := textsearch.Rank(q, n.memory.Content); ok {
+				hits = append(hits, searchHit{node: n, score: score})
+			}
+		}
+	}
+	if opts.Mode == models.SearchModeHybrid {
+		if err := r.hybridScores(hits, opts.AnchorID); err != nil {
+			return nil, models.Page{}, err
+		}
+	}
+
+	results := make([]models.SearchResult, len(hits))
+	for i, h := range hits {
+		results[i] = models.SearchResult{Memory: cloneMemory(h.node.memory), Score: h.score}
+	}
+	models.SortResults(results, opts.Sort, opts.Order)
+	results, page := models.Paginate(results, opts.Offset(), opts.Limit)
+
+	for i := range results {
+		results[i].Snippet = textsearch.Snippet(q, results[i].Memory.Content)
+
+		id := results[i].Memory.ID
+		seen := make(map[string]bool)
+		for _, e := range r.store.edges {
+			if !searchRelationTypes[e.relType] {
+				continue
+			}
+			var otherID string
+			switch id {
+			case e.from:
+				otherID = e.to
+			case e.to:
+				otherID = e.from
+			default:
+				continue
+			}
+			if r.store.lookup(otherID, labelMemory) != nil && !seen[otherID] {
+				seen[otherID] = true
+				results[i].Related = append(results[i].Related, otherID)
+			}
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, page, nil
+	}
+	return results, page, nil
+}
+
+// searchHit is a memory and its relevance to a query.
+type searchHit struct {
+	node  *node
+	score float64
+}
+
+// hybridScores replaces the text score of each hit with its ranking.Score,
+// counting links of every type and measuring hops from the anchor, if any.
+// Callers must hold a lock.
+func (r *Repository) hybridScores(hits []searchHit, anchorID string) error {
+	neighbours := make(map[string][]string)
+	for _, e := range r.store.edges {
+		neighbours[e.from] = append(neighbours[e.from], e.to)
+		neighbours[e.to] = append(neighbours[e.to], e.from)
+	}
+
+	hops := make(map[string]int)
+	if anchorID != "" {
+		if r.store.nodes[anchorID] == nil {
+			return fmt.Errorf("anchor not found: %s", anchorID)
+		}
+		hops[anchorID] = 1
+		for _, id := range neighbours[anchorID] {
+			hops[id] = 1
+		}
+		for _, id := range neighbours[anchorID] {
+			for _, next := range neighbours[id] {
+				if hops[next] == 0 {
+					hops[next] = 2
+				}
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	for i, h := range hits {
+		id := h.node.memory.ID
+		hits[i].score = ranking.Score(ranking.Features{
+			Text:       h.score,
+			Links:      len(neighbours[id]),
+			AnchorHops: hops[id],
+			UpdatedAt:  h.node.memory.UpdatedAt,
+		}, now)
+	}
+	return nil
+}
+
+// semanticHits scores every memory passing the filter by the cosine similarity of its embedding
+// to the query's, keeping positive scores. Memories without a vector from the
+// current model are skipped. Callers must hold a lock.
+func (r *Repository) semanticHits(ctx context.Context, query string, filter models.SearchFilter) ([]searchHit, error) {
+	queryVec, err := r.store.embedder.Embed(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	var hits []searchHit
+	for _, n := range r.store.sortedNodes(labelMemory) {
+		if !filter.Matches(n.memory) {
+			continue
+		}
+		vec := r.store.vectorFor(n)
+		if vec == nil {
+			continue
+		}
+		if score := embedding.Cosine(queryVec, vec); score > 0 {
+			hits = append(hits, searchHit{node: n, score: score})
+		}
+	}
+	return hits, nil
+}
+
+// SearchAll ranks memories, plans and tasks of the requested kinds against
+// the query and returns the best matches, highest score first. Plans are
+// matched on their name and description.
+func (r *Repository) SearchAll(ctx context.Context, query string, opts models.NodeSearchOptions) ([]models.NodeSearchResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts = opts.WithDefaults()
+	if models.ListOnly(query) {
+		return nil, nil
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var queryVec []float32
+	if opts.Mode == models.SearchModeSemantic {
+		var err error
+		if queryVec, err = r.store.embedder.Embed(ctx, query); err != nil {
+			return nil, fmt.Errorf("failed to embed query: %w", err)
+		}
+	}
+
+	q := textsearch.Parse(query)
+	var results []models.NodeSearchResult
+	for _, kind := range opts.Kinds {
+		for _, n := range r.store.sortedNodes(string(kind)) {
+			var score float64
+			var ok bool
+			if queryVec != nil {
+				if vec := r.store.vectorFor(n); vec != nil {
+					score = embedding.Cosine(queryVec, vec)
+					ok = score > 0
+				}
+			} else {
+				score, ok = textsearch.Rank(q, n.text())
+			}
+			if ok {
+				results = append(results, n.toNodeResult(score))
+			}
+		}
+	}
+
+	models.SortNodeResults(results)
+	if len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+	for i := range results {
+		results[i].Snippet = textsearch.Snippet(q, r.store.nodes[results[i].ID].text())
+	}
+	return results, nil
+}
+
+// Add creates a new memory and optional relationships
+func (r *Repository) Add(ctx context.Context, mem models.Memory, relationships []models.Relationship) (*models.Memory, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if mem.ID == "" {
+		mem.ID = uuid.New().String()
+	}
+	if _, exists := r.store.nodes[mem.ID]; exists {
+		return nil, fmt.Errorf("failed to create memory: node already exists: %s", mem.ID)
+	}
+	now := time.Now().UTC()
+	mem.CreatedAt = now
+	mem.UpdatedAt = now
+	mem.Version = 1
+
+	if mem.Type == "" {
+		mem.Type = models.TypeGeneral
+	}
+	if err := r.store.checkRelationships(mem.ID, relationships); err != nil {
+		return nil, err
+	}
+
+	mem = cloneMemory(mem)
+	n := &node{label: labelMemory, memory: mem}
+	r.store.embed(ctx, n, mem.Content)
+	r.store.insert(mem.ID, n)
+	n.recordRevision(store.Actor(ctx))
+
+	for _, rel := range relationships {
+		if err := r.store.createRelationship(mem.ID, rel); err != nil {
+			return nil, err
+		}
+	}
+
+	result := cloneMemory(mem)
+	return &result, nil
+}
+
+// Update modifies an existing memory
+func (r *Repository) Update(ctx context.Context, id string, expectedVersion int64, content *string, metadata map[string]string, tags []string, newRelationships []models.Relationship) (*models.Memory, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	n := r.store.lookup(id, labelMemory)
+	if n == nil {
+		return nil, fmt.Errorf("memory not found: %s", id)
+	}
+	if err := models.CheckVersion(id, expectedVersion, n.memory.Version); err != nil {
+		return nil, err
+	}
+	if err := r.store.checkRelationships(id, newRelationships); err != nil {
+		return nil, err
+	}
+
+	n.memory.UpdatedAt = time.Now().UTC()
+	n.memory.Version++
+	if content != nil {
+		n.memory.Content = *content
+		r.store.embed(ctx, n, n.memory.Content)
+	}
+	if metadata != nil {
+		n.memory.Metadata = cloneMetadata(metadata)
+	}
+	if tags != nil {
+		n.memory.Tags = cloneTags(tags)
+	}
+
+	n.recordRevision(store.Actor(ctx))
+
+	for _, rel := range newRelationships {
+		if err := r.store.createRelationship(id, rel); err != nil {
+			return nil, err
+		}
+	}
+
+	result := cloneMemory(n.memory)
+	return &result, nil
+}
+
+// GetByID retrieves a memory by ID
+func (r *Repository) GetByID(ctx context.Context, id string) (*models.Memory, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	n := r.store.lookup(id, labelMemory)
+	if n == nil {
+		return nil, nil // Not found
+	}
+	mem := cloneMemory(n.memory)
+	return &mem, nil
+}
+
+// GetByIDWithRelated retrieves a memory by ID along with its direct relationships to other memories
+func (r *Repository) GetByIDWithRelated(ctx context.Context, id string) (*models.Memory, []models.RelatedInfo, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	n := r.store.lookup(id, labelMemory)
+	if n == nil {
+		return nil, nil, nil // Not found
+	}
+	mem := cloneMemory(n.memory)
+
+	var related []models.RelatedInfo
+	for _, e := range r.store.edges {
+		if e.from != id {
+			continue
+		}
+		if out := r.store.lookup(e.to, labelMemory); out != nil {
+			related = append(related, models.RelatedInfo{
+				ID:                     out.memory.ID,
+				Type:                   out.memory.Type,
+				RelationType:           string(e.relType),
+				Direction:              "outgoing",
+				RelationshipProperties: e.properties(),
+			})
+		}
+	}
+	for _, e := range r.store.edges {
+		if e.to != id {
+			continue
+		}
+		if inc := r.store.lookup(e.from, labelMemory); inc != nil {
+			related = append(related, models.RelatedInfo{
+				ID:                     inc.memory.ID,
+				Type:                   inc.memory.Type,
+				RelationType:           string(e.relType),
+				Direction:              "incoming",
+				RelationshipProperties: e.properties(),
+			})
+		}
+	}
+
+	return &mem, related, nil
+}
+
+// Delete moves a memory and all its relationships to the trash
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.store.lookup(id, labelMemory) != nil {
+		r.store.trashNodes(id)
+	}
+	return nil
+}
+
+// PreviewDelete returns what Delete would move to the trash
+func (r *Repository) PreviewDelete(ctx context.Context, id string) (*models.TrashEntry, error) {
+	return r.store.previewDelete(id, labelMemory), nil
+}
+
+// GetRelated retrieves nodes related to the given ID with optional filtering,
+// expanding breadth-first up to depth hops.
+func (r *Repository) GetRelated(ctx context.Context, id string, relationType string, direction string, depth int) ([]models.RelatedMemoryResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return store.Traverse(ctx, id, depth, func(ctx context.Context, frontier []string) ([]store.Step, error) {
+		onFrontier := make(map[string]bool, len(frontier))
+		for _, id := range frontier {
+			onFrontier[id] = true
+		}
+
+		var steps []store.Step
+		for _, e := range r.store.edges {
+			if relationType != "" && string(e.relType) != relationType {
+				continue
+			}
+			edge := e.relationship()
+			if onFrontier[e.from] && direction != "incoming" {
+				if other, ok := r.store.nodes[e.to]; ok {
+					steps = append(steps, store.Step{Edge: edge, Node: other.toRelatedMemory()})
+				}
+			}
+			if onFrontier[e.to] && direction != "outgoing" {
+				if other, ok := r.store.nodes[e.from]; ok {
+					steps = append(steps, store.Step{Edge: edge, Node: other.toRelatedMemory()})
+				}
+			}
+		}
+		return steps, nil
+	})
+}
+
+// ListRelationships lists the relationships of a node, oldest first.
+func (r *Repository) ListRelationships(ctx context.Context, id string, relationType string, direction string) ([]models.Edge, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if _, ok := r.store.nodes[id]; !ok {
+		return nil, fmt.Errorf("node not found: %s", id)
+	}
+
+	var edges []models.Edge
+	for _, e := range r.store.edges {
+		if relationType != "" && string(e.relType) != relationType {
+			continue
+		}
+		if (e.from == id && direction != "incoming") || (e.to == id && direction != "outgoing") {
+			edges = append(edges, r.store.toEdge(e))
+		}
+	}
+	return edges, nil
+}
+
+// DeleteRelationship removes the relationship of the given type from one node
+// to another.
+func (r *Repository) DeleteRelationship(ctx context.Context, fromID, toID string, relType models.RelationType) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	target := r.store.findEdge(fromID, toID, relType)
+	if target == nil {
+		return fmt.Errorf("relationship not found: %s", models.EdgeID(fromID, toID, relType))
+	}
+	r.store.edges = slices.DeleteFunc(r.store.edges, func(e *edge) bool { return e == target })
+	return nil
+}
+
+// ReplaceRelationships replaces the outgoing relationships of a node with the
+// given ones.
+func (r *Repository) ReplaceRelationships(ctx context.Context, id string, relationships []models.Relationship) ([]models.Edge, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	n, ok := r.store.nodes[id]
+	if !ok {
+		return nil, fmt.Errorf("node not found: %s", id)
+	}
+	set, err := store.ReplacementSet(id, n.label, relationships)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(set))
+	for _, rel := range set {
+		wanted[models.EdgeID(id, rel.ToID, rel.Type)] = true
+	}
+
+	// Check every relationship against the edges that remain before changing
+	// any, so that dependencies being replaced cannot make a false cycle
+	current := r.store.edges
+	r.store.edges = slices.DeleteFunc(slices.Clone(current), func(e *edge) bool {
+		if e.from != id || (n.label == labelTask && e.relType == models.RelPartOf) {
+			return false
+		}
+		return !wanted[models.EdgeID(e.from, e.to, e.relType)]
+	})
+	if err := r.store.checkRelationships(id, set); err != nil {
+		r.store.edges = current
+		return nil, err
+	}
+	for _, rel := range set {
+		if err := r.store.createRelationship(id, rel); err != nil {
+			return nil, err
+		}
+	}
+
+	var edges []models.Edge
+	for _, e := range r.store.edges {
+		if e.from == id {
+			edges = append(edges, r.store.toEdge(e))
+		}
+	}
+	return edges, nil
+}
+
+// ExistingNodes reports which of the IDs name a node of any type.
+func (r *Repository) ExistingNodes(ctx context.Context, ids []string) (map[string]bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	existing := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if _, ok := r.store.nodes[id]; ok {
+			existing[id] = true
+		}
+	}
+	return existing, nil
+}
+
+// ListRevisions lists the revisions of a node of any type, newest first
+func (r *Repository) ListRevisions(ctx context.Context, id string) ([]models.Revision, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	n, ok := r.store.nodes[id]
+	if !ok {
+		return nil, fmt.Errorf("node not found: %s", id)
+	}
+	revisions := make([]models.Revision, 0, len(n.revisions))
+	for _, rev := range slices.Backward(n.revisions) {
+		revisions = append(revisions, cloneRevision(rev))
+	}
+	return revisions, nil
+}
+
+// GetRevision retrieves the revision of a node at the given version
+func (r *Repository) GetRevision(ctx context.Context, id string, version int64) (*models.Revision, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	n, ok := r.store.nodes[id]
+	if !ok {
+		return nil, nil
+	}
+	for _, rev := range n.revisions {
+		if rev.Version == version {
+			rev = cloneRevision(rev)
+			return &rev, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,529 @@
+// Package memstore provides an in-process implementation of the storage
+// interfaces in internal/store. It keeps the whole graph in memory and is
+// intended for tests and for running without PostgreSQL/AGE.
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/embedding"
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/store"
+)
+
+// Node labels, matching the AGE label names used by internal/graph.
+const (
+	labelMemory = "Memory"
+	labelPlan   = "Plan"
+	labelTask   = "Task"
+)
+
+// Store holds the nodes and edges shared by the memstore repositories.
+// All repositories created from the same Store see the same graph.
+type Store struct {
+	mu       sync.RWMutex
+	nodes    map[string]*node
+	edges    []*edge
+	seq      int
+	embedder embedding.Embedder
+	logger   *slog.Logger
+
+	// statusRules and planCompletion are set through TaskRepository
+	statusRules    bool
+	planCompletion bool
+
+	// trash holds deleted nodes and their edges, keyed by the deleted node's ID
+	trash map[string]models.TrashEntry
+}
+
+// node is a single Memory, Plan or Task. Only the field matching label is set.
+type node struct {
+	label  string
+	seq    int
+	memory models.Memory
+	plan   models.Plan
+	task   models.Task
+
+	// vector is the embedding of the node's text, computed by vectorModel
+	vector      []float32
+	vectorModel string
+
+	// revisions are the recorded states of the node, oldest first
+	revisions []models.Revision
+}
+
+// edge is a directed, typed relationship between two nodes.
+// Position is only meaningful for PART_OF edges from a Task to a Plan.
+type edge struct {
+	from     string
+	to       string
+	relType  models.RelationType
+	position float64
+	props    models.RelationshipProperties
+}
+
+// New creates an empty in-memory store that embeds text with the hash embedder.
+func New() *Store {
+	return &Store{
+		nodes:    make(map[string]*node),
+		trash:    make(map[string]models.TrashEntry),
+		embedder: embedding.NewHashEmbedder(embedding.DefaultDimensions),
+		logger:   slog.Default(),
+	}
+}
+
+// SetEmbedder replaces the embedder used for semantic search. Nodes embedded
+// by a previous model are left out of semantic search until
+// BackfillEmbeddings re-embeds them.
+func (s *Store) SetEmbedder(e embedding.Embedder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.embedder = e
+}
+
+// SetLogger replaces the logger that warnings, such as embedder failures, are
+// written to (default: slog.Default()).
+func (s *Store) SetLogger(logger *slog.Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logger = logger
+}
+
+// embed stores the embedding of text on n. Callers must hold the write lock;
+// memstore is meant for tests and local use, so embedding under the lock
+// keeps each node consistent with its vector.
+func (s *Store) embed(ctx context.Context, n *node, text string) {
+	n.vector = embedding.TryEmbed(ctx, s.embedder, s.logger, text)
+	n.vectorModel = s.embedder.Model()
+}
+
+// vectorFor returns the embedding of n for the current model, or nil if it
+// has none yet. Callers must hold a lock.
+func (s *Store) vectorFor(n *node) []float32 {
+	if n.vectorModel != s.embedder.Model() {
+		return nil
+	}
+	return n.vector
+}
+
+// BackfillEmbeddings embeds up to limit nodes that have no vector for the
+// current model, such as those written while the embedder was unavailable or
+// before it changed, and returns how many it embedded. Fewer than limit
+// without an error means none are left. It stops at the first node the
+// embedder fails on.
+func (s *Store) BackfillEmbeddings(ctx context.Context, limit int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	embedded := 0
+	for _, label := range []string{labelMemory, labelPlan, labelTask} {
+		for _, n := range s.sortedNodes(label) {
+			if embedded == limit {
+				return embedded, nil
+			}
+			if s.vectorFor(n) != nil {
+				continue
+			}
+			vec, err := s.embedder.Embed(ctx, n.text())
+			if err != nil {
+				return embedded, fmt.Errorf("failed to compute embedding: %w", err)
+			}
+			n.vector = vec
+			n.vectorModel = s.embedder.Model()
+			embedded++
+		}
+	}
+	return embedded, nil
+}
+
+// insert adds a node to the graph. Callers must hold the write lock.
+func (s *Store) insert(id string, n *node) {
+	s.seq++
+	n.seq = s.seq
+	s.nodes[id] = n
+}
+
+// lookup returns the node with the given ID if it has the given label.
+// Callers must hold a lock.
+func (s *Store) lookup(id, label string) *node {
+	n, ok := s.nodes[id]
+	if !ok || n.label != label {
+		return nil
+	}
+	return n
+}
+
+// sortedNodes returns all nodes with the given label in insertion order.
+// Callers must hold a lock.
+func (s *Store) sortedNodes(label string) []*node {
+	var result []*node
+	for _, n := range s.nodes {
+		if n.label == label {
+			result = append(result, n)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].seq < result[j].seq
+	})
+	return result
+}
+
+// findEdge returns the edge of the given type between two nodes, if any.
+// Callers must hold a lock.
+func (s *Store) findEdge(fromID, toID string, relType models.RelationType) *edge {
+	for _, e := range s.edges {
+		if e.from == fromID && e.to == toID && e.relType == relType {
+			return e
+		}
+	}
+	return nil
+}
+
+// checkRelationships returns the error creating the relationships from fromID
+// would fail with, if any, so that callers can fail before changing anything.
+// fromID may be a node about to be created. Callers must hold a lock.
+func (s *Store) checkRelationships(fromID string, relationships []models.Relationship) error {
+	var pending []models.Relationship
+	for _, rel := range relationships {
+		if err := models.ValidateRelationType(rel.Type); err != nil {
+			return &models.RelationshipError{FromID: fromID, ToID: rel.ToID, Type: rel.Type, Err: err}
+		}
+		if _, ok := s.nodes[rel.ToID]; !ok && rel.ToID != fromID {
+			return &models.RelationshipError{FromID: fromID, ToID: rel.ToID, Type: rel.Type, Err: models.ErrNodeNotFound}
+		}
+		rel.FromID = fromID
+		path, err := store.DependencyCycle(context.Background(), rel, s.dependencyEdges(pending))
+		if err != nil {
+			return err
+		}
+		if path != nil {
+			return &models.RelationshipError{FromID: fromID, ToID: rel.ToID, Type: rel.Type, Err: &models.CycleError{Path: path}}
+		}
+		pending = append(pending, rel)
+	}
+	return nil
+}
+
+// dependencyEdges finds the DEPENDS_ON and BLOCKS edges touching nodes, as
+// if the pending relationships had been created too. Callers must hold a lock
+// while it is used.
+func (s *Store) dependencyEdges(pending []models.Relationship) store.DependencyEdges {
+	return func(ctx context.Context, ids []string) ([]models.Relationship, error) {
+		all := slices.Clone(pending)
+		for _, e := range s.edges {
+			all = append(all, models.Relationship{FromID: e.from, ToID: e.to, Type: e.relType})
+		}
+		var rels []models.Relationship
+		for _, rel := range all {
+			if _, _, ok := models.Dependency(rel); ok && (slices.Contains(ids, rel.FromID) || slices.Contains(ids, rel.ToID)) {
+				rels = append(rels, rel)
+			}
+		}
+		return rels, nil
+	}
+}
+
+// createRelationship creates the typed edge rel from fromID between two
+// existing nodes, stamped with its creation time. Like the AGE repositories,
+// it only sets the properties rel gives when the edge already exists, and
+// fails when either endpoint is missing. Callers must hold the write lock.
+func (s *Store) createRelationship(fromID string, rel models.Relationship) error {
+	if err := s.checkRelationships(fromID, []models.Relationship{rel}); err != nil {
+		return err
+	}
+	props := rel.RelationshipProperties
+	props.Metadata = maps.Clone(props.Metadata)
+	if e := s.findEdge(fromID, rel.ToID, rel.Type); e != nil {
+		e.props = e.props.Merge(props)
+		return nil
+	}
+	if _, ok := s.nodes[fromID]; !ok {
+		return &models.RelationshipError{FromID: fromID, ToID: rel.ToID, Type: rel.Type, Err: fmt.Errorf("%w: %s", models.ErrNodeNotFound, fromID)}
+	}
+	props.CreatedAt = time.Now().UTC()
+	s.edges = append(s.edges, &edge{from: fromID, to: rel.ToID, relType: rel.Type, props: props})
+	return nil
+}
+
+// properties returns a copy of the edge's properties. Callers must hold a lock.
+func (e *edge) properties() models.RelationshipProperties {
+	props := e.props
+	props.Metadata = maps.Clone(props.Metadata)
+	return props
+}
+
+// relationship describes an edge as stored. Callers must hold a lock.
+func (e *edge) relationship() models.Relationship {
+	return models.Relationship{FromID: e.from, ToID: e.to, Type: e.relType, RelationshipProperties: e.properties()}
+}
+
+// toEdge describes an edge. Callers must hold a lock.
+func (s *Store) toEdge(e *edge) models.Edge {
+	edge := models.NewEdge(e.from, e.to, e.relType, e.properties())
+	if e.relType == models.RelPartOf && s.lookup(e.from, labelTask) != nil {
+		edge.Position = &e.position
+	}
+	return edge
+}
+
+// recordRevision records the current state of n, attributed to actor, unless
+// its version is already recorded. Callers must hold the write lock.
+func (n *node) recordRevision(actor string) {
+	var rev models.Revision
+	switch n.label {
+	case labelPlan:
+		rev = models.PlanRevision(clonePlan(n.plan), actor)
+	case labelTask:
+		rev = models.TaskRevision(cloneTask(n.task), actor)
+	default:
+		rev = models.MemoryRevision(cloneMemory(n.memory), actor)
+	}
+	if len(n.revisions) > 0 && n.revisions[len(n.revisions)-1].Version == rev.Version {
+		return
+	}
+	n.revisions = append(n.revisions, rev)
+}
+
+// detachDelete removes a node and every edge touching it.
+// Callers must hold the write lock.
+func (s *Store) detachDelete(id string) {
+	delete(s.nodes, id)
+	kept := s.edges[:0]
+	for _, e := range s.edges {
+		if e.from != id && e.to != id {
+			kept = append(kept, e)
+		}
+	}
+	s.edges = kept
+}
+
+// taskPlanIDs returns the IDs of the plans a task belongs to. Callers must
+// hold a lock.
+func (s *Store) taskPlanIDs(id string) []string {
+	var planIDs []string
+	for _, e := range s.edges {
+		if e.relType == models.RelPartOf && e.from == id {
+			planIDs = append(planIDs, e.to)
+		}
+	}
+	return planIDs
+}
+
+// planTasks returns the PART_OF edges pointing at a plan, ordered by position.
+// Callers must hold a lock.
+func (s *Store) planTasks(planID string) []*edge {
+	var result []*edge
+	for _, e := range s.edges {
+		if e.relType == models.RelPartOf && e.to == planID && s.lookup(e.from, labelTask) != nil {
+			result = append(result, e)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].position < result[j].position
+	})
+	return result
+}
+
+// waits returns the tasks that each of the given tasks waits for, keyed by
+// the waiting task's ID, in the order the relationships were created.
+// Callers must hold a lock.
+func (s *Store) waits(ids []string) map[string][]models.Wait {
+	result := make(map[string][]models.Wait)
+	for _, e := range s.edges {
+		waiter, waitsFor, ok := models.Dependency(models.Relationship{FromID: e.from, ToID: e.to, Type: e.relType})
+		if !ok || !slices.Contains(ids, waiter) {
+			continue
+		}
+		if n := s.lookup(waitsFor, labelTask); n != nil {
+			result[waiter] = append(result[waiter], models.Wait{Task: cloneTask(n.task), Type: e.relType})
+		}
+	}
+	return result
+}
+
+// planProgress returns the progress of each of the given plans that exists,
+// keyed by plan ID. Callers must hold a lock.
+func (s *Store) planProgress(ids []string) map[string]models.PlanProgress {
+	result := make(map[string]models.PlanProgress, len(ids))
+	for _, id := range ids {
+		n := s.lookup(id, labelPlan)
+		if n == nil {
+			continue
+		}
+		progress := models.NewPlanProgress(n.plan.UpdatedAt)
+		for _, e := range s.planTasks(id) {
+			task := s.nodes[e.from].task
+			progress.Add(task.Status, 1, task.UpdatedAt)
+		}
+		result[id] = progress
+	}
+	return result
+}
+
+// text returns the searchable text of a node: the name and description of a
+// plan, or the content of a memory or task.
+func (n *node) text() string {
+	switch n.label {
+	case labelPlan:
+		return embedding.PlanText(n.plan.Name, n.plan.Description)
+	case labelTask:
+		return n.task.Content
+	default:
+		return n.memory.Content
+	}
+}
+
+// toNodeResult describes a node matched by SearchAll with the given score.
+func (n *node) toNodeResult(score float64) models.NodeSearchResult {
+	switch n.label {
+	case labelPlan:
+		return models.NodeSearchResult{
+			Kind:      models.KindPlan,
+			ID:        n.plan.ID,
+			Title:     n.plan.Name,
+			Status:    string(n.plan.Status),
+			Score:     score,
+			UpdatedAt: n.plan.UpdatedAt,
+		}
+	case labelTask:
+		return models.NodeSearchResult{
+			Kind:      models.KindTask,
+			ID:        n.task.ID,
+			Title:     models.NodeTitle(n.task.Content),
+			Status:    string(n.task.Status),
+			Score:     score,
+			UpdatedAt: n.task.UpdatedAt,
+		}
+	default:
+		return models.NodeSearchResult{
+			Kind:      models.KindMemory,
+			ID:        n.memory.ID,
+			Title:     models.NodeTitle(n.memory.Content),
+			Score:     score,
+			UpdatedAt: n.memory.UpdatedAt,
+		}
+	}
+}
+
+// toRelatedMemory flattens any node into the Memory shape used by get_related.
+// Plans expose their name as content, and Type holds the node label.
+func (n *node) toRelatedMemory() models.Memory {
+	switch n.label {
+	case labelPlan:
+		return models.Memory{
+			ID:        n.plan.ID,
+			Type:      models.MemoryType(labelPlan),
+			Content:   n.plan.Name,
+			Metadata:  cloneMetadata(n.plan.Metadata),
+			Tags:      cloneTags(n.plan.Tags),
+			CreatedAt: n.plan.CreatedAt,
+			UpdatedAt: n.plan.UpdatedAt,
+			Version:   n.plan.Version,
+		}
+	case labelTask:
+		return models.Memory{
+			ID:        n.task.ID,
+			Type:      models.MemoryType(labelTask),
+			Content:   n.task.Content,
+			Metadata:  cloneMetadata(n.task.Metadata),
+			Tags:      cloneTags(n.task.Tags),
+			CreatedAt: n.task.CreatedAt,
+			UpdatedAt: n.task.UpdatedAt,
+			Version:   n.task.Version,
+		}
+	default:
+		mem := cloneMemory(n.memory)
+		mem.Type = models.MemoryType(labelMemory)
+		return mem
+	}
+}
+
+// listPage returns the page of items, already in list order, that follow the
+// item named by the cursor, at most limit long, and describes it. keyOf
+// returns the cursor naming an item, and after reports whether an item's key
+// follows the cursor.
+func listPage[T any](items []T, c models.Cursor, limit int, keyOf func(T) models.Cursor, after func(key, c models.Cursor) bool) ([]T, models.Page) {
+	page := models.Page{Total: len(items)}
+	start := 0
+	if !c.IsZero() {
+		for start < len(items) && !after(keyOf(items[start]), c) {
+			start++
+		}
+	}
+	end := min(start+limit, len(items))
+	if end < len(items) {
+		page.NextCursor = keyOf(items[end-1]).Encode()
+	}
+	return items[start:end], page
+}
+
+// byRecency orders list keys by most recent update, then by descending ID.
+func byRecency(key, c models.Cursor) bool {
+	return key.UpdatedAt.Before(c.UpdatedAt) || (key.UpdatedAt.Equal(c.UpdatedAt) && key.ID < c.ID)
+}
+
+// byPosition orders list keys by ascending position, then by ID.
+func byPosition(key, c models.Cursor) bool {
+	return key.Position > c.Position || (key.Position == c.Position && key.ID > c.ID)
+}
+
+// hasAnyTag reports whether any of the wanted tags is present.
+func hasAnyTag(tags, wanted []string) bool {
+	for _, w := range wanted {
+		for _, t := range tags {
+			if t == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func cloneMetadata(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func cloneTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	return append([]string(nil), tags...)
+}
+
+func cloneMemory(m models.Memory) models.Memory {
+	m.Metadata = cloneMetadata(m.Metadata)
+	m.Tags = cloneTags(m.Tags)
+	return m
+}
+
+func clonePlan(p models.Plan) models.Plan {
+	p.Metadata = cloneMetadata(p.Metadata)
+	p.Tags = cloneTags(p.Tags)
+	return p
+}
+
+func cloneTask(t models.Task) models.Task {
+	t.Metadata = cloneMetadata(t.Metadata)
+	t.Tags = cloneTags(t.Tags)
+	return t
+}
+
+func cloneRevision(r models.Revision) models.Revision {
+	r.Metadata = cloneMetadata(r.Metadata)
+	r.Tags = cloneTags(r.Tags)
+	return r
+}
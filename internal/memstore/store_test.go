@@ -0,0 +1,18 @@
+package memstore
+
+import (
+	"testing"
+
+	"github.com/Thomas-Fitz/associate/internal/store/storetest"
+)
+
+func TestStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		s := New()
+		return storetest.Stores{
+			Memories: NewRepository(s),
+			Plans:    NewPlanRepository(s),
+			Tasks:    NewTaskRepository(s),
+		}
+	})
+}
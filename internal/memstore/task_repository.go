@@ -0,0 +1,473 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.TaskStore = (*TaskRepository)(nil)
+
+// TaskRepository provides CRUD operations for tasks.
+type TaskRepository struct {
+	store *Store
+}
+
+// NewTaskRepository creates a new task repository backed by s
+func NewTaskRepository(s *Store) *TaskRepository {
+	return &TaskRepository{store: s}
+}
+
+// Add creates a new task with required plan links and optional relationships.
+func (r *TaskRepository) Add(ctx context.Context, task models.Task, planIDs []string, relationships []models.Relationship, afterTaskID, beforeTaskID *string) (*models.Task, models.StatusChanges, error) {
+	if len(planIDs) == 0 {
+		return nil, models.StatusChanges{}, fmt.Errorf("task must belong to at least one plan")
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// Validate everything up front so a failure leaves the graph untouched
+	for _, planID := range planIDs {
+		if r.store.lookup(planID, labelPlan) == nil {
+			return nil, models.StatusChanges{}, fmt.Errorf("plan not found: %s", planID)
+		}
+	}
+
+	if task.ID == "" {
+		task.ID = uuid.New().String()
+	}
+	if _, exists := r.store.nodes[task.ID]; exists {
+		return nil, models.StatusChanges{}, fmt.Errorf("failed to create task: node already exists: %s", task.ID)
+	}
+	now := time.Now().UTC()
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	task.Version = 1
+
+	if task.Status == "" {
+		task.Status = models.TaskStatusPending
+	}
+	if err := r.store.checkRelationships(task.ID, relationships); err != nil {
+		return nil, models.StatusChanges{}, err
+	}
+
+	positions := make([]float64, len(planIDs))
+	for i, planID := range planIDs {
+		positions[i] = r.calculateNewTaskPosition(planID, afterTaskID, beforeTaskID)
+	}
+
+	task = cloneTask(task)
+	n := &node{label: labelTask, task: task}
+	r.store.embed(ctx, n, task.Content)
+	r.store.insert(task.ID, n)
+	n.recordRevision(store.Actor(ctx))
+
+	for i, planID := range planIDs {
+		r.setPlanPosition(task.ID, planID, positions[i])
+	}
+	for _, rel := range relationships {
+		if err := r.store.createRelationship(task.ID, rel); err != nil {
+			return nil, models.StatusChanges{}, err
+		}
+	}
+
+	var changes models.StatusChanges
+	if r.store.statusRules {
+		targets, err := store.StatusRuleTargets(ctx, task.ID, false, relationships, r.store.dependencyEdges(nil))
+		if err != nil {
+			return nil, models.StatusChanges{}, err
+		}
+		changes.Tasks = r.applyStatusRules(ctx, targets, store.RuledStatus)
+	}
+	if r.store.planCompletion {
+		changes.Plans = r.applyPlanCompletion(ctx, planIDs)
+	}
+
+	result := cloneTask(n.task)
+	return &result, changes, nil
+}
+
+// GetByID retrieves a task by ID
+func (r *TaskRepository) GetByID(ctx context.Context, id string) (*models.Task, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	n := r.store.lookup(id, labelTask)
+	if n == nil {
+		return nil, nil
+	}
+	task := cloneTask(n.task)
+	return &task, nil
+}
+
+// GetWithPlans retrieves a task by ID along with its associated plans
+func (r *TaskRepository) GetWithPlans(ctx context.Context, id string) (*models.Task, []models.Plan, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	n := r.store.lookup(id, labelTask)
+	if n == nil {
+		return nil, nil, nil
+	}
+	task := cloneTask(n.task)
+
+	var plans []models.Plan
+	for _, e := range r.store.edges {
+		if e.from != id || e.relType != models.RelPartOf {
+			continue
+		}
+		if p := r.store.lookup(e.to, labelPlan); p != nil {
+			plans = append(plans, clonePlan(p.plan))
+		}
+	}
+
+	return &task, plans, nil
+}
+
+// Update modifies an existing task
+func (r *TaskRepository) Update(ctx context.Context, id string, expectedVersion int64, content *string, status *string, metadata map[string]string, tags []string, addPlanIDs []string, newRelationships []models.Relationship) (*models.Task, models.StatusChanges, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, planID := range addPlanIDs {
+		if r.store.lookup(planID, labelPlan) == nil {
+			return nil, models.StatusChanges{}, fmt.Errorf("plan not found: %s", planID)
+		}
+	}
+
+	n := r.store.lookup(id, labelTask)
+	if n == nil {
+		return nil, models.StatusChanges{}, fmt.Errorf("task not found: %s", id)
+	}
+
+	if err := models.CheckVersion(id, expectedVersion, n.task.Version); err != nil {
+		return nil, models.StatusChanges{}, err
+	}
+	if err := r.store.checkRelationships(id, newRelationships); err != nil {
+		return nil, models.StatusChanges{}, err
+	}
+
+	n.task.UpdatedAt = time.Now().UTC()
+	n.task.Version++
+	if content != nil {
+		n.task.Content = *content
+		r.store.embed(ctx, n, n.task.Content)
+	}
+	if status != nil {
+		n.task.Status = models.TaskStatus(*status)
+	}
+	if metadata != nil {
+		n.task.Metadata = cloneMetadata(metadata)
+	}
+	if tags != nil {
+		n.task.Tags = cloneTags(tags)
+	}
+
+	n.recordRevision(store.Actor(ctx))
+
+	// Add to new plans (append to end)
+	for _, planID := range addPlanIDs {
+		r.setPlanPosition(id, planID, r.getMaxPosition(planID)+models.DefaultPositionIncrement)
+	}
+
+	for _, rel := range newRelationships {
+		if err := r.store.createRelationship(id, rel); err != nil {
+			return nil, models.StatusChanges{}, err
+		}
+	}
+
+	var changes models.StatusChanges
+	if r.store.statusRules {
+		targets, err := store.StatusRuleTargets(ctx, id, status != nil, newRelationships, r.store.dependencyEdges(nil))
+		if err != nil {
+			return nil, models.StatusChanges{}, err
+		}
+		changes.Tasks = r.applyStatusRules(ctx, targets, store.RuledStatus)
+	}
+	if r.store.planCompletion && (status != nil || len(addPlanIDs) > 0) {
+		changes.Plans = r.applyPlanCompletion(ctx, r.store.taskPlanIDs(id))
+	}
+
+	result := cloneTask(n.task)
+	return &result, changes, nil
+}
+
+// SetStatusRules turns the status rules on or off for every repository
+// backed by the same store
+func (r *TaskRepository) SetStatusRules(enabled bool) {
+	r.store.statusRules = enabled
+}
+
+// SetPlanCompletion turns plan completion on or off for every repository
+// backed by the same store
+func (r *TaskRepository) SetPlanCompletion(enabled bool) {
+	r.store.planCompletion = enabled
+}
+
+// applyStatusRules applies rule to each of the target tasks. Callers must
+// hold the write lock.
+func (r *TaskRepository) applyStatusRules(ctx context.Context, targets []string, rule func([]models.Wait) (from, to models.TaskStatus, ok bool)) []models.StatusChange {
+	waits := r.store.waits(targets)
+
+	var changes []models.StatusChange
+	for _, taskID := range targets {
+		from, to, ok := rule(waits[taskID])
+		n := r.store.lookup(taskID, labelTask)
+		if !ok || n == nil || n.task.Status != from {
+			continue
+		}
+		n.task.Status = to
+		n.task.UpdatedAt = time.Now().UTC()
+		n.task.Version++
+		n.recordRevision(store.Actor(ctx))
+		changes = append(changes, models.StatusChange{Task: cloneTask(n.task), From: from})
+	}
+	return changes
+}
+
+// applyPlanCompletion applies plan completion to the given plans. Callers
+// must hold the write lock.
+func (r *TaskRepository) applyPlanCompletion(ctx context.Context, planIDs []string) []models.PlanStatusChange {
+	progress := r.store.planProgress(planIDs)
+
+	var changes []models.PlanStatusChange
+	for _, planID := range planIDs {
+		from, to, ok := store.PlanCompletion(progress[planID])
+		n := r.store.lookup(planID, labelPlan)
+		if !ok || n == nil || n.plan.Status != from {
+			continue
+		}
+		n.plan.Status = to
+		n.plan.UpdatedAt = time.Now().UTC()
+		n.plan.Version++
+		n.recordRevision(store.Actor(ctx))
+		changes = append(changes, models.PlanStatusChange{Plan: clonePlan(n.plan), From: from})
+	}
+	return changes
+}
+
+// Delete moves a task and all its relationships to the trash
+func (r *TaskRepository) Delete(ctx context.Context, id string) (models.StatusChanges, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var changes models.StatusChanges
+	if r.store.lookup(id, labelTask) == nil {
+		return changes, nil
+	}
+	// The plans lose the task with its relationships, so find them first
+	planIDs := r.store.taskPlanIDs(id)
+	var err error
+	if changes.Tasks, err = r.trashNodes(ctx, id); err != nil {
+		return models.StatusChanges{}, err
+	}
+	if r.store.planCompletion {
+		changes.Plans = r.applyPlanCompletion(ctx, planIDs)
+	}
+	return changes, nil
+}
+
+// trashNodes moves the given nodes to the trash and, with status rules on,
+// applies them to the tasks that waited for those. Callers must hold the
+// write lock.
+func (r *TaskRepository) trashNodes(ctx context.Context, ids ...string) ([]models.StatusChange, error) {
+	if !r.store.statusRules {
+		r.store.trashNodes(ids...)
+		return nil, nil
+	}
+	// The relationships go to the trash with the nodes, so find the
+	// dependants first
+	targets, err := store.DeletionRuleTargets(ctx, ids, r.store.dependencyEdges(nil))
+	if err != nil {
+		return nil, err
+	}
+	r.store.trashNodes(ids...)
+	return r.applyStatusRules(ctx, targets, store.RuledStatusAfterDeletion), nil
+}
+
+// PreviewDelete returns what Delete would move to the trash
+func (r *TaskRepository) PreviewDelete(ctx context.Context, id string) (*models.TrashEntry, error) {
+	return r.store.previewDelete(id, labelTask), nil
+}
+
+// UpdatePositions batch updates task positions within a plan.
+func (r *TaskRepository) UpdatePositions(ctx context.Context, planID string, taskPositions map[string]float64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	edges := make(map[string]*edge, len(taskPositions))
+	for taskID := range taskPositions {
+		e := r.store.findEdge(taskID, planID, models.RelPartOf)
+		if e == nil || r.store.lookup(taskID, labelTask) == nil || r.store.lookup(planID, labelPlan) == nil {
+			return fmt.Errorf("task %s not found in plan %s", taskID, planID)
+		}
+		edges[taskID] = e
+	}
+
+	for taskID, position := range taskPositions {
+		edges[taskID].position = position
+	}
+	return nil
+}
+
+// NormalizePositions rewrites the positions of the given tasks within a plan,
+// evenly spaced in the given order.
+func (r *TaskRepository) NormalizePositions(ctx context.Context, planID string, taskIDs []string) ([]float64, error) {
+	positions := models.CalculateInsertPositions(0, 0, len(taskIDs))
+	taskPositions := make(map[string]float64, len(taskIDs))
+	for i, taskID := range taskIDs {
+		taskPositions[taskID] = positions[i]
+	}
+	if err := r.UpdatePositions(ctx, planID, taskPositions); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// List retrieves tasks with optional filtering.
+func (r *TaskRepository) List(ctx context.Context, planID string, status string, tags []string, limit int, cursor string) ([]models.TaskListResult, models.Page, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	c, err := models.DecodeListCursor(cursor)
+	if err != nil {
+		return nil, models.Page{}, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	matches := func(t models.Task) bool {
+		if status != "" && string(t.Status) != status {
+			return false
+		}
+		if len(tags) > 0 && !hasAnyTag(t.Tags, tags) {
+			return false
+		}
+		return true
+	}
+
+	var matched []models.TaskListResult
+	keyOf := func(t models.TaskListResult) models.Cursor {
+		return models.Cursor{UpdatedAt: t.Task.UpdatedAt, ID: t.Task.ID}
+	}
+	after := byRecency
+	if planID != "" {
+		if r.store.lookup(planID, labelPlan) == nil {
+			return nil, models.Page{}, nil
+		}
+		for _, e := range r.store.planTasks(planID) {
+			t := r.store.nodes[e.from].task
+			if !matches(t) {
+				continue
+			}
+			position := e.position
+			matched = append(matched, models.TaskListResult{Task: t, Position: &position})
+		}
+		keyOf = func(t models.TaskListResult) models.Cursor {
+			return models.Cursor{Position: *t.Position, ID: t.Task.ID}
+		}
+		after = byPosition
+	} else {
+		for _, n := range r.store.sortedNodes(labelTask) {
+			if matches(n.task) {
+				matched = append(matched, models.TaskListResult{Task: n.task})
+			}
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return after(keyOf(matched[j]), keyOf(matched[i]))
+	})
+
+	page, info := listPage(matched, c, limit, keyOf, after)
+	var tasks []models.TaskListResult
+	for _, t := range page {
+		t.Task = cloneTask(t.Task)
+		tasks = append(tasks, t)
+	}
+	return tasks, info, nil
+}
+
+// Helper methods. Callers must hold the store lock.
+
+// setPlanPosition links a task to a plan, or moves it if already linked.
+func (r *TaskRepository) setPlanPosition(taskID, planID string, position float64) {
+	if e := r.store.findEdge(taskID, planID, models.RelPartOf); e != nil {
+		e.position = position
+		return
+	}
+	r.store.edges = append(r.store.edges, &edge{
+		from:     taskID,
+		to:       planID,
+		relType:  models.RelPartOf,
+		position: position,
+		props:    models.RelationshipProperties{CreatedAt: time.Now().UTC()},
+	})
+}
+
+func (r *TaskRepository) getMaxPosition(planID string) float64 {
+	var maxPos float64
+	for _, e := range r.store.planTasks(planID) {
+		if e.position > maxPos {
+			maxPos = e.position
+		}
+	}
+	return maxPos
+}
+
+func (r *TaskRepository) getTaskPosition(taskID, planID string) float64 {
+	if e := r.store.findEdge(taskID, planID, models.RelPartOf); e != nil {
+		return e.position
+	}
+	return 0
+}
+
+func (r *TaskRepository) getAdjacentPositions(taskID, planID string) (before, after float64) {
+	currentPos := r.getTaskPosition(taskID, planID)
+	for _, e := range r.store.planTasks(planID) {
+		if e.position < currentPos {
+			before = e.position
+		}
+		if e.position > currentPos {
+			after = e.position
+			break
+		}
+	}
+	return before, after
+}
+
+func (r *TaskRepository) calculateNewTaskPosition(planID string, afterTaskID, beforeTaskID *string) float64 {
+	hasAfter := afterTaskID != nil && *afterTaskID != ""
+	hasBefore := beforeTaskID != nil && *beforeTaskID != ""
+
+	// If neither specified, append to end
+	if !hasAfter && !hasBefore {
+		return r.getMaxPosition(planID) + models.DefaultPositionIncrement
+	}
+
+	var afterPos, beforePos float64
+	if hasAfter {
+		afterPos = r.getTaskPosition(*afterTaskID, planID)
+		if !hasBefore {
+			_, beforePos = r.getAdjacentPositions(*afterTaskID, planID)
+		}
+	}
+	if hasBefore {
+		beforePos = r.getTaskPosition(*beforeTaskID, planID)
+		if !hasAfter {
+			afterPos, _ = r.getAdjacentPositions(*beforeTaskID, planID)
+		}
+	}
+
+	positions := models.CalculateInsertPositions(afterPos, beforePos, 1)
+	if len(positions) == 0 {
+		return models.DefaultPositionIncrement
+	}
+	return positions[0]
+}
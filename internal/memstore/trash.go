@@ -0,0 +1,158 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/store"
+)
+
+// trashEntry returns what deleting the nodes with the given IDs, the first of
+// which is the node being deleted, moves to the trash: the nodes and every
+// edge touching them. Callers must hold a lock.
+func (s *Store) trashEntry(ids ...string) models.TrashEntry {
+	var nodes []models.TrashedNode
+	var edges []models.Edge
+	for _, id := range ids {
+		nodes = append(nodes, s.nodes[id].trashed())
+		for _, e := range s.edges {
+			if e.from == id || e.to == id {
+				edges = append(edges, s.toEdge(e))
+			}
+		}
+	}
+	return models.NewTrashEntry(nodes, edges, time.Now().UTC())
+}
+
+// trashNodes moves the nodes with the given IDs, the first of which is the
+// node being deleted, and every edge touching them to the trash. Callers must
+// hold the write lock.
+func (s *Store) trashNodes(ids ...string) {
+	entry := s.trashEntry(ids...)
+	for _, id := range ids {
+		s.detachDelete(id)
+	}
+	s.trash[entry.ID] = entry
+}
+
+// previewDelete returns what deleting the node with the given ID and label
+// would move to the trash, or nil if there is no such node.
+func (s *Store) previewDelete(id, label string) *models.TrashEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.lookup(id, label) == nil {
+		return nil
+	}
+	entry := s.trashEntry(id)
+	return &entry
+}
+
+// trashed returns a copy of n as kept in the trash. Callers must hold a lock.
+func (n *node) trashed() models.TrashedNode {
+	tn := models.TrashedNode{Kind: models.NodeKind(n.label)}
+	switch n.label {
+	case labelPlan:
+		p := clonePlan(n.plan)
+		tn.Plan = &p
+	case labelTask:
+		t := cloneTask(n.task)
+		tn.Task = &t
+	default:
+		m := cloneMemory(n.memory)
+		tn.Memory = &m
+	}
+	for _, rev := range n.revisions {
+		tn.Revisions = append(tn.Revisions, cloneRevision(rev))
+	}
+	return tn
+}
+
+// untrashed returns the node kept in the trash as tn.
+func untrashed(tn models.TrashedNode) *node {
+	n := &node{label: string(tn.Kind)}
+	switch {
+	case tn.Plan != nil:
+		n.plan = clonePlan(*tn.Plan)
+	case tn.Task != nil:
+		n.task = cloneTask(*tn.Task)
+	case tn.Memory != nil:
+		n.memory = cloneMemory(*tn.Memory)
+	}
+	for _, rev := range tn.Revisions {
+		n.revisions = append(n.revisions, cloneRevision(rev))
+	}
+	return n
+}
+
+// ListTrash lists the trash, most recently deleted first
+func (r *Repository) ListTrash(ctx context.Context) ([]models.TrashEntry, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	entries := slices.Collect(maps.Values(r.store.trash))
+	models.SortTrash(entries)
+	return entries, nil
+}
+
+// Restore brings back a deleted node, the nodes deleted with it and their edges
+func (r *Repository) Restore(ctx context.Context, id string) (*models.TrashEntry, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	entry, ok := r.store.trash[id]
+	if !ok {
+		return nil, fmt.Errorf("not in trash: %s", id)
+	}
+	existing := make(map[string]bool)
+	for _, nodeID := range store.RestoreIDs(entry) {
+		if _, ok := r.store.nodes[nodeID]; ok {
+			existing[nodeID] = true
+		}
+	}
+	restoration, err := store.PlanRestore(entry, existing, slices.Collect(maps.Values(r.store.trash)))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tn := range entry.Nodes {
+		n := untrashed(tn)
+		r.store.insert(tn.ID(), n)
+		r.store.embed(ctx, n, n.text())
+	}
+	for _, e := range restoration.Edges {
+		restored := &edge{from: e.FromID, to: e.ToID, relType: e.Type, props: e.RelationshipProperties}
+		restored.props.Metadata = maps.Clone(e.Metadata)
+		if e.Position != nil {
+			restored.position = *e.Position
+		}
+		r.store.edges = append(r.store.edges, restored)
+	}
+	for otherID, edges := range restoration.Deferred {
+		other := r.store.trash[otherID]
+		other.Edges = append(slices.Clip(other.Edges), edges...)
+		r.store.trash[otherID] = other
+	}
+	delete(r.store.trash, id)
+	entry.SkippedEdges = restoration.Skipped
+	return &entry, nil
+}
+
+// PurgeTrash permanently removes the trash entries deleted before the given time
+func (r *Repository) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	purged := 0
+	for id, entry := range r.store.trash {
+		if entry.DeletedAt.Before(before) {
+			delete(r.store.trash, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDependencyCycle is wrapped by a CycleError
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// CycleError reports a relationship that would close a cycle of DEPENDS_ON
+// and BLOCKS relationships, which would leave the tasks on it waiting for
+// each other forever. Stores return it wrapped in a RelationshipError.
+type CycleError struct {
+	Path []string // Each node waits for the next; the first and last are the same
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrDependencyCycle, strings.Join(e.Path, " -> "))
+}
+
+func (e *CycleError) Unwrap() error {
+	return ErrDependencyCycle
+}
+
+// Dependency returns the ends of a DEPENDS_ON or BLOCKS relationship as the
+// node that waits and the node it waits for: A DEPENDS_ON B and B BLOCKS A
+// both mean that A waits for B. ok is false for other relationship types.
+func Dependency(rel Relationship) (waiter, waitsFor string, ok bool) {
+	switch rel.Type {
+	case RelDependsOn:
+		return rel.FromID, rel.ToID, true
+	case RelBlocks:
+		return rel.ToID, rel.FromID, true
+	}
+	return "", "", false
+}
+
+// Wait is a task that another task waits for, through the other's DEPENDS_ON
+// relationship to it or its own BLOCKS relationship to the other.
+type Wait struct {
+	Task Task         `json:"task"`
+	Type RelationType `json:"type"` // RelDependsOn or RelBlocks
+}
+
+// Unfinished returns the waits whose task is neither completed nor cancelled.
+func Unfinished(waits []Wait) []Wait {
+	var unfinished []Wait
+	for _, w := range waits {
+		if w.Task.Status != TaskStatusCompleted && w.Task.Status != TaskStatusCancelled {
+			unfinished = append(unfinished, w)
+		}
+	}
+	return unfinished
+}
+
+// StatusChange is a change the status rules made to a task as a side effect
+// of creating, updating or deleting another.
+type StatusChange struct {
+	Task Task       `json:"task"` // As it is after the change
+	From TaskStatus `json:"from"`
+}
+
+// PlanStatusChange is a change plan completion made to a plan as a side
+// effect of changing its tasks.
+type PlanStatusChange struct {
+	Plan Plan       `json:"plan"` // As it is after the change
+	From PlanStatus `json:"from"`
+}
+
+// StatusChanges are the changes the status rules and plan completion made as
+// a side effect of creating, updating or deleting tasks.
+type StatusChanges struct {
+	Tasks []StatusChange     `json:"tasks,omitempty"`
+	Plans []PlanStatusChange `json:"plans,omitempty"`
+}
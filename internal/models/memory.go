@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 // MemoryType defines the category of a memory
 type MemoryType string
@@ -24,6 +28,25 @@ const (
 	RelImplements RelationType = "IMPLEMENTS" // Code implements a decision/task
 )
 
+// ValidRelationTypes is the complete set of allowed relationship type names.
+var ValidRelationTypes = map[RelationType]bool{
+	RelRelatesTo:  true,
+	RelPartOf:     true,
+	RelReferences: true,
+	RelDependsOn:  true,
+	RelBlocks:     true,
+	RelFollows:    true,
+	RelImplements: true,
+}
+
+// ValidateRelationType checks that a relationship type is one of the known constants.
+func ValidateRelationType(relType RelationType) error {
+	if !ValidRelationTypes[relType] {
+		return fmt.Errorf("invalid relationship type: %q", relType)
+	}
+	return nil
+}
+
 // Memory represents a memory node in the graph database.
 type Memory struct {
 	ID        string            `json:"id"`
@@ -33,6 +56,7 @@ type Memory struct {
 	Tags      []string          `json:"tags,omitempty"`
 	CreatedAt time.Time         `json:"created_at"`
 	UpdatedAt time.Time         `json:"updated_at"`
+	Version   int64             `json:"version"` // Starts at 1 and increases with every update
 }
 
 // Relationship represents a connection between two memories
@@ -40,12 +64,83 @@ type Relationship struct {
 	FromID string       `json:"from_id"`
 	ToID   string       `json:"to_id"`
 	Type   RelationType `json:"type"`
+	RelationshipProperties
+}
+
+// ErrNodeNotFound is wrapped by a RelationshipError whose target does not exist
+var ErrNodeNotFound = errors.New("node not found")
+
+// RelationshipError reports a relationship that could not be created. Stores
+// return it, and create nothing, when any relationship given to Add, Update or
+// ReplaceRelationships has an unknown type or target.
+type RelationshipError struct {
+	FromID string
+	ToID   string
+	Type   RelationType
+	Err    error
+}
+
+func (e *RelationshipError) Error() string {
+	return fmt.Sprintf("failed to create %s relationship to %s: %v", e.Type, e.ToID, e.Err)
+}
+
+func (e *RelationshipError) Unwrap() error {
+	return e.Err
+}
+
+// RelationshipProperties are the details a relationship carries besides its
+// endpoints and type. Zero fields are unset.
+type RelationshipProperties struct {
+	Reason    string            `json:"reason,omitempty"` // Why the relationship exists
+	Weight    *float64          `json:"weight,omitempty"` // Strength or confidence, typically in [0, 1]
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at,omitzero"` // Set by the store when the relationship is created
+}
+
+// Merge returns p updated with the fields set in update. CreatedAt is kept,
+// so giving an existing relationship again only changes what it sets.
+func (p RelationshipProperties) Merge(update RelationshipProperties) RelationshipProperties {
+	if update.Reason != "" {
+		p.Reason = update.Reason
+	}
+	if update.Weight != nil {
+		p.Weight = update.Weight
+	}
+	if update.Metadata != nil {
+		p.Metadata = update.Metadata
+	}
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = update.CreatedAt
+	}
+	return p
+}
+
+// Edge is a stored relationship between two nodes of any type
+type Edge struct {
+	ID     string       `json:"id"` // EdgeID of the endpoints and type
+	FromID string       `json:"from_id"`
+	ToID   string       `json:"to_id"`
+	Type   RelationType `json:"type"`
+	RelationshipProperties
+	Position *float64 `json:"position,omitempty"` // Of a task in the plan its PART_OF edge points to
+}
+
+// EdgeID identifies the relationship of type relType from fromID to toID.
+// Two nodes are linked at most once by each type, so it is unique.
+func EdgeID(fromID, toID string, relType RelationType) string {
+	return fromID + "-[" + string(relType) + "]->" + toID
+}
+
+// NewEdge returns the edge of type relType from fromID to toID.
+func NewEdge(fromID, toID string, relType RelationType, props RelationshipProperties) Edge {
+	return Edge{ID: EdgeID(fromID, toID, relType), FromID: fromID, ToID: toID, Type: relType, RelationshipProperties: props}
 }
 
 // SearchResult contains a memory with its relevance score
 type SearchResult struct {
 	Memory  Memory   `json:"memory"`
-	Score   float64  `json:"score"`
+	Score   float64  `json:"score"`             // Relevance in [0, 1); higher is better
+	Snippet string   `json:"snippet,omitempty"` // Matched excerpt with **highlighted** words
 	Related []string `json:"related,omitempty"` // IDs of related memories
 }
 
@@ -55,12 +150,19 @@ type RelatedInfo struct {
 	Type         MemoryType `json:"type"`
 	RelationType string     `json:"relationship_type"`
 	Direction    string     `json:"direction"` // "incoming" or "outgoing"
+	RelationshipProperties
 }
 
-// RelatedMemoryResult contains full memory data with relationship metadata
+// MaxRelatedDepth is the most relationship hops a get_related traversal follows
+const MaxRelatedDepth = 5
+
+// RelatedMemoryResult contains full memory data with relationship metadata.
+// Each node is reached by a shortest path from the root.
 type RelatedMemoryResult struct {
-	Memory       Memory `json:"memory"`
-	RelationType string `json:"relationship_type"`
-	Direction    string `json:"direction"`
-	Depth        int    `json:"depth"`
+	Memory       Memory         `json:"memory"`
+	RelationType string         `json:"relationship_type"` // Type of the last edge on the path
+	Direction    string         `json:"direction"`         // "outgoing" if the last edge points to this node, else "incoming"
+	Depth        int            `json:"depth"`
+	Path         []string       `json:"path"`  // Node IDs from the root to this node, inclusive
+	Edges        []Relationship `json:"edges"` // The relationships along Path, as stored
 }
@@ -92,6 +92,20 @@ func TestRelatedInfo_Struct(t *testing.T) {
 	}
 }
 
+func TestRelationshipProperties_Merge(t *testing.T) {
+	weight := 0.5
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	p := RelationshipProperties{Reason: "first", Weight: &weight, CreatedAt: created}
+
+	got := p.Merge(RelationshipProperties{Metadata: map[string]string{"k": "v"}, CreatedAt: time.Now()})
+	if got.Reason != "first" || got.Weight != &weight || got.Metadata["k"] != "v" || !got.CreatedAt.Equal(created) {
+		t.Errorf("Merge: got %+v", got)
+	}
+	if got := p.Merge(RelationshipProperties{Reason: "second"}); got.Reason != "second" || got.Weight != &weight {
+		t.Errorf("Merge reason: got %+v", got)
+	}
+}
+
 func TestRelatedMemoryResult_Struct(t *testing.T) {
 	now := time.Now()
 	rmr := RelatedMemoryResult{
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrInvalidCursor is returned for a cursor that was not issued by a
+// previous page of the same kind of listing.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page describes where a page of results sits in the full result set
+type Page struct {
+	NextCursor string // Fetches the following page; empty on the last page
+	Total      int    // Number of results across all pages
+}
+
+// Cursor marks where the next page of a listing starts. Clients only see it
+// encoded, as an opaque token. Lists are keyed on the last item returned, so
+// pages neither skip nor repeat items when others are added or removed;
+// ranked searches, whose scores have no stable key, count the results
+// already returned.
+type Cursor struct {
+	Offset    int       `json:"o,omitzero"` // Ranked searches: results already returned
+	UpdatedAt time.Time `json:"u,omitzero"` // Lists by recency: update time of the last item
+	Position  float64   `json:"p,omitzero"` // Tasks within a plan: position of the last task
+	ID        string    `json:"i,omitzero"` // Lists: ID of the last item, breaking ties
+}
+
+// Encode returns the cursor as an opaque token.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// IsZero reports whether the cursor is the start of a listing.
+func (c Cursor) IsZero() bool {
+	return c == Cursor{}
+}
+
+// DecodeCursor parses a token returned by Encode. The empty token is the
+// start of a listing.
+func DecodeCursor(token string) (Cursor, error) {
+	var c Cursor
+	if token == "" {
+		return c, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.Offset < 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// DecodeListCursor parses a token returned for a list, which names the last
+// item of its page.
+func DecodeListCursor(token string) (Cursor, error) {
+	c, err := DecodeCursor(token)
+	if err == nil && !c.IsZero() && c.ID == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, err
+}
+
+// Paginate returns the page of items starting at offset, at most limit long,
+// and describes it. items must hold the whole result set.
+func Paginate[T any](items []T, offset, limit int) ([]T, Page) {
+	page := PageAt(offset, limit, len(items))
+	if offset >= len(items) {
+		return nil, page
+	}
+	return items[offset:min(offset+limit, len(items))], page
+}
+
+// PageAt describes the page starting at offset, at most limit long, of a
+// result set of total items. Backends that page in their queries use it
+// instead of Paginate.
+func PageAt(offset, limit, total int) Page {
+	page := Page{Total: total}
+	if end := offset + limit; offset < total && end < total {
+		page.NextCursor = Cursor{Offset: end}.Encode()
+	}
+	return page
+}
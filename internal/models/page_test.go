@@ -0,0 +1,77 @@
+package models
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	cursors := []Cursor{
+		{Offset: 20},
+		{UpdatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), ID: "plan-1"},
+		{Position: 2500, ID: "task-1"},
+	}
+	for _, c := range cursors {
+		got, err := DecodeCursor(c.Encode())
+		if err != nil {
+			t.Fatalf("DecodeCursor(%+v): %v", c, err)
+		}
+		if !got.UpdatedAt.Equal(c.UpdatedAt) || got.Offset != c.Offset || got.Position != c.Position || got.ID != c.ID {
+			t.Errorf("round trip: got %+v, want %+v", got, c)
+		}
+	}
+
+	if c, err := DecodeCursor(""); err != nil || !c.IsZero() {
+		t.Errorf("empty token: got %+v, %v", c, err)
+	}
+	for _, token := range []string{"not a cursor", "bm90IGpzb24", Cursor{Offset: -1}.Encode()} {
+		if _, err := DecodeCursor(token); err != ErrInvalidCursor {
+			t.Errorf("DecodeCursor(%q): got %v, want ErrInvalidCursor", token, err)
+		}
+	}
+	if _, err := DecodeListCursor(Cursor{Offset: 10}.Encode()); err != ErrInvalidCursor {
+		t.Errorf("DecodeListCursor of a search cursor: got %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	var all []int
+	offset := 0
+	for {
+		page, info := Paginate(items, offset, 2)
+		if info.Total != len(items) {
+			t.Errorf("Total = %d, want %d", info.Total, len(items))
+		}
+		all = append(all, page...)
+		if info.NextCursor == "" {
+			break
+		}
+		c, err := DecodeCursor(info.NextCursor)
+		if err != nil {
+			t.Fatalf("DecodeCursor: %v", err)
+		}
+		offset = c.Offset
+	}
+	if !slices.Equal(all, items) {
+		t.Errorf("pages joined: got %v, want %v", all, items)
+	}
+
+	if page, info := Paginate(items, 10, 2); page != nil || info.NextCursor != "" {
+		t.Errorf("offset past the end: got %v, %+v", page, info)
+	}
+}
+
+func TestPageAt(t *testing.T) {
+	if info := PageAt(0, 2, 5); info.Total != 5 || info.NextCursor != (Cursor{Offset: 2}).Encode() {
+		t.Errorf("first page: got %+v", info)
+	}
+	if info := PageAt(4, 2, 5); info.NextCursor != "" {
+		t.Errorf("last page: got %+v", info)
+	}
+	if info := PageAt(10, 2, 5); info.Total != 5 || info.NextCursor != "" {
+		t.Errorf("offset past the end: got %+v", info)
+	}
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"math"
+	"time"
+)
 
 // PlanStatus defines the status of a plan
 type PlanStatus string
@@ -41,6 +45,7 @@ type Plan struct {
 	Tags        []string          `json:"tags,omitempty"`
 	CreatedAt   time.Time         `json:"created_at"`
 	UpdatedAt   time.Time         `json:"updated_at"`
+	Version     int64             `json:"version"` // Starts at 1 and increases with every update
 }
 
 // PlanSearchResult contains a plan with optional related information
@@ -48,3 +53,92 @@ type PlanSearchResult struct {
 	Plan    Plan     `json:"plan"`
 	Related []string `json:"related,omitempty"`
 }
+
+// OrphanTasks says what deleting a plan does with its tasks that belong to no
+// other plan.
+type OrphanTasks string
+
+const (
+	OrphanTasksDelete OrphanTasks = "delete" // Move them to the trash with the plan
+	OrphanTasksDetach OrphanTasks = "detach" // Keep them, in no plan
+	OrphanTasksMove   OrphanTasks = "move"   // Keep them, appended to a target plan
+)
+
+// PlanDeleteOptions controls what deleting a plan does with its tasks.
+type PlanDeleteOptions struct {
+	OrphanTasks  OrphanTasks // Empty means OrphanTasksDelete
+	TargetPlanID string      // The plan orphaned tasks move to, for OrphanTasksMove
+}
+
+// Validate checks the options for deleting the plan with the given ID. It
+// does not check that the target plan exists.
+func (o PlanDeleteOptions) Validate(planID string) error {
+	switch o.OrphanTasks {
+	case "", OrphanTasksDelete, OrphanTasksDetach:
+		if o.TargetPlanID != "" {
+			return fmt.Errorf("target plan is only used when moving orphan tasks")
+		}
+	case OrphanTasksMove:
+		if o.TargetPlanID == "" {
+			return fmt.Errorf("target plan is required to move orphan tasks")
+		}
+		if o.TargetPlanID == planID {
+			return fmt.Errorf("cannot move orphan tasks to the plan being deleted")
+		}
+	default:
+		return fmt.Errorf("invalid orphan tasks option %q: must be delete, detach or move", o.OrphanTasks)
+	}
+	return nil
+}
+
+// PlanProgress sums up how far along a plan's tasks are.
+type PlanProgress struct {
+	Total           int                `json:"total"`
+	ByStatus        map[TaskStatus]int `json:"by_status"` // Every task status, with zero counts too
+	Blocked         int                `json:"blocked"`
+	PercentComplete float64            `json:"percent_complete"` // Of the tasks not cancelled
+	LastActivity    time.Time          `json:"last_activity"`    // Latest update to the plan or one of its tasks
+}
+
+// NewPlanProgress returns the progress of a plan, last updated at updatedAt,
+// before any of its tasks are added.
+func NewPlanProgress(updatedAt time.Time) PlanProgress {
+	p := PlanProgress{
+		ByStatus:     make(map[TaskStatus]int, len(ValidTaskStatuses)),
+		LastActivity: updatedAt,
+	}
+	for _, status := range ValidTaskStatuses {
+		p.ByStatus[status] = 0
+	}
+	return p
+}
+
+// Add counts count tasks with the given status, the latest of them updated
+// at updatedAt.
+func (p *PlanProgress) Add(status TaskStatus, count int, updatedAt time.Time) {
+	p.Total += count
+	p.ByStatus[status] += count
+	if status == TaskStatusBlocked {
+		p.Blocked += count
+	}
+	if updatedAt.After(p.LastActivity) {
+		p.LastActivity = updatedAt
+	}
+
+	// Cancelled tasks count neither way, so a plan whose tasks are all
+	// completed or cancelled is 100% complete
+	switch counted := p.Total - p.ByStatus[TaskStatusCancelled]; {
+	case counted > 0:
+		p.PercentComplete = math.Round(1000*float64(p.ByStatus[TaskStatusCompleted])/float64(counted)) / 10
+	case p.Total > 0:
+		p.PercentComplete = 100
+	default:
+		p.PercentComplete = 0
+	}
+}
+
+// Finished reports whether the plan has tasks and all of them are completed
+// or cancelled.
+func (p PlanProgress) Finished() bool {
+	return p.Total > 0 && p.ByStatus[TaskStatusCompleted]+p.ByStatus[TaskStatusCancelled] == p.Total
+}
@@ -84,3 +84,61 @@ func TestValidPlanStatuses(t *testing.T) {
 		t.Errorf("expected %d valid plan statuses, got %d", expected, len(ValidPlanStatuses))
 	}
 }
+
+func TestPlanDeleteOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    PlanDeleteOptions
+		wantErr bool
+	}{
+		{"default", PlanDeleteOptions{}, false},
+		{"delete", PlanDeleteOptions{OrphanTasks: OrphanTasksDelete}, false},
+		{"detach", PlanDeleteOptions{OrphanTasks: OrphanTasksDetach}, false},
+		{"move", PlanDeleteOptions{OrphanTasks: OrphanTasksMove, TargetPlanID: "other"}, false},
+		{"move without target", PlanDeleteOptions{OrphanTasks: OrphanTasksMove}, true},
+		{"move to itself", PlanDeleteOptions{OrphanTasks: OrphanTasksMove, TargetPlanID: "plan"}, true},
+		{"target without move", PlanDeleteOptions{OrphanTasks: OrphanTasksDetach, TargetPlanID: "other"}, true},
+		{"unknown", PlanDeleteOptions{OrphanTasks: "keep"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate("plan"); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlanProgress(t *testing.T) {
+	planUpdated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := NewPlanProgress(planUpdated)
+	if p.Total != 0 || p.PercentComplete != 0 || p.Finished() || p.LastActivity != planUpdated {
+		t.Errorf("empty plan: got %+v", p)
+	}
+	if len(p.ByStatus) != len(ValidTaskStatuses) {
+		t.Errorf("ByStatus: got %v, want every status", p.ByStatus)
+	}
+
+	p.Add(TaskStatusCompleted, 1, planUpdated.Add(time.Hour))
+	p.Add(TaskStatusPending, 1, planUpdated.Add(-time.Hour))
+	p.Add(TaskStatusBlocked, 1, planUpdated)
+	if p.Total != 3 || p.Blocked != 1 || p.PercentComplete != 33.3 || p.Finished() {
+		t.Errorf("one of three completed: got %+v", p)
+	}
+	if !p.LastActivity.Equal(planUpdated.Add(time.Hour)) {
+		t.Errorf("LastActivity: got %v", p.LastActivity)
+	}
+
+	// Cancelled tasks are left out of the percentage
+	p = NewPlanProgress(planUpdated)
+	p.Add(TaskStatusCompleted, 1, planUpdated)
+	p.Add(TaskStatusCancelled, 2, planUpdated)
+	if p.PercentComplete != 100 || !p.Finished() {
+		t.Errorf("completed and cancelled: got %+v", p)
+	}
+	p = NewPlanProgress(planUpdated)
+	p.Add(TaskStatusCancelled, 1, planUpdated)
+	if p.PercentComplete != 100 || !p.Finished() {
+		t.Errorf("all cancelled: got %+v", p)
+	}
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Revision is a snapshot of a memory, plan or task at one of its versions.
+// Stores record one when a node is created and after every update, so the
+// revisions of a node list each state it has been in.
+type Revision struct {
+	NodeID      string            `json:"node_id"`
+	Kind        NodeKind          `json:"kind"`
+	Version     int64             `json:"version"`
+	Type        MemoryType        `json:"type,omitempty"`        // Memories only
+	Content     string            `json:"content,omitempty"`     // Memories and tasks
+	Name        string            `json:"name,omitempty"`        // Plans only
+	Description string            `json:"description,omitempty"` // Plans only
+	Status      string            `json:"status,omitempty"`      // Plans and tasks
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Actor       string            `json:"actor,omitempty"` // Who made the change; empty if unknown
+	CreatedAt   time.Time         `json:"created_at"`      // When the node reached this version
+}
+
+// MemoryRevision returns the revision holding the current state of m.
+func MemoryRevision(m Memory, actor string) Revision {
+	return Revision{
+		NodeID:    m.ID,
+		Kind:      KindMemory,
+		Version:   m.Version,
+		Type:      m.Type,
+		Content:   m.Content,
+		Metadata:  m.Metadata,
+		Tags:      m.Tags,
+		Actor:     actor,
+		CreatedAt: m.UpdatedAt,
+	}
+}
+
+// PlanRevision returns the revision holding the current state of p.
+func PlanRevision(p Plan, actor string) Revision {
+	return Revision{
+		NodeID:      p.ID,
+		Kind:        KindPlan,
+		Version:     p.Version,
+		Name:        p.Name,
+		Description: p.Description,
+		Status:      string(p.Status),
+		Metadata:    p.Metadata,
+		Tags:        p.Tags,
+		Actor:       actor,
+		CreatedAt:   p.UpdatedAt,
+	}
+}
+
+// TaskRevision returns the revision holding the current state of t.
+func TaskRevision(t Task, actor string) Revision {
+	return Revision{
+		NodeID:    t.ID,
+		Kind:      KindTask,
+		Version:   t.Version,
+		Content:   t.Content,
+		Status:    string(t.Status),
+		Metadata:  t.Metadata,
+		Tags:      t.Tags,
+		Actor:     actor,
+		CreatedAt: t.UpdatedAt,
+	}
+}
+
+// FieldChange describes how one field differs between two revisions. Single
+// line values are given in full; multi-line values, such as long content,
+// are given as a line diff instead.
+type FieldChange struct {
+	Field string   `json:"field"`
+	From  string   `json:"from,omitempty"`
+	To    string   `json:"to,omitempty"`
+	Diff  []string `json:"diff,omitempty"` // Lines prefixed with "-" (removed), "+" (added) or " " (kept)
+}
+
+// DiffRevisions lists the fields that differ between two revisions of a node.
+func DiffRevisions(from, to Revision) []FieldChange {
+	var changes []FieldChange
+	for _, f := range []struct {
+		name     string
+		from, to string
+	}{
+		{"type", string(from.Type), string(to.Type)},
+		{"name", from.Name, to.Name},
+		{"description", from.Description, to.Description},
+		{"content", from.Content, to.Content},
+		{"status", from.Status, to.Status},
+		{"metadata", metadataText(from.Metadata), metadataText(to.Metadata)},
+		{"tags", strings.Join(from.Tags, "\n"), strings.Join(to.Tags, "\n")},
+	} {
+		if f.from == f.to {
+			continue
+		}
+		change := FieldChange{Field: f.name}
+		if strings.Contains(f.from, "\n") || strings.Contains(f.to, "\n") {
+			change.Diff = LineDiff(f.from, f.to)
+		} else {
+			change.From, change.To = f.from, f.to
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// metadataText renders metadata as sorted "key: value" lines.
+func metadataText(m map[string]string) string {
+	lines := make([]string, 0, len(m))
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		lines = append(lines, fmt.Sprintf("%s: %s", k, m[k]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// LineDiff returns the shortest edit turning the lines of a into those of b,
+// as lines prefixed with "-" for removed, "+" for added and " " for kept.
+func LineDiff(a, b string) []string {
+	x, y := splitLines(a), splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			diff = append(diff, " "+x[i])
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "-"+x[i])
+			i++
+		default:
+			diff = append(diff, "+"+y[j])
+			j++
+		}
+	}
+	return diff
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	got := LineDiff("use postgres\nrun migrations\ndeploy", "use sqlite\nrun migrations\ndeploy\nverify")
+	want := []string{"-use postgres", "+use sqlite", " run migrations", " deploy", "+verify"}
+	if !slices.Equal(got, want) {
+		t.Errorf("LineDiff: got %q, want %q", got, want)
+	}
+	if got := LineDiff("", "one"); !slices.Equal(got, []string{"+one"}) {
+		t.Errorf("LineDiff from empty: got %q", got)
+	}
+}
+
+func TestDiffRevisions(t *testing.T) {
+	from := Revision{Content: "first\nsecond", Status: "pending", Tags: []string{"a"}, Metadata: map[string]string{"k": "v"}}
+	to := Revision{Content: "first\nthird", Status: "completed", Tags: []string{"a"}}
+
+	changes := DiffRevisions(from, to)
+	if len(changes) != 3 {
+		t.Fatalf("DiffRevisions: got %+v, want content, status and metadata changes", changes)
+	}
+	if c := changes[0]; c.Field != "content" || !slices.Equal(c.Diff, []string{" first", "-second", "+third"}) {
+		t.Errorf("content change: got %+v", c)
+	}
+	if c := changes[1]; c.Field != "status" || c.From != "pending" || c.To != "completed" || c.Diff != nil {
+		t.Errorf("status change: got %+v", c)
+	}
+	if c := changes[2]; c.Field != "metadata" || c.From != "k: v" || c.To != "" {
+		t.Errorf("metadata change: got %+v", c)
+	}
+	if changes := DiffRevisions(to, to); len(changes) != 0 {
+		t.Errorf("DiffRevisions of a revision with itself: got %+v", changes)
+	}
+}
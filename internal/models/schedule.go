@@ -0,0 +1,47 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// EstimateKey is the task metadata key holding an estimate of the work the
+// task takes, as a non-negative number in whatever unit the plan uses.
+const EstimateKey = "estimate"
+
+// DefaultEstimate is the estimate of a task that has none, so that a
+// critical path without estimates is the longest chain of tasks.
+const DefaultEstimate = 1.0
+
+// Estimate returns the task's estimate from its metadata, or DefaultEstimate
+// when it has none.
+func (t Task) Estimate() (float64, error) {
+	s, ok := t.Metadata[EstimateKey]
+	if !ok {
+		return DefaultEstimate, nil
+	}
+	estimate, err := strconv.ParseFloat(s, 64)
+	if err != nil || estimate < 0 {
+		return 0, fmt.Errorf("invalid estimate for task %s: %q (must be a non-negative number)", t.ID, s)
+	}
+	return estimate, nil
+}
+
+// ScheduledTask is a task's place in a Schedule.
+type ScheduledTask struct {
+	TaskInPlan
+	Layer    int     // Length of the longest chain of tasks it waits for
+	Estimate float64 // See Task.Estimate
+	Start    float64 // Earliest start: the latest Finish of the tasks it waits for
+	Finish   float64 // Start plus Estimate
+}
+
+// Schedule orders the tasks of a plan by the DEPENDS_ON and BLOCKS
+// relationships between them. Relationships to tasks of other plans are left
+// out.
+type Schedule struct {
+	Tasks        []ScheduledTask // Each after the tasks it waits for, otherwise in position order
+	Layers       [][]string      // Task IDs by Layer; each waits only for tasks of earlier layers
+	CriticalPath []string        // Task IDs along the chain that takes longest, first to last
+	Duration     float64         // Total estimate along the critical path
+}
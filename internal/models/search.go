@@ -0,0 +1,295 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SearchMode selects how a memory search matches the query
+type SearchMode string
+
+const (
+	SearchModeText     SearchMode = "text"     // Ranked full-text search (default)
+	SearchModeSemantic SearchMode = "semantic" // Cosine similarity of embeddings
+	SearchModeHybrid   SearchMode = "hybrid"   // Full-text score blended with graph proximity and recency
+)
+
+// ValidSearchMode reports whether m is empty or a known search mode
+func ValidSearchMode(m SearchMode) bool {
+	return m == "" || m == SearchModeText || m == SearchModeSemantic || m == SearchModeHybrid
+}
+
+// SearchSort selects the order of search results
+type SearchSort string
+
+const (
+	SortRelevance SearchSort = "relevance"  // By score (default when there is a query)
+	SortCreatedAt SearchSort = "created_at" // By creation time
+	SortUpdatedAt SearchSort = "updated_at" // By last update (default without a query)
+)
+
+// SortOrder is the direction of a SearchSort
+type SortOrder string
+
+const (
+	OrderDesc SortOrder = "desc" // Highest score or newest first (default)
+	OrderAsc  SortOrder = "asc"  // Lowest score or oldest first
+)
+
+// TagMatch selects whether a tag filter needs any or all of its tags
+type TagMatch string
+
+const (
+	TagMatchAny TagMatch = "any" // At least one tag (default)
+	TagMatchAll TagMatch = "all" // Every tag
+)
+
+// SearchFilter restricts a memory search. Zero fields do not filter. Time
+// ranges include their After bound and exclude their Before bound.
+type SearchFilter struct {
+	Type          MemoryType
+	Tags          []string
+	TagMatch      TagMatch
+	Metadata      map[string]string // Every key must have the given value
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+}
+
+// Empty reports whether the filter matches every memory
+func (f SearchFilter) Empty() bool {
+	return f.Type == "" && len(f.Tags) == 0 && len(f.Metadata) == 0 &&
+		f.CreatedAfter.IsZero() && f.CreatedBefore.IsZero() &&
+		f.UpdatedAfter.IsZero() && f.UpdatedBefore.IsZero()
+}
+
+// Matches reports whether a memory passes the filter
+func (f SearchFilter) Matches(m Memory) bool {
+	if f.Type != "" && m.Type != f.Type {
+		return false
+	}
+	if len(f.Tags) > 0 {
+		matched := 0
+		for _, tag := range f.Tags {
+			if slices.Contains(m.Tags, tag) {
+				matched++
+			}
+		}
+		if matched == 0 || (f.TagMatch == TagMatchAll && matched < len(f.Tags)) {
+			return false
+		}
+	}
+	for k, v := range f.Metadata {
+		if got, ok := m.Metadata[k]; !ok || got != v {
+			return false
+		}
+	}
+	return inRange(m.CreatedAt, f.CreatedAfter, f.CreatedBefore) &&
+		inRange(m.UpdatedAt, f.UpdatedAfter, f.UpdatedBefore)
+}
+
+func inRange(t, after, before time.Time) bool {
+	return (after.IsZero() || !t.Before(after)) && (before.IsZero() || t.Before(before))
+}
+
+// SearchOptions controls a memory search
+type SearchOptions struct {
+	Mode  SearchMode // Defaults to SearchModeText
+	Limit int        // Defaults to 10
+	// AnchorID is a node (memory, plan or task) that hybrid search favours
+	// memories near, typically the task being worked on
+	AnchorID string
+	Filter   SearchFilter
+	Sort     SearchSort // Defaults to SortRelevance, or SortUpdatedAt without a query
+	Order    SortOrder  // Defaults to OrderDesc
+	Cursor   string     // Page.NextCursor of the previous page, if any
+}
+
+// Validate checks that the mode, sort and tag match are known, that an anchor
+// is only given for hybrid search, that the cursor is well formed, and that
+// time ranges are not inverted.
+func (o SearchOptions) Validate() error {
+	if !ValidSearchMode(o.Mode) {
+		return fmt.Errorf("invalid search mode: %q", o.Mode)
+	}
+	if o.AnchorID != "" && o.Mode != SearchModeHybrid {
+		return fmt.Errorf("anchor_id requires hybrid search mode")
+	}
+	switch o.Sort {
+	case "", SortRelevance, SortCreatedAt, SortUpdatedAt:
+	default:
+		return fmt.Errorf("invalid sort: %q", o.Sort)
+	}
+	switch o.Order {
+	case "", OrderDesc, OrderAsc:
+	default:
+		return fmt.Errorf("invalid sort order: %q", o.Order)
+	}
+	switch o.Filter.TagMatch {
+	case "", TagMatchAny, TagMatchAll:
+	default:
+		return fmt.Errorf("invalid tag match: %q", o.Filter.TagMatch)
+	}
+	if _, err := DecodeCursor(o.Cursor); err != nil {
+		return err
+	}
+	f := o.Filter
+	if !f.CreatedAfter.IsZero() && !f.CreatedBefore.IsZero() && !f.CreatedAfter.Before(f.CreatedBefore) {
+		return fmt.Errorf("created_after must be before created_before")
+	}
+	if !f.UpdatedAfter.IsZero() && !f.UpdatedBefore.IsZero() && !f.UpdatedAfter.Before(f.UpdatedBefore) {
+		return fmt.Errorf("updated_after must be before updated_before")
+	}
+	return nil
+}
+
+// WithDefaults returns the options with unset fields filled in for a search
+// for query. Without a query all scores are zero, so results are sorted by
+// last update instead, except in hybrid mode where graph signals still rank.
+func (o SearchOptions) WithDefaults(query string) SearchOptions {
+	if o.Mode == "" {
+		o.Mode = SearchModeText
+	}
+	if o.Limit <= 0 {
+		o.Limit = 10
+	}
+	switch {
+	case ListOnly(query) && o.Mode != SearchModeHybrid && (o.Sort == "" || o.Sort == SortRelevance):
+		o.Sort = SortUpdatedAt
+	case o.Sort == "":
+		o.Sort = SortRelevance
+	}
+	if o.Order == "" {
+		o.Order = OrderDesc
+	}
+	if o.Filter.TagMatch == "" {
+		o.Filter.TagMatch = TagMatchAny
+	}
+	return o
+}
+
+// Offset returns the number of results before the page the cursor starts.
+// It assumes the options passed Validate.
+func (o SearchOptions) Offset() int {
+	c, _ := DecodeCursor(o.Cursor)
+	return c.Offset
+}
+
+// ListOnly reports whether a search has no query. Such a search returns
+// every memory that passes the filter, with a zero score.
+func ListOnly(query string) bool {
+	return strings.TrimSpace(query) == ""
+}
+
+// SortResults orders results by sort and order. Ties keep their order.
+func SortResults(results []SearchResult, by SearchSort, order SortOrder) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if order == OrderAsc {
+			a, b = b, a
+		}
+		switch by {
+		case SortCreatedAt:
+			return a.Memory.CreatedAt.After(b.Memory.CreatedAt)
+		case SortUpdatedAt:
+			return a.Memory.UpdatedAt.After(b.Memory.UpdatedAt)
+		default:
+			return a.Score > b.Score
+		}
+	})
+}
+
+// NodeKind is the label of a node found by a search across node types
+type NodeKind string
+
+const (
+	KindMemory NodeKind = "Memory"
+	KindPlan   NodeKind = "Plan"
+	KindTask   NodeKind = "Task"
+)
+
+// NodeKinds lists every kind of node
+var NodeKinds = []NodeKind{KindMemory, KindPlan, KindTask}
+
+// NodeSearchOptions controls a search across memories, plans and tasks
+type NodeSearchOptions struct {
+	Kinds []NodeKind // Defaults to every kind
+	Mode  SearchMode // SearchModeText (default) or SearchModeSemantic
+	Limit int        // Defaults to 10
+}
+
+// Validate checks that the kinds and mode are known. Hybrid ranking only
+// applies to memories.
+func (o NodeSearchOptions) Validate() error {
+	for _, k := range o.Kinds {
+		if !slices.Contains(NodeKinds, k) {
+			return fmt.Errorf("invalid node kind: %q", k)
+		}
+	}
+	switch o.Mode {
+	case "", SearchModeText, SearchModeSemantic:
+	case SearchModeHybrid:
+		return fmt.Errorf("hybrid search mode is only supported for memories")
+	default:
+		return fmt.Errorf("invalid search mode: %q", o.Mode)
+	}
+	return nil
+}
+
+// WithDefaults returns the options with unset fields filled in.
+func (o NodeSearchOptions) WithDefaults() NodeSearchOptions {
+	if len(o.Kinds) == 0 {
+		o.Kinds = NodeKinds
+	}
+	if o.Mode == "" {
+		o.Mode = SearchModeText
+	}
+	if o.Limit <= 0 {
+		o.Limit = 10
+	}
+	return o
+}
+
+// NodeSearchResult is a memory, plan or task matched by a search across node types
+type NodeSearchResult struct {
+	Kind      NodeKind  `json:"kind"`
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`             // Plan name, or the first line of memory or task content
+	Snippet   string    `json:"snippet,omitempty"` // Matched excerpt with **highlighted** words
+	Status    string    `json:"status,omitempty"`  // Plans and tasks only
+	Score     float64   `json:"score"`             // Relevance in [0, 1); higher is better
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// titleRunes is the longest title NodeTitle returns, in runes.
+const titleRunes = 80
+
+// NodeTitle returns the title of a memory or task: the first non-blank line
+// of its content, shortened to 80 characters.
+func NodeTitle(content string) string {
+	var title string
+	for line := range strings.Lines(content) {
+		if title = strings.TrimSpace(line); title != "" {
+			break
+		}
+	}
+	if r := []rune(title); len(r) > titleRunes {
+		title = strings.TrimSpace(string(r[:titleRunes-1])) + "…"
+	}
+	return title
+}
+
+// SortNodeResults orders results by score, highest first. Ties go to the
+// most recently updated node.
+func SortNodeResults(results []NodeSearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].UpdatedAt.After(results[j].UpdatedAt)
+	})
+}
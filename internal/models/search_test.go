@@ -0,0 +1,164 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchOptions_Validate(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		opts    SearchOptions
+		wantErr bool
+	}{
+		{SearchOptions{}, false},
+		{SearchOptions{Mode: SearchModeSemantic}, false},
+		{SearchOptions{Mode: SearchModeHybrid, AnchorID: "task-1"}, false},
+		{SearchOptions{Sort: SortUpdatedAt, Order: OrderAsc, Filter: SearchFilter{TagMatch: TagMatchAll}}, false},
+		{SearchOptions{Mode: "fuzzy"}, true},
+		{SearchOptions{AnchorID: "task-1"}, true},
+		{SearchOptions{Mode: SearchModeText, AnchorID: "task-1"}, true},
+		{SearchOptions{Sort: "name"}, true},
+		{SearchOptions{Order: "up"}, true},
+		{SearchOptions{Filter: SearchFilter{TagMatch: "some"}}, true},
+		{SearchOptions{Filter: SearchFilter{UpdatedAfter: now, UpdatedBefore: now.Add(-time.Hour)}}, true},
+		{SearchOptions{Filter: SearchFilter{CreatedAfter: now, CreatedBefore: now}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v): got %v, wantErr %v", tt.opts, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSearchOptions_WithDefaults(t *testing.T) {
+	o := SearchOptions{}.WithDefaults("auth")
+	if o.Mode != SearchModeText || o.Limit != 10 || o.Sort != SortRelevance || o.Order != OrderDesc || o.Filter.TagMatch != TagMatchAny {
+		t.Errorf("defaults with a query: %+v", o)
+	}
+	if o := (SearchOptions{}).WithDefaults("  "); o.Sort != SortUpdatedAt {
+		t.Errorf("sort without a query: got %s, want %s", o.Sort, SortUpdatedAt)
+	}
+	if o := (SearchOptions{Mode: SearchModeHybrid}).WithDefaults(""); o.Sort != SortRelevance {
+		t.Errorf("hybrid sort without a query: got %s, want %s", o.Sort, SortRelevance)
+	}
+	if o := (SearchOptions{Sort: SortCreatedAt}).WithDefaults(""); o.Sort != SortCreatedAt {
+		t.Errorf("explicit sort without a query: got %s", o.Sort)
+	}
+}
+
+func TestSearchFilter_Matches(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	mem := Memory{
+		Type:      TypeRepository,
+		Tags:      []string{"auth", "backend"},
+		Metadata:  map[string]string{"repo": "api"},
+		CreatedAt: now.Add(-48 * time.Hour),
+		UpdatedAt: now,
+	}
+
+	tests := []struct {
+		name   string
+		filter SearchFilter
+		want   bool
+	}{
+		{"empty", SearchFilter{}, true},
+		{"type", SearchFilter{Type: TypeRepository}, true},
+		{"other type", SearchFilter{Type: TypeNote}, false},
+		{"any tag", SearchFilter{Tags: []string{"auth", "frontend"}}, true},
+		{"no tag", SearchFilter{Tags: []string{"frontend"}}, false},
+		{"all tags", SearchFilter{Tags: []string{"auth", "backend"}, TagMatch: TagMatchAll}, true},
+		{"missing one of all tags", SearchFilter{Tags: []string{"auth", "frontend"}, TagMatch: TagMatchAll}, false},
+		{"metadata", SearchFilter{Metadata: map[string]string{"repo": "api"}}, true},
+		{"metadata value", SearchFilter{Metadata: map[string]string{"repo": "web"}}, false},
+		{"metadata key", SearchFilter{Metadata: map[string]string{"team": "api"}}, false},
+		{"updated after is inclusive", SearchFilter{UpdatedAfter: now}, true},
+		{"updated before is exclusive", SearchFilter{UpdatedBefore: now}, false},
+		{"created in range", SearchFilter{CreatedAfter: now.Add(-72 * time.Hour), CreatedBefore: now}, true},
+		{"created too early", SearchFilter{CreatedAfter: now.Add(-24 * time.Hour)}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Matches(mem); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		if tt.name == "empty" != tt.filter.Empty() {
+			t.Errorf("%s: Empty() = %v", tt.name, tt.filter.Empty())
+		}
+	}
+}
+
+func TestSortResults(t *testing.T) {
+	now := time.Now()
+	results := []SearchResult{
+		{Memory: Memory{ID: "a", CreatedAt: now.Add(-time.Hour), UpdatedAt: now}, Score: 0.5},
+		{Memory: Memory{ID: "b", CreatedAt: now, UpdatedAt: now.Add(-time.Hour)}, Score: 0.9},
+		{Memory: Memory{ID: "c", CreatedAt: now.Add(-2 * time.Hour), UpdatedAt: now.Add(-2 * time.Hour)}, Score: 0.7},
+	}
+	ids := func() string {
+		s := ""
+		for _, r := range results {
+			s += r.Memory.ID
+		}
+		return s
+	}
+
+	for _, tt := range []struct {
+		by    SearchSort
+		order SortOrder
+		want  string
+	}{
+		{SortRelevance, OrderDesc, "bca"},
+		{SortRelevance, OrderAsc, "acb"},
+		{SortCreatedAt, OrderDesc, "bac"},
+		{SortUpdatedAt, OrderDesc, "abc"},
+		{SortUpdatedAt, OrderAsc, "cba"},
+	} {
+		SortResults(results, tt.by, tt.order)
+		if got := ids(); got != tt.want {
+			t.Errorf("SortResults(%s, %s): got %s, want %s", tt.by, tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestNodeSearchOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    NodeSearchOptions
+		wantErr bool
+	}{
+		{"defaults", NodeSearchOptions{}, false},
+		{"kinds", NodeSearchOptions{Kinds: []NodeKind{KindPlan, KindTask}}, false},
+		{"semantic", NodeSearchOptions{Mode: SearchModeSemantic}, false},
+		{"unknown kind", NodeSearchOptions{Kinds: []NodeKind{"Project"}}, true},
+		{"hybrid", NodeSearchOptions{Mode: SearchModeHybrid}, true},
+		{"unknown mode", NodeSearchOptions{Mode: "fuzzy"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	opts := NodeSearchOptions{}.WithDefaults()
+	if len(opts.Kinds) != 3 || opts.Mode != SearchModeText || opts.Limit != 10 {
+		t.Errorf("WithDefaults() = %+v", opts)
+	}
+}
+
+func TestNodeTitle(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"Refresh tokens before expiry", "Refresh tokens before expiry"},
+		{"\n  Token refresh  \nDetails follow", "Token refresh"},
+		{"", ""},
+		{strings.Repeat("a", 100), strings.Repeat("a", 79) + "…"},
+	}
+	for _, tt := range tests {
+		if got := NodeTitle(tt.content); got != tt.want {
+			t.Errorf("NodeTitle(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
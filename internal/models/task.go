@@ -42,6 +42,7 @@ type Task struct {
 	Tags      []string          `json:"tags,omitempty"`
 	CreatedAt time.Time         `json:"created_at"`
 	UpdatedAt time.Time         `json:"updated_at"`
+	Version   int64             `json:"version"` // Starts at 1 and increases with every update
 }
 
 // TaskSearchResult contains a task with optional related information
@@ -63,3 +64,39 @@ type TaskListResult struct {
 	Task     Task     `json:"task"`
 	Position *float64 `json:"position,omitempty"` // Only set when listing tasks within a plan
 }
+
+// DefaultPositionIncrement is the gap between the positions of consecutive
+// tasks appended to a plan.
+const DefaultPositionIncrement = 1000.0
+
+// CalculateInsertPositions calculates position values for inserting tasks.
+func CalculateInsertPositions(afterPos, beforePos float64, count int) []float64 {
+	if count <= 0 {
+		return nil
+	}
+
+	positions := make([]float64, count)
+
+	switch {
+	case afterPos == 0 && beforePos == 0:
+		for i := 0; i < count; i++ {
+			positions[i] = DefaultPositionIncrement * float64(i+1)
+		}
+	case beforePos == 0:
+		for i := 0; i < count; i++ {
+			positions[i] = afterPos + DefaultPositionIncrement*float64(i+1)
+		}
+	case afterPos == 0:
+		gap := beforePos / float64(count+1)
+		for i := 0; i < count; i++ {
+			positions[i] = gap * float64(i+1)
+		}
+	default:
+		gap := (beforePos - afterPos) / float64(count+1)
+		for i := 0; i < count; i++ {
+			positions[i] = afterPos + gap*float64(i+1)
+		}
+	}
+
+	return positions
+}
@@ -83,3 +83,71 @@ func TestValidTaskStatuses(t *testing.T) {
 		t.Errorf("expected %d valid task statuses, got %d", expected, len(ValidTaskStatuses))
 	}
 }
+
+func TestTaskEstimate(t *testing.T) {
+	tests := []struct {
+		metadata map[string]string
+		expected float64
+		wantErr  bool
+	}{
+		{nil, DefaultEstimate, false},
+		{map[string]string{"priority": "high"}, DefaultEstimate, false},
+		{map[string]string{EstimateKey: "2.5"}, 2.5, false},
+		{map[string]string{EstimateKey: "0"}, 0, false},
+		{map[string]string{EstimateKey: "-1"}, 0, true},
+		{map[string]string{EstimateKey: "two days"}, 0, true},
+	}
+
+	for _, tc := range tests {
+		got, err := Task{ID: "t", Metadata: tc.metadata}.Estimate()
+		if (err != nil) != tc.wantErr {
+			t.Errorf("Estimate() with %v: error %v, wantErr %v", tc.metadata, err, tc.wantErr)
+		}
+		if got != tc.expected {
+			t.Errorf("Estimate() with %v: expected %v, got %v", tc.metadata, tc.expected, got)
+		}
+	}
+}
+
+func TestCalculateInsertPositions(t *testing.T) {
+	tests := []struct {
+		name      string
+		afterPos  float64
+		beforePos float64
+		count     int
+		wantLen   int
+	}{
+		{"Empty plan", 0, 0, 1, 1},
+		{"Append to end", 1000, 0, 1, 1},
+		{"Insert at start", 0, 1000, 1, 1},
+		{"Insert between", 1000, 2000, 1, 1},
+		{"Multiple insert", 1000, 2000, 3, 3},
+		{"Zero count", 1000, 2000, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			positions := CalculateInsertPositions(tt.afterPos, tt.beforePos, tt.count)
+			if len(positions) != tt.wantLen {
+				t.Errorf("CalculateInsertPositions(%f, %f, %d) returned %d positions, want %d",
+					tt.afterPos, tt.beforePos, tt.count, len(positions), tt.wantLen)
+			}
+
+			// Verify positions are in order
+			for i := 1; i < len(positions); i++ {
+				if positions[i] <= positions[i-1] {
+					t.Errorf("Positions not in ascending order: %v", positions)
+				}
+			}
+
+			// Verify positions are between afterPos and beforePos (when both are set)
+			if tt.afterPos > 0 && tt.beforePos > 0 {
+				for _, pos := range positions {
+					if pos <= tt.afterPos || pos >= tt.beforePos {
+						t.Errorf("Position %f not between %f and %f", pos, tt.afterPos, tt.beforePos)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// TrashedNode is a memory, plan or task as it was when deleted, along with
+// its revision history. Only the field matching Kind is set.
+type TrashedNode struct {
+	Kind      NodeKind   `json:"kind"`
+	Memory    *Memory    `json:"memory,omitempty"`
+	Plan      *Plan      `json:"plan,omitempty"`
+	Task      *Task      `json:"task,omitempty"`
+	Revisions []Revision `json:"revisions,omitempty"` // Oldest first
+}
+
+// ID returns the ID of the node.
+func (n TrashedNode) ID() string {
+	switch {
+	case n.Plan != nil:
+		return n.Plan.ID
+	case n.Task != nil:
+		return n.Task.ID
+	case n.Memory != nil:
+		return n.Memory.ID
+	}
+	return ""
+}
+
+// Title returns the name of a plan, or the first line of the content of a
+// memory or task.
+func (n TrashedNode) Title() string {
+	switch {
+	case n.Plan != nil:
+		return n.Plan.Name
+	case n.Task != nil:
+		return NodeTitle(n.Task.Content)
+	case n.Memory != nil:
+		return NodeTitle(n.Memory.Content)
+	}
+	return ""
+}
+
+// TrashEntry is what one delete moved to the trash: the deleted node, the
+// tasks deleted along with a plan, and every relationship that touched them,
+// task positions included, so that restoring it brings them back as they were.
+type TrashEntry struct {
+	ID        string        `json:"id"` // Of the deleted node
+	Kind      NodeKind      `json:"kind"`
+	Title     string        `json:"title"`
+	DeletedAt time.Time     `json:"deleted_at"`
+	Nodes     []TrashedNode `json:"nodes"` // The deleted node first
+	Edges     []Edge        `json:"edges"`
+	// SkippedEdges are set by a restore to the relationships it could not
+	// bring back because a node at their other end was purged.
+	SkippedEdges []Edge `json:"skipped_edges,omitempty"`
+}
+
+// NewTrashEntry returns the entry for deleting nodes, the first of which is
+// the node deleted, along with the given relationships. Relationships listed
+// more than once, such as those between two of the nodes, are kept once.
+func NewTrashEntry(nodes []TrashedNode, edges []Edge, deletedAt time.Time) TrashEntry {
+	entry := TrashEntry{DeletedAt: deletedAt, Nodes: nodes, Edges: []Edge{}}
+	if len(nodes) > 0 {
+		entry.ID = nodes[0].ID()
+		entry.Kind = nodes[0].Kind
+		entry.Title = nodes[0].Title()
+	}
+	seen := make(map[string]bool, len(edges))
+	for _, e := range edges {
+		if !seen[e.ID] {
+			seen[e.ID] = true
+			entry.Edges = append(entry.Edges, e)
+		}
+	}
+	return entry
+}
+
+// NodeIDs returns the IDs of the nodes in the entry.
+func (e TrashEntry) NodeIDs() []string {
+	ids := make([]string, len(e.Nodes))
+	for i, n := range e.Nodes {
+		ids[i] = n.ID()
+	}
+	return ids
+}
+
+// Contains reports whether id names one of the nodes in the entry.
+func (e TrashEntry) Contains(id string) bool {
+	for _, n := range e.Nodes {
+		if n.ID() == id {
+			return true
+		}
+	}
+	return false
+}
+
+// SortTrash orders entries by deletion time, most recent first.
+func SortTrash(entries []TrashEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].DeletedAt.After(entries[j].DeletedAt)
+	})
+}
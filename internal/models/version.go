@@ -0,0 +1,34 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrVersionConflict is wrapped by a VersionConflictError
+var ErrVersionConflict = errors.New("version conflict")
+
+// VersionConflictError reports an update that expected a node to be at a
+// version it is no longer at, because another writer changed it first.
+type VersionConflictError struct {
+	ID       string
+	Expected int64
+	Actual   int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("version conflict on %s: expected version %d, but it is at version %d", e.ID, e.Expected, e.Actual)
+}
+
+func (e *VersionConflictError) Unwrap() error {
+	return ErrVersionConflict
+}
+
+// CheckVersion returns a VersionConflictError if expected is set and differs
+// from the node's actual version. An expected version of 0 always passes.
+func CheckVersion(id string, expected, actual int64) error {
+	if expected == 0 || expected == actual {
+		return nil
+	}
+	return &VersionConflictError{ID: id, Expected: expected, Actual: actual}
+}
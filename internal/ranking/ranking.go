@@ -0,0 +1,69 @@
+// Package ranking blends text relevance with graph signals for hybrid search.
+// The backends gather the signals for each text match and re-rank with Score,
+// so every backend orders hybrid results the same way.
+package ranking
+
+import (
+	"math"
+	"time"
+)
+
+// Weights of each signal in a hybrid score. They sum to 1, so a hybrid score
+// stays in [0, 1] like the text score it starts from. A direct link to the
+// anchor outweighs the spread of exact text scores, so memories connected to
+// the anchor rank first.
+const (
+	textWeight    = 0.45
+	anchorWeight  = 0.30
+	recencyWeight = 0.15
+	linksWeight   = 0.10
+)
+
+// RecencyHalfLife is how long after its last update a memory keeps half of
+// its recency signal.
+const RecencyHalfLife = 30 * 24 * time.Hour
+
+// saturatingLinks is the link count that earns the full links signal. Beyond
+// it, more links say little about relevance.
+const saturatingLinks = 10
+
+// Features are the signals blended into a hybrid score.
+type Features struct {
+	// Text is the full-text relevance score, in [0, 1)
+	Text float64
+	// Links is the number of relationships the memory has, of any type
+	Links int
+	// AnchorHops is 1 for the anchor itself or a node linked to it, 2 for a
+	// node two links away, and 0 otherwise or when there is no anchor
+	AnchorHops int
+	// UpdatedAt is when the memory last changed
+	UpdatedAt time.Time
+}
+
+// Score blends the features into a hybrid score at time now.
+func Score(f Features, now time.Time) float64 {
+	var anchor float64
+	switch f.AnchorHops {
+	case 1:
+		anchor = 1
+	case 2:
+		anchor = 0.5
+	}
+
+	links := math.Min(1, math.Log1p(float64(f.Links))/math.Log1p(saturatingLinks))
+
+	age := now.Sub(f.UpdatedAt)
+	if age < 0 {
+		age = 0
+	}
+	recency := math.Exp2(-float64(age) / float64(RecencyHalfLife))
+
+	return textWeight*f.Text + anchorWeight*anchor + recencyWeight*recency + linksWeight*links
+}
+
+// Bounds returns the lowest and highest hybrid score a match with text score
+// text can reach, whatever its other features.
+func Bounds(text float64) (lo, hi float64) {
+	lo = textWeight * text
+	return lo, lo + anchorWeight + recencyWeight + linksWeight
+}
@@ -0,0 +1,62 @@
+package ranking
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestScore(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	base := Features{Text: 0.6, UpdatedAt: now.Add(-365 * 24 * time.Hour)}
+	anchored := base
+	anchored.AnchorHops = 1
+	twoHops := base
+	twoHops.AnchorHops = 2
+	linked := base
+	linked.Links = 3
+	recent := base
+	recent.UpdatedAt = now
+
+	b := Score(base, now)
+	for name, f := range map[string]Features{"anchored": anchored, "two hops": twoHops, "linked": linked, "recent": recent} {
+		if got := Score(f, now); got <= b {
+			t.Errorf("%s: score %v should exceed base %v", name, got, b)
+		}
+	}
+	if Score(anchored, now) <= Score(twoHops, now) {
+		t.Error("a direct link to the anchor should outrank two hops")
+	}
+
+	// A strong text match near the anchor beats a weak one; an anchored weak
+	// match beats an unanchored strong one
+	weakAnchored := Features{Text: 0.5, AnchorHops: 1, UpdatedAt: now}
+	strong := Features{Text: 0.9, UpdatedAt: now}
+	if Score(weakAnchored, now) <= Score(strong, now) {
+		t.Errorf("anchored weak match %v should outrank unanchored strong match %v", Score(weakAnchored, now), Score(strong, now))
+	}
+
+	best := Features{Text: 1, Links: 100, AnchorHops: 1, UpdatedAt: now}
+	if got := Score(best, now); math.Abs(got-1) > 1e-9 {
+		t.Errorf("maximal features: got %v, want 1", got)
+	}
+
+	halfLife := Features{UpdatedAt: now.Add(-RecencyHalfLife)}
+	if got := Score(halfLife, now); math.Abs(got-recencyWeight/2) > 1e-9 {
+		t.Errorf("recency after one half-life: got %v, want %v", got, recencyWeight/2)
+	}
+}
+
+func TestBounds(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	lo, hi := Bounds(0.6)
+	worst := Score(Features{Text: 0.6, UpdatedAt: now.Add(-100 * 365 * 24 * time.Hour)}, now)
+	best := Score(Features{Text: 0.6, Links: 100, AnchorHops: 1, UpdatedAt: now}, now)
+	if worst < lo || math.Abs(worst-lo) > 1e-6 {
+		t.Errorf("lowest score %v, want just above %v", worst, lo)
+	}
+	if math.Abs(best-hi) > 1e-9 {
+		t.Errorf("highest score %v, want %v", best, hi)
+	}
+}
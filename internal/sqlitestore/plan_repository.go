@@ -0,0 +1,487 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/embedding"
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.PlanStore = (*PlanRepository)(nil)
+
+// PlanRepository provides CRUD operations for plans.
+type PlanRepository struct {
+	store *Store
+}
+
+// NewPlanRepository creates a new plan repository backed by s
+func NewPlanRepository(s *Store) *PlanRepository {
+	return &PlanRepository{store: s}
+}
+
+// Add creates a new plan and optional relationships
+func (r *PlanRepository) Add(ctx context.Context, plan models.Plan, relationships []models.Relationship) (*models.Plan, error) {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if plan.ID == "" {
+		plan.ID = uuid.New().String()
+	}
+	now := time.Now().UTC()
+	plan.CreatedAt = now
+	plan.UpdatedAt = now
+	plan.Version = 1
+
+	if plan.Status == "" {
+		plan.Status = models.PlanStatusActive
+	}
+
+	err = insertNode(ctx, tx, &node{
+		id:          plan.ID,
+		label:       labelPlan,
+		name:        plan.Name,
+		description: plan.Description,
+		status:      string(plan.Status),
+		metadata:    plan.Metadata,
+		tags:        plan.Tags,
+		createdAt:   plan.CreatedAt,
+		updatedAt:   plan.UpdatedAt,
+		version:     plan.Version,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plan: %w", err)
+	}
+	if err := recordRevision(ctx, tx, plan.ID, store.Actor(ctx)); err != nil {
+		return nil, err
+	}
+
+	if err := r.store.saveEmbedding(ctx, tx, plan.ID, embedding.PlanText(plan.Name, plan.Description)); err != nil {
+		return nil, fmt.Errorf("failed to store embedding: %w", err)
+	}
+
+	for _, rel := range relationships {
+		if err := createRelationship(ctx, tx, plan.ID, rel); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	return &plan, nil
+}
+
+// GetByID retrieves a plan by ID
+func (r *PlanRepository) GetByID(ctx context.Context, id string) (*models.Plan, error) {
+	n, err := getNode(ctx, r.store.db, id, labelPlan)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	plan := n.toPlan()
+	return &plan, nil
+}
+
+// GetWithTasks retrieves a plan by ID along with all its tasks ordered by position.
+func (r *PlanRepository) GetWithTasks(ctx context.Context, id string) (*models.Plan, []models.TaskInPlan, error) {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	n, err := getNode(ctx, tx, id, labelPlan)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query failed: %w", err)
+	}
+	if n == nil {
+		return nil, nil, nil // Not found
+	}
+	plan := n.toPlan()
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT e.position, `+nodeColumns+` FROM edges e
+		 JOIN nodes n ON n.id = e.from_id
+		 WHERE e.to_id = ? AND e.rel_type = ? AND n.label = ?
+		 ORDER BY e.position ASC`,
+		id, string(models.RelPartOf), labelTask)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tasks query failed: %w", err)
+	}
+
+	var tasks []models.TaskInPlan
+	index := make(map[string]int)
+	for rows.Next() {
+		var position float64
+		t, err := scanNode(rows.Scan, &position)
+		if err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		index[t.id] = len(tasks)
+		tasks = append(tasks, models.TaskInPlan{
+			Task:     t.toTask(),
+			Position: position,
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// Dependencies between tasks of this plan
+	depRows, err := tx.QueryContext(ctx,
+		`SELECT e.from_id, e.to_id, e.rel_type FROM edges e
+		 WHERE e.rel_type IN (?, ?)
+		   AND e.from_id IN (SELECT p.from_id FROM edges p WHERE p.to_id = ?3 AND p.rel_type = ?4)
+		   AND e.to_id IN (SELECT p.from_id FROM edges p WHERE p.to_id = ?3 AND p.rel_type = ?4)
+		 ORDER BY e.seq`,
+		string(models.RelDependsOn), string(models.RelBlocks), id, string(models.RelPartOf))
+	if err != nil {
+		return nil, nil, fmt.Errorf("dependencies query failed: %w", err)
+	}
+	defer depRows.Close()
+
+	for depRows.Next() {
+		var fromID, toID, relType string
+		if err := depRows.Scan(&fromID, &toID, &relType); err != nil {
+			return nil, nil, err
+		}
+		i, ok := index[fromID]
+		if _, isTask := index[toID]; !ok || !isTask {
+			continue
+		}
+		switch models.RelationType(relType) {
+		case models.RelDependsOn:
+			tasks[i].DependsOn = append(tasks[i].DependsOn, toID)
+		case models.RelBlocks:
+			tasks[i].Blocks = append(tasks[i].Blocks, toID)
+		}
+	}
+	if err := depRows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return &plan, tasks, nil
+}
+
+// Update modifies an existing plan
+func (r *PlanRepository) Update(ctx context.Context, id string, expectedVersion int64, name *string, description *string, status *string, metadata map[string]string, tags []string, newRelationships []models.Relationship) (*models.Plan, error) {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	u := newNodeUpdate(expectedVersion)
+	if name != nil {
+		u.set("name", *name)
+	}
+	if description != nil {
+		u.set("description", *description)
+	}
+	if status != nil {
+		u.set("status", *status)
+	}
+	if metadata != nil {
+		u.set("metadata", encodeMetadata(metadata))
+	}
+	if tags != nil {
+		u.set("tags", encodeTags(tags))
+	}
+
+	n, err := u.exec(ctx, tx, id, labelPlan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update plan: %w", err)
+	}
+	if n == nil {
+		return nil, fmt.Errorf("plan not found: %s", id)
+	}
+	if err := recordRevision(ctx, tx, id, store.Actor(ctx)); err != nil {
+		return nil, err
+	}
+
+	if name != nil || description != nil {
+		if err := r.store.saveEmbedding(ctx, tx, id, embedding.PlanText(n.name, n.description)); err != nil {
+			return nil, fmt.Errorf("failed to store embedding: %w", err)
+		}
+	}
+
+	for _, rel := range newRelationships {
+		if err := createRelationship(ctx, tx, id, rel); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	plan := n.toPlan()
+	return &plan, nil
+}
+
+// Delete moves a plan to the trash and cascades to tasks not linked to other
+// plans, unless the options keep them.
+func (r *PlanRepository) Delete(ctx context.Context, id string, opts models.PlanDeleteOptions) (int, models.StatusChanges, error) {
+	if err := opts.Validate(id); err != nil {
+		return 0, models.StatusChanges{}, err
+	}
+
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, models.StatusChanges{}, err
+	}
+	defer tx.Rollback()
+
+	if exists, err := nodeExists(ctx, tx, id, labelPlan); err != nil || !exists {
+		return 0, models.StatusChanges{}, err
+	}
+	ids, kept, err := r.deletion(ctx, tx, id, opts)
+	if err != nil {
+		return 0, models.StatusChanges{}, err
+	}
+
+	tasks := NewTaskRepository(r.store)
+	if opts.OrphanTasks == models.OrphanTasksMove {
+		for _, taskID := range kept {
+			maxPos, err := tasks.getMaxPosition(ctx, tx, opts.TargetPlanID)
+			if err != nil {
+				return 0, models.StatusChanges{}, err
+			}
+			if err := tasks.setPlanPosition(ctx, tx, taskID, opts.TargetPlanID, maxPos+models.DefaultPositionIncrement); err != nil {
+				return 0, models.StatusChanges{}, fmt.Errorf("failed to move task %s: %w", taskID, err)
+			}
+		}
+	}
+	var changes models.StatusChanges
+	if changes.Tasks, err = tasks.trashNodes(ctx, tx, ids...); err != nil {
+		return 0, models.StatusChanges{}, err
+	}
+	if r.store.planCompletion && opts.OrphanTasks == models.OrphanTasksMove && len(kept) > 0 {
+		if changes.Plans, err = tasks.applyPlanCompletion(ctx, tx, []string{opts.TargetPlanID}); err != nil {
+			return 0, models.StatusChanges{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, models.StatusChanges{}, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	return len(ids) - 1, changes, nil
+}
+
+// PreviewDelete returns what Delete would move to the trash
+func (r *PlanRepository) PreviewDelete(ctx context.Context, id string, opts models.PlanDeleteOptions) (*models.TrashEntry, error) {
+	if err := opts.Validate(id); err != nil {
+		return nil, err
+	}
+
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if exists, err := nodeExists(ctx, tx, id, labelPlan); err != nil || !exists {
+		return nil, err
+	}
+	ids, _, err := r.deletion(ctx, tx, id, opts)
+	if err != nil {
+		return nil, err
+	}
+	return trashEntry(ctx, tx, ids...)
+}
+
+// DependencyCycles returns the dependency cycles the plan's tasks are on or wait on
+func (r *PlanRepository) DependencyCycles(ctx context.Context, id string) ([][]string, error) {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	taskIDs, err := planTaskIDs(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	return store.FindCycles(ctx, taskIDs, dependencyEdges(tx))
+}
+
+// Waits returns the tasks that each of the plan's tasks waits for
+func (r *PlanRepository) Waits(ctx context.Context, id string) (map[string][]models.Wait, error) {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	taskIDs, err := planTaskIDs(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	waits, err := taskWaits(ctx, tx, taskIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find dependencies: %w", err)
+	}
+	return waits, nil
+}
+
+// Progress returns the progress of each of the given plans
+func (r *PlanRepository) Progress(ctx context.Context, ids []string) (map[string]models.PlanProgress, error) {
+	progress, err := planProgress(ctx, r.store.db, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tasks: %w", err)
+	}
+	return progress, nil
+}
+
+// planTaskIDs returns the IDs of the tasks of a plan in position order. It
+// fails when there is no such plan.
+func planTaskIDs(ctx context.Context, q queryer, id string) ([]string, error) {
+	if exists, err := nodeExists(ctx, q, id, labelPlan); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, fmt.Errorf("plan not found: %s", id)
+	}
+
+	rows, err := q.QueryContext(ctx,
+		`SELECT e.from_id FROM edges e JOIN nodes t ON t.id = e.from_id
+		 WHERE e.to_id = ? AND e.rel_type = ? AND t.label = ?
+		 ORDER BY e.position, e.seq`,
+		id, string(models.RelPartOf), labelTask)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var taskIDs []string
+	for rows.Next() {
+		var taskID string
+		if err := rows.Scan(&taskID); err != nil {
+			return nil, err
+		}
+		taskIDs = append(taskIDs, taskID)
+	}
+	return taskIDs, rows.Err()
+}
+
+// deletion returns the IDs of the nodes deleting a plan moves to the trash,
+// the plan first, and of the tasks belonging to no other plan that the options
+// keep, in position order.
+func (r *PlanRepository) deletion(ctx context.Context, tx *sql.Tx, id string, opts models.PlanDeleteOptions) (trashed, kept []string, err error) {
+	if opts.OrphanTasks == models.OrphanTasksMove {
+		if exists, err := nodeExists(ctx, tx, opts.TargetPlanID, labelPlan); err != nil {
+			return nil, nil, err
+		} else if !exists {
+			return nil, nil, fmt.Errorf("target plan not found: %s", opts.TargetPlanID)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT n.id FROM nodes n JOIN edges e ON e.from_id = n.id AND e.to_id = ?2 AND e.rel_type = ?3
+		 WHERE n.label = ?1
+		   AND NOT EXISTS (
+		     SELECT 1 FROM edges o JOIN nodes p ON p.id = o.to_id
+		     WHERE o.from_id = n.id AND o.rel_type = ?3 AND o.to_id <> ?2 AND p.label = ?4)
+		 ORDER BY e.position, n.seq`,
+		labelTask, id, string(models.RelPartOf), labelPlan)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var orphans []string
+	for rows.Next() {
+		var taskID string
+		if err := rows.Scan(&taskID); err != nil {
+			return nil, nil, err
+		}
+		orphans = append(orphans, taskID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if opts.OrphanTasks == models.OrphanTasksDetach || opts.OrphanTasks == models.OrphanTasksMove {
+		return []string{id}, orphans, nil
+	}
+	return append([]string{id}, orphans...), nil, nil
+}
+
+// List retrieves a page of plans with optional filtering
+func (r *PlanRepository) List(ctx context.Context, status string, tags []string, limit int, cursor string) ([]models.Plan, models.Page, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	c, err := models.DecodeListCursor(cursor)
+	if err != nil {
+		return nil, models.Page{}, err
+	}
+
+	whereClauses := []string{"n.label = ?"}
+	args := []any{labelPlan}
+	if status != "" {
+		whereClauses = append(whereClauses, "n.status = ?")
+		args = append(args, status)
+	}
+	if len(tags) > 0 {
+		clause, tagArgs := tagFilter("n.tags", tags)
+		whereClauses = append(whereClauses, clause)
+		args = append(args, tagArgs...)
+	}
+
+	var page models.Page
+	if err := r.store.db.QueryRowContext(ctx,
+		`SELECT count(*) FROM nodes n WHERE `+strings.Join(whereClauses, " AND "),
+		args...).Scan(&page.Total); err != nil {
+		return nil, models.Page{}, fmt.Errorf("list failed: %w", err)
+	}
+
+	if !c.IsZero() {
+		clause, keyArgs := recencyKeyset(c)
+		whereClauses = append(whereClauses, clause)
+		args = append(args, keyArgs...)
+	}
+	// One extra row tells whether there is a next page
+	args = append(args, limit+1)
+
+	rows, err := r.store.db.QueryContext(ctx,
+		`SELECT `+nodeColumns+` FROM nodes n
+		 WHERE `+strings.Join(whereClauses, " AND ")+`
+		 ORDER BY `+recencyOrder+`
+		 LIMIT ?`,
+		args...)
+	if err != nil {
+		return nil, models.Page{}, fmt.Errorf("list failed: %w", err)
+	}
+	defer rows.Close()
+
+	var plans []models.Plan
+	for rows.Next() {
+		n, err := scanNode(rows.Scan)
+		if err != nil {
+			return nil, models.Page{}, err
+		}
+		plans = append(plans, n.toPlan())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.Page{}, err
+	}
+
+	if len(plans) > limit {
+		plans = plans[:limit]
+		last := plans[limit-1]
+		page.NextCursor = models.Cursor{UpdatedAt: last.UpdatedAt, ID: last.ID}.Encode()
+	}
+	return plans, page, nil
+}
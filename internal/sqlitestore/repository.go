@@ -0,0 +1,566 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/store"
+	"github.com/Thomas-Fitz/associate/internal/textsearch"
+	"github.com/google/uuid"
+)
+
+var _ store.MemoryStore = (*Repository)(nil)
+
+// Repository provides CRUD operations for memories.
+type Repository struct {
+	store *Store
+}
+
+// NewRepository creates a new memory repository backed by s
+func NewRepository(s *Store) *Repository {
+	return &Repository{store: s}
+}
+
+// Search ranks memories against the query and returns the page of best
+// matches that opts.Cursor starts, highest score first, with related memory
+// IDs. Text mode uses the FTS5 index; semantic mode compares embedding
+// vectors; hybrid mode re-ranks text matches with graph proximity and
+// recency. The filter is applied in SQL before ranking.
+func (r *Repository) Search(ctx context.Context, query string, opts models.SearchOptions) ([]models.SearchResult, models.Page, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, models.Page{}, err
+	}
+	opts = opts.WithDefaults(query)
+	if models.ListOnly(query) && opts.Filter.Empty() && opts.AnchorID == "" {
+		return nil, models.Page{}, nil
+	}
+
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, models.Page{}, err
+	}
+	defer tx.Rollback()
+
+	// Every match is ranked so that pages partition one stable ordering
+	all := opts
+	all.Limit = noLimit
+
+	parsed := textsearch.Parse(query)
+	var hits []searchHit
+	switch {
+	case models.ListOnly(query):
+		hits, err = listMemories(ctx, tx, all)
+	case opts.Mode == models.SearchModeSemantic:
+		hits, err = r.store.semanticNodes(ctx, tx, query, []string{labelMemory}, opts.Filter)
+	default:
+		hits, err = searchNodes(ctx, tx, parsed, []string{labelMemory}, all)
+	}
+	if err == nil && opts.Mode == models.SearchModeHybrid {
+		err = hybridScores(ctx, tx, hits, opts.AnchorID)
+	}
+	if err != nil {
+		return nil, models.Page{}, fmt.Errorf("search query failed: %w", err)
+	}
+
+	results := make([]models.SearchResult, len(hits))
+	for i, h := range hits {
+		results[i] = h.toSearchResult()
+	}
+	models.SortResults(results, opts.Sort, opts.Order)
+	results, page := models.Paginate(results, opts.Offset(), opts.Limit)
+	if len(results) == 0 {
+		return nil, page, tx.Commit()
+	}
+
+	index := make(map[string]int, len(results))
+	for i := range results {
+		index[results[i].Memory.ID] = i
+		if results[i].Snippet == "" {
+			results[i].Snippet = textsearch.Snippet(parsed, results[i].Memory.Content)
+		}
+	}
+
+	// Collect related memory IDs for every hit in one query
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(results)), ", ")
+	args := make([]any, 0, 2*len(results)+1)
+	args = append(args, labelMemory)
+	for _, res := range results {
+		args = append(args, res.Memory.ID)
+	}
+	for _, res := range results {
+		args = append(args, res.Memory.ID)
+	}
+	relRows, err := tx.QueryContext(ctx,
+		`SELECT e.from_id, e.to_id FROM edges e
+		 JOIN nodes a ON a.id = e.from_id
+		 JOIN nodes b ON b.id = e.to_id
+		 WHERE a.label = ?1 AND b.label = ?1
+		   AND (e.from_id IN (`+placeholders+`) OR e.to_id IN (`+placeholders+`))
+		 ORDER BY e.seq`,
+		args...)
+	if err != nil {
+		return nil, models.Page{}, fmt.Errorf("related query failed: %w", err)
+	}
+	defer relRows.Close()
+
+	seen := make(map[[2]string]bool)
+	addRelated := func(hitID, relatedID string) {
+		i, ok := index[hitID]
+		if !ok || seen[[2]string{hitID, relatedID}] {
+			return
+		}
+		seen[[2]string{hitID, relatedID}] = true
+		results[i].Related = append(results[i].Related, relatedID)
+	}
+	for relRows.Next() {
+		var fromID, toID string
+		if err := relRows.Scan(&fromID, &toID); err != nil {
+			return nil, models.Page{}, err
+		}
+		addRelated(fromID, toID)
+		addRelated(toID, fromID)
+	}
+	if err := relRows.Err(); err != nil {
+		return nil, models.Page{}, err
+	}
+	relRows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return nil, models.Page{}, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	return results, page, nil
+}
+
+// SearchAll ranks memories, plans and tasks of the requested kinds against
+// the query and returns the best matches, highest score first. Text mode uses
+// the FTS5 index, which covers plan names and descriptions; semantic mode
+// compares embedding vectors.
+func (r *Repository) SearchAll(ctx context.Context, query string, opts models.NodeSearchOptions) ([]models.NodeSearchResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts = opts.WithDefaults()
+	if models.ListOnly(query) {
+		return nil, nil
+	}
+
+	labels := make([]string, len(opts.Kinds))
+	for i, k := range opts.Kinds {
+		labels[i] = string(k)
+	}
+
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	parsed := textsearch.Parse(query)
+	var hits []searchHit
+	if opts.Mode == models.SearchModeSemantic {
+		hits, err = r.store.semanticNodes(ctx, tx, query, labels, models.SearchFilter{})
+	} else {
+		hits, err = searchNodes(ctx, tx, parsed, labels, models.SearchOptions{
+			Limit: opts.Limit,
+			Sort:  models.SortRelevance,
+			Order: models.OrderDesc,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("search query failed: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	var results []models.NodeSearchResult
+	nodes := make(map[string]*node, len(hits))
+	for _, h := range hits {
+		nodes[h.node.id] = h.node
+		results = append(results, h.node.toNodeResult(h.score, h.snippet))
+	}
+	models.SortNodeResults(results)
+	if len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+	for i := range results {
+		if results[i].Snippet == "" {
+			results[i].Snippet = textsearch.Snippet(parsed, nodes[results[i].ID].text())
+		}
+	}
+	return results, nil
+}
+
+// Add creates a new memory and optional relationships
+func (r *Repository) Add(ctx context.Context, mem models.Memory, relationships []models.Relationship) (*models.Memory, error) {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if mem.ID == "" {
+		mem.ID = uuid.New().String()
+	}
+	now := time.Now().UTC()
+	mem.CreatedAt = now
+	mem.UpdatedAt = now
+	mem.Version = 1
+
+	if mem.Type == "" {
+		mem.Type = models.TypeGeneral
+	}
+
+	err = insertNode(ctx, tx, &node{
+		id:        mem.ID,
+		label:     labelMemory,
+		nodeType:  string(mem.Type),
+		content:   mem.Content,
+		metadata:  mem.Metadata,
+		tags:      mem.Tags,
+		createdAt: mem.CreatedAt,
+		updatedAt: mem.UpdatedAt,
+		version:   mem.Version,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create memory: %w", err)
+	}
+	if err := recordRevision(ctx, tx, mem.ID, store.Actor(ctx)); err != nil {
+		return nil, err
+	}
+
+	if err := r.store.saveEmbedding(ctx, tx, mem.ID, mem.Content); err != nil {
+		return nil, fmt.Errorf("failed to store embedding: %w", err)
+	}
+
+	for _, rel := range relationships {
+		if err := createRelationship(ctx, tx, mem.ID, rel); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	return &mem, nil
+}
+
+// Update modifies an existing memory
+func (r *Repository) Update(ctx context.Context, id string, expectedVersion int64, content *string, metadata map[string]string, tags []string, newRelationships []models.Relationship) (*models.Memory, error) {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	u := newNodeUpdate(expectedVersion)
+	if content != nil {
+		u.set("content", *content)
+	}
+	if metadata != nil {
+		u.set("metadata", encodeMetadata(metadata))
+	}
+	if tags != nil {
+		u.set("tags", encodeTags(tags))
+	}
+
+	n, err := u.exec(ctx, tx, id, labelMemory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update memory: %w", err)
+	}
+	if n == nil {
+		return nil, fmt.Errorf("memory not found: %s", id)
+	}
+	if err := recordRevision(ctx, tx, id, store.Actor(ctx)); err != nil {
+		return nil, err
+	}
+
+	if content != nil {
+		if err := r.store.saveEmbedding(ctx, tx, id, n.content); err != nil {
+			return nil, fmt.Errorf("failed to store embedding: %w", err)
+		}
+	}
+
+	for _, rel := range newRelationships {
+		if err := createRelationship(ctx, tx, id, rel); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	mem := n.toMemory()
+	return &mem, nil
+}
+
+// GetByID retrieves a memory by ID
+func (r *Repository) GetByID(ctx context.Context, id string) (*models.Memory, error) {
+	n, err := getNode(ctx, r.store.db, id, labelMemory)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	mem := n.toMemory()
+	return &mem, nil
+}
+
+// GetByIDWithRelated retrieves a memory by ID along with its direct relationships to other memories
+func (r *Repository) GetByIDWithRelated(ctx context.Context, id string) (*models.Memory, []models.RelatedInfo, error) {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	n, err := getNode(ctx, tx, id, labelMemory)
+	if err != nil || n == nil {
+		return nil, nil, err
+	}
+	mem := n.toMemory()
+
+	var related []models.RelatedInfo
+	queries := []struct {
+		direction string
+		query     string
+	}{
+		{"outgoing", `SELECT o.id, o.type, e.rel_type, ` + edgePropColumns + ` FROM edges e JOIN nodes o ON o.id = e.to_id
+		              WHERE e.from_id = ? AND o.label = ? ORDER BY e.seq`},
+		{"incoming", `SELECT o.id, o.type, e.rel_type, ` + edgePropColumns + ` FROM edges e JOIN nodes o ON o.id = e.from_id
+		              WHERE e.to_id = ? AND o.label = ? ORDER BY e.seq`},
+	}
+	for _, q := range queries {
+		rows, err := tx.QueryContext(ctx, q.query, id, labelMemory)
+		if err != nil {
+			return nil, nil, err
+		}
+		for rows.Next() {
+			var otherID, otherType, relType string
+			var props edgeProps
+			if err := rows.Scan(append([]any{&otherID, &otherType, &relType}, props.dest()...)...); err != nil {
+				rows.Close()
+				return nil, nil, err
+			}
+			related = append(related, models.RelatedInfo{
+				ID:                     otherID,
+				Type:                   models.MemoryType(otherType),
+				RelationType:           relType,
+				Direction:              q.direction,
+				RelationshipProperties: props.value(),
+			})
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return &mem, related, nil
+}
+
+// Delete moves a memory and all its relationships to the trash
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	return trashNode(ctx, r.store.db, id, labelMemory)
+}
+
+// PreviewDelete returns what Delete would move to the trash
+func (r *Repository) PreviewDelete(ctx context.Context, id string) (*models.TrashEntry, error) {
+	return previewTrash(ctx, r.store.db, id, labelMemory)
+}
+
+// GetRelated retrieves nodes related to the given ID with optional filtering,
+// expanding breadth-first up to depth hops with one query per hop.
+func (r *Repository) GetRelated(ctx context.Context, id string, relationType string, direction string, depth int) ([]models.RelatedMemoryResult, error) {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results, err := store.Traverse(ctx, id, depth, func(ctx context.Context, frontier []string) ([]store.Step, error) {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(frontier)), ", ")
+		var args []any
+		for _, end := range []string{"incoming", "outgoing"} {
+			args = append(args, direction, end)
+			for _, id := range frontier {
+				args = append(args, id)
+			}
+			args = append(args, relationType, relationType)
+		}
+
+		// Outgoing edges reach their to_id, incoming edges their from_id
+		rows, err := tx.QueryContext(ctx,
+			`SELECT e.seq, e.from_id, e.to_id, e.rel_type, `+edgePropColumns+`, `+nodeColumns+` FROM edges e
+			 JOIN nodes n ON n.id = e.to_id
+			 WHERE ? <> ? AND e.from_id IN (`+placeholders+`) AND (? = '' OR e.rel_type = ?)
+			 UNION ALL
+			 SELECT e.seq, e.from_id, e.to_id, e.rel_type, `+edgePropColumns+`, `+nodeColumns+` FROM edges e
+			 JOIN nodes n ON n.id = e.from_id
+			 WHERE ? <> ? AND e.to_id IN (`+placeholders+`) AND (? = '' OR e.rel_type = ?)
+			 ORDER BY 1`,
+			args...)
+		if err != nil {
+			return nil, fmt.Errorf("related query failed: %w", err)
+		}
+		defer rows.Close()
+
+		var steps []store.Step
+		for rows.Next() {
+			var seq int64
+			var edge models.Relationship
+			var props edgeProps
+			n, err := scanNode(rows.Scan, append([]any{&seq, &edge.FromID, &edge.ToID, &edge.Type}, props.dest()...)...)
+			if err != nil {
+				return nil, err
+			}
+			edge.RelationshipProperties = props.value()
+			steps = append(steps, store.Step{Edge: edge, Node: n.toRelatedMemory()})
+		}
+		return steps, rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, tx.Commit()
+}
+
+// ListRelationships lists the relationships of a node, oldest first.
+func (r *Repository) ListRelationships(ctx context.Context, id string, relationType string, direction string) ([]models.Edge, error) {
+	label, err := nodeLabel(ctx, r.store.db, id)
+	if err != nil {
+		return nil, fmt.Errorf("list relationships failed: %w", err)
+	}
+	if label == "" {
+		return nil, fmt.Errorf("node not found: %s", id)
+	}
+	edges, err := listEdges(ctx, r.store.db, id, relationType, direction)
+	if err != nil {
+		return nil, fmt.Errorf("list relationships failed: %w", err)
+	}
+	return edges, nil
+}
+
+// DeleteRelationship removes the relationship of the given type from one node
+// to another.
+func (r *Repository) DeleteRelationship(ctx context.Context, fromID, toID string, relType models.RelationType) error {
+	res, err := r.store.db.ExecContext(ctx,
+		`DELETE FROM edges WHERE from_id = ? AND to_id = ? AND rel_type = ?`,
+		fromID, toID, string(relType))
+	if err != nil {
+		return fmt.Errorf("delete relationship failed: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("relationship not found: %s", models.EdgeID(fromID, toID, relType))
+	}
+	return nil
+}
+
+// ReplaceRelationships replaces the outgoing relationships of a node with the
+// given ones in one transaction.
+func (r *Repository) ReplaceRelationships(ctx context.Context, id string, relationships []models.Relationship) ([]models.Edge, error) {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	label, err := nodeLabel(ctx, tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("replace relationships failed: %w", err)
+	}
+	if label == "" {
+		return nil, fmt.Errorf("node not found: %s", id)
+	}
+	set, err := store.ReplacementSet(id, label, relationships)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(set))
+	for _, rel := range set {
+		wanted[models.EdgeID(id, rel.ToID, rel.Type)] = true
+	}
+
+	current, err := listEdges(ctx, tx, id, "", "outgoing")
+	if err != nil {
+		return nil, fmt.Errorf("replace relationships failed: %w", err)
+	}
+	for _, e := range current {
+		if wanted[e.ID] || (label == labelTask && e.Type == models.RelPartOf) {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM edges WHERE from_id = ? AND to_id = ? AND rel_type = ?`,
+			e.FromID, e.ToID, string(e.Type)); err != nil {
+			return nil, fmt.Errorf("replace relationships failed: %w", err)
+		}
+	}
+
+	// Create after removing the relationships being replaced, so that those
+	// cannot make a false dependency cycle
+	for _, rel := range set {
+		if err := createRelationship(ctx, tx, id, rel); err != nil {
+			return nil, err
+		}
+	}
+
+	edges, err := listEdges(ctx, tx, id, "", "outgoing")
+	if err != nil {
+		return nil, fmt.Errorf("replace relationships failed: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return edges, nil
+}
+
+// ExistingNodes reports which of the IDs name a node of any type.
+func (r *Repository) ExistingNodes(ctx context.Context, ids []string) (map[string]bool, error) {
+	return existingNodes(ctx, r.store.db, ids)
+}
+
+// ListRevisions lists the revisions of a node of any type, newest first
+func (r *Repository) ListRevisions(ctx context.Context, id string) ([]models.Revision, error) {
+	label, err := nodeLabel(ctx, r.store.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if label == "" {
+		return nil, fmt.Errorf("node not found: %s", id)
+	}
+
+	rows, err := r.store.db.QueryContext(ctx,
+		`SELECT `+revisionColumns+` FROM revisions WHERE node_id = ? ORDER BY version DESC`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []models.Revision
+	for rows.Next() {
+		rev, err := scanRevision(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *rev)
+	}
+	return revisions, rows.Err()
+}
+
+// GetRevision retrieves the revision of a node at the given version
+func (r *Repository) GetRevision(ctx context.Context, id string, version int64) (*models.Revision, error) {
+	row := r.store.db.QueryRowContext(ctx,
+		`SELECT `+revisionColumns+` FROM revisions WHERE node_id = ? AND version = ?`, id, version)
+	rev, err := scanRevision(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rev, err
+}
@@ -0,0 +1,504 @@
+package sqlitestore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Thomas-Fitz/associate/internal/embedding"
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/ranking"
+	"github.com/Thomas-Fitz/associate/internal/textsearch"
+)
+
+// noLimit is the SQLite LIMIT that returns every row.
+const noLimit = -1
+
+// searchHit is a node matched by the full-text index or by embedding.
+type searchHit struct {
+	node    *node
+	score   float64
+	snippet string
+}
+
+// searchNodes ranks the nodes with the given labels that pass the filter with
+// FTS5, returning up to opts.Limit of them, or all with noLimit, in opts.Sort
+// order. Exact matches are returned first; if they do not fill the limit and
+// the query has no phrases, misspelled words are retried with their closest
+// indexed spellings. Scores use the same bands as textsearch.Rank: [0.5, 1)
+// for exact matches, [0.25, 0.5) for fuzzy.
+func searchNodes(ctx context.Context, q queryer, query textsearch.Query, labels []string, opts models.SearchOptions) ([]searchHit, error) {
+	if query.Empty() {
+		return nil, nil
+	}
+	limit := opts.Limit
+
+	hits, err := matchNodes(ctx, q, ftsQuery(query, nil), labels, opts, limit, 0.5)
+	if err != nil {
+		return nil, err
+	}
+	if (limit != noLimit && len(hits) >= limit) || len(query.Phrases) > 0 {
+		return hits, nil
+	}
+
+	corrections, err := spellingCorrections(ctx, q, query.Terms)
+	if err != nil || len(corrections) == 0 {
+		return hits, err
+	}
+
+	fuzzyLimit := noLimit
+	if limit != noLimit {
+		fuzzyLimit = limit + len(hits)
+	}
+	fuzzy, err := matchNodes(ctx, q, ftsQuery(query, corrections), labels, opts, fuzzyLimit, 0.25)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool, len(hits))
+	for _, h := range hits {
+		found[h.node.id] = true
+	}
+	for _, h := range fuzzy {
+		if len(hits) == limit {
+			break
+		}
+		if !found[h.node.id] {
+			hits = append(hits, h)
+		}
+	}
+	return hits, nil
+}
+
+// matchNodes runs an FTS5 MATCH expression over the nodes with the given
+// labels that pass the filter and maps bm25 into [base, 2*base).
+func matchNodes(ctx context.Context, q queryer, match string, labels []string, opts models.SearchOptions, limit int, base float64) ([]searchHit, error) {
+	labelFilter, labelArgs := labelSQL(labels)
+	filter, filterArgs := filterSQL(opts.Filter)
+	args := append([]any{textsearch.HighlightStart, textsearch.HighlightEnd, match}, labelArgs...)
+	args = append(args, filterArgs...)
+	rows, err := q.QueryContext(ctx,
+		`SELECT bm25(node_fts), snippet(node_fts, 0, ?, ?, '…', 24), `+nodeColumns+`
+		 FROM node_fts JOIN nodes n ON n.seq = node_fts.rowid
+		 WHERE node_fts MATCH ?`+labelFilter+filter+`
+		 ORDER BY `+orderSQL(opts, "-bm25(node_fts)")+`
+		 LIMIT ?`,
+		append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []searchHit
+	for rows.Next() {
+		var rank float64
+		var snippet string
+		n, err := scanNode(rows.Scan, &rank, &snippet)
+		if err != nil {
+			return nil, err
+		}
+		// bm25 is negative, more so for better matches
+		relevance := -rank / (1 - rank)
+		hits = append(hits, searchHit{node: n, score: base + base*relevance, snippet: snippet})
+	}
+	return hits, rows.Err()
+}
+
+// spellingCorrections maps query terms to the other indexed words within
+// typo distance of them.
+func spellingCorrections(ctx context.Context, q queryer, terms []string) (map[string][]string, error) {
+	corrections := make(map[string][]string)
+	for _, term := range terms {
+		n := utf8.RuneCountInString(term)
+		if n < 4 {
+			continue
+		}
+		rows, err := q.QueryContext(ctx,
+			`SELECT term FROM node_words_vocab WHERE length(term) BETWEEN ? AND ?`, n-2, n+2)
+		if err != nil {
+			return nil, err
+		}
+		var alternatives []string
+		for rows.Next() {
+			var word string
+			if err := rows.Scan(&word); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if word != term && textsearch.Similar(term, word) {
+				alternatives = append(alternatives, word)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		if len(alternatives) > 0 {
+			corrections[term] = alternatives
+		}
+	}
+	return corrections, nil
+}
+
+// ftsQuery converts a parsed query into an FTS5 MATCH expression. Terms and
+// phrases are ANDed; each term is ORed with its corrections, if any.
+func ftsQuery(query textsearch.Query, corrections map[string][]string) string {
+	var parts []string
+	for _, term := range query.Terms {
+		alternatives := append([]string{term}, corrections[term]...)
+		for i, a := range alternatives {
+			alternatives[i] = ftsString(a)
+		}
+		if len(alternatives) == 1 {
+			parts = append(parts, alternatives[0])
+		} else {
+			parts = append(parts, "("+strings.Join(alternatives, " OR ")+")")
+		}
+	}
+	for _, phrase := range query.Phrases {
+		parts = append(parts, ftsString(strings.Join(phrase, " ")))
+	}
+
+	expr := "(" + strings.Join(parts, " AND ") + ")"
+	for _, ex := range query.Excluded {
+		expr += " NOT " + ftsString(ex)
+	}
+	return expr
+}
+
+// ftsString quotes s as an FTS5 string.
+func ftsString(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// toSearchResult converts a hit into a search result without related IDs.
+func (h searchHit) toSearchResult() models.SearchResult {
+	return models.SearchResult{
+		Memory:  h.node.toMemory(),
+		Score:   h.score,
+		Snippet: h.snippet,
+	}
+}
+
+// semanticNodes scores the nodes with the given labels that pass the filter
+// by the cosine similarity of their embedding to the query's, best first.
+// Nodes without a vector for the current model, such as those written before
+// the embedder changed, are skipped until BackfillEmbeddings embeds them. Only
+// positively similar nodes are returned.
+func (s *Store) semanticNodes(ctx context.Context, q queryer, query string, labels []string, f models.SearchFilter) ([]searchHit, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	model := s.embedder.Model()
+
+	queryVec, err := s.embedder.Embed(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	labelFilter, labelArgs := labelSQL(labels)
+	filter, filterArgs := filterSQL(f)
+	args := append(append([]any{model}, labelArgs...), filterArgs...)
+	rows, err := q.QueryContext(ctx,
+		`SELECT e.vector, `+nodeColumns+`
+		 FROM embeddings e JOIN nodes n ON n.id = e.node_id
+		 WHERE e.model = ?`+labelFilter+filter,
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []searchHit
+	for rows.Next() {
+		var vector []byte
+		n, err := scanNode(rows.Scan, &vector)
+		if err != nil {
+			return nil, err
+		}
+		if score := embedding.Cosine(queryVec, decodeVector(vector)); score > 0 {
+			hits = append(hits, searchHit{node: n, score: score})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].node.id < hits[j].node.id
+	})
+	return hits, nil
+}
+
+// BackfillEmbeddings embeds up to limit nodes that have no vector for the
+// current model, such as those written while the embedder was unavailable or
+// before it changed, and returns how many it embedded. Fewer than limit
+// without an error means none are left. The embedder is called outside any
+// transaction; it fails on the first node the embedder fails on, after
+// storing the vectors computed before it.
+func (s *Store) BackfillEmbeddings(ctx context.Context, limit int) (int, error) {
+	model := s.embedder.Model()
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+nodeColumns+` FROM nodes n
+		 LEFT JOIN embeddings e ON e.node_id = n.id AND e.model = ?
+		 WHERE e.node_id IS NULL
+		 ORDER BY n.seq
+		 LIMIT ?`,
+		model, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find nodes to embed: %w", err)
+	}
+	var missing []*node
+	for rows.Next() {
+		n, err := scanNode(rows.Scan)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		missing = append(missing, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var embedErr error
+	vectors := make([][]float32, 0, len(missing))
+	for _, n := range missing {
+		vec, err := s.embedder.Embed(ctx, n.text())
+		if err != nil {
+			embedErr = fmt.Errorf("failed to embed %s: %w", n.id, err)
+			break
+		}
+		vectors = append(vectors, vec)
+	}
+	for i, vec := range vectors {
+		// The node may have been deleted, changed or re-embedded since it
+		// was read
+		_, err := s.db.ExecContext(ctx,
+			`INSERT INTO embeddings (node_id, model, vector)
+			 SELECT id, ?, ? FROM nodes WHERE id = ? AND version = ?
+			 ON CONFLICT (node_id) DO UPDATE SET model = excluded.model, vector = excluded.vector
+			 WHERE embeddings.model <> excluded.model`,
+			model, encodeVector(vec), missing[i].id, missing[i].version)
+		if err != nil {
+			return i, fmt.Errorf("failed to store embedding: %w", err)
+		}
+	}
+	return len(vectors), embedErr
+}
+
+// hybridScores replaces the text score of each hit with its ranking.Score,
+// counting links of every type and measuring hops from the anchor, if any.
+func hybridScores(ctx context.Context, q queryer, hits []searchHit, anchorID string) error {
+	hops := make(map[string]int)
+	if anchorID != "" {
+		var exists bool
+		if err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM nodes WHERE id = ?)`, anchorID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("anchor not found: %s", anchorID)
+		}
+		var err error
+		if hops, err = anchorHops(ctx, q, anchorID); err != nil {
+			return err
+		}
+	}
+	if len(hits) == 0 {
+		return nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(hits)), ", ")
+	args := make([]any, 0, 2*len(hits))
+	for _, h := range hits {
+		args = append(args, h.node.id)
+	}
+	args = append(args, args...)
+	rows, err := q.QueryContext(ctx,
+		`SELECT id, count(*) FROM (
+			SELECT from_id AS id FROM edges WHERE from_id IN (`+placeholders+`)
+			UNION ALL
+			SELECT to_id FROM edges WHERE to_id IN (`+placeholders+`)
+		 ) GROUP BY id`,
+		args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	links := make(map[string]int)
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return err
+		}
+		links[id] = n
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for i, h := range hits {
+		hits[i].score = ranking.Score(ranking.Features{
+			Text:       h.score,
+			Links:      links[h.node.id],
+			AnchorHops: hops[h.node.id],
+			UpdatedAt:  h.node.updatedAt,
+		}, now)
+	}
+	return nil
+}
+
+// anchorHops maps the nodes within two links of the anchor, in either
+// direction, to their distance as defined by ranking.Features.AnchorHops.
+func anchorHops(ctx context.Context, q queryer, anchorID string) (map[string]int, error) {
+	rows, err := q.QueryContext(ctx,
+		`WITH first (id) AS (
+			SELECT to_id FROM edges WHERE from_id = ?1
+			UNION SELECT from_id FROM edges WHERE to_id = ?1
+		 ), second (id) AS (
+			SELECT e.to_id FROM edges e JOIN first f ON e.from_id = f.id
+			UNION SELECT e.from_id FROM edges e JOIN first f ON e.to_id = f.id
+		 )
+		 SELECT id, 1 FROM first
+		 UNION ALL SELECT id, 2 FROM second`,
+		anchorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hops := map[string]int{anchorID: 1}
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		if hops[id] == 0 || n < hops[id] {
+			hops[id] = n
+		}
+	}
+	return hops, rows.Err()
+}
+
+// listMemories returns up to opts.Limit memories passing the filter, or all
+// with noLimit, in opts.Sort order, with a zero score. It serves searches
+// without a query.
+func listMemories(ctx context.Context, q queryer, opts models.SearchOptions) ([]searchHit, error) {
+	filter, filterArgs := filterSQL(opts.Filter)
+	rows, err := q.QueryContext(ctx,
+		`SELECT `+nodeColumns+` FROM nodes n
+		 WHERE n.label = ?`+filter+`
+		 ORDER BY `+orderSQL(opts, "n.updated_at")+`
+		 LIMIT ?`,
+		append(append([]any{labelMemory}, filterArgs...), opts.Limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []searchHit
+	for rows.Next() {
+		n, err := scanNode(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, searchHit{node: n})
+	}
+	return hits, rows.Err()
+}
+
+// labelSQL returns a predicate, prefixed with AND, restricting the nodes table
+// aliased n to the given labels, and its arguments.
+func labelSQL(labels []string) (string, []any) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(labels)), ", ")
+	args := make([]any, len(labels))
+	for i, label := range labels {
+		args[i] = label
+	}
+	return ` AND n.label IN (` + placeholders + `)`, args
+}
+
+// filterSQL returns the predicates for f, each prefixed with AND, over the
+// nodes table aliased n, and their arguments.
+func filterSQL(f models.SearchFilter) (string, []any) {
+	var sb strings.Builder
+	var args []any
+	if f.Type != "" {
+		sb.WriteString(` AND n.type = ?`)
+		args = append(args, string(f.Type))
+	}
+	if len(f.Tags) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(f.Tags)), ", ")
+		want := 1
+		if f.TagMatch == models.TagMatchAll {
+			want = len(uniqueStrings(f.Tags))
+		}
+		sb.WriteString(` AND (SELECT count(DISTINCT value) FROM json_each(n.tags) WHERE value IN (` + placeholders + `)) >= ?`)
+		for _, tag := range f.Tags {
+			args = append(args, tag)
+		}
+		args = append(args, want)
+	}
+	keys := make([]string, 0, len(f.Metadata))
+	for k := range f.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteString(` AND EXISTS (SELECT 1 FROM json_each(NULLIF(n.metadata, '')) WHERE key = ? AND value = ?)`)
+		args = append(args, k, f.Metadata[k])
+	}
+	for _, bound := range []struct {
+		predicate string
+		t         time.Time
+	}{
+		{` AND n.created_at >= ?`, f.CreatedAfter},
+		{` AND n.created_at < ?`, f.CreatedBefore},
+		{` AND n.updated_at >= ?`, f.UpdatedAfter},
+		{` AND n.updated_at < ?`, f.UpdatedBefore},
+	} {
+		if !bound.t.IsZero() {
+			sb.WriteString(bound.predicate)
+			args = append(args, bound.t.UnixNano())
+		}
+	}
+	return sb.String(), args
+}
+
+// orderSQL returns the ORDER BY terms for opts, using relevance, where higher
+// is better, for SortRelevance.
+func orderSQL(opts models.SearchOptions, relevance string) string {
+	expr := relevance
+	switch opts.Sort {
+	case models.SortCreatedAt:
+		expr = "n.created_at"
+	case models.SortUpdatedAt:
+		expr = "n.updated_at"
+	}
+	if opts.Order == models.OrderAsc {
+		return expr + " ASC, n.seq"
+	}
+	return expr + " DESC, n.seq"
+}
+
+// uniqueStrings returns ss without duplicates.
+func uniqueStrings(ss []string) []string {
+	seen := make(map[string]bool, len(ss))
+	var out []string
+	for _, s := range ss {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
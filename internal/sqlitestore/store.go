@@ -0,0 +1,812 @@
+// Package sqlitestore provides an implementation of the storage interfaces in
+// internal/store backed by a local SQLite file. Nodes and typed edges live in
+// two tables, so a single binary can run without PostgreSQL/AGE.
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/embedding"
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/store"
+
+	_ "modernc.org/sqlite"
+)
+
+// Node labels, matching the AGE label names used by internal/graph.
+const (
+	labelMemory = "Memory"
+	labelPlan   = "Plan"
+	labelTask   = "Task"
+)
+
+// Config holds SQLite configuration
+type Config struct {
+	// Path is the database file. It is created, along with its directory, if missing.
+	Path string
+}
+
+// ConfigFromEnv creates a Config from environment variables
+func ConfigFromEnv() Config {
+	path := os.Getenv("SQLITE_PATH")
+	if path == "" {
+		path = DefaultPath()
+	}
+	return Config{Path: path}
+}
+
+// DefaultPath returns the database location used when SQLITE_PATH is unset:
+// associate/associate.db inside the user's configuration directory.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "associate.db"
+	}
+	return filepath.Join(dir, "associate", "associate.db")
+}
+
+// Store wraps the SQLite database shared by the sqlitestore repositories.
+type Store struct {
+	db       *sql.DB
+	embedder embedding.Embedder
+	logger   *slog.Logger
+
+	// statusRules and planCompletion are set through TaskRepository
+	statusRules    bool
+	planCompletion bool
+}
+
+// schema creates the node and edge tables. Every node type shares one table;
+// columns that do not apply to a label are left empty.
+var schema = `
+CREATE TABLE IF NOT EXISTS nodes (
+	seq         INTEGER PRIMARY KEY AUTOINCREMENT,
+	id          TEXT    NOT NULL UNIQUE,
+	label       TEXT    NOT NULL,
+	type        TEXT    NOT NULL DEFAULT '',
+	content     TEXT    NOT NULL DEFAULT '',
+	name        TEXT    NOT NULL DEFAULT '',
+	description TEXT    NOT NULL DEFAULT '',
+	status      TEXT    NOT NULL DEFAULT '',
+	metadata    TEXT    NOT NULL DEFAULT '',
+	tags        TEXT    NOT NULL DEFAULT '[]',
+	created_at  INTEGER NOT NULL,
+	updated_at  INTEGER NOT NULL,
+	version     INTEGER NOT NULL DEFAULT 1
+);
+CREATE INDEX IF NOT EXISTS nodes_label_updated_at ON nodes (label, updated_at);
+
+CREATE TABLE IF NOT EXISTS edges (
+	seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+	from_id    TEXT    NOT NULL REFERENCES nodes (id) ON DELETE CASCADE,
+	to_id      TEXT    NOT NULL REFERENCES nodes (id) ON DELETE CASCADE,
+	rel_type   TEXT    NOT NULL,
+	position   REAL,
+	created_at INTEGER NOT NULL DEFAULT 0,
+	reason     TEXT    NOT NULL DEFAULT '',
+	weight     REAL,
+	metadata   TEXT    NOT NULL DEFAULT '',
+	UNIQUE (from_id, to_id, rel_type)
+);
+CREATE INDEX IF NOT EXISTS edges_to_id ON edges (to_id, rel_type);
+
+-- Full-text index over the searchable text of every node, keyed by
+-- nodes.seq: memory and task content, and plan name and description.
+-- node_fts stems words for ranked matching; node_words keeps them as written
+-- so that node_words_vocab can suggest corrections for misspelled query words.
+CREATE VIRTUAL TABLE IF NOT EXISTS node_fts USING fts5(body, tokenize = 'porter unicode61');
+CREATE VIRTUAL TABLE IF NOT EXISTS node_words USING fts5(body, tokenize = 'unicode61');
+CREATE VIRTUAL TABLE IF NOT EXISTS node_words_vocab USING fts5vocab(node_words, 'row');
+
+CREATE TRIGGER IF NOT EXISTS node_fts_insert AFTER INSERT ON nodes BEGIN
+	INSERT INTO node_fts (rowid, body) VALUES (new.seq, ` + nodeTextSQL("new") + `);
+	INSERT INTO node_words (rowid, body) VALUES (new.seq, ` + nodeTextSQL("new") + `);
+END;
+CREATE TRIGGER IF NOT EXISTS node_fts_update AFTER UPDATE OF content, name, description ON nodes BEGIN
+	DELETE FROM node_fts WHERE rowid = old.seq;
+	DELETE FROM node_words WHERE rowid = old.seq;
+	INSERT INTO node_fts (rowid, body) VALUES (new.seq, ` + nodeTextSQL("new") + `);
+	INSERT INTO node_words (rowid, body) VALUES (new.seq, ` + nodeTextSQL("new") + `);
+END;
+CREATE TRIGGER IF NOT EXISTS node_fts_delete AFTER DELETE ON nodes BEGIN
+	DELETE FROM node_fts WHERE rowid = old.seq;
+	DELETE FROM node_words WHERE rowid = old.seq;
+END;
+
+-- Revision history: the state of each node at each of its versions
+CREATE TABLE IF NOT EXISTS revisions (
+	node_id     TEXT    NOT NULL REFERENCES nodes (id) ON DELETE CASCADE,
+	version     INTEGER NOT NULL,
+	label       TEXT    NOT NULL,
+	type        TEXT    NOT NULL DEFAULT '',
+	content     TEXT    NOT NULL DEFAULT '',
+	name        TEXT    NOT NULL DEFAULT '',
+	description TEXT    NOT NULL DEFAULT '',
+	status      TEXT    NOT NULL DEFAULT '',
+	metadata    TEXT    NOT NULL DEFAULT '',
+	tags        TEXT    NOT NULL DEFAULT '[]',
+	actor       TEXT    NOT NULL DEFAULT '',
+	created_at  INTEGER NOT NULL,
+	PRIMARY KEY (node_id, version)
+);
+
+-- Trash: what each delete removed, as a JSON models.TrashEntry keyed by the
+-- deleted node's ID, kept until it is restored or purged
+CREATE TABLE IF NOT EXISTS trash (
+	id         TEXT    NOT NULL PRIMARY KEY,
+	deleted_at INTEGER NOT NULL,
+	entry      TEXT    NOT NULL
+);
+
+-- Embedding vectors for semantic search, as little-endian float32s. Vectors
+-- whose model differs from the configured embedder are ignored and replaced.
+CREATE TABLE IF NOT EXISTS embeddings (
+	node_id TEXT NOT NULL PRIMARY KEY REFERENCES nodes (id) ON DELETE CASCADE,
+	model   TEXT NOT NULL,
+	vector  BLOB NOT NULL
+);
+`
+
+// nodeTextSQL returns the searchable text of the nodes row named by alias:
+// the name and description of a plan, or the content of a memory or task.
+// node.text is its Go counterpart.
+func nodeTextSQL(alias string) string {
+	return fmt.Sprintf("CASE %[1]s.label WHEN 'Plan' THEN %[1]s.name || char(10) || %[1]s.description ELSE %[1]s.content END", alias)
+}
+
+// Open opens (creating if needed) the SQLite database at cfg.Path and
+// initializes the schema.
+func Open(ctx context.Context, cfg Config) (*Store, error) {
+	if dir := filepath.Dir(cfg.Path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	dsn := "file:" + cfg.Path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// SQLite allows a single writer; serializing connections avoids SQLITE_BUSY
+	// between concurrent tool calls.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+
+	return &Store{db: db, embedder: embedding.NewHashEmbedder(embedding.DefaultDimensions), logger: slog.Default()}, nil
+}
+
+// SetEmbedder replaces the embedder used for semantic search (default: the
+// offline hash embedder). Stored vectors from another model are recomputed
+// by BackfillEmbeddings; until then those nodes are left out of semantic
+// search.
+func (s *Store) SetEmbedder(e embedding.Embedder) {
+	s.embedder = e
+}
+
+// SetLogger replaces the logger that warnings, such as embedder failures, are
+// written to (default: slog.Default()).
+func (s *Store) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// nodeColumns lists the columns read by scanNode, in order.
+const nodeColumns = "n.id, n.label, n.type, n.content, n.name, n.description, n.status, n.metadata, n.tags, n.created_at, n.updated_at, n.version"
+
+// node is a row of the nodes table.
+type node struct {
+	id          string
+	label       string
+	nodeType    string
+	content     string
+	name        string
+	description string
+	status      string
+	metadata    map[string]string
+	tags        []string
+	createdAt   time.Time
+	updatedAt   time.Time
+	version     int64
+}
+
+// scanNode reads a row selected with nodeColumns, optionally preceded by extra columns.
+func scanNode(scan func(dest ...any) error, extra ...any) (*node, error) {
+	var n node
+	var metadata, tags string
+	var createdAt, updatedAt int64
+	dest := append(extra, &n.id, &n.label, &n.nodeType, &n.content, &n.name, &n.description, &n.status, &metadata, &tags, &createdAt, &updatedAt, &n.version)
+	if err := scan(dest...); err != nil {
+		return nil, err
+	}
+	n.metadata = decodeMetadata(metadata)
+	n.tags = decodeTags(tags)
+	n.createdAt = time.Unix(0, createdAt).UTC()
+	n.updatedAt = time.Unix(0, updatedAt).UTC()
+	return &n, nil
+}
+
+// getNode returns the node with the given ID and label, or nil if there is none.
+func getNode(ctx context.Context, q queryer, id, label string) (*node, error) {
+	row := q.QueryRowContext(ctx, `SELECT `+nodeColumns+` FROM nodes n WHERE n.id = ? AND n.label = ?`, id, label)
+	n, err := scanNode(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return n, err
+}
+
+// nodeExists reports whether a node with the given ID and label exists.
+func nodeExists(ctx context.Context, q queryer, id, label string) (bool, error) {
+	var exists bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM nodes WHERE id = ? AND label = ?)`, id, label).Scan(&exists)
+	return exists, err
+}
+
+// nodeLabel returns the label of the node with the given ID, or "" if there
+// is none.
+func nodeLabel(ctx context.Context, q queryer, id string) (string, error) {
+	var label string
+	err := q.QueryRowContext(ctx, `SELECT label FROM nodes WHERE id = ?`, id).Scan(&label)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return label, err
+}
+
+// existingNodes reports which of the IDs name a node of any type.
+func existingNodes(ctx context.Context, q queryer, ids []string) (map[string]bool, error) {
+	existing := make(map[string]bool, len(ids))
+	if len(ids) == 0 {
+		return existing, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := q.QueryContext(ctx, `SELECT id FROM nodes WHERE id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		existing[id] = true
+	}
+	return existing, rows.Err()
+}
+
+// insertNode adds a node to the graph.
+func insertNode(ctx context.Context, q queryer, n *node) error {
+	_, err := q.ExecContext(ctx,
+		`INSERT INTO nodes (id, label, type, content, name, description, status, metadata, tags, created_at, updated_at, version)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		n.id, n.label, n.nodeType, n.content, n.name, n.description, n.status,
+		encodeMetadata(n.metadata), encodeTags(n.tags), n.createdAt.UnixNano(), n.updatedAt.UnixNano(), n.version)
+	return err
+}
+
+// nodeUpdate accumulates the SET clause of an UPDATE on the nodes table.
+type nodeUpdate struct {
+	sets []string
+	args []any
+	// expectedVersion, if non-zero, is the only version the update applies to
+	expectedVersion int64
+}
+
+// newNodeUpdate starts an update that always bumps updated_at and version.
+func newNodeUpdate(expectedVersion int64) *nodeUpdate {
+	return &nodeUpdate{
+		sets:            []string{"updated_at = ?", "version = version + 1"},
+		args:            []any{time.Now().UTC().UnixNano()},
+		expectedVersion: expectedVersion,
+	}
+}
+
+func (u *nodeUpdate) set(column string, value any) {
+	u.sets = append(u.sets, column+" = ?")
+	u.args = append(u.args, value)
+}
+
+// exec applies the update to the node and returns it, or nil if there is
+// none. It returns a VersionConflictError if the node is not at the expected
+// version.
+func (u *nodeUpdate) exec(ctx context.Context, q queryer, id, label string) (*node, error) {
+	where := "id = ? AND label = ?"
+	args := append(u.args, id, label)
+	if u.expectedVersion != 0 {
+		where += " AND version = ?"
+		args = append(args, u.expectedVersion)
+	}
+	res, err := q.ExecContext(ctx, `UPDATE nodes SET `+strings.Join(u.sets, ", ")+` WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	n, err := getNode(ctx, q, id, label)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, models.CheckVersion(id, u.expectedVersion, n.version)
+	}
+	return n, nil
+}
+
+// revisionColumns lists the columns read by scanRevision, in order.
+const revisionColumns = "node_id, version, label, type, content, name, description, status, metadata, tags, actor, created_at"
+
+// recordRevision records the current state of a node, attributed to actor,
+// unless its version is already recorded.
+func recordRevision(ctx context.Context, q queryer, id, actor string) error {
+	_, err := q.ExecContext(ctx,
+		`INSERT OR IGNORE INTO revisions (`+revisionColumns+`)
+		 SELECT id, version, label, type, content, name, description, status, metadata, tags, ?, updated_at
+		 FROM nodes WHERE id = ?`, actor, id)
+	if err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	return nil
+}
+
+// scanRevision reads a row selected with revisionColumns.
+func scanRevision(scan func(dest ...any) error) (*models.Revision, error) {
+	var rev models.Revision
+	var label, nodeType, metadata, tags string
+	var createdAt int64
+	err := scan(&rev.NodeID, &rev.Version, &label, &nodeType, &rev.Content, &rev.Name, &rev.Description,
+		&rev.Status, &metadata, &tags, &rev.Actor, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	rev.Kind = models.NodeKind(label)
+	rev.Type = models.MemoryType(nodeType)
+	rev.Metadata = decodeMetadata(metadata)
+	rev.Tags = decodeTags(tags)
+	rev.CreatedAt = time.Unix(0, createdAt).UTC()
+	return &rev, nil
+}
+
+// createRelationship creates the typed edge rel from fromID between two
+// existing nodes, stamped with its creation time. Like the AGE repositories,
+// it only sets the properties rel gives when the edge already exists, and
+// fails when either endpoint is missing.
+func createRelationship(ctx context.Context, q queryer, fromID string, rel models.Relationship) error {
+	if err := models.ValidateRelationType(rel.Type); err != nil {
+		return &models.RelationshipError{FromID: fromID, ToID: rel.ToID, Type: rel.Type, Err: err}
+	}
+	rel.FromID = fromID
+	path, err := store.DependencyCycle(ctx, rel, dependencyEdges(q))
+	if err != nil {
+		return fmt.Errorf("failed to check for dependency cycles: %w", err)
+	}
+	if path != nil {
+		return &models.RelationshipError{FromID: fromID, ToID: rel.ToID, Type: rel.Type, Err: &models.CycleError{Path: path}}
+	}
+	var weight, metadata any
+	if rel.Weight != nil {
+		weight = *rel.Weight
+	}
+	if rel.Metadata != nil {
+		metadata = encodeMetadata(rel.Metadata)
+	}
+	res, err := q.ExecContext(ctx,
+		`INSERT INTO edges (from_id, to_id, rel_type, created_at, reason, weight, metadata)
+		 SELECT ?1, ?2, ?3, ?4, ?5, ?6, coalesce(?7, '')
+		 WHERE EXISTS (SELECT 1 FROM nodes WHERE id = ?1) AND EXISTS (SELECT 1 FROM nodes WHERE id = ?2)
+		 ON CONFLICT (from_id, to_id, rel_type) DO UPDATE SET
+		   reason = CASE ?5 WHEN '' THEN reason ELSE ?5 END,
+		   weight = coalesce(?6, weight),
+		   metadata = coalesce(?7, metadata)`,
+		fromID, rel.ToID, string(rel.Type), time.Now().UnixNano(), rel.Reason, weight, metadata)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return &models.RelationshipError{FromID: fromID, ToID: rel.ToID, Type: rel.Type, Err: models.ErrNodeNotFound}
+	}
+	return nil
+}
+
+// dependencyEdges finds the DEPENDS_ON and BLOCKS edges touching nodes.
+func dependencyEdges(q queryer) store.DependencyEdges {
+	return func(ctx context.Context, ids []string) ([]models.Relationship, error) {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+		args := []any{string(models.RelDependsOn), string(models.RelBlocks)}
+		for range 2 {
+			for _, id := range ids {
+				args = append(args, id)
+			}
+		}
+		rows, err := q.QueryContext(ctx,
+			`SELECT from_id, to_id, rel_type FROM edges
+			 WHERE rel_type IN (?, ?) AND (from_id IN (`+placeholders+`) OR to_id IN (`+placeholders+`))
+			 ORDER BY seq`,
+			args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var rels []models.Relationship
+		for rows.Next() {
+			var rel models.Relationship
+			var relType string
+			if err := rows.Scan(&rel.FromID, &rel.ToID, &relType); err != nil {
+				return nil, err
+			}
+			rel.Type = models.RelationType(relType)
+			rels = append(rels, rel)
+		}
+		return rels, rows.Err()
+	}
+}
+
+// taskWaits returns the tasks that each of the given tasks waits for, keyed
+// by the waiting task's ID, in the order the relationships were created.
+func taskWaits(ctx context.Context, q queryer, ids []string) (map[string][]models.Wait, error) {
+	waits := make(map[string][]models.Wait)
+	if len(ids) == 0 {
+		return waits, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := []any{string(models.RelDependsOn)}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, string(models.RelBlocks))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, labelTask)
+	rows, err := q.QueryContext(ctx,
+		`SELECT w.waiter, w.rel_type, `+nodeColumns+` FROM (
+		   SELECT from_id AS waiter, to_id AS waits_for, rel_type, seq FROM edges
+		   WHERE rel_type = ? AND from_id IN (`+placeholders+`)
+		   UNION ALL
+		   SELECT to_id, from_id, rel_type, seq FROM edges
+		   WHERE rel_type = ? AND to_id IN (`+placeholders+`)
+		 ) w
+		 JOIN nodes n ON n.id = w.waits_for
+		 WHERE n.label = ?
+		 ORDER BY w.seq`,
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var waiter, relType string
+		n, err := scanNode(rows.Scan, &waiter, &relType)
+		if err != nil {
+			return nil, err
+		}
+		waits[waiter] = append(waits[waiter], models.Wait{Task: n.toTask(), Type: models.RelationType(relType)})
+	}
+	return waits, rows.Err()
+}
+
+// planProgress returns the progress of each of the given plans that exists,
+// keyed by plan ID, counting their tasks by status in a single query.
+func planProgress(ctx context.Context, q queryer, ids []string) (map[string]models.PlanProgress, error) {
+	progress := make(map[string]models.PlanProgress, len(ids))
+	if len(ids) == 0 {
+		return progress, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := []any{string(models.RelPartOf), labelTask, labelPlan}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	rows, err := q.QueryContext(ctx,
+		`SELECT p.id, p.updated_at, t.status, COUNT(t.id), MAX(t.updated_at)
+		 FROM nodes p
+		 LEFT JOIN edges e ON e.to_id = p.id AND e.rel_type = ?
+		 LEFT JOIN nodes t ON t.id = e.from_id AND t.label = ?
+		 WHERE p.label = ? AND p.id IN (`+placeholders+`)
+		 GROUP BY p.id, t.status`,
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var planUpdatedAt int64
+		var status sql.NullString
+		var count int
+		var taskUpdatedAt sql.NullInt64
+		if err := rows.Scan(&id, &planUpdatedAt, &status, &count, &taskUpdatedAt); err != nil {
+			return nil, err
+		}
+		p, ok := progress[id]
+		if !ok {
+			p = models.NewPlanProgress(time.Unix(0, planUpdatedAt).UTC())
+		}
+		// A plan without tasks has a single row without a status
+		if status.Valid {
+			p.Add(models.TaskStatus(status.String), count, time.Unix(0, taskUpdatedAt.Int64).UTC())
+		}
+		progress[id] = p
+	}
+	return progress, rows.Err()
+}
+
+// edgePropColumns lists the columns read by edgeProps.dest, in order.
+const edgePropColumns = "e.created_at, e.reason, e.weight, e.metadata"
+
+// edgeProps receives the property columns of an edge.
+type edgeProps struct {
+	createdAt int64
+	reason    string
+	weight    sql.NullFloat64
+	metadata  string
+}
+
+// dest returns the scan destinations for edgePropColumns.
+func (p *edgeProps) dest() []any {
+	return []any{&p.createdAt, &p.reason, &p.weight, &p.metadata}
+}
+
+func (p *edgeProps) value() models.RelationshipProperties {
+	props := models.RelationshipProperties{
+		Reason:   p.reason,
+		Metadata: decodeMetadata(p.metadata),
+	}
+	if p.weight.Valid {
+		props.Weight = &p.weight.Float64
+	}
+	// Edges created before relationships had properties have no creation time
+	if p.createdAt != 0 {
+		props.CreatedAt = time.Unix(0, p.createdAt).UTC()
+	}
+	return props
+}
+
+// listEdges returns the relationships of a node, oldest first, optionally
+// restricted to one type and to a direction (incoming, outgoing or both).
+func listEdges(ctx context.Context, q queryer, id, relationType, direction string) ([]models.Edge, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT e.from_id, e.to_id, e.rel_type, e.position, `+edgePropColumns+` FROM edges e
+		 WHERE ((e.from_id = ?1 AND ?2 <> 'incoming') OR (e.to_id = ?1 AND ?2 <> 'outgoing'))
+		   AND (?3 = '' OR e.rel_type = ?3)
+		 ORDER BY e.seq`,
+		id, direction, relationType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var edges []models.Edge
+	for rows.Next() {
+		var fromID, toID, relType string
+		var position sql.NullFloat64
+		var props edgeProps
+		if err := rows.Scan(append([]any{&fromID, &toID, &relType, &position}, props.dest()...)...); err != nil {
+			return nil, err
+		}
+		edge := models.NewEdge(fromID, toID, models.RelationType(relType), props.value())
+		if position.Valid {
+			edge.Position = &position.Float64
+		}
+		edges = append(edges, edge)
+	}
+	return edges, rows.Err()
+}
+
+// tagFilter returns a WHERE fragment matching nodes that carry any of the
+// given tags, along with its arguments.
+func tagFilter(column string, tags []string) (string, []any) {
+	placeholders := make([]string, len(tags))
+	args := make([]any, len(tags))
+	for i, t := range tags {
+		placeholders[i] = "?"
+		args[i] = t
+	}
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s) WHERE json_each.value IN (%s))", column, strings.Join(placeholders, ", ")), args
+}
+
+// List orders, each broken by node ID so that cursors name a unique row
+const (
+	recencyOrder  = "n.updated_at DESC, n.id DESC"
+	positionOrder = "e.position ASC, n.id ASC"
+)
+
+// recencyKeyset returns the predicate keeping the rows after the cursor in
+// recencyOrder, and its arguments.
+func recencyKeyset(c models.Cursor) (string, []any) {
+	t := c.UpdatedAt.UnixNano()
+	return "(n.updated_at < ? OR (n.updated_at = ? AND n.id < ?))", []any{t, t, c.ID}
+}
+
+// positionKeyset returns the predicate keeping the rows after the cursor in
+// positionOrder, and its arguments.
+func positionKeyset(c models.Cursor) (string, []any) {
+	return "(e.position > ? OR (e.position = ? AND n.id > ?))", []any{c.Position, c.Position, c.ID}
+}
+
+func (n *node) toMemory() models.Memory {
+	return models.Memory{
+		ID:        n.id,
+		Type:      models.MemoryType(n.nodeType),
+		Content:   n.content,
+		Metadata:  n.metadata,
+		Tags:      n.tags,
+		CreatedAt: n.createdAt,
+		UpdatedAt: n.updatedAt,
+		Version:   n.version,
+	}
+}
+
+func (n *node) toPlan() models.Plan {
+	return models.Plan{
+		ID:          n.id,
+		Name:        n.name,
+		Description: n.description,
+		Status:      models.PlanStatus(n.status),
+		Metadata:    n.metadata,
+		Tags:        n.tags,
+		CreatedAt:   n.createdAt,
+		UpdatedAt:   n.updatedAt,
+		Version:     n.version,
+	}
+}
+
+func (n *node) toTask() models.Task {
+	return models.Task{
+		ID:        n.id,
+		Content:   n.content,
+		Status:    models.TaskStatus(n.status),
+		Metadata:  n.metadata,
+		Tags:      n.tags,
+		CreatedAt: n.createdAt,
+		UpdatedAt: n.updatedAt,
+		Version:   n.version,
+	}
+}
+
+// text returns the searchable text of a node: the name and description of a
+// plan, or the content of a memory or task.
+func (n *node) text() string {
+	if n.label == labelPlan {
+		return embedding.PlanText(n.name, n.description)
+	}
+	return n.content
+}
+
+// toNodeResult describes a node matched by SearchAll.
+func (n *node) toNodeResult(score float64, snippet string) models.NodeSearchResult {
+	res := models.NodeSearchResult{
+		Kind:      models.NodeKind(n.label),
+		ID:        n.id,
+		Title:     models.NodeTitle(n.content),
+		Snippet:   snippet,
+		Status:    n.status,
+		Score:     score,
+		UpdatedAt: n.updatedAt,
+	}
+	if n.label == labelPlan {
+		res.Title = n.name
+	}
+	return res
+}
+
+// toRelatedMemory flattens any node into the Memory shape used by get_related.
+// Plans expose their name as content, and Type holds the node label.
+func (n *node) toRelatedMemory() models.Memory {
+	mem := n.toMemory()
+	mem.Type = models.MemoryType(n.label)
+	if n.label == labelPlan {
+		mem.Content = n.name
+	}
+	return mem
+}
+
+func encodeMetadata(m map[string]string) string {
+	if len(m) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func decodeMetadata(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+func encodeTags(tags []string) string {
+	if len(tags) == 0 {
+		return "[]"
+	}
+	b, err := json.Marshal(tags)
+	if err != nil {
+		return "[]"
+	}
+	return string(b)
+}
+
+// saveEmbedding stores the embedding of text for node id. If the embedder
+// fails, any previous vector is dropped so semantic search recomputes it.
+func (s *Store) saveEmbedding(ctx context.Context, q queryer, id, text string) error {
+	vec := embedding.TryEmbed(ctx, s.embedder, s.logger, text)
+	if vec == nil {
+		_, err := q.ExecContext(ctx, `DELETE FROM embeddings WHERE node_id = ?`, id)
+		return err
+	}
+	_, err := q.ExecContext(ctx,
+		`INSERT INTO embeddings (node_id, model, vector) VALUES (?, ?, ?)
+		 ON CONFLICT (node_id) DO UPDATE SET model = excluded.model, vector = excluded.vector`,
+		id, s.embedder.Model(), encodeVector(vec))
+	return err
+}
+
+func encodeVector(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(x))
+	}
+	return b
+}
+
+func decodeVector(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
+
+func decodeTags(s string) []string {
+	var tags []string
+	if err := json.Unmarshal([]byte(s), &tags); err != nil || len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
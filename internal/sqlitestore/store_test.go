@@ -0,0 +1,116 @@
+package sqlitestore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Thomas-Fitz/associate/internal/embedding"
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/store/storetest"
+)
+
+func openTestStore(t *testing.T, path string) *Store {
+	t.Helper()
+	s, err := Open(context.Background(), Config{Path: path})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		s := openTestStore(t, filepath.Join(t.TempDir(), "associate.db"))
+		return storetest.Stores{
+			Memories: NewRepository(s),
+			Plans:    NewPlanRepository(s),
+			Tasks:    NewTaskRepository(s),
+		}
+	})
+}
+
+func TestOpen_PersistsAcrossReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "nested", "associate.db")
+
+	s, err := Open(ctx, Config{Path: path})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	plan, err := NewPlanRepository(s).Add(ctx, models.Plan{Name: "persisted"}, nil)
+	if err != nil {
+		t.Fatalf("Add plan: %v", err)
+	}
+	if _, _, err := NewTaskRepository(s).Add(ctx, models.Task{Content: "task"}, []string{plan.ID}, nil, nil, nil); err != nil {
+		t.Fatalf("Add task: %v", err)
+	}
+	s.Close()
+
+	reopened := openTestStore(t, path)
+	got, tasks, err := NewPlanRepository(reopened).GetWithTasks(ctx, plan.ID)
+	if err != nil {
+		t.Fatalf("GetWithTasks: %v", err)
+	}
+	if got == nil || got.Name != "persisted" || len(tasks) != 1 {
+		t.Errorf("after reopen: got %+v with %d tasks", got, len(tasks))
+	}
+}
+
+func TestSemanticSearch_ReembedsAfterModelChange(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t, filepath.Join(t.TempDir(), "associate.db"))
+	repo := NewRepository(s)
+
+	mem, err := repo.Add(ctx, models.Memory{Content: "rotate the database credentials nightly"}, nil)
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	s.SetEmbedder(embedding.NewHashEmbedder(64))
+
+	// Searches skip nodes without a vector for the model until the backfill
+	// embeds them
+	results, _, err := repo.Search(ctx, "credential rotation", models.SearchOptions{Mode: models.SearchModeSemantic})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Search before backfill: got %+v, want none", results)
+	}
+	if n, err := s.BackfillEmbeddings(ctx, 10); err != nil || n != 1 {
+		t.Fatalf("BackfillEmbeddings: got %d, %v, want 1", n, err)
+	}
+	if n, err := s.BackfillEmbeddings(ctx, 10); err != nil || n != 0 {
+		t.Errorf("BackfillEmbeddings again: got %d, %v, want 0", n, err)
+	}
+
+	results, _, err = repo.Search(ctx, "credential rotation", models.SearchOptions{Mode: models.SearchModeSemantic})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 1 || results[0].Memory.ID != mem.ID {
+		t.Fatalf("Search: got %+v, want the memory", results)
+	}
+
+	var model string
+	if err := s.db.QueryRowContext(ctx, `SELECT model FROM embeddings WHERE node_id = ?`, mem.ID).Scan(&model); err != nil {
+		t.Fatalf("read embedding: %v", err)
+	}
+	if model != "hash-64" {
+		t.Errorf("stored model: got %s, want hash-64", model)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("SQLITE_PATH", "/tmp/custom.db")
+	if got := ConfigFromEnv().Path; got != "/tmp/custom.db" {
+		t.Errorf("Path: got %s, want /tmp/custom.db", got)
+	}
+
+	t.Setenv("SQLITE_PATH", "")
+	if got := ConfigFromEnv().Path; got != DefaultPath() {
+		t.Errorf("Path: got %s, want %s", got, DefaultPath())
+	}
+}
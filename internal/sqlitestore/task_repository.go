@@ -0,0 +1,689 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.TaskStore = (*TaskRepository)(nil)
+
+// TaskRepository provides CRUD operations for tasks.
+type TaskRepository struct {
+	store *Store
+}
+
+// NewTaskRepository creates a new task repository backed by s
+func NewTaskRepository(s *Store) *TaskRepository {
+	return &TaskRepository{store: s}
+}
+
+// Add creates a new task with required plan links and optional relationships.
+func (r *TaskRepository) Add(ctx context.Context, task models.Task, planIDs []string, relationships []models.Relationship, afterTaskID, beforeTaskID *string) (*models.Task, models.StatusChanges, error) {
+	if len(planIDs) == 0 {
+		return nil, models.StatusChanges{}, fmt.Errorf("task must belong to at least one plan")
+	}
+
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, models.StatusChanges{}, err
+	}
+	defer tx.Rollback()
+
+	// Verify all plans exist
+	for _, planID := range planIDs {
+		exists, err := nodeExists(ctx, tx, planID, labelPlan)
+		if err != nil {
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to verify plan %s: %w", planID, err)
+		}
+		if !exists {
+			return nil, models.StatusChanges{}, fmt.Errorf("plan not found: %s", planID)
+		}
+	}
+
+	if task.ID == "" {
+		task.ID = uuid.New().String()
+	}
+	now := time.Now().UTC()
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	task.Version = 1
+
+	if task.Status == "" {
+		task.Status = models.TaskStatusPending
+	}
+
+	err = insertNode(ctx, tx, &node{
+		id:        task.ID,
+		label:     labelTask,
+		content:   task.Content,
+		status:    string(task.Status),
+		metadata:  task.Metadata,
+		tags:      task.Tags,
+		createdAt: task.CreatedAt,
+		updatedAt: task.UpdatedAt,
+		version:   task.Version,
+	})
+	if err != nil {
+		return nil, models.StatusChanges{}, fmt.Errorf("failed to create task: %w", err)
+	}
+	if err := recordRevision(ctx, tx, task.ID, store.Actor(ctx)); err != nil {
+		return nil, models.StatusChanges{}, err
+	}
+
+	if err := r.store.saveEmbedding(ctx, tx, task.ID, task.Content); err != nil {
+		return nil, models.StatusChanges{}, fmt.Errorf("failed to store embedding: %w", err)
+	}
+
+	// Create PART_OF relationships to plans
+	for _, planID := range planIDs {
+		position, err := r.calculateNewTaskPosition(ctx, tx, planID, afterTaskID, beforeTaskID)
+		if err != nil {
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to calculate position for plan %s: %w", planID, err)
+		}
+
+		if err := r.setPlanPosition(ctx, tx, task.ID, planID, position); err != nil {
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to link task to plan %s: %w", planID, err)
+		}
+	}
+
+	// Create other relationships
+	for _, rel := range relationships {
+		if err := createRelationship(ctx, tx, task.ID, rel); err != nil {
+			return nil, models.StatusChanges{}, err
+		}
+	}
+
+	var changes models.StatusChanges
+	if r.store.statusRules {
+		targets, err := store.StatusRuleTargets(ctx, task.ID, false, relationships, dependencyEdges(tx))
+		if err != nil {
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to find dependants: %w", err)
+		}
+		if changes.Tasks, err = r.applyStatusRules(ctx, tx, targets, store.RuledStatus); err != nil {
+			return nil, models.StatusChanges{}, err
+		}
+	}
+	if r.store.planCompletion {
+		if changes.Plans, err = r.applyPlanCompletion(ctx, tx, planIDs); err != nil {
+			return nil, models.StatusChanges{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, models.StatusChanges{}, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	for _, c := range changes.Tasks {
+		if c.Task.ID == task.ID {
+			task = c.Task
+		}
+	}
+	return &task, changes, nil
+}
+
+// GetByID retrieves a task by ID
+func (r *TaskRepository) GetByID(ctx context.Context, id string) (*models.Task, error) {
+	n, err := getNode(ctx, r.store.db, id, labelTask)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	task := n.toTask()
+	return &task, nil
+}
+
+// GetWithPlans retrieves a task by ID along with its associated plans
+func (r *TaskRepository) GetWithPlans(ctx context.Context, id string) (*models.Task, []models.Plan, error) {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	n, err := getNode(ctx, tx, id, labelTask)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query failed: %w", err)
+	}
+	if n == nil {
+		return nil, nil, nil
+	}
+	task := n.toTask()
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT `+nodeColumns+` FROM edges e
+		 JOIN nodes n ON n.id = e.to_id
+		 WHERE e.from_id = ? AND e.rel_type = ? AND n.label = ?
+		 ORDER BY e.seq`,
+		id, string(models.RelPartOf), labelPlan)
+	if err != nil {
+		return nil, nil, fmt.Errorf("plans query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var plans []models.Plan
+	for rows.Next() {
+		p, err := scanNode(rows.Scan)
+		if err != nil {
+			return nil, nil, err
+		}
+		plans = append(plans, p.toPlan())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return &task, plans, nil
+}
+
+// Update modifies an existing task
+func (r *TaskRepository) Update(ctx context.Context, id string, expectedVersion int64, content *string, status *string, metadata map[string]string, tags []string, addPlanIDs []string, newRelationships []models.Relationship) (*models.Task, models.StatusChanges, error) {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, models.StatusChanges{}, err
+	}
+	defer tx.Rollback()
+
+	// Verify all plans exist
+	for _, planID := range addPlanIDs {
+		exists, err := nodeExists(ctx, tx, planID, labelPlan)
+		if err != nil {
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to verify plan %s: %w", planID, err)
+		}
+		if !exists {
+			return nil, models.StatusChanges{}, fmt.Errorf("plan not found: %s", planID)
+		}
+	}
+
+	u := newNodeUpdate(expectedVersion)
+	if content != nil {
+		u.set("content", *content)
+	}
+	if status != nil {
+		u.set("status", *status)
+	}
+	if metadata != nil {
+		u.set("metadata", encodeMetadata(metadata))
+	}
+	if tags != nil {
+		u.set("tags", encodeTags(tags))
+	}
+
+	n, err := u.exec(ctx, tx, id, labelTask)
+	if err != nil {
+		return nil, models.StatusChanges{}, fmt.Errorf("failed to update task: %w", err)
+	}
+	if n == nil {
+		return nil, models.StatusChanges{}, fmt.Errorf("task not found: %s", id)
+	}
+	if err := recordRevision(ctx, tx, id, store.Actor(ctx)); err != nil {
+		return nil, models.StatusChanges{}, err
+	}
+
+	if content != nil {
+		if err := r.store.saveEmbedding(ctx, tx, id, n.content); err != nil {
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to store embedding: %w", err)
+		}
+	}
+
+	// Add to new plans (append to end)
+	for _, planID := range addPlanIDs {
+		maxPos, err := r.getMaxPosition(ctx, tx, planID)
+		if err != nil {
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to get max position for plan %s: %w", planID, err)
+		}
+
+		if err := r.setPlanPosition(ctx, tx, id, planID, maxPos+models.DefaultPositionIncrement); err != nil {
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to link task to plan %s: %w", planID, err)
+		}
+	}
+
+	// Create new relationships
+	for _, rel := range newRelationships {
+		if err := createRelationship(ctx, tx, id, rel); err != nil {
+			return nil, models.StatusChanges{}, err
+		}
+	}
+
+	var changes models.StatusChanges
+	if r.store.statusRules {
+		targets, err := store.StatusRuleTargets(ctx, id, status != nil, newRelationships, dependencyEdges(tx))
+		if err != nil {
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to find dependants: %w", err)
+		}
+		if changes.Tasks, err = r.applyStatusRules(ctx, tx, targets, store.RuledStatus); err != nil {
+			return nil, models.StatusChanges{}, err
+		}
+	}
+	if r.store.planCompletion && (status != nil || len(addPlanIDs) > 0) {
+		planIDs, err := taskPlanIDs(ctx, tx, id)
+		if err != nil {
+			return nil, models.StatusChanges{}, fmt.Errorf("failed to find plans: %w", err)
+		}
+		if changes.Plans, err = r.applyPlanCompletion(ctx, tx, planIDs); err != nil {
+			return nil, models.StatusChanges{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, models.StatusChanges{}, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	task := n.toTask()
+	for _, c := range changes.Tasks {
+		if c.Task.ID == id {
+			task = c.Task
+		}
+	}
+	return &task, changes, nil
+}
+
+// SetStatusRules turns the status rules on or off for every repository
+// backed by the same store
+func (r *TaskRepository) SetStatusRules(enabled bool) {
+	r.store.statusRules = enabled
+}
+
+// SetPlanCompletion turns plan completion on or off for every repository
+// backed by the same store
+func (r *TaskRepository) SetPlanCompletion(enabled bool) {
+	r.store.planCompletion = enabled
+}
+
+// applyStatusRules applies rule to each of the target tasks.
+func (r *TaskRepository) applyStatusRules(ctx context.Context, tx *sql.Tx, targets []string, rule func([]models.Wait) (from, to models.TaskStatus, ok bool)) ([]models.StatusChange, error) {
+	waits, err := taskWaits(ctx, tx, targets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find dependencies: %w", err)
+	}
+
+	var changes []models.StatusChange
+	for _, taskID := range targets {
+		from, to, ok := rule(waits[taskID])
+		if !ok {
+			continue
+		}
+		res, err := tx.ExecContext(ctx,
+			`UPDATE nodes SET status = ?, updated_at = ?, version = version + 1
+			 WHERE id = ? AND label = ? AND status = ?`,
+			string(to), time.Now().UTC().UnixNano(), taskID, labelTask, string(from))
+		if err != nil {
+			return nil, fmt.Errorf("failed to update status of task %s: %w", taskID, err)
+		}
+		if affected, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if affected == 0 {
+			continue
+		}
+		if err := recordRevision(ctx, tx, taskID, store.Actor(ctx)); err != nil {
+			return nil, err
+		}
+		n, err := getNode(ctx, tx, taskID, labelTask)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, models.StatusChange{Task: n.toTask(), From: from})
+	}
+	return changes, nil
+}
+
+// applyPlanCompletion applies plan completion to the given plans.
+func (r *TaskRepository) applyPlanCompletion(ctx context.Context, tx *sql.Tx, planIDs []string) ([]models.PlanStatusChange, error) {
+	progress, err := planProgress(ctx, tx, planIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tasks: %w", err)
+	}
+
+	var changes []models.PlanStatusChange
+	for _, planID := range planIDs {
+		from, to, ok := store.PlanCompletion(progress[planID])
+		if !ok {
+			continue
+		}
+		res, err := tx.ExecContext(ctx,
+			`UPDATE nodes SET status = ?, updated_at = ?, version = version + 1
+			 WHERE id = ? AND label = ? AND status = ?`,
+			string(to), time.Now().UTC().UnixNano(), planID, labelPlan, string(from))
+		if err != nil {
+			return nil, fmt.Errorf("failed to update status of plan %s: %w", planID, err)
+		}
+		if affected, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if affected == 0 {
+			continue
+		}
+		if err := recordRevision(ctx, tx, planID, store.Actor(ctx)); err != nil {
+			return nil, err
+		}
+		n, err := getNode(ctx, tx, planID, labelPlan)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, models.PlanStatusChange{Plan: n.toPlan(), From: from})
+	}
+	return changes, nil
+}
+
+// taskPlanIDs returns the IDs of the plans a task belongs to.
+func taskPlanIDs(ctx context.Context, q queryer, id string) ([]string, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT to_id FROM edges WHERE from_id = ? AND rel_type = ? ORDER BY seq`,
+		id, string(models.RelPartOf))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var planIDs []string
+	for rows.Next() {
+		var planID string
+		if err := rows.Scan(&planID); err != nil {
+			return nil, err
+		}
+		planIDs = append(planIDs, planID)
+	}
+	return planIDs, rows.Err()
+}
+
+// Delete moves a task and all its relationships to the trash
+func (r *TaskRepository) Delete(ctx context.Context, id string) (models.StatusChanges, error) {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.StatusChanges{}, err
+	}
+	defer tx.Rollback()
+
+	var changes models.StatusChanges
+	if exists, err := nodeExists(ctx, tx, id, labelTask); err != nil || !exists {
+		return changes, err
+	}
+	// The plans lose the task with its relationships, so find them first
+	planIDs, err := taskPlanIDs(ctx, tx, id)
+	if err != nil {
+		return models.StatusChanges{}, fmt.Errorf("failed to find plans: %w", err)
+	}
+	if changes.Tasks, err = r.trashNodes(ctx, tx, id); err != nil {
+		return models.StatusChanges{}, err
+	}
+	if r.store.planCompletion {
+		if changes.Plans, err = r.applyPlanCompletion(ctx, tx, planIDs); err != nil {
+			return models.StatusChanges{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.StatusChanges{}, fmt.Errorf("failed to commit: %w", err)
+	}
+	return changes, nil
+}
+
+// trashNodes moves the given nodes to the trash and, with status rules on,
+// applies them to the tasks that waited for those.
+func (r *TaskRepository) trashNodes(ctx context.Context, tx *sql.Tx, ids ...string) ([]models.StatusChange, error) {
+	var targets []string
+	if r.store.statusRules {
+		// The relationships go to the trash with the nodes, so find the
+		// dependants first
+		var err error
+		if targets, err = store.DeletionRuleTargets(ctx, ids, dependencyEdges(tx)); err != nil {
+			return nil, fmt.Errorf("failed to find dependants: %w", err)
+		}
+	}
+	if err := trashNodes(ctx, tx, ids...); err != nil {
+		return nil, fmt.Errorf("delete failed: %w", err)
+	}
+	if !r.store.statusRules {
+		return nil, nil
+	}
+	return r.applyStatusRules(ctx, tx, targets, store.RuledStatusAfterDeletion)
+}
+
+// PreviewDelete returns what Delete would move to the trash
+func (r *TaskRepository) PreviewDelete(ctx context.Context, id string) (*models.TrashEntry, error) {
+	return previewTrash(ctx, r.store.db, id, labelTask)
+}
+
+// UpdatePositions batch updates task positions within a plan.
+func (r *TaskRepository) UpdatePositions(ctx context.Context, planID string, taskPositions map[string]float64) error {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for taskID, position := range taskPositions {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE edges SET position = ?
+			 WHERE from_id = ? AND to_id = ? AND rel_type = ?
+			   AND from_id IN (SELECT id FROM nodes WHERE label = ?)
+			   AND to_id IN (SELECT id FROM nodes WHERE label = ?)`,
+			position, taskID, planID, string(models.RelPartOf), labelTask, labelPlan)
+		if err != nil {
+			return fmt.Errorf("failed to update position for task %s: %w", taskID, err)
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			return fmt.Errorf("task %s not found in plan %s", taskID, planID)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// NormalizePositions rewrites the positions of the given tasks within a plan,
+// evenly spaced in the given order.
+func (r *TaskRepository) NormalizePositions(ctx context.Context, planID string, taskIDs []string) ([]float64, error) {
+	positions := models.CalculateInsertPositions(0, 0, len(taskIDs))
+	taskPositions := make(map[string]float64, len(taskIDs))
+	for i, taskID := range taskIDs {
+		taskPositions[taskID] = positions[i]
+	}
+	if err := r.UpdatePositions(ctx, planID, taskPositions); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// List retrieves a page of tasks with optional filtering.
+func (r *TaskRepository) List(ctx context.Context, planID string, status string, tags []string, limit int, cursor string) ([]models.TaskListResult, models.Page, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	c, err := models.DecodeListCursor(cursor)
+	if err != nil {
+		return nil, models.Page{}, err
+	}
+
+	whereClauses := []string{"n.label = ?"}
+	args := []any{labelTask}
+	if status != "" {
+		whereClauses = append(whereClauses, "n.status = ?")
+		args = append(args, status)
+	}
+	if len(tags) > 0 {
+		clause, tagArgs := tagFilter("n.tags", tags)
+		whereClauses = append(whereClauses, clause)
+		args = append(args, tagArgs...)
+	}
+
+	from := `nodes n`
+	order := recencyOrder
+	keyset := recencyKeyset
+	if planID != "" {
+		whereClauses = append(whereClauses, "e.to_id = ?", "e.rel_type = ?")
+		args = append(args, planID, string(models.RelPartOf))
+		from = `nodes n
+			JOIN edges e ON e.from_id = n.id
+			JOIN nodes p ON p.id = e.to_id AND p.label = '` + labelPlan + `'`
+		order = positionOrder
+		keyset = positionKeyset
+	}
+
+	var page models.Page
+	if err := r.store.db.QueryRowContext(ctx,
+		`SELECT count(*) FROM `+from+` WHERE `+strings.Join(whereClauses, " AND "),
+		args...).Scan(&page.Total); err != nil {
+		return nil, models.Page{}, fmt.Errorf("list failed: %w", err)
+	}
+
+	if !c.IsZero() {
+		clause, keyArgs := keyset(c)
+		whereClauses = append(whereClauses, clause)
+		args = append(args, keyArgs...)
+	}
+	// One extra row tells whether there is a next page
+	args = append(args, limit+1)
+
+	position := "NULL"
+	if planID != "" {
+		position = "e.position"
+	}
+	rows, err := r.store.db.QueryContext(ctx,
+		`SELECT `+position+`, `+nodeColumns+` FROM `+from+`
+		 WHERE `+strings.Join(whereClauses, " AND ")+`
+		 ORDER BY `+order+`
+		 LIMIT ?`,
+		args...)
+	if err != nil {
+		return nil, models.Page{}, fmt.Errorf("list failed: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []models.TaskListResult
+	for rows.Next() {
+		var position sql.NullFloat64
+		n, err := scanNode(rows.Scan, &position)
+		if err != nil {
+			return nil, models.Page{}, err
+		}
+		result := models.TaskListResult{Task: n.toTask()}
+		if position.Valid {
+			pos := position.Float64
+			result.Position = &pos
+		}
+		tasks = append(tasks, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.Page{}, err
+	}
+
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+		last := tasks[limit-1]
+		next := models.Cursor{UpdatedAt: last.Task.UpdatedAt, ID: last.Task.ID}
+		if last.Position != nil {
+			next = models.Cursor{Position: *last.Position, ID: last.Task.ID}
+		}
+		page.NextCursor = next.Encode()
+	}
+	return tasks, page, nil
+}
+
+// Helper methods
+
+// setPlanPosition links a task to a plan, or moves it if already linked.
+func (r *TaskRepository) setPlanPosition(ctx context.Context, tx *sql.Tx, taskID, planID string, position float64) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO edges (from_id, to_id, rel_type, position, created_at) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT (from_id, to_id, rel_type) DO UPDATE SET position = excluded.position`,
+		taskID, planID, string(models.RelPartOf), position, time.Now().UnixNano())
+	return err
+}
+
+func (r *TaskRepository) getMaxPosition(ctx context.Context, tx *sql.Tx, planID string) (float64, error) {
+	var maxPos sql.NullFloat64
+	err := tx.QueryRowContext(ctx,
+		`SELECT MAX(e.position) FROM edges e JOIN nodes t ON t.id = e.from_id
+		 WHERE e.to_id = ? AND e.rel_type = ? AND t.label = ?`,
+		planID, string(models.RelPartOf), labelTask).Scan(&maxPos)
+	if err != nil {
+		return 0, err
+	}
+	return maxPos.Float64, nil
+}
+
+func (r *TaskRepository) getTaskPosition(ctx context.Context, tx *sql.Tx, taskID, planID string) (float64, error) {
+	var position sql.NullFloat64
+	err := tx.QueryRowContext(ctx,
+		`SELECT position FROM edges WHERE from_id = ? AND to_id = ? AND rel_type = ?`,
+		taskID, planID, string(models.RelPartOf)).Scan(&position)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return position.Float64, nil
+}
+
+func (r *TaskRepository) getAdjacentPositions(ctx context.Context, tx *sql.Tx, taskID, planID string) (before, after float64, err error) {
+	currentPos, err := r.getTaskPosition(ctx, tx, taskID, planID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var beforePos, afterPos sql.NullFloat64
+	err = tx.QueryRowContext(ctx,
+		`SELECT MAX(e.position) FROM edges e JOIN nodes t ON t.id = e.from_id
+		 WHERE e.to_id = ? AND e.rel_type = ? AND t.label = ? AND e.position < ?`,
+		planID, string(models.RelPartOf), labelTask, currentPos).Scan(&beforePos)
+	if err != nil {
+		return 0, 0, err
+	}
+	err = tx.QueryRowContext(ctx,
+		`SELECT MIN(e.position) FROM edges e JOIN nodes t ON t.id = e.from_id
+		 WHERE e.to_id = ? AND e.rel_type = ? AND t.label = ? AND e.position > ?`,
+		planID, string(models.RelPartOf), labelTask, currentPos).Scan(&afterPos)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return beforePos.Float64, afterPos.Float64, nil
+}
+
+func (r *TaskRepository) calculateNewTaskPosition(ctx context.Context, tx *sql.Tx, planID string, afterTaskID, beforeTaskID *string) (float64, error) {
+	hasAfter := afterTaskID != nil && *afterTaskID != ""
+	hasBefore := beforeTaskID != nil && *beforeTaskID != ""
+
+	// If neither specified, append to end
+	if !hasAfter && !hasBefore {
+		maxPos, err := r.getMaxPosition(ctx, tx, planID)
+		if err != nil {
+			return 0, err
+		}
+		return maxPos + models.DefaultPositionIncrement, nil
+	}
+
+	var afterPos, beforePos float64
+	var err error
+	if hasAfter {
+		if afterPos, err = r.getTaskPosition(ctx, tx, *afterTaskID, planID); err != nil {
+			return 0, err
+		}
+		if !hasBefore {
+			if _, beforePos, err = r.getAdjacentPositions(ctx, tx, *afterTaskID, planID); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if hasBefore {
+		if beforePos, err = r.getTaskPosition(ctx, tx, *beforeTaskID, planID); err != nil {
+			return 0, err
+		}
+		if !hasAfter {
+			if afterPos, _, err = r.getAdjacentPositions(ctx, tx, *beforeTaskID, planID); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	positions := models.CalculateInsertPositions(afterPos, beforePos, 1)
+	if len(positions) == 0 {
+		return models.DefaultPositionIncrement, nil
+	}
+	return positions[0], nil
+}
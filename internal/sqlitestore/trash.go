@@ -0,0 +1,318 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/store"
+)
+
+// trashNode moves the node with the given ID and label to the trash, if it exists.
+func trashNode(ctx context.Context, db *sql.DB, id, label string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if exists, err := nodeExists(ctx, tx, id, label); err != nil || !exists {
+		return err
+	}
+	if err := trashNodes(ctx, tx, id); err != nil {
+		return fmt.Errorf("delete failed: %w", err)
+	}
+	return tx.Commit()
+}
+
+// previewTrash returns what trashNode would move to the trash, or nil if there
+// is no node with the given ID and label.
+func previewTrash(ctx context.Context, db *sql.DB, id, label string) (*models.TrashEntry, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if exists, err := nodeExists(ctx, tx, id, label); err != nil || !exists {
+		return nil, err
+	}
+	return trashEntry(ctx, tx, id)
+}
+
+// trashEntry returns what deleting the nodes with the given IDs, the first of
+// which is the node being deleted, moves to the trash: the nodes, their
+// revisions and every edge touching them. Missing nodes are skipped, and it
+// returns nil if all of them are.
+func trashEntry(ctx context.Context, q queryer, ids ...string) (*models.TrashEntry, error) {
+	var nodes []models.TrashedNode
+	var edges []models.Edge
+	for _, id := range ids {
+		label, err := nodeLabel(ctx, q, id)
+		if err != nil {
+			return nil, err
+		}
+		n, err := getNode(ctx, q, id, label)
+		if err != nil {
+			return nil, err
+		}
+		if n == nil {
+			continue
+		}
+		tn := n.trashed()
+		if tn.Revisions, err = nodeRevisions(ctx, q, id); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, tn)
+
+		nodeEdges, err := listEdges(ctx, q, id, "", "both")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read relationships: %w", err)
+		}
+		edges = append(edges, nodeEdges...)
+	}
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	entry := models.NewTrashEntry(nodes, edges, time.Now().UTC())
+	return &entry, nil
+}
+
+// trashNodes moves the nodes with the given IDs, the first of which is the
+// node being deleted, to the trash along with their revisions and every edge
+// touching them, then deletes them. Missing nodes are skipped.
+func trashNodes(ctx context.Context, q queryer, ids ...string) error {
+	entry, err := trashEntry(ctx, q, ids...)
+	if err != nil || entry == nil {
+		return err
+	}
+
+	if err := saveTrashEntry(ctx, q, *entry); err != nil {
+		return err
+	}
+	for _, id := range entry.NodeIDs() {
+		// Edges, revisions and embeddings go with the node by the foreign key cascade
+		if _, err := q.ExecContext(ctx, `DELETE FROM nodes WHERE id = ?`, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nodeRevisions returns the revisions of a node, oldest first.
+func nodeRevisions(ctx context.Context, q queryer, id string) ([]models.Revision, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT `+revisionColumns+` FROM revisions WHERE node_id = ? ORDER BY version`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []models.Revision
+	for rows.Next() {
+		rev, err := scanRevision(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *rev)
+	}
+	return revisions, rows.Err()
+}
+
+// saveTrashEntry stores entry, replacing any earlier entry with its ID.
+func saveTrashEntry(ctx context.Context, q queryer, entry models.TrashEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx,
+		`INSERT INTO trash (id, deleted_at, entry) VALUES (?, ?, ?)
+		 ON CONFLICT (id) DO UPDATE SET deleted_at = excluded.deleted_at, entry = excluded.entry`,
+		entry.ID, entry.DeletedAt.UnixNano(), string(b))
+	if err != nil {
+		return fmt.Errorf("failed to save trash entry: %w", err)
+	}
+	return nil
+}
+
+// listTrash returns the trash entries, most recently deleted first.
+func listTrash(ctx context.Context, q queryer) ([]models.TrashEntry, error) {
+	rows, err := q.QueryContext(ctx, `SELECT entry FROM trash ORDER BY deleted_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.TrashEntry
+	for rows.Next() {
+		var b string
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var entry models.TrashEntry
+		if err := json.Unmarshal([]byte(b), &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode trash entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// trashed returns n as kept in the trash, without its revisions.
+func (n *node) trashed() models.TrashedNode {
+	tn := models.TrashedNode{Kind: models.NodeKind(n.label)}
+	switch n.label {
+	case labelPlan:
+		p := n.toPlan()
+		tn.Plan = &p
+	case labelTask:
+		t := n.toTask()
+		tn.Task = &t
+	default:
+		m := n.toMemory()
+		tn.Memory = &m
+	}
+	return tn
+}
+
+// untrashed returns the row of the node kept in the trash as tn.
+func untrashed(tn models.TrashedNode) *node {
+	n := &node{label: string(tn.Kind)}
+	switch {
+	case tn.Plan != nil:
+		n.id, n.name, n.description, n.status = tn.Plan.ID, tn.Plan.Name, tn.Plan.Description, string(tn.Plan.Status)
+		n.metadata, n.tags = tn.Plan.Metadata, tn.Plan.Tags
+		n.createdAt, n.updatedAt, n.version = tn.Plan.CreatedAt, tn.Plan.UpdatedAt, tn.Plan.Version
+	case tn.Task != nil:
+		n.id, n.content, n.status = tn.Task.ID, tn.Task.Content, string(tn.Task.Status)
+		n.metadata, n.tags = tn.Task.Metadata, tn.Task.Tags
+		n.createdAt, n.updatedAt, n.version = tn.Task.CreatedAt, tn.Task.UpdatedAt, tn.Task.Version
+	case tn.Memory != nil:
+		n.id, n.nodeType, n.content = tn.Memory.ID, string(tn.Memory.Type), tn.Memory.Content
+		n.metadata, n.tags = tn.Memory.Metadata, tn.Memory.Tags
+		n.createdAt, n.updatedAt, n.version = tn.Memory.CreatedAt, tn.Memory.UpdatedAt, tn.Memory.Version
+	}
+	return n
+}
+
+// insertRevision stores a revision unless its version is already recorded.
+func insertRevision(ctx context.Context, q queryer, rev models.Revision) error {
+	_, err := q.ExecContext(ctx,
+		`INSERT OR IGNORE INTO revisions (`+revisionColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rev.NodeID, rev.Version, string(rev.Kind), string(rev.Type), rev.Content, rev.Name, rev.Description,
+		rev.Status, encodeMetadata(rev.Metadata), encodeTags(rev.Tags), rev.Actor, rev.CreatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to restore revision: %w", err)
+	}
+	return nil
+}
+
+// insertEdge re-creates an edge exactly as described, position included.
+func insertEdge(ctx context.Context, q queryer, e models.Edge) error {
+	var position, weight any
+	if e.Position != nil {
+		position = *e.Position
+	}
+	if e.Weight != nil {
+		weight = *e.Weight
+	}
+	var createdAt int64
+	if !e.CreatedAt.IsZero() {
+		createdAt = e.CreatedAt.UnixNano()
+	}
+	_, err := q.ExecContext(ctx,
+		`INSERT OR IGNORE INTO edges (from_id, to_id, rel_type, position, created_at, reason, weight, metadata)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.FromID, e.ToID, string(e.Type), position, createdAt, e.Reason, weight, encodeMetadata(e.Metadata))
+	if err != nil {
+		return fmt.Errorf("failed to restore relationship %s: %w", e.ID, err)
+	}
+	return nil
+}
+
+// ListTrash lists the trash, most recently deleted first
+func (r *Repository) ListTrash(ctx context.Context) ([]models.TrashEntry, error) {
+	return listTrash(ctx, r.store.db)
+}
+
+// Restore brings back a deleted node, the nodes deleted with it and their edges
+func (r *Repository) Restore(ctx context.Context, id string) (*models.TrashEntry, error) {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	trash, err := listTrash(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	var entry *models.TrashEntry
+	for i := range trash {
+		if trash[i].ID == id {
+			entry = &trash[i]
+			break
+		}
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("not in trash: %s", id)
+	}
+	existing, err := existingNodes(ctx, tx, store.RestoreIDs(*entry))
+	if err != nil {
+		return nil, err
+	}
+	restoration, err := store.PlanRestore(*entry, existing, trash)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tn := range entry.Nodes {
+		n := untrashed(tn)
+		if err := insertNode(ctx, tx, n); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", n.id, err)
+		}
+		for _, rev := range tn.Revisions {
+			if err := insertRevision(ctx, tx, rev); err != nil {
+				return nil, err
+			}
+		}
+		if err := r.store.saveEmbedding(ctx, tx, n.id, n.text()); err != nil {
+			return nil, fmt.Errorf("failed to store embedding: %w", err)
+		}
+	}
+	for _, e := range restoration.Edges {
+		if err := insertEdge(ctx, tx, e); err != nil {
+			return nil, err
+		}
+	}
+	for _, other := range trash {
+		if edges := restoration.Deferred[other.ID]; len(edges) > 0 {
+			other.Edges = append(other.Edges, edges...)
+			if err := saveTrashEntry(ctx, tx, other); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM trash WHERE id = ?`, id); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	entry.SkippedEdges = restoration.Skipped
+	return entry, nil
+}
+
+// PurgeTrash permanently removes the trash entries deleted before the given time
+func (r *Repository) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	res, err := r.store.db.ExecContext(ctx, `DELETE FROM trash WHERE deleted_at < ?`, before.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,18 @@
+package store
+
+import "context"
+
+type actorKey struct{}
+
+// WithActor returns a context that attributes the changes made with it to
+// actor, typically the agent or client making the request. Stores record the
+// actor in the revisions they write.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the actor set by WithActor, or "" if there is none.
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
@@ -0,0 +1,201 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+)
+
+// DependencyEdges returns the DEPENDS_ON and BLOCKS relationships touching
+// any of the given nodes, in either direction.
+type DependencyEdges func(ctx context.Context, ids []string) ([]models.Relationship, error)
+
+// DependencyCycle returns the cycle of DEPENDS_ON and BLOCKS relationships
+// that adding rel to those found through edges would close, as the IDs along
+// it, each waiting for the next, from rel's waiting node back to itself. It
+// returns nil if there is none, or if rel is of another type.
+func DependencyCycle(ctx context.Context, rel models.Relationship, edges DependencyEdges) ([]string, error) {
+	waiter, waitsFor, ok := models.Dependency(rel)
+	if !ok {
+		return nil, nil
+	}
+
+	// Walk breadth-first from what the waiter would wait for, looking for a
+	// way back to the waiter. prev holds the node before each one reached.
+	prev := map[string]string{waitsFor: waiter}
+	frontier := []string{waitsFor}
+	for len(frontier) > 0 {
+		if _, found := prev[waiter]; found {
+			break
+		}
+		waits, err := expandDependencies(ctx, frontier, edges)
+		if err != nil {
+			return nil, err
+		}
+		var next []string
+		for _, id := range frontier {
+			for _, other := range waits[id] {
+				if _, seen := prev[other]; !seen {
+					prev[other] = id
+					next = append(next, other)
+				}
+			}
+		}
+		frontier = next
+	}
+	if _, found := prev[waiter]; !found {
+		return nil, nil
+	}
+
+	path := []string{waiter}
+	for id := prev[waiter]; id != waiter; id = prev[id] {
+		path = append(path, id)
+	}
+	slices.Reverse(path)
+	return append([]string{waiter}, path...), nil
+}
+
+// FindCycles returns the cycles of DEPENDS_ON and BLOCKS relationships that
+// the given nodes are on or wait on, one per group of nodes that wait for
+// each other. Each is a shortest cycle through the group's lowest ID, as the
+// IDs along it, each waiting for the next, starting and ending with that ID.
+// Cycles are ordered by their first ID.
+func FindCycles(ctx context.Context, ids []string, edges DependencyEdges) ([][]string, error) {
+	// Gather every node the given ones wait on, directly or not
+	waits := make(map[string][]string)
+	var nodes []string
+	seen := make(map[string]bool)
+	frontier := slices.Clone(ids)
+	for _, id := range frontier {
+		seen[id] = true
+	}
+	for len(frontier) > 0 {
+		expanded, err := expandDependencies(ctx, frontier, edges)
+		if err != nil {
+			return nil, err
+		}
+		var next []string
+		for _, id := range frontier {
+			nodes = append(nodes, id)
+			waits[id] = expanded[id]
+			for _, other := range expanded[id] {
+				if !seen[other] {
+					seen[other] = true
+					next = append(next, other)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	var cycles [][]string
+	for _, group := range stronglyConnected(nodes, waits) {
+		start := slices.Min(group)
+		if len(group) == 1 && !slices.Contains(waits[start], start) {
+			continue
+		}
+		cycles = append(cycles, shortestCycle(start, group, waits))
+	}
+	slices.SortFunc(cycles, func(a, b []string) int {
+		return strings.Compare(a[0], b[0])
+	})
+	return cycles, nil
+}
+
+// expandDependencies returns what each of the frontier nodes waits for, in
+// the order edges gives them.
+func expandDependencies(ctx context.Context, frontier []string, edges DependencyEdges) (map[string][]string, error) {
+	rels, err := edges(ctx, frontier)
+	if err != nil {
+		return nil, err
+	}
+	inFrontier := make(map[string]bool, len(frontier))
+	for _, id := range frontier {
+		inFrontier[id] = true
+	}
+	waits := make(map[string][]string)
+	for _, rel := range rels {
+		waiter, waitsFor, ok := models.Dependency(rel)
+		if ok && inFrontier[waiter] && !slices.Contains(waits[waiter], waitsFor) {
+			waits[waiter] = append(waits[waiter], waitsFor)
+		}
+	}
+	return waits, nil
+}
+
+// stronglyConnected returns the groups of nodes that can each reach all the
+// others by following waits, using Tarjan's algorithm.
+func stronglyConnected(nodes []string, waits map[string][]string) [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var groups [][]string
+
+	var visit func(id string)
+	visit = func(id string) {
+		index[id] = len(index)
+		low[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, other := range waits[id] {
+			if _, visited := index[other]; !visited {
+				visit(other)
+				low[id] = min(low[id], low[other])
+			} else if onStack[other] {
+				low[id] = min(low[id], index[other])
+			}
+		}
+		if low[id] != index[id] {
+			return
+		}
+		var group []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			group = append(group, top)
+			if top == id {
+				break
+			}
+		}
+		groups = append(groups, group)
+	}
+
+	for _, id := range nodes {
+		if _, visited := index[id]; !visited {
+			visit(id)
+		}
+	}
+	return groups
+}
+
+// shortestCycle returns a shortest cycle from start back to itself through
+// the nodes of its group.
+func shortestCycle(start string, group []string, waits map[string][]string) []string {
+	prev := make(map[string]string, len(group))
+	frontier := []string{start}
+	for len(frontier) > 0 {
+		var next []string
+		for _, id := range frontier {
+			for _, other := range waits[id] {
+				if other == start {
+					var path []string
+					for at := id; at != start; at = prev[at] {
+						path = append(path, at)
+					}
+					slices.Reverse(path)
+					return append(append([]string{start}, path...), start)
+				}
+				if _, seen := prev[other]; !seen && slices.Contains(group, other) {
+					prev[other] = id
+					next = append(next, other)
+				}
+			}
+		}
+		frontier = next
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+)
+
+// fixedEdges serves dependency relationships from a fixed list.
+func fixedEdges(rels ...models.Relationship) DependencyEdges {
+	return func(ctx context.Context, ids []string) ([]models.Relationship, error) {
+		var touching []models.Relationship
+		for _, rel := range rels {
+			if slices.Contains(ids, rel.FromID) || slices.Contains(ids, rel.ToID) {
+				touching = append(touching, rel)
+			}
+		}
+		return touching, nil
+	}
+}
+
+func dependsOn(from, to string) models.Relationship {
+	return models.Relationship{FromID: from, ToID: to, Type: models.RelDependsOn}
+}
+
+func blocks(from, to string) models.Relationship {
+	return models.Relationship{FromID: from, ToID: to, Type: models.RelBlocks}
+}
+
+func TestDependencyCycle(t *testing.T) {
+	edges := fixedEdges(dependsOn("a", "b"), blocks("c", "b"), dependsOn("c", "d"), models.Relationship{FromID: "d", ToID: "a", Type: models.RelRelatesTo})
+
+	tests := []struct {
+		name string
+		rel  models.Relationship
+		want []string
+	}{
+		{"closes", dependsOn("c", "a"), []string{"c", "a", "b", "c"}},
+		{"closes by blocking", blocks("a", "c"), []string{"c", "a", "b", "c"}},
+		{"self", dependsOn("a", "a"), []string{"a", "a"}},
+		{"agrees", dependsOn("a", "c"), nil},
+		{"other type", models.Relationship{FromID: "c", ToID: "a", Type: models.RelRelatesTo}, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := DependencyCycle(context.Background(), tc.rel, edges)
+			if err != nil {
+				t.Fatalf("DependencyCycle: %v", err)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("DependencyCycle: got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFindCycles(t *testing.T) {
+	edges := fixedEdges(
+		// c -> d -> e -> c, with a shortcut d -> c
+		dependsOn("c", "d"), dependsOn("d", "e"), blocks("c", "e"), dependsOn("d", "c"),
+		// a waits on the cycle without being on it
+		dependsOn("a", "d"),
+		// x waits for itself, out of reach of a
+		dependsOn("x", "x"),
+		// b -> y -> b, reached only through a
+		dependsOn("a", "y"), dependsOn("y", "b"), blocks("y", "b"),
+	)
+
+	got, err := FindCycles(context.Background(), []string{"a"}, edges)
+	if err != nil {
+		t.Fatalf("FindCycles: %v", err)
+	}
+	want := [][]string{{"b", "y", "b"}, {"c", "d", "c"}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("FindCycles: got %v, want %v", got, want)
+	}
+
+	got, err = FindCycles(context.Background(), []string{"e"}, fixedEdges(dependsOn("e", "f")))
+	if err != nil || len(got) != 0 {
+		t.Errorf("FindCycles without cycles: got %v, %v", got, err)
+	}
+}
@@ -0,0 +1,32 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+)
+
+// ReplacementSet checks the relationships given to ReplaceRelationships for
+// the node id with the given label, and returns them from that node, without
+// duplicates. The properties of a relationship given twice are merged.
+func ReplacementSet(id, label string, relationships []models.Relationship) ([]models.Relationship, error) {
+	var set []models.Relationship
+	index := make(map[string]int, len(relationships))
+	for _, rel := range relationships {
+		if rel.ToID == "" {
+			return nil, fmt.Errorf("relationship to_id is required")
+		}
+		if label == "Task" && rel.Type == models.RelPartOf {
+			return nil, fmt.Errorf("PART_OF relationships of tasks are set with plan_ids")
+		}
+		key := models.EdgeID(id, rel.ToID, rel.Type)
+		if i, ok := index[key]; ok {
+			set[i].RelationshipProperties = set[i].Merge(rel.RelationshipProperties)
+			continue
+		}
+		rel.FromID = id
+		index[key] = len(set)
+		set = append(set, rel)
+	}
+	return set, nil
+}
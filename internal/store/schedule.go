@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+)
+
+// Schedule orders a plan's tasks, given in position order as GetWithTasks
+// returns them, so that each comes after the tasks it waits for, keeping the
+// position order where the relationships allow it. It fails with a
+// *models.CycleError when the tasks wait for each other in a cycle.
+func Schedule(ctx context.Context, tasks []models.TaskInPlan) (*models.Schedule, error) {
+	index := make(map[string]int, len(tasks))
+	for i, t := range tasks {
+		index[t.Task.ID] = i
+	}
+	waits := make([][]int, len(tasks))
+	addWait := func(waiter, waitsFor string) {
+		i, ok := index[waiter]
+		j, isTask := index[waitsFor]
+		if ok && isTask && !slices.Contains(waits[i], j) {
+			waits[i] = append(waits[i], j)
+		}
+	}
+	for _, t := range tasks {
+		for _, id := range t.DependsOn {
+			addWait(t.Task.ID, id)
+		}
+		for _, id := range t.Blocks {
+			addWait(id, t.Task.ID)
+		}
+	}
+
+	// Repeatedly take the first task in position order whose waits are all
+	// scheduled. Plans are small enough for this not to need a heap.
+	scheduled := make([]*models.ScheduledTask, len(tasks))
+	schedule := &models.Schedule{}
+	for len(schedule.Tasks) < len(tasks) {
+		next := -1
+		for i := range tasks {
+			if scheduled[i] == nil && allScheduled(waits[i], scheduled) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, scheduleCycle(ctx, tasks, waits, scheduled)
+		}
+
+		estimate, err := tasks[next].Task.Estimate()
+		if err != nil {
+			return nil, err
+		}
+		st := models.ScheduledTask{TaskInPlan: tasks[next], Estimate: estimate}
+		for _, j := range waits[next] {
+			st.Layer = max(st.Layer, scheduled[j].Layer+1)
+			st.Start = max(st.Start, scheduled[j].Finish)
+		}
+		st.Finish = st.Start + estimate
+		schedule.Tasks = append(schedule.Tasks, st)
+		scheduled[next] = &schedule.Tasks[len(schedule.Tasks)-1]
+	}
+
+	for _, st := range schedule.Tasks {
+		for len(schedule.Layers) <= st.Layer {
+			schedule.Layers = append(schedule.Layers, nil)
+		}
+		schedule.Layers[st.Layer] = append(schedule.Layers[st.Layer], st.Task.ID)
+	}
+	schedule.CriticalPath, schedule.Duration = criticalPath(waits, scheduled)
+	return schedule, nil
+}
+
+// allScheduled reports whether all the given tasks are scheduled.
+func allScheduled(ids []int, scheduled []*models.ScheduledTask) bool {
+	for _, j := range ids {
+		if scheduled[j] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// criticalPath returns the chain of waiting tasks that finishes last, first
+// to last, and when it finishes. Ties go to the task first in position order.
+func criticalPath(waits [][]int, scheduled []*models.ScheduledTask) ([]string, float64) {
+	end := -1
+	for i, st := range scheduled {
+		if end < 0 || st.Finish > scheduled[end].Finish {
+			end = i
+		}
+	}
+	if end < 0 {
+		return nil, 0
+	}
+
+	var path []string
+	for at := end; at >= 0; {
+		path = append(path, scheduled[at].Task.ID)
+		prev := -1
+		for _, j := range waits[at] {
+			if scheduled[j].Finish == scheduled[at].Start && (prev < 0 || j < prev) {
+				prev = j
+			}
+		}
+		at = prev
+	}
+	slices.Reverse(path)
+	return path, scheduled[end].Finish
+}
+
+// scheduleCycle returns a *models.CycleError for a cycle among the tasks that
+// could not be scheduled.
+func scheduleCycle(ctx context.Context, tasks []models.TaskInPlan, waits [][]int, scheduled []*models.ScheduledTask) error {
+	var ids []string
+	var rels []models.Relationship
+	for i, t := range tasks {
+		if scheduled[i] != nil {
+			continue
+		}
+		ids = append(ids, t.Task.ID)
+		for _, j := range waits[i] {
+			rels = append(rels, models.Relationship{FromID: t.Task.ID, ToID: tasks[j].Task.ID, Type: models.RelDependsOn})
+		}
+	}
+	edges := func(ctx context.Context, ids []string) ([]models.Relationship, error) {
+		return rels, nil
+	}
+	cycles, err := FindCycles(ctx, ids, edges)
+	if err != nil {
+		return err
+	}
+	return &models.CycleError{Path: cycles[0]}
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+)
+
+// planTask returns a task as GetWithTasks would, with an estimate unless it
+// is empty.
+func planTask(id, estimate string, dependsOn, blocks []string) models.TaskInPlan {
+	t := models.TaskInPlan{Task: models.Task{ID: id}, DependsOn: dependsOn, Blocks: blocks}
+	if estimate != "" {
+		t.Task.Metadata = map[string]string{models.EstimateKey: estimate}
+	}
+	return t
+}
+
+func TestSchedule(t *testing.T) {
+	// Positions put deploy first, against its dependencies. design blocks
+	// build; docs only needs design, but takes long.
+	tasks := []models.TaskInPlan{
+		planTask("deploy", "1", []string{"build", "test"}, nil),
+		planTask("design", "2", nil, []string{"build"}),
+		planTask("build", "3", nil, nil),
+		planTask("docs", "5", []string{"design", "elsewhere"}, nil),
+		planTask("test", "", []string{"build"}, nil),
+	}
+
+	schedule, err := Schedule(context.Background(), tasks)
+	if err != nil {
+		t.Fatalf("Schedule: %v", err)
+	}
+	var order []string
+	for _, st := range schedule.Tasks {
+		order = append(order, st.Task.ID)
+	}
+	if want := []string{"design", "build", "docs", "test", "deploy"}; !slices.Equal(order, want) {
+		t.Errorf("order: got %v, want %v", order, want)
+	}
+	wantLayers := [][]string{{"design"}, {"build", "docs"}, {"test"}, {"deploy"}}
+	if !slices.EqualFunc(schedule.Layers, wantLayers, slices.Equal) {
+		t.Errorf("layers: got %v, want %v", schedule.Layers, wantLayers)
+	}
+	if want := []string{"design", "build", "test", "deploy"}; !slices.Equal(schedule.CriticalPath, want) {
+		t.Errorf("critical path: got %v, want %v", schedule.CriticalPath, want)
+	}
+	if schedule.Duration != 7 {
+		t.Errorf("duration: got %v, want 7", schedule.Duration)
+	}
+	if test := schedule.Tasks[3]; test.Estimate != models.DefaultEstimate || test.Start != 5 || test.Finish != 6 {
+		t.Errorf("test: got %+v", test)
+	}
+
+	// A longer side task takes over the critical path
+	tasks[3] = planTask("docs", "8", []string{"design"}, nil)
+	schedule, err = Schedule(context.Background(), tasks)
+	if err != nil {
+		t.Fatalf("Schedule: %v", err)
+	}
+	if want := []string{"design", "docs"}; !slices.Equal(schedule.CriticalPath, want) || schedule.Duration != 10 {
+		t.Errorf("critical path: got %v taking %v, want %v", schedule.CriticalPath, schedule.Duration, want)
+	}
+}
+
+func TestScheduleFailures(t *testing.T) {
+	_, err := Schedule(context.Background(), []models.TaskInPlan{
+		planTask("a", "", []string{"b"}, nil),
+		planTask("b", "", nil, nil),
+		planTask("c", "", []string{"a"}, []string{"b"}),
+		planTask("d", "", []string{"a"}, nil),
+	})
+	var cycleErr *models.CycleError
+	if !errors.As(err, &cycleErr) || !slices.Equal(cycleErr.Path, []string{"a", "b", "c", "a"}) {
+		t.Errorf("Schedule with a cycle: got %v", err)
+	}
+
+	if _, err := Schedule(context.Background(), []models.TaskInPlan{planTask("a", "soon", nil, nil)}); err == nil {
+		t.Error("Schedule with an invalid estimate should fail")
+	}
+
+	schedule, err := Schedule(context.Background(), nil)
+	if err != nil || len(schedule.Tasks) != 0 || len(schedule.CriticalPath) != 0 {
+		t.Errorf("Schedule of no tasks: got %+v, %v", schedule, err)
+	}
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+)
+
+// StatusRuleTargets returns the IDs of the tasks whose status the status
+// rules check after an update of task id: those waiting for it when the
+// update sets its status, and those that its new relationships make wait.
+// The task itself is left out when the update sets its status, so that the
+// rules never override a status set on purpose.
+func StatusRuleTargets(ctx context.Context, id string, setsStatus bool, newRelationships []models.Relationship, edges DependencyEdges) ([]string, error) {
+	var targets []string
+	add := func(taskID string) {
+		if !(setsStatus && taskID == id) && !slices.Contains(targets, taskID) {
+			targets = append(targets, taskID)
+		}
+	}
+
+	if setsStatus {
+		rels, err := edges(ctx, []string{id})
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range rels {
+			if waiter, waitsFor, ok := models.Dependency(rel); ok && waitsFor == id {
+				add(waiter)
+			}
+		}
+	}
+	for _, rel := range newRelationships {
+		rel.FromID = id
+		if waiter, _, ok := models.Dependency(rel); ok {
+			add(waiter)
+		}
+	}
+	return targets, nil
+}
+
+// DeletionRuleTargets returns the IDs of the tasks whose status the status
+// rules check after the given tasks are deleted: those waiting for any of
+// them, the deleted tasks themselves left out. Call it before deleting, while
+// edges still finds their relationships.
+func DeletionRuleTargets(ctx context.Context, ids []string, edges DependencyEdges) ([]string, error) {
+	rels, err := edges(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	var targets []string
+	for _, rel := range rels {
+		waiter, waitsFor, ok := models.Dependency(rel)
+		if ok && slices.Contains(ids, waitsFor) && !slices.Contains(ids, waiter) && !slices.Contains(targets, waiter) {
+			targets = append(targets, waiter)
+		}
+	}
+	return targets, nil
+}
+
+// RuledStatus returns the status change the status rules make to a task that
+// waits for the given tasks: from pending to blocked while any of them is
+// unfinished, and from blocked back to pending once all are completed or
+// cancelled. The change only applies to a task with the from status. ok is
+// false for a task that waits for none, which the rules leave alone.
+func RuledStatus(waits []models.Wait) (from, to models.TaskStatus, ok bool) {
+	switch {
+	case len(waits) == 0:
+		return "", "", false
+	case len(models.Unfinished(waits)) > 0:
+		return models.TaskStatusPending, models.TaskStatusBlocked, true
+	default:
+		return models.TaskStatusBlocked, models.TaskStatusPending, true
+	}
+}
+
+// RuledStatusAfterDeletion is RuledStatus for a task that waited for a
+// deleted task: one left waiting for none also moves from blocked back to
+// pending.
+func RuledStatusAfterDeletion(waits []models.Wait) (from, to models.TaskStatus, ok bool) {
+	if len(waits) == 0 {
+		return models.TaskStatusBlocked, models.TaskStatusPending, true
+	}
+	return RuledStatus(waits)
+}
+
+// PlanCompletion returns the status change plan completion makes to a plan
+// with the given progress: from active to completed once all its tasks are
+// completed or cancelled, and from completed back to active while any is not.
+// The change only applies to a plan with the from status. ok is false for a
+// plan without tasks, which is left alone.
+func PlanCompletion(progress models.PlanProgress) (from, to models.PlanStatus, ok bool) {
+	switch {
+	case progress.Total == 0:
+		return "", "", false
+	case progress.Finished():
+		return models.PlanStatusActive, models.PlanStatusCompleted, true
+	default:
+		return models.PlanStatusCompleted, models.PlanStatusActive, true
+	}
+}
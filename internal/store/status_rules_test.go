@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+)
+
+func TestStatusRuleTargets(t *testing.T) {
+	edges := fixedEdges(dependsOn("b", "a"), blocks("a", "c"), dependsOn("a", "d"), blocks("e", "a"))
+	newRels := []models.Relationship{
+		{ToID: "f", Type: models.RelBlocks},
+		{ToID: "g", Type: models.RelDependsOn},
+		{ToID: "h", Type: models.RelRelatesTo},
+	}
+
+	tests := []struct {
+		name       string
+		setsStatus bool
+		rels       []models.Relationship
+		want       []string
+	}{
+		{"status", true, nil, []string{"b", "c"}},
+		{"relationships", false, newRels, []string{"f", "a"}},
+		{"both", true, newRels, []string{"b", "c", "f"}},
+		{"neither", false, nil, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := StatusRuleTargets(context.Background(), "a", tc.setsStatus, tc.rels, edges)
+			if err != nil {
+				t.Fatalf("StatusRuleTargets: %v", err)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("StatusRuleTargets: got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRuledStatus(t *testing.T) {
+	wait := func(status models.TaskStatus) models.Wait {
+		return models.Wait{Task: models.Task{Status: status}, Type: models.RelDependsOn}
+	}
+
+	tests := []struct {
+		name     string
+		waits    []models.Wait
+		from, to models.TaskStatus
+		ok       bool
+	}{
+		{"no waits", nil, "", "", false},
+		{"unfinished", []models.Wait{wait(models.TaskStatusCompleted), wait(models.TaskStatusPending)}, models.TaskStatusPending, models.TaskStatusBlocked, true},
+		{"all finished", []models.Wait{wait(models.TaskStatusCompleted), wait(models.TaskStatusCancelled)}, models.TaskStatusBlocked, models.TaskStatusPending, true},
+	}
+	for _, tc := range tests {
+		from, to, ok := RuledStatus(tc.waits)
+		if from != tc.from || to != tc.to || ok != tc.ok {
+			t.Errorf("%s: got %q -> %q, %v, want %q -> %q, %v", tc.name, from, to, ok, tc.from, tc.to, tc.ok)
+		}
+	}
+
+	if from, to, ok := RuledStatusAfterDeletion(nil); from != models.TaskStatusBlocked || to != models.TaskStatusPending || !ok {
+		t.Errorf("RuledStatusAfterDeletion with no waits: got %q -> %q, %v", from, to, ok)
+	}
+}
+
+func TestDeletionRuleTargets(t *testing.T) {
+	edges := fixedEdges(dependsOn("b", "a"), blocks("a", "c"), dependsOn("a", "d"), dependsOn("e", "a"), blocks("e", "c"))
+
+	got, err := DeletionRuleTargets(context.Background(), []string{"a", "e"}, edges)
+	if err != nil {
+		t.Fatalf("DeletionRuleTargets: %v", err)
+	}
+	if want := []string{"b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("DeletionRuleTargets: got %v, want %v", got, want)
+	}
+}
+
+func TestPlanCompletion(t *testing.T) {
+	progress := func(statuses ...models.TaskStatus) models.PlanProgress {
+		p := models.NewPlanProgress(time.Time{})
+		for _, status := range statuses {
+			p.Add(status, 1, time.Time{})
+		}
+		return p
+	}
+
+	tests := []struct {
+		name     string
+		progress models.PlanProgress
+		from, to models.PlanStatus
+		ok       bool
+	}{
+		{"no tasks", progress(), "", "", false},
+		{"finished", progress(models.TaskStatusCompleted, models.TaskStatusCancelled), models.PlanStatusActive, models.PlanStatusCompleted, true},
+		{"unfinished", progress(models.TaskStatusCompleted, models.TaskStatusPending), models.PlanStatusCompleted, models.PlanStatusActive, true},
+	}
+	for _, tc := range tests {
+		from, to, ok := PlanCompletion(tc.progress)
+		if from != tc.from || to != tc.to || ok != tc.ok {
+			t.Errorf("%s: got %q -> %q, %v, want %q -> %q, %v", tc.name, from, to, ok, tc.from, tc.to, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Package store defines the storage interfaces used by the MCP tool handlers.
+// The PostgreSQL/AGE repositories in internal/graph implement these interfaces,
+// as does the in-process backend in internal/memstore.
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+)
+
+// MemoryStore provides CRUD and traversal operations for memories.
+type MemoryStore interface {
+	// Search ranks memories against the query, best match first, and returns
+	// the page of results that opts.Cursor starts.
+	Search(ctx context.Context, query string, opts models.SearchOptions) ([]models.SearchResult, models.Page, error)
+	// SearchAll ranks memories, plans and tasks against the query, best match first.
+	SearchAll(ctx context.Context, query string, opts models.NodeSearchOptions) ([]models.NodeSearchResult, error)
+	// Add creates a new memory and optional relationships.
+	Add(ctx context.Context, mem models.Memory, relationships []models.Relationship) (*models.Memory, error)
+	// Update modifies an existing memory and increments its version. Nil
+	// arguments leave the field unchanged. A non-zero expectedVersion must be
+	// the current version, or Update fails with a *models.VersionConflictError.
+	Update(ctx context.Context, id string, expectedVersion int64, content *string, metadata map[string]string, tags []string, newRelationships []models.Relationship) (*models.Memory, error)
+	// GetByID retrieves a memory by ID. It returns nil, nil when not found.
+	GetByID(ctx context.Context, id string) (*models.Memory, error)
+	// GetByIDWithRelated retrieves a memory along with its direct memory relationships.
+	GetByIDWithRelated(ctx context.Context, id string) (*models.Memory, []models.RelatedInfo, error)
+	// Delete moves a memory and all its relationships to the trash.
+	Delete(ctx context.Context, id string) error
+	// PreviewDelete returns what Delete would move to the trash, without
+	// changing anything. It returns nil, nil when there is no such memory.
+	PreviewDelete(ctx context.Context, id string) (*models.TrashEntry, error)
+	// GetRelated retrieves nodes of any type related to the given ID.
+	GetRelated(ctx context.Context, id string, relationType string, direction string, depth int) ([]models.RelatedMemoryResult, error)
+	// ListRelationships lists the relationships of a node of any type, oldest
+	// first, optionally restricted to one type and to a direction (incoming,
+	// outgoing or both).
+	ListRelationships(ctx context.Context, id string, relationType string, direction string) ([]models.Edge, error)
+	// DeleteRelationship removes the relationship of the given type from one
+	// node to another. It is an error if there is none.
+	DeleteRelationship(ctx context.Context, fromID, toID string, relType models.RelationType) error
+	// ReplaceRelationships atomically replaces the outgoing relationships of a
+	// node of any type with the given ones, and returns the node's outgoing
+	// relationships. Relationships already present are kept as they are. A task's PART_OF relationships,
+	// which place it in plans, are left alone and cannot be given.
+	ReplaceRelationships(ctx context.Context, id string, relationships []models.Relationship) ([]models.Edge, error)
+	// ExistingNodes reports which of the IDs name a node of any type.
+	ExistingNodes(ctx context.Context, ids []string) (map[string]bool, error)
+	// ListRevisions lists the revisions of a node of any type, newest first.
+	// Each Add and Update records one, attributed to the Actor of its context.
+	ListRevisions(ctx context.Context, id string) ([]models.Revision, error)
+	// GetRevision retrieves the revision of a node at the given version. It
+	// returns nil, nil when there is none.
+	GetRevision(ctx context.Context, id string, version int64) (*models.Revision, error)
+	// ListTrash lists what deletes have moved to the trash, most recently
+	// deleted first.
+	ListTrash(ctx context.Context) ([]models.TrashEntry, error)
+	// Restore brings back the deleted node with the given ID, along with the
+	// nodes deleted with it, their revisions and their relationships, task
+	// positions included. Relationships to nodes still in the trash come back
+	// when those nodes are restored. It fails if the ID is not in the trash or
+	// if a node with one of the IDs being restored exists.
+	Restore(ctx context.Context, id string) (*models.TrashEntry, error)
+	// PurgeTrash permanently removes the trash entries deleted before the
+	// given time and returns how many it removed.
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
+}
+
+// PlanStore provides CRUD operations for plans.
+type PlanStore interface {
+	// Add creates a new plan and optional relationships.
+	Add(ctx context.Context, plan models.Plan, relationships []models.Relationship) (*models.Plan, error)
+	// GetByID retrieves a plan by ID. It returns nil, nil when not found.
+	GetByID(ctx context.Context, id string) (*models.Plan, error)
+	// GetWithTasks retrieves a plan along with its tasks ordered by position.
+	GetWithTasks(ctx context.Context, id string) (*models.Plan, []models.TaskInPlan, error)
+	// Update modifies an existing plan and increments its version. Nil
+	// arguments leave the field unchanged. A non-zero expectedVersion must be
+	// the current version, or Update fails with a *models.VersionConflictError.
+	Update(ctx context.Context, id string, expectedVersion int64, name *string, description *string, status *string, metadata map[string]string, tags []string, newRelationships []models.Relationship) (*models.Plan, error)
+	// Delete moves a plan and the tasks that belong to no other plan to the
+	// trash, along with their relationships. Options can instead keep those
+	// tasks, detached from any plan or appended in order to another plan. With
+	// status rules on, it then applies RuledStatusAfterDeletion to the tasks
+	// that waited for the deleted ones, and with plan completion on and tasks
+	// moved, PlanCompletion to the target plan. It returns the number of tasks
+	// deleted and the changes both made.
+	Delete(ctx context.Context, id string, opts models.PlanDeleteOptions) (int, models.StatusChanges, error)
+	// PreviewDelete returns what Delete would move to the trash with the same
+	// options, without changing anything. It returns nil, nil when there is no
+	// such plan.
+	PreviewDelete(ctx context.Context, id string, opts models.PlanDeleteOptions) (*models.TrashEntry, error)
+	// List retrieves a page of plans ordered by most recently updated, then by
+	// ID. An empty cursor starts at the first page.
+	List(ctx context.Context, status string, tags []string, limit int, cursor string) ([]models.Plan, models.Page, error)
+	// DependencyCycles returns the cycles of DEPENDS_ON and BLOCKS
+	// relationships that the plan's tasks are on or wait on, as FindCycles
+	// does. Creating relationships fails with a *models.CycleError rather than
+	// close a cycle, so these only come from data written before that check.
+	DependencyCycles(ctx context.Context, id string) ([][]string, error)
+	// Waits returns the tasks that each of the plan's tasks waits for through
+	// DEPENDS_ON and BLOCKS relationships, whichever plans they belong to,
+	// keyed by the waiting task's ID. Tasks that wait for none are left out.
+	// It fails when there is no such plan.
+	Waits(ctx context.Context, id string) (map[string][]models.Wait, error)
+	// Progress returns the progress of each of the given plans, keyed by plan
+	// ID, counted without fetching their tasks. Plans that do not exist are
+	// left out.
+	Progress(ctx context.Context, ids []string) (map[string]models.PlanProgress, error)
+}
+
+// TaskStore provides CRUD and ordering operations for tasks.
+type TaskStore interface {
+	// Add creates a new task linked to one or more plans. With status rules
+	// on, Add also applies them, in the same transaction, to the tasks
+	// StatusRuleTargets names for its relationships, the new task included.
+	// With plan completion on, it then applies PlanCompletion to the task's
+	// plans. It returns the changes both made.
+	Add(ctx context.Context, task models.Task, planIDs []string, relationships []models.Relationship, afterTaskID, beforeTaskID *string) (*models.Task, models.StatusChanges, error)
+	// GetByID retrieves a task by ID. It returns nil, nil when not found.
+	GetByID(ctx context.Context, id string) (*models.Task, error)
+	// GetWithPlans retrieves a task along with the plans it belongs to.
+	GetWithPlans(ctx context.Context, id string) (*models.Task, []models.Plan, error)
+	// Update modifies an existing task and increments its version. Nil
+	// arguments leave the field unchanged. A non-zero expectedVersion must be
+	// the current version, or Update fails with a *models.VersionConflictError.
+	// With status rules on, Update also applies them, in the same
+	// transaction, to the tasks StatusRuleTargets names. With plan completion
+	// on and the status or plans of the task changed, it then applies
+	// PlanCompletion to the plans the task belongs to. It returns the changes
+	// both made.
+	Update(ctx context.Context, id string, expectedVersion int64, content *string, status *string, metadata map[string]string, tags []string, addPlanIDs []string, newRelationships []models.Relationship) (*models.Task, models.StatusChanges, error)
+	// SetStatusRules turns the status rules on or off; they are off until
+	// turned on. The rules, as RuledStatus gives them, move pending tasks to
+	// blocked while they wait for unfinished tasks, and blocked tasks back to
+	// pending once those are completed, cancelled or deleted. They apply to
+	// the plan store of the same backend too. Call it before the store is
+	// used.
+	SetStatusRules(enabled bool)
+	// SetPlanCompletion turns plan completion on or off; it is off until
+	// turned on. As PlanCompletion gives it, an active plan moves to completed
+	// once all its tasks are completed or cancelled, and back to active when
+	// one is reopened or an unfinished one joins it. It applies to the plan store of the same
+	// backend too. Call it before the store is used.
+	SetPlanCompletion(enabled bool)
+	// Delete moves a task and all its relationships to the trash. With status
+	// rules on, it then applies RuledStatusAfterDeletion to the tasks that
+	// waited for it, and with plan completion on, PlanCompletion to the plans
+	// it belonged to. It returns the changes both made.
+	Delete(ctx context.Context, id string) (models.StatusChanges, error)
+	// PreviewDelete returns what Delete would move to the trash, without
+	// changing anything. It returns nil, nil when there is no such task.
+	PreviewDelete(ctx context.Context, id string) (*models.TrashEntry, error)
+	// UpdatePositions batch updates task positions within a plan.
+	UpdatePositions(ctx context.Context, planID string, taskPositions map[string]float64) error
+	// NormalizePositions rewrites the positions of the given tasks within a
+	// plan, evenly spaced in the given order, and returns the new positions
+	// in that order.
+	NormalizePositions(ctx context.Context, planID string, taskIDs []string) ([]float64, error)
+	// List retrieves a page of tasks, ordered by position when filtered by
+	// plan and by most recently updated otherwise, then by ID. An empty
+	// cursor starts at the first page.
+	List(ctx context.Context, planID string, status string, tags []string, limit int, cursor string) ([]models.TaskListResult, models.Page, error)
+}
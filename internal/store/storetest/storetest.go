@@ -0,0 +1,2225 @@
+// Package storetest provides a behavioural test suite shared by every
+// implementation of the interfaces in internal/store.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+	"github.com/Thomas-Fitz/associate/internal/store"
+)
+
+// Stores groups the three stores of a single backend.
+// All three must operate on the same underlying graph.
+type Stores struct {
+	Memories store.MemoryStore
+	Plans    store.PlanStore
+	Tasks    store.TaskStore
+}
+
+// Factory returns the stores under test. It is called once per top-level test,
+// so backends may return a fresh, empty graph each time.
+type Factory func(t *testing.T) Stores
+
+// Run executes the full behavioural suite against the stores returned by newStores.
+// Every ID the suite creates is unique to the run and is deleted on cleanup,
+// so it is safe to run against a shared database.
+func Run(t *testing.T, newStores Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s *suite)
+	}{
+		{"MemoryCRUD", testMemoryCRUD},
+		{"MemorySearch", testMemorySearch},
+		{"MemorySearchRanking", testMemorySearchRanking},
+		{"MemorySemanticSearch", testMemorySemanticSearch},
+		{"MemoryHybridSearch", testMemoryHybridSearch},
+		{"MemorySearchFilters", testMemorySearchFilters},
+		{"SearchAll", testSearchAll},
+		{"MemoryRelationships", testMemoryRelationships},
+		{"GetRelated", testGetRelated},
+		{"RelationshipEdits", testRelationshipEdits},
+		{"RelationshipProperties", testRelationshipProperties},
+		{"RelationshipFailures", testRelationshipFailures},
+		{"PlanCRUD", testPlanCRUD},
+		{"PlanList", testPlanList},
+		{"TaskCRUD", testTaskCRUD},
+		{"TaskList", testTaskList},
+		{"TaskPositioning", testTaskPositioning},
+		{"Pagination", testPagination},
+		{"TaskDependencies", testTaskDependencies},
+		{"DependencyCycles", testDependencyCycles},
+		{"Waits", testWaits},
+		{"StatusRules", testStatusRules},
+		{"PlanProgress", testPlanProgress},
+		{"PlanCompletion", testPlanCompletion},
+		{"Versions", testVersions},
+		{"Revisions", testRevisions},
+		{"CascadeDelete", testCascadeDelete},
+		{"DeletePreview", testDeletePreview},
+		{"KeepOrphanTasks", testKeepOrphanTasks},
+		{"Trash", testTrash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &suite{
+				Stores: newStores(t),
+				ctx:    context.Background(),
+				prefix: fmt.Sprintf("storetest-%d-%d", time.Now().UnixNano(), runCounter.Add(1)),
+			}
+			t.Cleanup(s.cleanup)
+			tt.fn(t, s)
+		})
+	}
+}
+
+var runCounter atomic.Int64
+
+// suite carries per-test state: the stores, a unique ID prefix and the
+// nodes to remove when the test finishes.
+type suite struct {
+	Stores
+	ctx      context.Context
+	prefix   string
+	memories []string
+	plans    []string
+	tasks    []string
+}
+
+// id returns a unique node ID for this test.
+func (s *suite) id(name string) string {
+	return s.prefix + "-" + name
+}
+
+// word returns a lower-case word unique to this test, so that full-text
+// queries only match content the test created.
+func (s *suite) word() string {
+	letters := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return 'a' + (r - '0')
+		}
+		return -1
+	}, s.prefix)
+	return "zq" + letters
+}
+
+func (s *suite) cleanup() {
+	for _, id := range s.tasks {
+		_, _ = s.Tasks.Delete(s.ctx, id)
+	}
+	for _, id := range s.plans {
+		_, _, _ = s.Plans.Delete(s.ctx, id, models.PlanDeleteOptions{})
+	}
+	for _, id := range s.memories {
+		_ = s.Memories.Delete(s.ctx, id)
+	}
+}
+
+// addMemory creates a Note with a generated ID, so that searching for the
+// test prefix only matches on content.
+func (s *suite) addMemory(t *testing.T, name, content string, rels ...models.Relationship) *models.Memory {
+	t.Helper()
+	mem, err := s.Memories.Add(s.ctx, models.Memory{Type: models.TypeNote, Content: content}, rels)
+	if err != nil {
+		t.Fatalf("Add memory %s: %v", name, err)
+	}
+	s.memories = append(s.memories, mem.ID)
+	return mem
+}
+
+func (s *suite) addPlan(t *testing.T, name string, tags ...string) *models.Plan {
+	t.Helper()
+	plan, err := s.Plans.Add(s.ctx, models.Plan{ID: s.id(name), Name: name, Tags: tags}, nil)
+	if err != nil {
+		t.Fatalf("Add plan %s: %v", name, err)
+	}
+	s.plans = append(s.plans, plan.ID)
+	return plan
+}
+
+func (s *suite) addTask(t *testing.T, name string, planIDs []string, rels ...models.Relationship) *models.Task {
+	t.Helper()
+	task, _, err := s.Tasks.Add(s.ctx, models.Task{ID: s.id(name), Content: name}, planIDs, rels, nil, nil)
+	if err != nil {
+		t.Fatalf("Add task %s: %v", name, err)
+	}
+	s.tasks = append(s.tasks, task.ID)
+	return task
+}
+
+func testMemoryCRUD(t *testing.T, s *suite) {
+	created, err := s.Memories.Add(s.ctx, models.Memory{
+		Content:  "crud content",
+		Metadata: map[string]string{"k": "v"},
+		Tags:     []string{"a", "b"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	s.memories = append(s.memories, created.ID)
+
+	if created.ID == "" {
+		t.Error("Add should generate an ID")
+	}
+	if created.Type != models.TypeGeneral {
+		t.Errorf("Type: got %s, want %s", created.Type, models.TypeGeneral)
+	}
+	if created.CreatedAt.IsZero() || created.UpdatedAt.IsZero() {
+		t.Error("Add should set timestamps")
+	}
+
+	got, err := s.Memories.GetByID(s.ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got == nil {
+		t.Fatal("GetByID returned nil for existing memory")
+	}
+	if got.Content != "crud content" || got.Metadata["k"] != "v" || len(got.Tags) != 2 {
+		t.Errorf("GetByID returned %+v", got)
+	}
+
+	missing, err := s.Memories.GetByID(s.ctx, s.id("missing"))
+	if err != nil || missing != nil {
+		t.Errorf("GetByID missing: got %v, %v; want nil, nil", missing, err)
+	}
+
+	content := "updated content"
+	updated, err := s.Memories.Update(s.ctx, created.ID, 0, &content, nil, []string{"c"}, nil)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Content != content {
+		t.Errorf("Update content: got %q, want %q", updated.Content, content)
+	}
+	if len(updated.Tags) != 1 || updated.Tags[0] != "c" {
+		t.Errorf("Update tags: got %v, want [c]", updated.Tags)
+	}
+	if updated.Metadata["k"] != "v" {
+		t.Errorf("Update with nil metadata should keep existing, got %v", updated.Metadata)
+	}
+
+	if _, err := s.Memories.Update(s.ctx, s.id("missing"), 0, &content, nil, nil, nil); err == nil {
+		t.Error("Update of missing memory should fail")
+	}
+
+	if err := s.Memories.Delete(s.ctx, created.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	gone, err := s.Memories.GetByID(s.ctx, created.ID)
+	if err != nil || gone != nil {
+		t.Errorf("GetByID after Delete: got %v, %v; want nil, nil", gone, err)
+	}
+}
+
+func testMemorySearch(t *testing.T, s *suite) {
+	token := strings.ToUpper(s.prefix)
+	first := s.addMemory(t, "first", "alpha "+token+" first")
+	second := s.addMemory(t, "second", "beta "+token+" second", models.Relationship{ToID: first.ID, Type: models.RelRelatesTo})
+	s.addMemory(t, "other", "unrelated content")
+
+	results, _, err := s.Memories.Search(s.ctx, strings.ToLower(token), models.SearchOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Search: got %d results, want 2", len(results))
+	}
+
+	byID := map[string]models.SearchResult{}
+	for _, r := range results {
+		byID[r.Memory.ID] = r
+	}
+	if got := byID[first.ID].Related; len(got) != 1 || got[0] != second.ID {
+		t.Errorf("first.Related: got %v, want [%s]", got, second.ID)
+	}
+	if got := byID[second.ID].Related; len(got) != 1 || got[0] != first.ID {
+		t.Errorf("second.Related: got %v, want [%s]", got, first.ID)
+	}
+
+	limited, _, err := s.Memories.Search(s.ctx, token, models.SearchOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("Search with limit: %v", err)
+	}
+	if len(limited) != 1 {
+		t.Errorf("Search with limit 1: got %d results", len(limited))
+	}
+}
+
+func testMemorySearchRanking(t *testing.T, s *suite) {
+	word := s.word()
+	strong := s.addMemory(t, "strong", word+" deploy notes: the "+word+" pipeline deploys "+word+" twice")
+	weak := s.addMemory(t, "weak", "A longer note about several unrelated things, which mentions "+word+" once near the end of the deploy checklist")
+
+	results, _, err := s.Memories.Search(s.ctx, word, models.SearchOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 2 || results[0].Memory.ID != strong.ID || results[1].Memory.ID != weak.ID {
+		t.Fatalf("Search: want [strong weak], got %v", searchIDs(results))
+	}
+	if !(results[0].Score > results[1].Score && results[1].Score > 0 && results[0].Score < 1) {
+		t.Errorf("scores should be in (0, 1) and descending: %v, %v", results[0].Score, results[1].Score)
+	}
+	if !strings.Contains(results[0].Snippet, "**"+word+"**") {
+		t.Errorf("snippet should highlight the match: %q", results[0].Snippet)
+	}
+
+	// Word order and inflections do not matter
+	for _, q := range []string{"deploy " + word, word + " deploying"} {
+		found, _, err := s.Memories.Search(s.ctx, q, models.SearchOptions{Limit: 10})
+		if err != nil {
+			t.Fatalf("Search %q: %v", q, err)
+		}
+		if len(found) != 2 {
+			t.Errorf("Search %q: got %v, want both memories", q, searchIDs(found))
+		}
+	}
+
+	// A single typo still finds the memories, ranked below exact matches
+	typo := word[:5] + word[6:]
+	found, _, err := s.Memories.Search(s.ctx, typo, models.SearchOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("Search with typo: %v", err)
+	}
+	if len(found) == 0 || found[0].Memory.ID != strong.ID {
+		t.Errorf("Search with typo: got %v, want strong first", searchIDs(found))
+	} else if found[0].Score >= results[1].Score {
+		t.Errorf("fuzzy score %v should be below exact score %v", found[0].Score, results[1].Score)
+	}
+
+	// Quoted phrases must match in order, and -word excludes
+	found, _, err = s.Memories.Search(s.ctx, `"`+word+` pipeline"`, models.SearchOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("Search phrase: %v", err)
+	}
+	if len(found) != 1 || found[0].Memory.ID != strong.ID {
+		t.Errorf("Search phrase: got %v, want [strong]", searchIDs(found))
+	}
+	found, _, err = s.Memories.Search(s.ctx, word+" -pipeline", models.SearchOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("Search with exclusion: %v", err)
+	}
+	if len(found) != 1 || found[0].Memory.ID != weak.ID {
+		t.Errorf("Search with exclusion: got %v, want [weak]", searchIDs(found))
+	}
+}
+
+func testMemorySemanticSearch(t *testing.T, s *suite) {
+	word := s.word()
+	creds := s.addMemory(t, "creds", word+": the deploy pipeline rotates database credentials every night")
+	colours := s.addMemory(t, "colours", word+": frontend button colours follow the brand palette")
+
+	semantic := models.SearchOptions{Mode: models.SearchModeSemantic, Limit: 50}
+	position := func(results []models.SearchResult, id string) int {
+		for i, r := range results {
+			if r.Memory.ID == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	results, _, err := s.Memories.Search(s.ctx, "how are "+word+" db credentials rotated by deployment?", semantic)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	c, o := position(results, creds.ID), position(results, colours.ID)
+	if c < 0 || (o >= 0 && o < c) {
+		t.Fatalf("semantic Search: want creds ranked above colours, got %v", searchIDs(results))
+	}
+	if score := results[c].Score; score <= 0 || score > 1 {
+		t.Errorf("semantic score %v should be in (0, 1]", score)
+	}
+
+	// Updated content is re-embedded
+	query := word + " database credentials rotation"
+	scoreOf := func(id string) float64 {
+		results, _, err := s.Memories.Search(s.ctx, query, semantic)
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		if i := position(results, id); i >= 0 {
+			return results[i].Score
+		}
+		return 0
+	}
+	before := scoreOf(creds.ID)
+	newContent := word + ": palette and button colour tokens for the frontend"
+	if _, err := s.Memories.Update(s.ctx, creds.ID, 0, &newContent, nil, nil, nil); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if after := scoreOf(creds.ID); after >= before {
+		t.Errorf("score after changing the content away from the query: got %v, want below %v", after, before)
+	}
+
+	if _, _, err := s.Memories.Search(s.ctx, word, models.SearchOptions{Mode: "fuzzy"}); err == nil {
+		t.Error("Search with an unknown mode should fail")
+	}
+}
+
+func testMemoryHybridSearch(t *testing.T, s *suite) {
+	word := s.word()
+	plan := s.addPlan(t, "plan")
+	strong := s.addMemory(t, "strong", word+" deploy notes: the "+word+" pipeline deploys "+word+" twice")
+	near := s.addMemory(t, "near", "A longer note about several unrelated things, which mentions "+word+" once near the end of the deploy checklist")
+	viaPlan := s.addMemory(t, "via-plan", "Another long note on assorted topics that happens to mention "+word+" a single time somewhere",
+		models.Relationship{ToID: plan.ID, Type: models.RelReferences})
+	task := s.addTask(t, "task", []string{plan.ID}, models.Relationship{ToID: near.ID, Type: models.RelReferences})
+
+	scores := func(opts models.SearchOptions) ([]models.SearchResult, map[string]float64) {
+		t.Helper()
+		results, _, err := s.Memories.Search(s.ctx, word, opts)
+		if err != nil {
+			t.Fatalf("Search %+v: %v", opts, err)
+		}
+		byID := make(map[string]float64)
+		for _, r := range results {
+			if r.Score <= 0 || r.Score > 1 {
+				t.Errorf("hybrid score %v should be in (0, 1]", r.Score)
+			}
+			byID[r.Memory.ID] = r.Score
+		}
+		return results, byID
+	}
+
+	results, plain := scores(models.SearchOptions{Mode: models.SearchModeHybrid})
+	if len(results) != 3 {
+		t.Fatalf("hybrid Search: want 3 memories, got %v", searchIDs(results))
+	}
+
+	// Anchored on the task, its linked memory ranks first and a memory linked
+	// to the task's plan gains too
+	results, anchored := scores(models.SearchOptions{Mode: models.SearchModeHybrid, AnchorID: task.ID})
+	if len(results) != 3 || results[0].Memory.ID != near.ID {
+		t.Fatalf("anchored hybrid Search: want near first of 3, got %v", searchIDs(results))
+	}
+	if anchored[viaPlan.ID] <= plain[viaPlan.ID] {
+		t.Errorf("two-hop memory: anchored score %v should exceed %v", anchored[viaPlan.ID], plain[viaPlan.ID])
+	}
+	if math.Abs(anchored[strong.ID]-plain[strong.ID]) > 1e-6 {
+		t.Errorf("unconnected memory: anchored score %v should equal %v", anchored[strong.ID], plain[strong.ID])
+	}
+
+	if _, _, err := s.Memories.Search(s.ctx, word, models.SearchOptions{Mode: models.SearchModeHybrid, AnchorID: s.id("missing")}); err == nil {
+		t.Error("hybrid Search with an unknown anchor should fail")
+	}
+	if _, _, err := s.Memories.Search(s.ctx, word, models.SearchOptions{AnchorID: task.ID}); err == nil {
+		t.Error("text Search with an anchor should fail")
+	}
+}
+
+func testMemorySearchFilters(t *testing.T, s *suite) {
+	word := s.word()
+	start := time.Now().UTC().Add(-2 * time.Second)
+	add := func(name string, memType models.MemoryType, tags []string, repo string) *models.Memory {
+		t.Helper()
+		mem, err := s.Memories.Add(s.ctx, models.Memory{
+			Type:     memType,
+			Content:  word + " " + name + " notes",
+			Tags:     tags,
+			Metadata: map[string]string{"suite": s.prefix, "repo": repo},
+		}, nil)
+		if err != nil {
+			t.Fatalf("Add memory %s: %v", name, err)
+		}
+		s.memories = append(s.memories, mem.ID)
+		return mem
+	}
+	authRepo := add("auth repository", models.TypeRepository, []string{"auth", "backend"}, "api")
+	authNote := add("auth note", models.TypeNote, []string{"auth"}, "api")
+	webRepo := add("web repository", models.TypeRepository, []string{"frontend"}, "web")
+	suiteOnly := map[string]string{"suite": s.prefix}
+
+	tests := []struct {
+		name  string
+		query string
+		opts  models.SearchOptions
+		want  []*models.Memory
+	}{
+		{"type", word, models.SearchOptions{Filter: models.SearchFilter{Type: models.TypeRepository}}, []*models.Memory{authRepo, webRepo}},
+		{"any tag", word, models.SearchOptions{Filter: models.SearchFilter{Tags: []string{"auth", "frontend"}}}, []*models.Memory{authRepo, authNote, webRepo}},
+		{"all tags", word, models.SearchOptions{Filter: models.SearchFilter{Tags: []string{"auth", "backend"}, TagMatch: models.TagMatchAll}}, []*models.Memory{authRepo}},
+		{"metadata", word, models.SearchOptions{Filter: models.SearchFilter{Metadata: map[string]string{"repo": "api"}}}, []*models.Memory{authRepo, authNote}},
+		{"updated after", word, models.SearchOptions{Filter: models.SearchFilter{UpdatedAfter: start}}, []*models.Memory{authRepo, authNote, webRepo}},
+		{"updated before", word, models.SearchOptions{Filter: models.SearchFilter{UpdatedBefore: start}}, nil},
+		{"created range", word, models.SearchOptions{Filter: models.SearchFilter{CreatedAfter: start, CreatedBefore: time.Now().UTC().Add(time.Hour)}}, []*models.Memory{authRepo, authNote, webRepo}},
+		{"semantic", word + " notes", models.SearchOptions{Mode: models.SearchModeSemantic, Filter: models.SearchFilter{Type: models.TypeNote, Metadata: suiteOnly}}, []*models.Memory{authNote}},
+		{"no query", "", models.SearchOptions{Filter: models.SearchFilter{Type: models.TypeRepository, Tags: []string{"auth"}, Metadata: suiteOnly}}, []*models.Memory{authRepo}},
+	}
+	for _, tt := range tests {
+		results, _, err := s.Memories.Search(s.ctx, tt.query, tt.opts)
+		if err != nil {
+			t.Fatalf("%s: Search: %v", tt.name, err)
+		}
+		got := make(map[string]bool)
+		for _, r := range results {
+			got[r.Memory.ID] = true
+		}
+		match := len(got) == len(tt.want)
+		for _, m := range tt.want {
+			match = match && got[m.ID]
+		}
+		if !match {
+			t.Errorf("%s: got %v, want %d memories", tt.name, searchIDs(results), len(tt.want))
+		}
+	}
+
+	// Sorting by time, in either direction
+	for _, order := range []models.SortOrder{models.OrderAsc, models.OrderDesc} {
+		results, _, err := s.Memories.Search(s.ctx, "", models.SearchOptions{
+			Filter: models.SearchFilter{Metadata: suiteOnly},
+			Sort:   models.SortUpdatedAt,
+			Order:  order,
+		})
+		if err != nil {
+			t.Fatalf("Search sorted %s: %v", order, err)
+		}
+		if len(results) != 3 {
+			t.Fatalf("Search sorted %s: got %v, want 3 memories", order, searchIDs(results))
+		}
+		for i := 1; i < len(results); i++ {
+			prev, cur := results[i-1].Memory.UpdatedAt, results[i].Memory.UpdatedAt
+			if (order == models.OrderAsc && cur.Before(prev)) || (order == models.OrderDesc && cur.After(prev)) {
+				t.Errorf("Search sorted %s: %v is out of order", order, searchIDs(results))
+			}
+		}
+	}
+
+	// A limit applies after filtering
+	results, _, err := s.Memories.Search(s.ctx, word, models.SearchOptions{Limit: 1, Filter: models.SearchFilter{Type: models.TypeNote}})
+	if err != nil {
+		t.Fatalf("Search with limit: %v", err)
+	}
+	if len(results) != 1 || results[0].Memory.ID != authNote.ID {
+		t.Errorf("Search with limit: got %v, want [%s]", searchIDs(results), authNote.ID)
+	}
+
+	if _, _, err := s.Memories.Search(s.ctx, word, models.SearchOptions{Sort: "size"}); err == nil {
+		t.Error("Search with an unknown sort should fail")
+	}
+}
+
+func testSearchAll(t *testing.T, s *suite) {
+	word := s.word()
+	plan, err := s.Plans.Add(s.ctx, models.Plan{
+		ID:          s.id("auth"),
+		Name:        word + " auth overhaul",
+		Description: "Token rotation for every service",
+	}, nil)
+	if err != nil {
+		t.Fatalf("Add plan: %v", err)
+	}
+	s.plans = append(s.plans, plan.ID)
+	task, _, err := s.Tasks.Add(s.ctx, models.Task{
+		ID:      s.id("refresh"),
+		Content: word + " refresh the token before expiry\nDetails follow",
+		Status:  models.TaskStatusInProgress,
+	}, []string{plan.ID}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Add task: %v", err)
+	}
+	s.tasks = append(s.tasks, task.ID)
+	mem := s.addMemory(t, "notes", word+" token notes")
+
+	results, err := s.Memories.SearchAll(s.ctx, word+" token", models.NodeSearchOptions{})
+	if err != nil {
+		t.Fatalf("SearchAll: %v", err)
+	}
+	found := make(map[string]models.NodeSearchResult)
+	for _, r := range results {
+		found[r.ID] = r
+	}
+	if len(results) != 3 {
+		t.Fatalf("SearchAll: got %d results, want 3: %+v", len(results), results)
+	}
+	if r := found[plan.ID]; r.Kind != models.KindPlan || r.Title != plan.Name || r.Status != string(models.PlanStatusActive) {
+		t.Errorf("plan result = %+v", r)
+	}
+	if r := found[task.ID]; r.Kind != models.KindTask || r.Title != word+" refresh the token before expiry" || r.Status != string(models.TaskStatusInProgress) {
+		t.Errorf("task result = %+v", r)
+	}
+	if r := found[mem.ID]; r.Kind != models.KindMemory || r.Status != "" || r.Snippet == "" {
+		t.Errorf("memory result = %+v", r)
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i].Score > results[i-1].Score {
+			t.Errorf("results not sorted by score: %+v", results)
+		}
+	}
+
+	// Plans match on their description too
+	results, err = s.Memories.SearchAll(s.ctx, word+" rotation", models.NodeSearchOptions{})
+	if err != nil {
+		t.Fatalf("SearchAll description: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != plan.ID {
+		t.Errorf("SearchAll description: got %+v, want the plan", results)
+	}
+
+	// Kinds restrict the labels searched
+	results, err = s.Memories.SearchAll(s.ctx, word+" token", models.NodeSearchOptions{Kinds: []models.NodeKind{models.KindTask}})
+	if err != nil {
+		t.Fatalf("SearchAll tasks: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != task.ID {
+		t.Errorf("SearchAll tasks: got %+v, want the task", results)
+	}
+
+	results, err = s.Memories.SearchAll(s.ctx, word+" token refresh", models.NodeSearchOptions{
+		Mode:  models.SearchModeSemantic,
+		Kinds: []models.NodeKind{models.KindTask, models.KindPlan},
+	})
+	if err != nil {
+		t.Fatalf("SearchAll semantic: %v", err)
+	}
+	if len(results) == 0 || results[0].ID != task.ID {
+		t.Errorf("SearchAll semantic: got %+v, want the task first", results)
+	}
+
+	// Updates and deletes are reflected in the index
+	content := word + " rewrite the session cache"
+	if _, _, err := s.Tasks.Update(s.ctx, task.ID, 0, &content, nil, nil, nil, nil, nil); err != nil {
+		t.Fatalf("Update task: %v", err)
+	}
+	results, err = s.Memories.SearchAll(s.ctx, word+" session", models.NodeSearchOptions{})
+	if err != nil {
+		t.Fatalf("SearchAll after update: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != task.ID {
+		t.Errorf("SearchAll after update: got %+v, want the task", results)
+	}
+	if _, err := s.Tasks.Delete(s.ctx, task.ID); err != nil {
+		t.Fatalf("Delete task: %v", err)
+	}
+	if results, err = s.Memories.SearchAll(s.ctx, word+" session", models.NodeSearchOptions{}); err != nil || len(results) != 0 {
+		t.Errorf("SearchAll after delete: got %+v, %v", results, err)
+	}
+
+	if results, err = s.Memories.SearchAll(s.ctx, " ", models.NodeSearchOptions{}); err != nil || len(results) != 0 {
+		t.Errorf("SearchAll without a query: got %+v, %v", results, err)
+	}
+	if _, err := s.Memories.SearchAll(s.ctx, word, models.NodeSearchOptions{Mode: models.SearchModeHybrid}); err == nil {
+		t.Error("SearchAll in hybrid mode should fail")
+	}
+}
+
+// searchIDs returns the memory IDs of search results, in order.
+func searchIDs(results []models.SearchResult) []string {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.Memory.ID
+	}
+	return ids
+}
+
+func testMemoryRelationships(t *testing.T, s *suite) {
+	target := s.addMemory(t, "target", "target")
+	source := s.addMemory(t, "source", "source",
+		models.Relationship{ToID: target.ID, Type: models.RelReferences},
+		models.Relationship{ToID: target.ID, Type: models.RelReferences},
+	)
+
+	// Adding an existing relationship again must not duplicate it
+	if _, err := s.Memories.Update(s.ctx, source.ID, 0, nil, nil, nil, []models.Relationship{{ToID: target.ID, Type: models.RelReferences}}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	_, related, err := s.Memories.GetByIDWithRelated(s.ctx, source.ID)
+	if err != nil {
+		t.Fatalf("GetByIDWithRelated: %v", err)
+	}
+	if len(related) != 1 {
+		t.Fatalf("source related: got %d, want 1: %+v", len(related), related)
+	}
+	if related[0].ID != target.ID || related[0].Direction != "outgoing" || related[0].RelationType != string(models.RelReferences) {
+		t.Errorf("source related: got %+v", related[0])
+	}
+	if related[0].Type != models.TypeNote {
+		t.Errorf("related type: got %s, want %s", related[0].Type, models.TypeNote)
+	}
+
+	_, related, err = s.Memories.GetByIDWithRelated(s.ctx, target.ID)
+	if err != nil {
+		t.Fatalf("GetByIDWithRelated: %v", err)
+	}
+	if len(related) != 1 || related[0].ID != source.ID || related[0].Direction != "incoming" {
+		t.Errorf("target related: got %+v", related)
+	}
+
+	mem, related, err := s.Memories.GetByIDWithRelated(s.ctx, s.id("missing"))
+	if err != nil || mem != nil || related != nil {
+		t.Errorf("GetByIDWithRelated missing: got %v, %v, %v", mem, related, err)
+	}
+}
+
+func testGetRelated(t *testing.T, s *suite) {
+	plan := s.addPlan(t, "plan")
+	task := s.addTask(t, "task", []string{plan.ID})
+	mem := s.addMemory(t, "mem", "about the task", models.Relationship{ToID: task.ID, Type: models.RelReferences})
+
+	related, err := s.Memories.GetRelated(s.ctx, mem.ID, "", "both", 1)
+	if err != nil {
+		t.Fatalf("GetRelated depth 1: %v", err)
+	}
+	if len(related) != 1 || related[0].Memory.ID != task.ID || related[0].Depth != 1 {
+		t.Fatalf("GetRelated depth 1: got %+v", related)
+	}
+	if related[0].Memory.Type != "Task" || related[0].RelationType != string(models.RelReferences) {
+		t.Errorf("GetRelated depth 1: got %+v", related[0])
+	}
+
+	related, err = s.Memories.GetRelated(s.ctx, mem.ID, "", "both", 2)
+	if err != nil {
+		t.Fatalf("GetRelated depth 2: %v", err)
+	}
+	if len(related) != 2 {
+		t.Fatalf("GetRelated depth 2: got %d results, want 2", len(related))
+	}
+	planResult := related[1]
+	if planResult.Memory.ID != plan.ID || planResult.Depth != 2 {
+		t.Errorf("GetRelated depth 2: got %+v", planResult)
+	}
+	if planResult.Memory.Type != "Plan" || planResult.Memory.Content != plan.Name {
+		t.Errorf("Plan should be returned with its name as content, got %+v", planResult.Memory)
+	}
+	if !slices.Equal(planResult.Path, []string{mem.ID, task.ID, plan.ID}) {
+		t.Errorf("GetRelated depth 2 path: got %v", planResult.Path)
+	}
+	wantEdges := []models.Relationship{
+		{FromID: mem.ID, ToID: task.ID, Type: models.RelReferences},
+		{FromID: task.ID, ToID: plan.ID, Type: models.RelPartOf},
+	}
+	if !sameRelationships(planResult.Edges, wantEdges) || planResult.Direction != "outgoing" {
+		t.Errorf("GetRelated depth 2 edges: got %+v, direction %s", planResult.Edges, planResult.Direction)
+	}
+
+	// Walking back from the plan, every edge points towards the root
+	related, err = s.Memories.GetRelated(s.ctx, plan.ID, "", "both", 2)
+	if err != nil {
+		t.Fatalf("GetRelated from plan: %v", err)
+	}
+	if len(related) != 2 || related[1].Memory.ID != mem.ID {
+		t.Fatalf("GetRelated from plan: got %+v", related)
+	}
+	for _, r := range related {
+		if r.Direction != "incoming" {
+			t.Errorf("GetRelated from plan: %s direction = %s, want incoming", r.Memory.ID, r.Direction)
+		}
+	}
+	if !slices.Equal(related[1].Path, []string{plan.ID, task.ID, mem.ID}) || !sameRelationships(related[1].Edges, []models.Relationship{wantEdges[1], wantEdges[0]}) {
+		t.Errorf("GetRelated from plan: got path %v, edges %+v", related[1].Path, related[1].Edges)
+	}
+
+	// A direct link is preferred over the longer path through the task
+	shortcut := s.addMemory(t, "shortcut", "links the plan", models.Relationship{ToID: plan.ID, Type: models.RelRelatesTo})
+	related, err = s.Memories.GetRelated(s.ctx, mem.ID, "", "both", 3)
+	if err != nil {
+		t.Fatalf("GetRelated depth 3: %v", err)
+	}
+	if len(related) != 3 || related[2].Memory.ID != shortcut.ID || related[2].Depth != 3 || related[2].Direction != "incoming" {
+		t.Fatalf("GetRelated depth 3: got %+v", related)
+	}
+	related, err = s.Memories.GetRelated(s.ctx, shortcut.ID, "", "both", 3)
+	if err != nil {
+		t.Fatalf("GetRelated from shortcut: %v", err)
+	}
+	for _, r := range related {
+		if r.Memory.ID == plan.ID && r.Depth != 1 {
+			t.Errorf("GetRelated from shortcut: plan at depth %d, want 1", r.Depth)
+		}
+	}
+
+	related, err = s.Memories.GetRelated(s.ctx, mem.ID, "", "incoming", 1)
+	if err != nil {
+		t.Fatalf("GetRelated incoming: %v", err)
+	}
+	if len(related) != 0 {
+		t.Errorf("GetRelated incoming: got %d results, want 0", len(related))
+	}
+
+	related, err = s.Memories.GetRelated(s.ctx, task.ID, string(models.RelPartOf), "outgoing", 1)
+	if err != nil {
+		t.Fatalf("GetRelated PART_OF: %v", err)
+	}
+	if len(related) != 1 || related[0].Memory.ID != plan.ID {
+		t.Errorf("GetRelated PART_OF: got %+v", related)
+	}
+}
+
+func testRelationshipEdits(t *testing.T, s *suite) {
+	plan := s.addPlan(t, "plan")
+	task := s.addTask(t, "task", []string{plan.ID})
+	other := s.addMemory(t, "other", "other")
+	mem := s.addMemory(t, "mem", "mem",
+		models.Relationship{ToID: task.ID, Type: models.RelReferences},
+		models.Relationship{ToID: other.ID, Type: models.RelRelatesTo},
+	)
+	back := s.addMemory(t, "back", "back", models.Relationship{ToID: mem.ID, Type: models.RelRelatesTo})
+
+	edges, err := s.Memories.ListRelationships(s.ctx, mem.ID, "", "both")
+	if err != nil {
+		t.Fatalf("ListRelationships: %v", err)
+	}
+	want := []models.Edge{
+		models.NewEdge(mem.ID, task.ID, models.RelReferences, models.RelationshipProperties{}),
+		models.NewEdge(mem.ID, other.ID, models.RelRelatesTo, models.RelationshipProperties{}),
+		models.NewEdge(back.ID, mem.ID, models.RelRelatesTo, models.RelationshipProperties{}),
+	}
+	if !sameEdges(edges, want) {
+		t.Errorf("ListRelationships: got %+v, want %+v", edges, want)
+	}
+
+	edges, err = s.Memories.ListRelationships(s.ctx, mem.ID, string(models.RelRelatesTo), "outgoing")
+	if err != nil {
+		t.Fatalf("ListRelationships filtered: %v", err)
+	}
+	if !sameEdges(edges, want[1:2]) {
+		t.Errorf("ListRelationships filtered: got %+v", edges)
+	}
+
+	edges, err = s.Memories.ListRelationships(s.ctx, task.ID, string(models.RelPartOf), "outgoing")
+	if err != nil {
+		t.Fatalf("ListRelationships PART_OF: %v", err)
+	}
+	if len(edges) != 1 || edges[0].ToID != plan.ID || edges[0].Position == nil {
+		t.Errorf("ListRelationships PART_OF: got %+v, want one edge with a position", edges)
+	}
+
+	if _, err := s.Memories.ListRelationships(s.ctx, s.id("missing"), "", "both"); err == nil {
+		t.Error("ListRelationships of a missing node should fail")
+	}
+
+	if err := s.Memories.DeleteRelationship(s.ctx, back.ID, mem.ID, models.RelRelatesTo); err != nil {
+		t.Fatalf("DeleteRelationship: %v", err)
+	}
+	if err := s.Memories.DeleteRelationship(s.ctx, back.ID, mem.ID, models.RelRelatesTo); err == nil {
+		t.Error("deleting a missing relationship should fail")
+	}
+	if edges, _ := s.Memories.ListRelationships(s.ctx, mem.ID, "", "incoming"); len(edges) != 0 {
+		t.Errorf("incoming after delete: got %+v", edges)
+	}
+	if m, _ := s.Memories.GetByID(s.ctx, back.ID); m == nil {
+		t.Error("DeleteRelationship should keep both nodes")
+	}
+
+	// Replacing keeps wanted edges, adds missing ones and drops the rest
+	edges, err = s.Memories.ReplaceRelationships(s.ctx, mem.ID, []models.Relationship{
+		{ToID: other.ID, Type: models.RelRelatesTo},
+		{ToID: plan.ID, Type: models.RelImplements},
+	})
+	if err != nil {
+		t.Fatalf("ReplaceRelationships: %v", err)
+	}
+	want = []models.Edge{
+		models.NewEdge(mem.ID, other.ID, models.RelRelatesTo, models.RelationshipProperties{}),
+		models.NewEdge(mem.ID, plan.ID, models.RelImplements, models.RelationshipProperties{}),
+	}
+	if !sameEdges(edges, want) {
+		t.Errorf("ReplaceRelationships: got %+v, want %+v", edges, want)
+	}
+
+	// A missing target leaves every edge as it was
+	_, err = s.Memories.ReplaceRelationships(s.ctx, mem.ID, []models.Relationship{
+		{ToID: task.ID, Type: models.RelReferences},
+		{ToID: s.id("missing"), Type: models.RelReferences},
+	})
+	if err == nil {
+		t.Error("ReplaceRelationships with a missing target should fail")
+	}
+	if edges, _ := s.Memories.ListRelationships(s.ctx, mem.ID, "", "outgoing"); !sameEdges(edges, want) {
+		t.Errorf("after failed replace: got %+v, want %+v", edges, want)
+	}
+
+	// A task keeps its plan, which cannot be replaced
+	edges, err = s.Memories.ReplaceRelationships(s.ctx, task.ID, nil)
+	if err != nil {
+		t.Fatalf("ReplaceRelationships task: %v", err)
+	}
+	if len(edges) != 1 || edges[0].Type != models.RelPartOf || edges[0].ToID != plan.ID {
+		t.Errorf("ReplaceRelationships task: got %+v", edges)
+	}
+	if _, err := s.Memories.ReplaceRelationships(s.ctx, task.ID, []models.Relationship{{ToID: plan.ID, Type: models.RelPartOf}}); err == nil {
+		t.Error("ReplaceRelationships should reject PART_OF for a task")
+	}
+}
+
+// sameRelationships reports whether got and want link the same endpoints by
+// the same types, in order. Properties are not compared.
+func sameRelationships(got, want []models.Relationship) bool {
+	return slices.EqualFunc(got, want, func(a, b models.Relationship) bool {
+		return a.FromID == b.FromID && a.ToID == b.ToID && a.Type == b.Type
+	})
+}
+
+// sameEdges reports whether got and want hold the same edges by ID, in any
+// order. Properties are not compared.
+func sameEdges(got, want []models.Edge) bool {
+	ids := func(edges []models.Edge) []string {
+		out := make([]string, len(edges))
+		for i, e := range edges {
+			out[i] = e.ID
+		}
+		slices.Sort(out)
+		return out
+	}
+	return slices.Equal(ids(got), ids(want))
+}
+
+func testRelationshipProperties(t *testing.T, s *suite) {
+	weight := 0.8
+	decision := s.addMemory(t, "decision", "use sqlite")
+	mem := s.addMemory(t, "mem", "benchmark results", models.Relationship{
+		ToID: decision.ID,
+		Type: models.RelReferences,
+		RelationshipProperties: models.RelationshipProperties{
+			Reason:   "measured the write throughput",
+			Weight:   &weight,
+			Metadata: map[string]string{"source": "bench"},
+		},
+	})
+
+	_, related, err := s.Memories.GetByIDWithRelated(s.ctx, mem.ID)
+	if err != nil {
+		t.Fatalf("GetByIDWithRelated: %v", err)
+	}
+	if len(related) != 1 {
+		t.Fatalf("GetByIDWithRelated: got %+v", related)
+	}
+	props := related[0].RelationshipProperties
+	if props.Reason != "measured the write throughput" || props.Weight == nil || *props.Weight != weight || props.Metadata["source"] != "bench" {
+		t.Errorf("properties: got %+v", props)
+	}
+	if props.CreatedAt.IsZero() {
+		t.Error("relationship should have a creation time")
+	}
+	createdAt := props.CreatedAt
+
+	// Giving the relationship again only changes the properties it sets
+	if _, err := s.Memories.Update(s.ctx, mem.ID, 0, nil, nil, nil, []models.Relationship{{
+		ToID:                   decision.ID,
+		Type:                   models.RelReferences,
+		RelationshipProperties: models.RelationshipProperties{Reason: "confirmed in production"},
+	}}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	results, err := s.Memories.GetRelated(s.ctx, decision.ID, "", "incoming", 1)
+	if err != nil {
+		t.Fatalf("GetRelated: %v", err)
+	}
+	if len(results) != 1 || len(results[0].Edges) != 1 {
+		t.Fatalf("GetRelated: got %+v", results)
+	}
+	props = results[0].Edges[0].RelationshipProperties
+	if props.Reason != "confirmed in production" || props.Weight == nil || *props.Weight != weight || props.Metadata["source"] != "bench" {
+		t.Errorf("properties after update: got %+v", props)
+	}
+	if !props.CreatedAt.Equal(createdAt) {
+		t.Errorf("created_at changed from %v to %v", createdAt, props.CreatedAt)
+	}
+
+	edges, err := s.Memories.ListRelationships(s.ctx, mem.ID, "", "outgoing")
+	if err != nil {
+		t.Fatalf("ListRelationships: %v", err)
+	}
+	if len(edges) != 1 || edges[0].Reason != "confirmed in production" || edges[0].Weight == nil {
+		t.Errorf("ListRelationships: got %+v", edges)
+	}
+}
+
+func testRelationshipFailures(t *testing.T, s *suite) {
+	target := s.addMemory(t, "target", "existing target")
+	missing := models.Relationship{ToID: s.id("missing"), Type: models.RelRelatesTo}
+
+	var relErr *models.RelationshipError
+	_, err := s.Memories.Add(s.ctx, models.Memory{ID: s.id("orphan"), Type: models.TypeNote, Content: "orphan"},
+		[]models.Relationship{{ToID: target.ID, Type: models.RelReferences}, missing})
+	if !errors.As(err, &relErr) || relErr.ToID != missing.ToID || !errors.Is(err, models.ErrNodeNotFound) {
+		t.Fatalf("Add with a missing target: got %v, want a RelationshipError for %s", err, missing.ToID)
+	}
+	if mem, _ := s.Memories.GetByID(s.ctx, s.id("orphan")); mem != nil {
+		s.memories = append(s.memories, mem.ID)
+		t.Error("failed Add should not create the memory")
+	}
+
+	_, err = s.Memories.Add(s.ctx, models.Memory{ID: s.id("badtype"), Type: models.TypeNote, Content: "bad type"},
+		[]models.Relationship{{ToID: target.ID, Type: "LIKES"}})
+	if !errors.As(err, &relErr) || relErr.Type != "LIKES" {
+		t.Errorf("Add with an invalid type: got %v, want a RelationshipError", err)
+	}
+
+	mem := s.addMemory(t, "mem", "original content")
+	content := "changed content"
+	if _, err := s.Memories.Update(s.ctx, mem.ID, 0, &content, nil, nil, []models.Relationship{missing}); !errors.Is(err, models.ErrNodeNotFound) {
+		t.Errorf("Update with a missing target: got %v, want ErrNodeNotFound", err)
+	}
+	got, err := s.Memories.GetByID(s.ctx, mem.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Content != "original content" {
+		t.Errorf("failed Update changed content to %q", got.Content)
+	}
+
+	plan := s.addPlan(t, "plan")
+	if _, _, err := s.Tasks.Add(s.ctx, models.Task{ID: s.id("task"), Content: "task"}, []string{plan.ID}, []models.Relationship{missing}, nil, nil); !errors.Is(err, models.ErrNodeNotFound) {
+		t.Errorf("task Add with a missing target: got %v, want ErrNodeNotFound", err)
+	}
+	if task, _ := s.Tasks.GetByID(s.ctx, s.id("task")); task != nil {
+		s.tasks = append(s.tasks, task.ID)
+		t.Error("failed task Add should not create the task")
+	}
+	if _, err := s.Plans.Update(s.ctx, plan.ID, 0, nil, nil, nil, nil, nil, []models.Relationship{missing}); !errors.Is(err, models.ErrNodeNotFound) {
+		t.Errorf("plan Update with a missing target: got %v, want ErrNodeNotFound", err)
+	}
+
+	existing, err := s.Memories.ExistingNodes(s.ctx, []string{target.ID, plan.ID, missing.ToID})
+	if err != nil {
+		t.Fatalf("ExistingNodes: %v", err)
+	}
+	if !existing[target.ID] || !existing[plan.ID] || existing[missing.ToID] {
+		t.Errorf("ExistingNodes: got %v", existing)
+	}
+}
+
+func testPlanCRUD(t *testing.T, s *suite) {
+	created, err := s.Plans.Add(s.ctx, models.Plan{
+		ID:          s.id("plan"),
+		Name:        "Test Plan",
+		Description: "desc",
+		Tags:        []string{"x"},
+		Metadata:    map[string]string{"priority": "high"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	s.plans = append(s.plans, created.ID)
+
+	if created.Status != models.PlanStatusActive {
+		t.Errorf("default status: got %s, want active", created.Status)
+	}
+
+	got, err := s.Plans.GetByID(s.ctx, created.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetByID: %v, %v", got, err)
+	}
+	if got.Name != "Test Plan" || got.Metadata["priority"] != "high" {
+		t.Errorf("GetByID returned %+v", got)
+	}
+
+	missing, err := s.Plans.GetByID(s.ctx, s.id("missing"))
+	if err != nil || missing != nil {
+		t.Errorf("GetByID missing: got %v, %v", missing, err)
+	}
+
+	name := "Renamed"
+	status := string(models.PlanStatusCompleted)
+	updated, err := s.Plans.Update(s.ctx, created.ID, 0, &name, nil, &status, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Name != name || updated.Status != models.PlanStatusCompleted || updated.Description != "desc" {
+		t.Errorf("Update returned %+v", updated)
+	}
+
+	if _, err := s.Plans.Update(s.ctx, s.id("missing"), 0, &name, nil, nil, nil, nil, nil); err == nil {
+		t.Error("Update of missing plan should fail")
+	}
+
+	plan, tasks, err := s.Plans.GetWithTasks(s.ctx, s.id("missing"))
+	if err != nil || plan != nil || tasks != nil {
+		t.Errorf("GetWithTasks missing: got %v, %v, %v", plan, tasks, err)
+	}
+}
+
+func testPlanList(t *testing.T, s *suite) {
+	tag := s.prefix
+	first := s.addPlan(t, "first", tag)
+	s.addPlan(t, "second", tag, "extra")
+	draft := models.PlanStatusDraft
+	third, err := s.Plans.Add(s.ctx, models.Plan{ID: s.id("third"), Name: "third", Status: draft, Tags: []string{tag}}, nil)
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	s.plans = append(s.plans, third.ID)
+
+	plans, _, err := s.Plans.List(s.ctx, "", []string{tag}, 0, "")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(plans) != 3 {
+		t.Fatalf("List by tag: got %d, want 3", len(plans))
+	}
+
+	plans, _, err = s.Plans.List(s.ctx, string(models.PlanStatusDraft), []string{tag}, 0, "")
+	if err != nil {
+		t.Fatalf("List by status: %v", err)
+	}
+	if len(plans) != 1 || plans[0].ID != third.ID {
+		t.Errorf("List by status: got %+v", plans)
+	}
+
+	plans, _, err = s.Plans.List(s.ctx, "", []string{tag}, 2, "")
+	if err != nil {
+		t.Fatalf("List with limit: %v", err)
+	}
+	if len(plans) != 2 {
+		t.Errorf("List with limit: got %d, want 2", len(plans))
+	}
+
+	// Touch the first plan so it becomes the most recently updated
+	time.Sleep(1100 * time.Millisecond)
+	desc := "touched"
+	if _, err := s.Plans.Update(s.ctx, first.ID, 0, nil, &desc, nil, nil, nil, nil); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	plans, _, err = s.Plans.List(s.ctx, "", []string{tag}, 0, "")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if plans[0].ID != first.ID {
+		t.Errorf("List order: got %s first, want %s", plans[0].ID, first.ID)
+	}
+}
+
+func testTaskCRUD(t *testing.T, s *suite) {
+	plan := s.addPlan(t, "plan")
+	other := s.addPlan(t, "other")
+
+	if _, _, err := s.Tasks.Add(s.ctx, models.Task{Content: "orphan"}, nil, nil, nil, nil); err == nil {
+		t.Error("Add without plans should fail")
+	}
+	if _, _, err := s.Tasks.Add(s.ctx, models.Task{ID: s.id("bad"), Content: "bad"}, []string{s.id("missing")}, nil, nil, nil); err == nil {
+		t.Error("Add with missing plan should fail")
+		s.tasks = append(s.tasks, s.id("bad"))
+	}
+	if got, _ := s.Tasks.GetByID(s.ctx, s.id("bad")); got != nil {
+		t.Error("failed Add must not leave a task behind")
+	}
+
+	task := s.addTask(t, "task", []string{plan.ID})
+	if task.Status != models.TaskStatusPending {
+		t.Errorf("default status: got %s, want pending", task.Status)
+	}
+
+	got, plans, err := s.Tasks.GetWithPlans(s.ctx, task.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetWithPlans: %v, %v", got, err)
+	}
+	if len(plans) != 1 || plans[0].ID != plan.ID {
+		t.Errorf("GetWithPlans plans: got %+v", plans)
+	}
+
+	status := string(models.TaskStatusInProgress)
+	updated, _, err := s.Tasks.Update(s.ctx, task.ID, 0, nil, &status, nil, []string{"t"}, []string{other.ID}, nil)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Status != models.TaskStatusInProgress || len(updated.Tags) != 1 {
+		t.Errorf("Update returned %+v", updated)
+	}
+	_, plans, err = s.Tasks.GetWithPlans(s.ctx, task.ID)
+	if err != nil {
+		t.Fatalf("GetWithPlans: %v", err)
+	}
+	if len(plans) != 2 {
+		t.Errorf("after adding a plan: got %d plans, want 2", len(plans))
+	}
+
+	if _, _, err := s.Tasks.Update(s.ctx, task.ID, 0, nil, nil, nil, nil, []string{s.id("missing")}, nil); err == nil {
+		t.Error("Update with missing plan should fail")
+	}
+	if _, _, err := s.Tasks.Update(s.ctx, s.id("missing"), 0, nil, &status, nil, nil, nil, nil); err == nil {
+		t.Error("Update of missing task should fail")
+	}
+
+	if _, err := s.Tasks.Delete(s.ctx, task.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	gone, err := s.Tasks.GetByID(s.ctx, task.ID)
+	if err != nil || gone != nil {
+		t.Errorf("GetByID after Delete: got %v, %v", gone, err)
+	}
+}
+
+func testTaskList(t *testing.T, s *suite) {
+	plan := s.addPlan(t, "plan")
+	tag := s.prefix
+	first, _, err := s.Tasks.Add(s.ctx, models.Task{ID: s.id("first"), Content: "first", Tags: []string{tag}}, []string{plan.ID}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	s.tasks = append(s.tasks, first.ID)
+	done := models.TaskStatusCompleted
+	second, _, err := s.Tasks.Add(s.ctx, models.Task{ID: s.id("second"), Content: "second", Status: done, Tags: []string{tag}}, []string{plan.ID}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	s.tasks = append(s.tasks, second.ID)
+
+	tasks, _, err := s.Tasks.List(s.ctx, plan.ID, "", nil, 0, "")
+	if err != nil {
+		t.Fatalf("List by plan: %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].Task.ID != first.ID || tasks[1].Task.ID != second.ID {
+		t.Fatalf("List by plan: got %+v", tasks)
+	}
+	if tasks[0].Position == nil || tasks[1].Position == nil || *tasks[0].Position >= *tasks[1].Position {
+		t.Error("List by plan should include ascending positions")
+	}
+
+	tasks, _, err = s.Tasks.List(s.ctx, plan.ID, string(done), nil, 0, "")
+	if err != nil {
+		t.Fatalf("List by status: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].Task.ID != second.ID {
+		t.Errorf("List by status: got %+v", tasks)
+	}
+
+	tasks, _, err = s.Tasks.List(s.ctx, "", "", []string{tag}, 0, "")
+	if err != nil {
+		t.Fatalf("List by tag: %v", err)
+	}
+	if len(tasks) != 2 {
+		t.Errorf("List by tag: got %d, want 2", len(tasks))
+	}
+	for _, task := range tasks {
+		if task.Position != nil {
+			t.Error("List without plan should not include positions")
+		}
+	}
+}
+
+func testPagination(t *testing.T, s *suite) {
+	const n = 5
+	word := s.word()
+	tag := s.prefix
+	plan := s.addPlan(t, "plan")
+	want := map[string]bool{}
+	for i := range n {
+		name := fmt.Sprintf("item%d", i)
+		mem := s.addMemory(t, name, word+" "+name)
+		tagged := s.addPlan(t, "plan-"+name, tag)
+		task, _, err := s.Tasks.Add(s.ctx, models.Task{ID: s.id("task-" + name), Content: name, Tags: []string{tag}}, []string{plan.ID}, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Add task: %v", err)
+		}
+		s.tasks = append(s.tasks, task.ID)
+		want[mem.ID], want[tagged.ID], want[task.ID] = true, true, true
+	}
+
+	// collect pages through a listing two items at a time, checking that
+	// pages neither repeat nor skip items and that Total counts them all.
+	collect := func(name string, fetch func(cursor string) ([]string, models.Page, error)) {
+		t.Helper()
+		seen := map[string]bool{}
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > n {
+				t.Fatalf("%s: too many pages", name)
+			}
+			ids, page, err := fetch(cursor)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if page.Total != n {
+				t.Errorf("%s: Total = %d, want %d", name, page.Total, n)
+			}
+			if len(ids) > 2 {
+				t.Fatalf("%s: page of %d, want at most 2", name, len(ids))
+			}
+			for _, id := range ids {
+				if !want[id] || seen[id] {
+					t.Errorf("%s: unexpected or repeated %s", name, id)
+				}
+				seen[id] = true
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+		if len(seen) != n {
+			t.Errorf("%s: saw %d items, want %d", name, len(seen), n)
+		}
+		if _, _, err := fetch("not a cursor"); err == nil {
+			t.Errorf("%s: expected error for invalid cursor", name)
+		}
+	}
+
+	collect("Search", func(cursor string) ([]string, models.Page, error) {
+		results, page, err := s.Memories.Search(s.ctx, word, models.SearchOptions{Limit: 2, Cursor: cursor})
+		ids := make([]string, len(results))
+		for i, r := range results {
+			ids[i] = r.Memory.ID
+		}
+		return ids, page, err
+	})
+	collect("Plans.List", func(cursor string) ([]string, models.Page, error) {
+		plans, page, err := s.Plans.List(s.ctx, "", []string{tag}, 2, cursor)
+		ids := make([]string, len(plans))
+		for i, p := range plans {
+			ids[i] = p.ID
+		}
+		return ids, page, err
+	})
+	collect("Tasks.List by plan", func(cursor string) ([]string, models.Page, error) {
+		tasks, page, err := s.Tasks.List(s.ctx, plan.ID, "", nil, 2, cursor)
+		ids := make([]string, len(tasks))
+		for i, task := range tasks {
+			ids[i] = task.Task.ID
+		}
+		return ids, page, err
+	})
+	collect("Tasks.List by tag", func(cursor string) ([]string, models.Page, error) {
+		tasks, page, err := s.Tasks.List(s.ctx, "", "", []string{tag}, 2, cursor)
+		ids := make([]string, len(tasks))
+		for i, task := range tasks {
+			ids[i] = task.Task.ID
+		}
+		return ids, page, err
+	})
+}
+
+func testTaskPositioning(t *testing.T, s *suite) {
+	plan := s.addPlan(t, "plan")
+	first := s.addTask(t, "first", []string{plan.ID})
+	third := s.addTask(t, "third", []string{plan.ID})
+
+	secondID := s.id("second")
+	second, _, err := s.Tasks.Add(s.ctx, models.Task{ID: secondID, Content: "second"}, []string{plan.ID}, nil, &first.ID, nil)
+	if err != nil {
+		t.Fatalf("Add after: %v", err)
+	}
+	s.tasks = append(s.tasks, second.ID)
+
+	zeroID := s.id("zero")
+	zero, _, err := s.Tasks.Add(s.ctx, models.Task{ID: zeroID, Content: "zero"}, []string{plan.ID}, nil, nil, &first.ID)
+	if err != nil {
+		t.Fatalf("Add before: %v", err)
+	}
+	s.tasks = append(s.tasks, zero.ID)
+
+	assertOrder(t, s, plan.ID, zero.ID, first.ID, second.ID, third.ID)
+
+	if err := s.Tasks.UpdatePositions(s.ctx, plan.ID, map[string]float64{zero.ID: 1e7}); err != nil {
+		t.Fatalf("UpdatePositions: %v", err)
+	}
+	assertOrder(t, s, plan.ID, first.ID, second.ID, third.ID, zero.ID)
+
+	if err := s.Tasks.UpdatePositions(s.ctx, plan.ID, map[string]float64{s.id("missing"): 1}); err == nil {
+		t.Error("UpdatePositions with unknown task should fail")
+	}
+
+	positions, err := s.Tasks.NormalizePositions(s.ctx, plan.ID, []string{third.ID, second.ID, first.ID, zero.ID})
+	if err != nil {
+		t.Fatalf("NormalizePositions: %v", err)
+	}
+	if !slices.Equal(positions, []float64{1000, 2000, 3000, 4000}) {
+		t.Errorf("NormalizePositions: got %v", positions)
+	}
+	assertOrder(t, s, plan.ID, third.ID, second.ID, first.ID, zero.ID)
+	if _, err := s.Tasks.NormalizePositions(s.ctx, plan.ID, []string{first.ID, s.id("missing")}); err == nil {
+		t.Error("NormalizePositions with unknown task should fail")
+	}
+	assertOrder(t, s, plan.ID, third.ID, second.ID, first.ID, zero.ID)
+}
+
+func assertOrder(t *testing.T, s *suite, planID string, want ...string) {
+	t.Helper()
+	_, tasks, err := s.Plans.GetWithTasks(s.ctx, planID)
+	if err != nil {
+		t.Fatalf("GetWithTasks: %v", err)
+	}
+	var got []string
+	for _, task := range tasks {
+		got = append(got, task.Task.ID)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("task order:\ngot:  %v\nwant: %v", got, want)
+	}
+}
+
+func testVersions(t *testing.T, s *suite) {
+	mem := s.addMemory(t, "mem", "first draft")
+	if mem.Version != 1 {
+		t.Errorf("new memory version: got %d, want 1", mem.Version)
+	}
+	content := "second draft"
+	updated, err := s.Memories.Update(s.ctx, mem.ID, 1, &content, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Update at the current version: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("updated memory version: got %d, want 2", updated.Version)
+	}
+
+	// A writer that read version 1 must not overwrite the second draft
+	stale := "stale draft"
+	var conflict *models.VersionConflictError
+	_, err = s.Memories.Update(s.ctx, mem.ID, 1, &stale, nil, nil, nil)
+	if !errors.As(err, &conflict) || conflict.Expected != 1 || conflict.Actual != 2 || !errors.Is(err, models.ErrVersionConflict) {
+		t.Fatalf("Update at a stale version: got %v, want a conflict at version 2", err)
+	}
+	got, err := s.Memories.GetByID(s.ctx, mem.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Content != content || got.Version != 2 {
+		t.Errorf("after a conflict: got %q at version %d", got.Content, got.Version)
+	}
+	// Without an expected version the update always applies
+	if updated, err := s.Memories.Update(s.ctx, mem.ID, 0, nil, nil, []string{"t"}, nil); err != nil || updated.Version != 3 {
+		t.Errorf("unconditional Update: got %+v, %v, want version 3", updated, err)
+	}
+	if _, err := s.Memories.Update(s.ctx, s.id("missing"), 1, &content, nil, nil, nil); err == nil || errors.Is(err, models.ErrVersionConflict) {
+		t.Errorf("Update of a missing memory: got %v, want not found", err)
+	}
+
+	plan := s.addPlan(t, "plan")
+	status := string(models.PlanStatusActive)
+	if updated, err := s.Plans.Update(s.ctx, plan.ID, plan.Version, nil, nil, &status, nil, nil, nil); err != nil || updated.Version != 2 {
+		t.Errorf("plan Update: got %+v, %v, want version 2", updated, err)
+	}
+	if _, err := s.Plans.Update(s.ctx, plan.ID, plan.Version, nil, nil, &status, nil, nil, nil); !errors.Is(err, models.ErrVersionConflict) {
+		t.Errorf("plan Update at a stale version: got %v, want a conflict", err)
+	}
+	plans, _, err := s.Plans.List(s.ctx, "", nil, 100, "")
+	if err != nil {
+		t.Fatalf("List plans: %v", err)
+	}
+	for _, p := range plans {
+		if p.ID == plan.ID && p.Version != 2 {
+			t.Errorf("listed plan version: got %d, want 2", p.Version)
+		}
+	}
+
+	task := s.addTask(t, "task", []string{plan.ID})
+	done := string(models.TaskStatusCompleted)
+	if _, _, err := s.Tasks.Update(s.ctx, task.ID, 2, nil, &done, nil, nil, nil, nil); !errors.Is(err, models.ErrVersionConflict) {
+		t.Errorf("task Update at a future version: got %v, want a conflict", err)
+	}
+	if updated, _, err := s.Tasks.Update(s.ctx, task.ID, 1, nil, &done, nil, nil, nil, nil); err != nil || updated.Version != 2 {
+		t.Errorf("task Update: got %+v, %v, want version 2", updated, err)
+	}
+	_, tasks, err := s.Plans.GetWithTasks(s.ctx, plan.ID)
+	if err != nil {
+		t.Fatalf("GetWithTasks: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].Task.Version != 2 {
+		t.Errorf("task in plan: got %+v, want version 2", tasks)
+	}
+}
+
+func testRevisions(t *testing.T, s *suite) {
+	ctx := store.WithActor(s.ctx, "agent-a")
+	mem, err := s.Memories.Add(ctx, models.Memory{Type: models.TypeNote, Content: "use postgres", Tags: []string{"db"}}, nil)
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	s.memories = append(s.memories, mem.ID)
+
+	content := "use sqlite"
+	ctx = store.WithActor(s.ctx, "agent-b")
+	if _, err := s.Memories.Update(ctx, mem.ID, 0, &content, map[string]string{"why": "single binary"}, nil, nil); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	revisions, err := s.Memories.ListRevisions(s.ctx, mem.ID)
+	if err != nil {
+		t.Fatalf("ListRevisions: %v", err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("ListRevisions: got %+v, want 2 revisions", revisions)
+	}
+	latest, first := revisions[0], revisions[1]
+	if first.Version != 1 || first.Kind != models.KindMemory || first.Content != "use postgres" || first.Actor != "agent-a" || !slices.Equal(first.Tags, []string{"db"}) {
+		t.Errorf("first revision: got %+v", first)
+	}
+	if latest.Version != 2 || latest.Content != "use sqlite" || latest.Actor != "agent-b" || latest.Metadata["why"] != "single binary" {
+		t.Errorf("latest revision: got %+v", latest)
+	}
+
+	rev, err := s.Memories.GetRevision(s.ctx, mem.ID, 1)
+	if err != nil || rev == nil || rev.Content != "use postgres" {
+		t.Errorf("GetRevision: got %+v, %v", rev, err)
+	}
+	if rev, err := s.Memories.GetRevision(s.ctx, mem.ID, 3); err != nil || rev != nil {
+		t.Errorf("GetRevision of a missing version: got %+v, %v", rev, err)
+	}
+	if _, err := s.Memories.ListRevisions(s.ctx, s.id("missing")); err == nil {
+		t.Error("ListRevisions of a missing node should fail")
+	}
+
+	// A failed update records nothing
+	if _, err := s.Memories.Update(ctx, mem.ID, 1, &content, nil, nil, nil); err == nil {
+		t.Fatal("Update at a stale version should fail")
+	}
+	if revisions, _ := s.Memories.ListRevisions(s.ctx, mem.ID); len(revisions) != 2 {
+		t.Errorf("after a failed update: got %d revisions, want 2", len(revisions))
+	}
+
+	plan := s.addPlan(t, "plan")
+	status := string(models.PlanStatusActive)
+	if _, err := s.Plans.Update(ctx, plan.ID, 0, nil, nil, &status, nil, nil, nil); err != nil {
+		t.Fatalf("plan Update: %v", err)
+	}
+	task := s.addTask(t, "task", []string{plan.ID})
+	done := string(models.TaskStatusCompleted)
+	if _, _, err := s.Tasks.Update(ctx, task.ID, 0, nil, &done, nil, nil, nil, nil); err != nil {
+		t.Fatalf("task Update: %v", err)
+	}
+	planRevs, err := s.Memories.ListRevisions(s.ctx, plan.ID)
+	if err != nil || len(planRevs) != 2 || planRevs[0].Kind != models.KindPlan || planRevs[0].Status != status || planRevs[1].Name != "plan" {
+		t.Errorf("plan revisions: got %+v, %v", planRevs, err)
+	}
+	taskRevs, err := s.Memories.ListRevisions(s.ctx, task.ID)
+	if err != nil || len(taskRevs) != 2 || taskRevs[0].Kind != models.KindTask || taskRevs[0].Status != done || taskRevs[1].Status != string(models.TaskStatusPending) {
+		t.Errorf("task revisions: got %+v, %v", taskRevs, err)
+	}
+}
+
+func testTaskDependencies(t *testing.T, s *suite) {
+	plan := s.addPlan(t, "plan")
+	other := s.addPlan(t, "other")
+	base := s.addTask(t, "base", []string{plan.ID})
+	outside := s.addTask(t, "outside", []string{other.ID})
+	dependent := s.addTask(t, "dependent", []string{plan.ID},
+		models.Relationship{ToID: base.ID, Type: models.RelDependsOn},
+		models.Relationship{ToID: outside.ID, Type: models.RelDependsOn},
+	)
+	if _, _, err := s.Tasks.Update(s.ctx, base.ID, 0, nil, nil, nil, nil, nil, []models.Relationship{{ToID: dependent.ID, Type: models.RelBlocks}}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	if _, _, err := s.Tasks.Update(s.ctx, base.ID, 0, nil, nil, nil, nil, nil, []models.Relationship{{ToID: dependent.ID, Type: "NOT_A_TYPE"}}); err == nil {
+		t.Error("Update with invalid relationship type should fail")
+	}
+
+	_, tasks, err := s.Plans.GetWithTasks(s.ctx, plan.ID)
+	if err != nil {
+		t.Fatalf("GetWithTasks: %v", err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("GetWithTasks: got %d tasks, want 2", len(tasks))
+	}
+	for _, task := range tasks {
+		switch task.Task.ID {
+		case base.ID:
+			if len(task.Blocks) != 1 || task.Blocks[0] != dependent.ID {
+				t.Errorf("base.Blocks: got %v", task.Blocks)
+			}
+		case dependent.ID:
+			// Dependencies outside the plan are not reported
+			if len(task.DependsOn) != 1 || task.DependsOn[0] != base.ID {
+				t.Errorf("dependent.DependsOn: got %v", task.DependsOn)
+			}
+		}
+	}
+}
+
+func testDependencyCycles(t *testing.T, s *suite) {
+	plan := s.addPlan(t, "plan")
+	a := s.addTask(t, "a", []string{plan.ID})
+	b := s.addTask(t, "b", []string{plan.ID}, models.Relationship{ToID: a.ID, Type: models.RelDependsOn})
+	c := s.addTask(t, "c", []string{plan.ID}, models.Relationship{ToID: b.ID, Type: models.RelDependsOn})
+
+	assertCycle := func(name string, err error, want ...string) {
+		t.Helper()
+		var cycleErr *models.CycleError
+		if !errors.As(err, &cycleErr) || !errors.Is(err, models.ErrDependencyCycle) {
+			t.Errorf("%s: got %v, want a dependency cycle", name, err)
+			return
+		}
+		if !slices.Equal(cycleErr.Path, want) {
+			t.Errorf("%s: got path %v, want %v", name, cycleErr.Path, want)
+		}
+	}
+
+	// a waits for c, which waits for b, which waits for a
+	_, _, err := s.Tasks.Update(s.ctx, a.ID, 0, nil, nil, nil, nil, nil, []models.Relationship{{ToID: c.ID, Type: models.RelDependsOn}})
+	assertCycle("DEPENDS_ON", err, a.ID, c.ID, b.ID, a.ID)
+	_, _, err = s.Tasks.Update(s.ctx, c.ID, 0, nil, nil, nil, nil, nil, []models.Relationship{{ToID: a.ID, Type: models.RelBlocks}})
+	assertCycle("BLOCKS", err, a.ID, c.ID, b.ID, a.ID)
+	_, _, err = s.Tasks.Update(s.ctx, a.ID, 0, nil, nil, nil, nil, nil, []models.Relationship{{ToID: a.ID, Type: models.RelDependsOn}})
+	assertCycle("self", err, a.ID, a.ID)
+
+	// Relationships given together are checked against each other, and a
+	// rejected call writes none of them
+	d := s.addTask(t, "d", []string{plan.ID})
+	_, _, err = s.Tasks.Update(s.ctx, d.ID, 0, nil, nil, nil, nil, nil, []models.Relationship{
+		{ToID: c.ID, Type: models.RelDependsOn},
+		{ToID: a.ID, Type: models.RelBlocks},
+	})
+	assertCycle("together", err, a.ID, d.ID, c.ID, b.ID, a.ID)
+	if edges, err := s.Memories.ListRelationships(s.ctx, d.ID, "", "outgoing"); err != nil || len(edges) != 1 {
+		t.Errorf("ListRelationships after a rejected update: got %+v, %v, want only PART_OF", edges, err)
+	}
+
+	// Turning c's dependency on b around is no cycle, as the dependency is
+	// replaced, though the two together would be one
+	if _, err := s.Memories.ReplaceRelationships(s.ctx, c.ID, []models.Relationship{{ToID: b.ID, Type: models.RelBlocks}}); err != nil {
+		t.Errorf("ReplaceRelationships: %v", err)
+	}
+	_, err = s.Memories.ReplaceRelationships(s.ctx, a.ID, []models.Relationship{{ToID: b.ID, Type: models.RelDependsOn}})
+	assertCycle("replace", err, a.ID, b.ID, a.ID)
+
+	cycles, err := s.Plans.DependencyCycles(s.ctx, plan.ID)
+	if err != nil {
+		t.Fatalf("DependencyCycles: %v", err)
+	}
+	if len(cycles) != 0 {
+		t.Errorf("DependencyCycles: got %v, want none", cycles)
+	}
+	if _, err := s.Plans.DependencyCycles(s.ctx, s.id("missing")); err == nil {
+		t.Error("DependencyCycles of a missing plan should fail")
+	}
+}
+
+func testWaits(t *testing.T, s *suite) {
+	plan := s.addPlan(t, "plan")
+	other := s.addPlan(t, "other")
+	outside := s.addTask(t, "outside", []string{other.ID})
+	blocker := s.addTask(t, "blocker", []string{plan.ID})
+	free := s.addTask(t, "free", []string{plan.ID},
+		models.Relationship{ToID: blocker.ID, Type: models.RelRelatesTo},
+	)
+	waiting := s.addTask(t, "waiting", []string{plan.ID},
+		models.Relationship{ToID: outside.ID, Type: models.RelDependsOn},
+	)
+	if _, _, err := s.Tasks.Update(s.ctx, blocker.ID, 0, nil, nil, nil, nil, nil, []models.Relationship{{ToID: waiting.ID, Type: models.RelBlocks}}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	done := string(models.TaskStatusCompleted)
+	if _, _, err := s.Tasks.Update(s.ctx, outside.ID, 0, nil, &done, nil, nil, nil, nil); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	waits, err := s.Plans.Waits(s.ctx, plan.ID)
+	if err != nil {
+		t.Fatalf("Waits: %v", err)
+	}
+	if len(waits) != 1 {
+		t.Errorf("Waits: got waits for %d tasks, want 1: %+v", len(waits), waits)
+	}
+	if _, ok := waits[free.ID]; ok {
+		t.Errorf("Waits: free task waits for %+v", waits[free.ID])
+	}
+	got := make(map[string]models.Wait)
+	for _, w := range waits[waiting.ID] {
+		got[w.Task.ID] = w
+	}
+	if len(got) != 2 || got[outside.ID].Type != models.RelDependsOn || got[blocker.ID].Type != models.RelBlocks {
+		t.Fatalf("Waits for waiting task: got %+v", waits[waiting.ID])
+	}
+	if got[outside.ID].Task.Status != models.TaskStatusCompleted || got[blocker.ID].Task.Status != models.TaskStatusPending {
+		t.Errorf("Waits for waiting task: got statuses %s and %s", got[outside.ID].Task.Status, got[blocker.ID].Task.Status)
+	}
+	if unfinished := models.Unfinished(waits[waiting.ID]); len(unfinished) != 1 || unfinished[0].Task.ID != blocker.ID {
+		t.Errorf("Unfinished: got %+v", unfinished)
+	}
+
+	// A cancelled task no longer holds up the tasks waiting for it
+	cancelled := string(models.TaskStatusCancelled)
+	if _, _, err := s.Tasks.Update(s.ctx, blocker.ID, 0, nil, &cancelled, nil, nil, nil, nil); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if waits, err = s.Plans.Waits(s.ctx, plan.ID); err != nil {
+		t.Fatalf("Waits: %v", err)
+	}
+	if len(waits[waiting.ID]) != 2 {
+		t.Errorf("Waits for waiting task after cancelling: got %+v", waits[waiting.ID])
+	}
+	if unfinished := models.Unfinished(waits[waiting.ID]); len(unfinished) != 0 {
+		t.Errorf("Unfinished after cancelling: got %+v", unfinished)
+	}
+
+	if _, err := s.Plans.Waits(s.ctx, s.id("missing")); err == nil {
+		t.Error("Waits of a missing plan should fail")
+	}
+}
+
+func testStatusRules(t *testing.T, s *suite) {
+	s.Tasks.SetStatusRules(true)
+	t.Cleanup(func() { s.Tasks.SetStatusRules(false) })
+
+	plan := s.addPlan(t, "plan")
+	a := s.addTask(t, "a", []string{plan.ID})
+	b := s.addTask(t, "b", []string{plan.ID})
+	d := s.addTask(t, "d", []string{plan.ID})
+
+	// changed returns the IDs of the tasks the rules changed, each with its
+	// new status, after checking that each change is stored
+	changed := func(changes models.StatusChanges) map[string]models.TaskStatus {
+		t.Helper()
+		got := make(map[string]models.TaskStatus)
+		for _, c := range changes.Tasks {
+			got[c.Task.ID] = c.Task.Status
+			if stored, err := s.Tasks.GetByID(s.ctx, c.Task.ID); err != nil || stored.Status != c.Task.Status || stored.Version != c.Task.Version {
+				t.Errorf("change of %s: got %+v, stored %+v, %v", c.Task.ID, c.Task, stored, err)
+			}
+		}
+		return got
+	}
+	// add creates a task with the given relationships and returns it along
+	// with the changes
+	add := func(name string, rels ...models.Relationship) (*models.Task, map[string]models.TaskStatus) {
+		t.Helper()
+		task, changes, err := s.Tasks.Add(s.ctx, models.Task{ID: s.id(name), Content: name}, []string{plan.ID}, rels, nil, nil)
+		if err != nil {
+			t.Fatalf("Add task %s: %v", name, err)
+		}
+		s.tasks = append(s.tasks, task.ID)
+		got := changed(changes)
+		if status, ok := got[task.ID]; ok && task.Status != status {
+			t.Errorf("Add returned status %s, the rules set %s", task.Status, status)
+		}
+		return task, got
+	}
+	// update sets a status, if any, and returns the changes
+	update := func(id string, status models.TaskStatus, rels ...models.Relationship) map[string]models.TaskStatus {
+		t.Helper()
+		var statusArg *string
+		if status != "" {
+			st := string(status)
+			statusArg = &st
+		}
+		updated, changes, err := s.Tasks.Update(s.ctx, id, 0, nil, statusArg, nil, nil, nil, rels)
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got := changed(changes)
+		if status, ok := got[id]; ok && updated.Status != status {
+			t.Errorf("Update returned status %s, the rules set %s", updated.Status, status)
+		}
+		return got
+	}
+	expect := func(name string, got map[string]models.TaskStatus, want map[string]models.TaskStatus) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Errorf("%s: got changes %v, want %v", name, got, want)
+			return
+		}
+		for id, status := range want {
+			if got[id] != status {
+				t.Errorf("%s: got changes %v, want %v", name, got, want)
+				return
+			}
+		}
+	}
+
+	// A new task that waits for an unfinished one is created blocked
+	c, changes := add("c", models.Relationship{ToID: a.ID, Type: models.RelDependsOn})
+	expect("c created depending on a", changes, map[string]models.TaskStatus{c.ID: models.TaskStatusBlocked})
+
+	// New relationships block the tasks they make wait
+	expect("d depends on a", update(d.ID, "", models.Relationship{ToID: a.ID, Type: models.RelDependsOn}),
+		map[string]models.TaskStatus{d.ID: models.TaskStatusBlocked})
+	expect("b blocks c", update(b.ID, "", models.Relationship{ToID: c.ID, Type: models.RelBlocks}), nil)
+
+	// Completing a unblocks d; c still waits for b
+	expect("a completed", update(a.ID, models.TaskStatusCompleted),
+		map[string]models.TaskStatus{d.ID: models.TaskStatusPending})
+	expect("b in progress", update(b.ID, models.TaskStatusInProgress), nil)
+	expect("b completed", update(b.ID, models.TaskStatusCompleted),
+		map[string]models.TaskStatus{c.ID: models.TaskStatusPending})
+
+	// Reopening a blocks its pending dependants, but not those in progress
+	expect("c in progress", update(c.ID, models.TaskStatusInProgress), nil)
+	expect("a reopened", update(a.ID, models.TaskStatusPending),
+		map[string]models.TaskStatus{d.ID: models.TaskStatusBlocked})
+
+	// A status set on purpose is kept
+	expect("d set pending", update(d.ID, models.TaskStatusPending), nil)
+	if got, _ := s.Tasks.GetByID(s.ctx, d.ID); got == nil || got.Status != models.TaskStatusPending {
+		t.Errorf("d: got %+v, want pending", got)
+	}
+
+	// A new task blocks the pending tasks it BLOCKS
+	e, changes := add("e", models.Relationship{ToID: d.ID, Type: models.RelBlocks})
+	expect("e created blocking d", changes, map[string]models.TaskStatus{d.ID: models.TaskStatusBlocked})
+
+	// Cancelled tasks count as finished, and deleting a task unblocks those
+	// left waiting only for finished ones
+	expect("a cancelled", update(a.ID, models.TaskStatusCancelled), nil)
+	deleted, err := s.Tasks.Delete(s.ctx, e.ID)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	expect("e deleted", changed(deleted), map[string]models.TaskStatus{d.ID: models.TaskStatusPending})
+
+	// Deleting a plan unblocks the tasks left waiting for nothing
+	doomed := s.addPlan(t, "doomed")
+	x := s.addTask(t, "x", []string{doomed.ID})
+	f, changes := add("f", models.Relationship{ToID: x.ID, Type: models.RelDependsOn})
+	expect("f created depending on x", changes, map[string]models.TaskStatus{f.ID: models.TaskStatusBlocked})
+	_, deleted, err = s.Plans.Delete(s.ctx, doomed.ID, models.PlanDeleteOptions{})
+	if err != nil {
+		t.Fatalf("Delete plan: %v", err)
+	}
+	expect("doomed deleted", changed(deleted), map[string]models.TaskStatus{f.ID: models.TaskStatusPending})
+
+	s.Tasks.SetStatusRules(false)
+	expect("rules off", update(a.ID, models.TaskStatusInProgress), nil)
+	g, changes := add("g", models.Relationship{ToID: a.ID, Type: models.RelDependsOn})
+	expect("rules off, g created", changes, nil)
+	if g.Status != models.TaskStatusPending {
+		t.Errorf("g: got %s, want pending", g.Status)
+	}
+}
+
+func testPlanProgress(t *testing.T, s *suite) {
+	plan := s.addPlan(t, "plan")
+	empty := s.addPlan(t, "empty")
+	var last *models.Task
+	for i, status := range []models.TaskStatus{"", models.TaskStatusCancelled, models.TaskStatusBlocked, models.TaskStatusCompleted, models.TaskStatusCompleted} {
+		task := s.addTask(t, fmt.Sprintf("task %d", i), []string{plan.ID})
+		if status == "" {
+			continue
+		}
+		st := string(status)
+		updated, _, err := s.Tasks.Update(s.ctx, task.ID, 0, nil, &st, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		last = updated
+	}
+
+	progress, err := s.Plans.Progress(s.ctx, []string{plan.ID, empty.ID, s.id("missing")})
+	if err != nil {
+		t.Fatalf("Progress: %v", err)
+	}
+	if len(progress) != 2 {
+		t.Errorf("Progress: got %d plans, want 2", len(progress))
+	}
+
+	got := progress[plan.ID]
+	if got.Total != 5 || got.Blocked != 1 || got.ByStatus[models.TaskStatusCompleted] != 2 || got.ByStatus[models.TaskStatusPending] != 1 || got.ByStatus[models.TaskStatusCancelled] != 1 {
+		t.Errorf("Progress of plan: got %+v", got)
+	}
+	if got.PercentComplete != 50 {
+		t.Errorf("PercentComplete: got %v, want 50", got.PercentComplete)
+	}
+	if !got.LastActivity.Equal(last.UpdatedAt) {
+		t.Errorf("LastActivity: got %v, want %v", got.LastActivity, last.UpdatedAt)
+	}
+
+	got = progress[empty.ID]
+	if got.Total != 0 || got.PercentComplete != 0 || len(got.ByStatus) != len(models.ValidTaskStatuses) || !got.LastActivity.Equal(empty.UpdatedAt) {
+		t.Errorf("Progress of empty plan: got %+v", got)
+	}
+}
+
+func testPlanCompletion(t *testing.T, s *suite) {
+	s.Tasks.SetPlanCompletion(true)
+	t.Cleanup(func() { s.Tasks.SetPlanCompletion(false) })
+
+	plan := s.addPlan(t, "plan")
+	other := s.addPlan(t, "other")
+	draft := s.addPlan(t, "draft")
+	a := s.addTask(t, "a", []string{plan.ID})
+	b := s.addTask(t, "b", []string{plan.ID, other.ID})
+	c := s.addTask(t, "c", []string{draft.ID})
+	status := string(models.PlanStatusDraft)
+	if _, err := s.Plans.Update(s.ctx, draft.ID, 0, nil, nil, &status, nil, nil, nil); err != nil {
+		t.Fatalf("Update plan: %v", err)
+	}
+
+	// update sets the status of a task and returns the IDs of the plans plan
+	// completion changed, each with its new status
+	update := func(id string, status models.TaskStatus) map[string]models.PlanStatus {
+		t.Helper()
+		st := string(status)
+		_, changes, err := s.Tasks.Update(s.ctx, id, 0, nil, &st, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got := make(map[string]models.PlanStatus)
+		for _, c := range changes.Plans {
+			got[c.Plan.ID] = c.Plan.Status
+			if stored, err := s.Plans.GetByID(s.ctx, c.Plan.ID); err != nil || stored.Status != c.Plan.Status || stored.Version != c.Plan.Version {
+				t.Errorf("change of %s: got %+v, stored %+v, %v", c.Plan.ID, c.Plan, stored, err)
+			}
+		}
+		return got
+	}
+	expect := func(name string, got map[string]models.PlanStatus, want map[string]models.PlanStatus) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Errorf("%s: got changes %v, want %v", name, got, want)
+			return
+		}
+		for id, status := range want {
+			if got[id] != status {
+				t.Errorf("%s: got changes %v, want %v", name, got, want)
+				return
+			}
+		}
+	}
+
+	expect("a completed", update(a.ID, models.TaskStatusCompleted), nil)
+	expect("b cancelled", update(b.ID, models.TaskStatusCancelled),
+		map[string]models.PlanStatus{plan.ID: models.PlanStatusCompleted, other.ID: models.PlanStatusCompleted})
+	expect("a reopened", update(a.ID, models.TaskStatusInProgress),
+		map[string]models.PlanStatus{plan.ID: models.PlanStatusActive})
+	expect("a completed again", update(a.ID, models.TaskStatusCompleted),
+		map[string]models.PlanStatus{plan.ID: models.PlanStatusCompleted})
+
+	// Only active and completed plans change
+	expect("c completed", update(c.ID, models.TaskStatusCompleted), nil)
+
+	// planChanges returns the IDs of the plans in changes, each with its new
+	// status
+	planChanges := func(changes models.StatusChanges) map[string]models.PlanStatus {
+		got := make(map[string]models.PlanStatus)
+		for _, c := range changes.Plans {
+			got[c.Plan.ID] = c.Plan.Status
+		}
+		return got
+	}
+
+	// A new unfinished task reopens its plans, and deleting it completes them
+	// again
+	d, changes, err := s.Tasks.Add(s.ctx, models.Task{ID: s.id("d"), Content: "d"}, []string{plan.ID}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	s.tasks = append(s.tasks, d.ID)
+	expect("d created", planChanges(changes), map[string]models.PlanStatus{plan.ID: models.PlanStatusActive})
+	if changes, err = s.Tasks.Delete(s.ctx, d.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	expect("d deleted", planChanges(changes), map[string]models.PlanStatus{plan.ID: models.PlanStatusCompleted})
+
+	// Tasks moved by a plan deletion reopen the target plan
+	source := s.addPlan(t, "source")
+	s.addTask(t, "moved", []string{source.ID})
+	_, changes, err = s.Plans.Delete(s.ctx, source.ID, models.PlanDeleteOptions{OrphanTasks: models.OrphanTasksMove, TargetPlanID: plan.ID})
+	if err != nil {
+		t.Fatalf("Delete plan: %v", err)
+	}
+	expect("source deleted", planChanges(changes), map[string]models.PlanStatus{plan.ID: models.PlanStatusActive})
+	if stored, err := s.Plans.GetByID(s.ctx, plan.ID); err != nil || stored.Status != models.PlanStatusActive {
+		t.Errorf("plan: got %+v, %v, want active", stored, err)
+	}
+
+	s.Tasks.SetPlanCompletion(false)
+	expect("completion off", update(b.ID, models.TaskStatusPending), nil)
+}
+
+func testCascadeDelete(t *testing.T, s *suite) {
+	plan := s.addPlan(t, "plan")
+	other := s.addPlan(t, "other")
+	exclusive := s.addTask(t, "exclusive", []string{plan.ID})
+	shared := s.addTask(t, "shared", []string{plan.ID, other.ID})
+
+	deleted, _, err := s.Plans.Delete(s.ctx, plan.ID, models.PlanDeleteOptions{})
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("tasks deleted: got %d, want 1", deleted)
+	}
+
+	if got, _ := s.Plans.GetByID(s.ctx, plan.ID); got != nil {
+		t.Error("plan should be deleted")
+	}
+	if got, _ := s.Tasks.GetByID(s.ctx, exclusive.ID); got != nil {
+		t.Error("exclusive task should be deleted")
+	}
+	got, plans, err := s.Tasks.GetWithPlans(s.ctx, shared.ID)
+	if err != nil || got == nil {
+		t.Fatalf("shared task should survive: %v, %v", got, err)
+	}
+	if len(plans) != 1 || plans[0].ID != other.ID {
+		t.Errorf("shared task plans: got %+v", plans)
+	}
+}
+
+func testDeletePreview(t *testing.T, s *suite) {
+	plan := s.addPlan(t, "plan")
+	other := s.addPlan(t, "other")
+	exclusive := s.addTask(t, "exclusive", []string{plan.ID})
+	shared := s.addTask(t, "shared", []string{plan.ID, other.ID})
+	mem := s.addMemory(t, "mem", "about the exclusive task", models.Relationship{ToID: exclusive.ID, Type: models.RelReferences})
+
+	edgeIDs := func(edges []models.Edge) []string {
+		var ids []string
+		for _, e := range edges {
+			ids = append(ids, e.ID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+	partOf := models.EdgeID(exclusive.ID, plan.ID, models.RelPartOf)
+	sharedPartOf := models.EdgeID(shared.ID, plan.ID, models.RelPartOf)
+	references := models.EdgeID(mem.ID, exclusive.ID, models.RelReferences)
+
+	preview, err := s.Plans.PreviewDelete(s.ctx, plan.ID, models.PlanDeleteOptions{})
+	if err != nil || preview == nil {
+		t.Fatalf("plan PreviewDelete: %v, %v", preview, err)
+	}
+	if ids := preview.NodeIDs(); !slices.Equal(ids, []string{plan.ID, exclusive.ID}) {
+		t.Errorf("plan preview nodes: got %v", ids)
+	}
+	want := []string{partOf, sharedPartOf, references}
+	slices.Sort(want)
+	if got := edgeIDs(preview.Edges); !slices.Equal(got, want) {
+		t.Errorf("plan preview edges: got %v, want %v", got, want)
+	}
+
+	detached, err := s.Plans.PreviewDelete(s.ctx, plan.ID, models.PlanDeleteOptions{OrphanTasks: models.OrphanTasksDetach})
+	if err != nil || detached == nil {
+		t.Fatalf("detach PreviewDelete: %v, %v", detached, err)
+	}
+	if ids := detached.NodeIDs(); !slices.Equal(ids, []string{plan.ID}) {
+		t.Errorf("detach preview nodes: got %v", ids)
+	}
+
+	if task, err := s.Tasks.PreviewDelete(s.ctx, exclusive.ID); err != nil || task == nil {
+		t.Errorf("task PreviewDelete: %v, %v", task, err)
+	} else if got := edgeIDs(task.Edges); len(got) != 2 || !slices.Contains(got, partOf) || !slices.Contains(got, references) {
+		t.Errorf("task preview edges: got %v, want %s and %s", got, partOf, references)
+	}
+	if memory, err := s.Memories.PreviewDelete(s.ctx, mem.ID); err != nil || memory == nil {
+		t.Errorf("memory PreviewDelete: %v, %v", memory, err)
+	} else if ids := memory.NodeIDs(); !slices.Equal(ids, []string{mem.ID}) || len(memory.Edges) != 1 {
+		t.Errorf("memory preview: got nodes %v, edges %+v", ids, memory.Edges)
+	}
+
+	// Previews of missing nodes, or of nodes of another kind, are nil
+	if got, err := s.Tasks.PreviewDelete(s.ctx, plan.ID); got != nil || err != nil {
+		t.Errorf("task PreviewDelete of a plan: got %+v, %v", got, err)
+	}
+	if got, err := s.Memories.PreviewDelete(s.ctx, s.id("missing")); got != nil || err != nil {
+		t.Errorf("PreviewDelete of a missing memory: got %+v, %v", got, err)
+	}
+	if _, err := s.Plans.PreviewDelete(s.ctx, plan.ID, models.PlanDeleteOptions{OrphanTasks: models.OrphanTasksMove, TargetPlanID: s.id("missing")}); err == nil {
+		t.Error("PreviewDelete moving tasks to a missing plan should fail")
+	}
+
+	// Previewing changes nothing
+	if got, _ := s.Tasks.GetByID(s.ctx, exclusive.ID); got == nil {
+		t.Error("PreviewDelete deleted a task")
+	}
+	if trash, _ := s.Memories.ListTrash(s.ctx); slices.ContainsFunc(trash, func(e models.TrashEntry) bool { return e.ID == plan.ID || e.ID == mem.ID }) {
+		t.Error("PreviewDelete moved something to the trash")
+	}
+
+	// The delete removes exactly what the preview said
+	if _, _, err := s.Plans.Delete(s.ctx, plan.ID, models.PlanDeleteOptions{}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	trash, err := s.Memories.ListTrash(s.ctx)
+	if err != nil {
+		t.Fatalf("ListTrash: %v", err)
+	}
+	i := slices.IndexFunc(trash, func(e models.TrashEntry) bool { return e.ID == plan.ID })
+	if i < 0 {
+		t.Fatal("deleted plan not in the trash")
+	}
+	if !slices.Equal(trash[i].NodeIDs(), preview.NodeIDs()) || !slices.Equal(edgeIDs(trash[i].Edges), edgeIDs(preview.Edges)) {
+		t.Errorf("trash entry: got %+v, previewed %+v", trash[i], preview)
+	}
+}
+
+func testKeepOrphanTasks(t *testing.T, s *suite) {
+	plan := s.addPlan(t, "plan")
+	target := s.addPlan(t, "target")
+	first := s.addTask(t, "first", []string{plan.ID})
+	second := s.addTask(t, "second", []string{plan.ID})
+	existing := s.addTask(t, "existing", []string{target.ID})
+	if err := s.Tasks.UpdatePositions(s.ctx, plan.ID, map[string]float64{first.ID: 2, second.ID: 1}); err != nil {
+		t.Fatalf("UpdatePositions: %v", err)
+	}
+
+	invalid := []models.PlanDeleteOptions{
+		{OrphanTasks: models.OrphanTasksMove},
+		{OrphanTasks: models.OrphanTasksMove, TargetPlanID: plan.ID},
+		{OrphanTasks: models.OrphanTasksMove, TargetPlanID: s.id("missing")},
+		{OrphanTasks: models.OrphanTasksMove, TargetPlanID: first.ID},
+		{OrphanTasks: "keep"},
+	}
+	for _, opts := range invalid {
+		if _, _, err := s.Plans.Delete(s.ctx, plan.ID, opts); err == nil {
+			t.Errorf("Delete with %+v should fail", opts)
+		}
+	}
+	if got, _ := s.Plans.GetByID(s.ctx, plan.ID); got == nil {
+		t.Fatal("a failed Delete removed the plan")
+	}
+
+	// Moved tasks are appended to the target plan in their order
+	deleted, _, err := s.Plans.Delete(s.ctx, plan.ID, models.PlanDeleteOptions{OrphanTasks: models.OrphanTasksMove, TargetPlanID: target.ID})
+	if err != nil || deleted != 0 {
+		t.Fatalf("Delete moving tasks: got %d, %v", deleted, err)
+	}
+	if got, _ := s.Plans.GetByID(s.ctx, plan.ID); got != nil {
+		t.Error("plan should be deleted")
+	}
+	_, tasks, err := s.Plans.GetWithTasks(s.ctx, target.ID)
+	if err != nil {
+		t.Fatalf("GetWithTasks: %v", err)
+	}
+	var order []string
+	for _, task := range tasks {
+		order = append(order, task.Task.ID)
+	}
+	if want := []string{existing.ID, second.ID, first.ID}; !slices.Equal(order, want) {
+		t.Errorf("target plan tasks: got %v, want %v", order, want)
+	}
+
+	// Detached tasks are kept in no plan
+	detach := s.addPlan(t, "detach")
+	kept := s.addTask(t, "kept", []string{detach.ID})
+	if deleted, _, err := s.Plans.Delete(s.ctx, detach.ID, models.PlanDeleteOptions{OrphanTasks: models.OrphanTasksDetach}); err != nil || deleted != 0 {
+		t.Fatalf("Delete detaching tasks: got %d, %v", deleted, err)
+	}
+	got, plans, err := s.Tasks.GetWithPlans(s.ctx, kept.ID)
+	if err != nil || got == nil || len(plans) != 0 {
+		t.Errorf("detached task: got %+v in %+v, %v", got, plans, err)
+	}
+	trash, _ := s.Memories.ListTrash(s.ctx)
+	i := slices.IndexFunc(trash, func(e models.TrashEntry) bool { return e.ID == detach.ID })
+	if i < 0 || len(trash[i].Nodes) != 1 || len(trash[i].Edges) != 1 {
+		t.Fatalf("detached plan trash entry: got %+v", trash)
+	}
+}
+
+func testTrash(t *testing.T, s *suite) {
+	plan := s.addPlan(t, "plan")
+	other := s.addPlan(t, "other")
+	first := s.addTask(t, "first", []string{plan.ID})
+	second := s.addTask(t, "second", []string{plan.ID}, models.Relationship{ToID: first.ID, Type: models.RelDependsOn})
+	shared := s.addTask(t, "shared", []string{plan.ID, other.ID})
+	if err := s.Tasks.UpdatePositions(s.ctx, plan.ID, map[string]float64{first.ID: 5.5, second.ID: 2.25}); err != nil {
+		t.Fatalf("UpdatePositions: %v", err)
+	}
+	mem := s.addMemory(t, "note", "note "+s.word(), models.Relationship{ToID: first.ID, Type: models.RelRelatesTo, RelationshipProperties: models.RelationshipProperties{Reason: "context"}})
+	content := "note " + s.word() + " revised"
+	mem, err := s.Memories.Update(s.ctx, mem.ID, 0, &content, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	_, before, err := s.Plans.GetWithTasks(s.ctx, plan.ID)
+	if err != nil {
+		t.Fatalf("GetWithTasks: %v", err)
+	}
+
+	// Deleting the plan trashes it with the tasks that belong to no other plan
+	if deleted, _, err := s.Plans.Delete(s.ctx, plan.ID, models.PlanDeleteOptions{}); err != nil || deleted != 2 {
+		t.Fatalf("plan Delete: got %d, %v, want 2 tasks", deleted, err)
+	}
+	if got, _ := s.Tasks.GetByID(s.ctx, first.ID); got != nil {
+		t.Error("a deleted task should not be found")
+	}
+	if edges, _ := s.Memories.ListRelationships(s.ctx, mem.ID, "", "both"); len(edges) != 0 {
+		t.Errorf("relationships to a deleted task: got %+v", edges)
+	}
+	if err := s.Memories.Delete(s.ctx, mem.ID); err != nil {
+		t.Fatalf("memory Delete: %v", err)
+	}
+	if results, _, _ := s.Memories.Search(s.ctx, s.word(), models.SearchOptions{}); len(results) != 0 {
+		t.Errorf("Search found a deleted memory: %+v", results)
+	}
+
+	trash, err := s.Memories.ListTrash(s.ctx)
+	if err != nil {
+		t.Fatalf("ListTrash: %v", err)
+	}
+	var ours []models.TrashEntry
+	for _, entry := range trash {
+		if entry.ID == plan.ID || entry.ID == mem.ID {
+			ours = append(ours, entry)
+		}
+	}
+	if len(ours) != 2 || ours[0].ID != mem.ID || ours[1].ID != plan.ID {
+		t.Fatalf("ListTrash: got %+v, want the memory then the plan", ours)
+	}
+	if ids := ours[1].NodeIDs(); len(ids) != 3 || ids[0] != plan.ID || !slices.Contains(ids, first.ID) || !slices.Contains(ids, second.ID) {
+		t.Errorf("plan entry nodes: got %v", ids)
+	}
+	if ours[1].Kind != models.KindPlan || ours[1].Title != "plan" {
+		t.Errorf("plan entry: got kind %q, title %q", ours[1].Kind, ours[1].Title)
+	}
+
+	// Restoring the plan brings back its tasks, order and dependencies. The
+	// memory's relationship waits until the memory is restored too.
+	if _, err := s.Memories.Restore(s.ctx, plan.ID); err != nil {
+		t.Fatalf("Restore plan: %v", err)
+	}
+	restored, after, err := s.Plans.GetWithTasks(s.ctx, plan.ID)
+	if err != nil || restored == nil {
+		t.Fatalf("GetWithTasks after restore: %v, %v", restored, err)
+	}
+	if restored.Version != plan.Version || !restored.CreatedAt.Equal(plan.CreatedAt) {
+		t.Errorf("restored plan: got %+v, want %+v", restored, plan)
+	}
+	if len(after) != len(before) {
+		t.Fatalf("restored tasks: got %+v, want %+v", after, before)
+	}
+	for i := range before {
+		if after[i].Task.ID != before[i].Task.ID || after[i].Position != before[i].Position || !slices.Equal(after[i].DependsOn, before[i].DependsOn) {
+			t.Errorf("restored task %d: got %+v, want %+v", i, after[i], before[i])
+		}
+	}
+	if _, plans, _ := s.Tasks.GetWithPlans(s.ctx, shared.ID); len(plans) != 2 {
+		t.Errorf("shared task plans after restore: got %+v", plans)
+	}
+	if revisions, err := s.Memories.ListRevisions(s.ctx, first.ID); err != nil || len(revisions) != 1 {
+		t.Errorf("restored task revisions: got %+v, %v", revisions, err)
+	}
+
+	if _, err := s.Memories.Restore(s.ctx, mem.ID); err != nil {
+		t.Fatalf("Restore memory: %v", err)
+	}
+	got, err := s.Memories.GetByID(s.ctx, mem.ID)
+	if err != nil || got == nil || got.Version != 2 || got.Content != content {
+		t.Errorf("restored memory: got %+v, %v", got, err)
+	}
+	edges, err := s.Memories.ListRelationships(s.ctx, mem.ID, "", "outgoing")
+	if err != nil || len(edges) != 1 || edges[0].ToID != first.ID || edges[0].Reason != "context" {
+		t.Errorf("restored memory relationships: got %+v, %v", edges, err)
+	}
+	if results, _, _ := s.Memories.Search(s.ctx, s.word(), models.SearchOptions{}); len(results) != 1 {
+		t.Errorf("Search after restore: got %+v", results)
+	}
+
+	if _, err := s.Memories.Restore(s.ctx, mem.ID); err == nil {
+		t.Error("Restore of an ID not in the trash should fail")
+	}
+
+	// A node cannot be restored over one that took its ID
+	if _, err := s.Tasks.Delete(s.ctx, shared.ID); err != nil {
+		t.Fatalf("task Delete: %v", err)
+	}
+	if _, err := s.Memories.Add(s.ctx, models.Memory{ID: shared.ID, Content: "taken"}, nil); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	s.memories = append(s.memories, shared.ID)
+	if _, err := s.Memories.Restore(s.ctx, shared.ID); err == nil {
+		t.Error("Restore over an existing ID should fail")
+	}
+
+	// Purging removes entries deleted before the cutoff
+	if purged, err := s.Memories.PurgeTrash(s.ctx, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("PurgeTrash: %v", err)
+	} else if trash, _ := s.Memories.ListTrash(s.ctx); !slices.ContainsFunc(trash, func(e models.TrashEntry) bool { return e.ID == shared.ID }) {
+		t.Errorf("PurgeTrash removed a recent entry (purged %d)", purged)
+	}
+	if purged, err := s.Memories.PurgeTrash(s.ctx, time.Now().Add(time.Minute)); err != nil || purged < 1 {
+		t.Fatalf("PurgeTrash: got %d, %v", purged, err)
+	}
+	if _, err := s.Memories.Restore(s.ctx, shared.ID); err == nil {
+		t.Error("Restore of a purged entry should fail")
+	}
+}
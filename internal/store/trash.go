@@ -0,0 +1,82 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+)
+
+// Restoration says how to bring back a trash entry: which of its
+// relationships to re-create, and which to hand over to other entries.
+type Restoration struct {
+	// Edges are the relationships whose endpoints both exist once the
+	// entry's nodes are back.
+	Edges []models.Edge
+	// Deferred holds, keyed by trash entry ID, the relationships to nodes
+	// still in the trash. Adding them to that entry restores them with it.
+	Deferred map[string][]models.Edge
+	// Skipped are the relationships to nodes that are neither live nor in
+	// the trash, because they were purged. They cannot be restored.
+	Skipped []models.Edge
+}
+
+// RestoreIDs returns the IDs whose existence PlanRestore needs: those of the
+// nodes of entry and of the other ends of its relationships.
+func RestoreIDs(entry models.TrashEntry) []string {
+	ids := entry.NodeIDs()
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	for _, e := range entry.Edges {
+		for _, id := range []string{e.FromID, e.ToID} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// PlanRestore works out how to restore entry, given which of the RestoreIDs
+// exist and the other entries in the trash. It fails if one of the entry's
+// nodes exists again. Relationships to nodes that are neither live nor in the
+// trash, because they were purged, are skipped. trash needs only the entries
+// holding the RestoreIDs that do not exist.
+func PlanRestore(entry models.TrashEntry, existing map[string]bool, trash []models.TrashEntry) (*Restoration, error) {
+	live := make(map[string]bool, len(existing)+len(entry.Nodes))
+	for id, ok := range existing {
+		live[id] = ok
+	}
+	for _, id := range entry.NodeIDs() {
+		if existing[id] {
+			return nil, fmt.Errorf("cannot restore %s: a node with ID %s exists", entry.ID, id)
+		}
+		live[id] = true
+	}
+
+	r := &Restoration{Deferred: make(map[string][]models.Edge)}
+	for _, e := range entry.Edges {
+		if live[e.FromID] && live[e.ToID] {
+			r.Edges = append(r.Edges, e)
+			continue
+		}
+		other := e.FromID
+		if live[other] {
+			other = e.ToID
+		}
+		deferred := false
+		for _, t := range trash {
+			if t.ID != entry.ID && t.Contains(other) {
+				r.Deferred[t.ID] = append(r.Deferred[t.ID], e)
+				deferred = true
+				break
+			}
+		}
+		if !deferred {
+			r.Skipped = append(r.Skipped, e)
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+)
+
+func TestPlanRestore(t *testing.T) {
+	memory := func(id string) models.TrashedNode {
+		return models.TrashedNode{Kind: models.KindMemory, Memory: &models.Memory{ID: id}}
+	}
+	live := models.NewEdge("note", "live", models.RelRelatesTo, models.RelationshipProperties{})
+	trashed := models.NewEdge("note", "trashed", models.RelRelatesTo, models.RelationshipProperties{})
+	purged := models.NewEdge("purged", "note", models.RelRelatesTo, models.RelationshipProperties{})
+	entry := models.NewTrashEntry([]models.TrashedNode{memory("note")}, []models.Edge{live, trashed, purged}, time.Now())
+	other := models.NewTrashEntry([]models.TrashedNode{memory("trashed")}, nil, time.Now())
+
+	r, err := PlanRestore(entry, map[string]bool{"live": true}, []models.TrashEntry{entry, other})
+	if err != nil {
+		t.Fatalf("PlanRestore: %v", err)
+	}
+	if len(r.Edges) != 1 || r.Edges[0].ID != live.ID {
+		t.Errorf("Edges: got %+v, want the one to the live node", r.Edges)
+	}
+	if d := r.Deferred[other.ID]; len(d) != 1 || d[0].ID != trashed.ID {
+		t.Errorf("Deferred: got %+v, want the one to the trashed node", r.Deferred)
+	}
+	if len(r.Skipped) != 1 || r.Skipped[0].ID != purged.ID {
+		t.Errorf("Skipped: got %+v, want the one from the purged node", r.Skipped)
+	}
+
+	if _, err := PlanRestore(entry, map[string]bool{"note": true}, nil); err == nil {
+		t.Error("PlanRestore over an existing node should fail")
+	}
+}
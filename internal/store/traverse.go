@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+
+	"github.com/Thomas-Fitz/associate/internal/models"
+)
+
+// Step is a relationship leading away from a node on the frontier of a
+// traversal.
+type Step struct {
+	Edge models.Relationship // As stored, so the frontier node may be either end
+	Node models.Memory       // The node at the other end, flattened as for get_related
+}
+
+// Expander returns the steps leading away from the frontier nodes that pass
+// a traversal's relationship type and direction filters, in a stable order.
+type Expander func(ctx context.Context, frontier []string) ([]Step, error)
+
+// Traverse walks breadth-first from rootID for up to depth hops, capped at
+// models.MaxRelatedDepth, expanding a whole depth level per call to expand.
+// Each node is reported once, with the first path found to it, which is a
+// shortest one. Results are ordered by depth, then in expansion order.
+func Traverse(ctx context.Context, rootID string, depth int, expand Expander) ([]models.RelatedMemoryResult, error) {
+	depth = min(depth, models.MaxRelatedDepth)
+
+	var results []models.RelatedMemoryResult
+	// reached holds the path to every node visited so far
+	reached := map[string]models.RelatedMemoryResult{rootID: {Path: []string{rootID}}}
+	frontier := []string{rootID}
+
+	for d := 1; d <= depth && len(frontier) > 0; d++ {
+		steps, err := expand(ctx, frontier)
+		if err != nil {
+			return nil, err
+		}
+
+		var nextFrontier []string
+		for _, step := range steps {
+			fromID, direction := step.Edge.FromID, "outgoing"
+			if step.Node.ID == step.Edge.FromID {
+				fromID, direction = step.Edge.ToID, "incoming"
+			}
+			from, ok := reached[fromID]
+			if _, seen := reached[step.Node.ID]; !ok || seen {
+				continue
+			}
+			// Only nodes of the current frontier are one hop shorter
+			if len(from.Path) != d {
+				continue
+			}
+
+			result := models.RelatedMemoryResult{
+				Memory:       step.Node,
+				RelationType: string(step.Edge.Type),
+				Direction:    direction,
+				Depth:        d,
+				Path:         append(append([]string(nil), from.Path...), step.Node.ID),
+				Edges:        append(append([]models.Relationship(nil), from.Edges...), step.Edge),
+			}
+			results = append(results, result)
+			reached[step.Node.ID] = result
+			nextFrontier = append(nextFrontier, step.Node.ID)
+		}
+		frontier = nextFrontier
+	}
+
+	return results, nil
+}
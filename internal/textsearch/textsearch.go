@@ -0,0 +1,329 @@
+// Package textsearch parses search queries and ranks text against them. It
+// backs the in-memory store and the SQLite fuzzy fallback, mirroring what
+// PostgreSQL's websearch_to_tsquery, ts_rank and pg_trgm do for AGE.
+package textsearch
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Highlight markers wrapped around matched words in snippets.
+const (
+	HighlightStart = "**"
+	HighlightEnd   = "**"
+)
+
+// snippetWords is the length of a snippet window, in words.
+const snippetWords = 24
+
+// Query is a parsed search query. Bare words are Terms, double-quoted text is
+// a Phrase, and words prefixed with '-' are Excluded. All words are lower case.
+type Query struct {
+	Terms    []string
+	Phrases  [][]string
+	Excluded []string
+}
+
+// Parse splits a query in web-search syntax into terms, phrases and exclusions.
+func Parse(s string) Query {
+	var q Query
+	for i, part := range strings.Split(s, `"`) {
+		if i%2 == 1 {
+			// Inside quotes
+			if words := Tokenize(part); len(words) == 1 {
+				q.Terms = append(q.Terms, words[0])
+			} else if len(words) > 1 {
+				q.Phrases = append(q.Phrases, words)
+			}
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			excluded := strings.HasPrefix(field, "-")
+			for _, w := range Tokenize(field) {
+				if excluded {
+					q.Excluded = append(q.Excluded, w)
+				} else {
+					q.Terms = append(q.Terms, w)
+				}
+			}
+		}
+	}
+	return q
+}
+
+// Empty reports whether the query has nothing to match.
+func (q Query) Empty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0
+}
+
+// token is a word in a text and its byte range.
+type token struct {
+	word       string
+	start, end int
+}
+
+// tokens splits text into lower-cased runs of letters and digits.
+func tokens(text string) []token {
+	var toks []token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		} else if !isWord && start >= 0 {
+			toks = append(toks, token{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		toks = append(toks, token{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return toks
+}
+
+// Tokenize splits text into lower-cased words.
+func Tokenize(text string) []string {
+	toks := tokens(text)
+	words := make([]string, len(toks))
+	for i, t := range toks {
+		words[i] = t.word
+	}
+	return words
+}
+
+// stemSuffixes are stripped by Stem, longest first. A replacement follows each suffix.
+var stemSuffixes = [][2]string{
+	{"ational", "ate"}, {"ations", "ate"}, {"ation", "ate"}, {"ities", ""}, {"ity", ""},
+	{"ments", ""}, {"ment", ""}, {"ingly", ""}, {"edly", ""}, {"ings", ""}, {"ing", ""},
+	{"ies", "y"}, {"ied", "y"}, {"ers", ""}, {"er", ""}, {"ed", ""}, {"es", ""}, {"ly", ""}, {"s", ""},
+}
+
+// Stem reduces an English word to a crude root so that inflections such as
+// "indexes", "indexing" and "indexed" compare equal.
+func Stem(word string) string {
+	for _, s := range stemSuffixes {
+		if strings.HasSuffix(word, s[0]) && len(word)-len(s[0]) >= 3 {
+			word = word[:len(word)-len(s[0])] + s[1]
+			break
+		}
+	}
+	// Drop a trailing "e" so that "cache" and "caching" share a root
+	if len(word) > 3 && strings.HasSuffix(word, "e") {
+		word = word[:len(word)-1]
+	}
+	return word
+}
+
+// Similar reports whether two words are within typo distance of each other:
+// one edit for words of four to seven letters, two for longer words.
+func Similar(a, b string) bool {
+	if a == b {
+		return true
+	}
+	n := min(len(a), len(b))
+	maxDist := 0
+	switch {
+	case n >= 8:
+		maxDist = 2
+	case n >= 4:
+		maxDist = 1
+	}
+	if maxDist == 0 || abs(len(a)-len(b)) > maxDist {
+		return false
+	}
+	return editDistance(a, b) <= maxDist
+}
+
+// editDistance returns the optimal string alignment distance between a and b,
+// counting an adjacent transposition as one edit.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// matchKind describes how a text word matched a query word.
+type matchKind int
+
+const (
+	noMatch matchKind = iota
+	fuzzyMatch
+	exactMatch
+)
+
+// matchWord compares a query word with a text word, after stemming both.
+func matchWord(queryWord, textWord string) matchKind {
+	if Stem(queryWord) == Stem(textWord) {
+		return exactMatch
+	}
+	if Similar(queryWord, textWord) || Similar(Stem(queryWord), Stem(textWord)) {
+		return fuzzyMatch
+	}
+	return noMatch
+}
+
+// Rank scores text against the query. Every term and phrase must match for
+// ok to be true; terms may match with a typo. Texts in which every term
+// matched exactly score in [0.5, 1), texts that needed a fuzzy match score
+// in [0.25, 0.5), and more occurrences score higher within each band.
+func Rank(q Query, text string) (score float64, ok bool) {
+	if q.Empty() {
+		return 0, false
+	}
+	words := Tokenize(text)
+
+	for _, ex := range q.Excluded {
+		for _, w := range words {
+			if Stem(w) == Stem(ex) {
+				return 0, false
+			}
+		}
+	}
+
+	occurrences := 0
+	strict := true
+	for _, term := range q.Terms {
+		best := noMatch
+		for _, w := range words {
+			if m := matchWord(term, w); m != noMatch {
+				occurrences++
+				best = max(best, m)
+			}
+		}
+		if best == noMatch {
+			return 0, false
+		}
+		if best == fuzzyMatch {
+			strict = false
+		}
+	}
+	for _, phrase := range q.Phrases {
+		n := phraseCount(phrase, words)
+		if n == 0 {
+			return 0, false
+		}
+		occurrences += n
+	}
+
+	density := float64(occurrences) / float64(occurrences+1)
+	if strict {
+		return 0.5 + 0.5*density, true
+	}
+	return 0.25 + 0.25*density, true
+}
+
+// phraseCount counts the places where the stems of phrase appear consecutively in words.
+func phraseCount(phrase, words []string) int {
+	count := 0
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		matched := true
+		for j, p := range phrase {
+			if Stem(p) != Stem(words[i+j]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			count++
+		}
+	}
+	return count
+}
+
+// Snippet returns the part of text around the first match of the query, with
+// matched words wrapped in HighlightStart and HighlightEnd. Text cut off at
+// either end is marked with an ellipsis.
+func Snippet(q Query, text string) string {
+	toks := tokens(text)
+	if len(toks) == 0 {
+		return ""
+	}
+
+	highlight := make([]bool, len(toks))
+	first := -1
+	mark := func(i int) {
+		highlight[i] = true
+		if first < 0 || i < first {
+			first = i
+		}
+	}
+	for i, t := range toks {
+		for _, term := range q.Terms {
+			if matchWord(term, t.word) != noMatch {
+				mark(i)
+			}
+		}
+	}
+	for _, phrase := range q.Phrases {
+		for i := 0; i+len(phrase) <= len(toks); i++ {
+			matched := true
+			for j, p := range phrase {
+				if Stem(p) != Stem(toks[i+j].word) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				for j := range phrase {
+					mark(i + j)
+				}
+			}
+		}
+	}
+
+	// Start a few words before the first match
+	from := 0
+	if first > snippetWords/3 {
+		from = first - snippetWords/3
+	}
+	to := min(from+snippetWords, len(toks))
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := toks[from].start
+	for i := from; i < to; i++ {
+		t := toks[i]
+		b.WriteString(text[pos:t.start])
+		if highlight[i] {
+			b.WriteString(HighlightStart + text[t.start:t.end] + HighlightEnd)
+		} else {
+			b.WriteString(text[t.start:t.end])
+		}
+		pos = t.end
+	}
+	if to < len(toks) {
+		b.WriteString("…")
+	} else {
+		b.WriteString(text[pos:])
+	}
+	return strings.TrimSpace(b.String())
+}
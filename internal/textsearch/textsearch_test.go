@@ -0,0 +1,116 @@
+package textsearch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	q := Parse(`Deploy "blue green" -staging rollback "k8s"`)
+
+	if want := []string{"deploy", "rollback", "k8s"}; !reflect.DeepEqual(q.Terms, want) {
+		t.Errorf("Terms = %v, want %v", q.Terms, want)
+	}
+	if want := [][]string{{"blue", "green"}}; !reflect.DeepEqual(q.Phrases, want) {
+		t.Errorf("Phrases = %v, want %v", q.Phrases, want)
+	}
+	if want := []string{"staging"}; !reflect.DeepEqual(q.Excluded, want) {
+		t.Errorf("Excluded = %v, want %v", q.Excluded, want)
+	}
+
+	if !Parse("  -only ").Empty() {
+		t.Error("a query with only exclusions should be empty")
+	}
+}
+
+func TestStem(t *testing.T) {
+	groups := [][]string{
+		{"index", "indexes", "indexing", "indexed"},
+		{"cache", "caching", "caches"},
+		{"token", "tokens"},
+	}
+	for _, group := range groups {
+		root := Stem(group[0])
+		for _, w := range group[1:] {
+			if got := Stem(w); got != root {
+				t.Errorf("Stem(%q) = %q, want %q (as for %q)", w, got, root, group[0])
+			}
+		}
+	}
+}
+
+func TestSimilar(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"authentication", "authentcation", true},
+		{"authentication", "authetnication", true},
+		{"migration", "migratoin", true},
+		{"cat", "car", false},
+		{"deploy", "destroy", false},
+	}
+	for _, tt := range tests {
+		if got := Similar(tt.a, tt.b); got != tt.want {
+			t.Errorf("Similar(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	q := Parse("token refresh")
+
+	both, ok := Rank(q, "Refresh the auth token before it expires; refresh tokens hourly")
+	if !ok {
+		t.Fatal("expected text with both terms to match")
+	}
+	once, ok := Rank(q, "The token refresh job")
+	if !ok {
+		t.Fatal("expected text with both terms once to match")
+	}
+	if both <= once {
+		t.Errorf("more occurrences should rank higher: %v <= %v", both, once)
+	}
+
+	reordered, ok := Rank(Parse("refresh token"), "The token refresh job")
+	if !ok || reordered != once {
+		t.Errorf("word order should not matter: got %v, %v", reordered, ok)
+	}
+
+	typo, ok := Rank(Parse("tokne refresh"), "The token refresh job")
+	if !ok {
+		t.Fatal("expected a single typo to match")
+	}
+	if typo >= once {
+		t.Errorf("fuzzy match should rank below exact: %v >= %v", typo, once)
+	}
+
+	if _, ok := Rank(q, "Only mentions tokens"); ok {
+		t.Error("text missing a term should not match")
+	}
+	if _, ok := Rank(Parse("token -job"), "The token refresh job"); ok {
+		t.Error("excluded word should prevent a match")
+	}
+	if _, ok := Rank(Parse(`"refresh token"`), "The token refresh job"); ok {
+		t.Error("phrase should require word order")
+	}
+	if _, ok := Rank(Parse(`"token refresh"`), "The token refresh job"); !ok {
+		t.Error("phrase in order should match")
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := "Notes: the deploy pipeline runs migrations first, then swaps the blue and green environments once health checks pass on every node in the cluster and traffic drains."
+
+	got := Snippet(Parse("migration"), text)
+	want := "Notes: the deploy pipeline runs **migrations** first, then swaps the blue and green environments once health checks pass on every node in the cluster…"
+	if got != want {
+		t.Errorf("Snippet:\n got %q\nwant %q", got, want)
+	}
+
+	got = Snippet(Parse(`"traffic drains"`), text)
+	want = "…pass on every node in the cluster and **traffic** **drains**."
+	if got != want {
+		t.Errorf("Snippet phrase:\n got %q\nwant %q", got, want)
+	}
+}